@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	// --app-version-from sources
+	appVersionFromGitTag = "git-tag"
+	appVersionFromEnv    = "env:"
+)
+
+// validateAppVersionFrom checks the --app-version-from source
+func validateAppVersionFrom(source string) error {
+	if source == appVersionFromGitTag {
+		return nil
+	}
+	if strings.HasPrefix(source, appVersionFromEnv) && len(source) > len(appVersionFromEnv) {
+		return nil
+	}
+	return fmt.Errorf("invalid --app-version-from %q (must be %s or %s<VAR>)", source, appVersionFromGitTag, appVersionFromEnv)
+}
+
+// resolveAppVersion returns the appVersion from the --app-version-from
+// source, either the latest git tag reachable from the chart's directory
+// or an environment variable
+func resolveAppVersion(source string, chartName string) (string, error) {
+	if strings.HasPrefix(source, appVersionFromEnv) {
+		name := strings.TrimPrefix(source, appVersionFromEnv)
+		v := strings.TrimSpace(os.Getenv(name))
+		if v == "" {
+			return "", fmt.Errorf("could not resolve appVersion: environment variable %s is not set", name)
+		}
+		return v, nil
+	}
+
+	dir, err := localChartDir(chartName)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve appVersion: %s", err)
+	}
+	return gitLatestTag(dir)
+}
+
+// gitLatestTag returns the latest tag reachable from HEAD in the git
+// work tree containing dir
+func gitLatestTag(dir string) (string, error) {
+	out, err := exec.Command("git", "-C", dir, "describe", "--tags", "--abbrev=0").Output()
+	if err != nil {
+		if e, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("could not resolve appVersion: no git tag found for %s: %s", dir, strings.TrimSpace(string(e.Stderr)))
+		}
+		return "", fmt.Errorf("could not resolve appVersion from git tag: %s", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateAppVersionFrom(t *testing.T) {
+	for source, valid := range map[string]bool{
+		"git-tag":     true,
+		"env:APP_TAG": true,
+		"env:":        false,
+		"git":         false,
+		"":            false,
+	} {
+		if err := validateAppVersionFrom(source); (err == nil) != valid {
+			t.Errorf("%q: expected valid=%v, instead got %v", source, valid, err)
+		}
+	}
+}
+
+func TestResolveAppVersion(t *testing.T) {
+	chartsDir, cleanup := setupTestGitCharts(t)
+	defer cleanup()
+
+	// the latest reachable tag wins
+	chartDir := filepath.Join(chartsDir, "a")
+	args := []string{"-C", chartDir, "-c", "user.name=test", "-c", "user.email=test@example.com", "tag", "v1.2.3"}
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		t.Fatalf("unexpected error tagging test repo: %s", out)
+	}
+	for _, chartName := range []string{chartDir, filepath.Join(chartDir, "Chart.yaml")} {
+		if v, err := resolveAppVersion("git-tag", chartName); err != nil || v != "v1.2.3" {
+			t.Errorf("%s: expected appVersion v1.2.3 from git tag, instead got %q, %v", chartName, v, err)
+		}
+	}
+
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+	if _, err := resolveAppVersion("git-tag", tmp); err == nil || !strings.Contains(err.Error(), "no git tag found") {
+		t.Errorf("expected error outside of a git work tree, instead got %v", err)
+	}
+
+	os.Setenv("HELM_PUSH_TEST_APP_TAG", "2.0.1")
+	defer os.Unsetenv("HELM_PUSH_TEST_APP_TAG")
+	if v, err := resolveAppVersion("env:HELM_PUSH_TEST_APP_TAG", chartDir); err != nil || v != "2.0.1" {
+		t.Errorf("expected appVersion 2.0.1 from env, instead got %q, %v", v, err)
+	}
+	if _, err := resolveAppVersion("env:HELM_PUSH_TEST_UNSET", chartDir); err == nil || !strings.Contains(err.Error(), "HELM_PUSH_TEST_UNSET is not set") {
+		t.Errorf("expected error naming the unset variable, instead got %v", err)
+	}
+}
+
+func TestPushCmdAppVersionFrom(t *testing.T) {
+	var uploads int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uploads++
+		w.WriteHeader(201)
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+
+	push := func(args ...string) (string, error) {
+		args = append([]string{testTarballPath, "helm-push-test", "-o", "json"}, args...)
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&bytes.Buffer{})
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	os.Setenv("HELM_PUSH_TEST_APP_TAG", "v2.0.1")
+	defer os.Unsetenv("HELM_PUSH_TEST_APP_TAG")
+	out, err := push("--app-version-from", "env:HELM_PUSH_TEST_APP_TAG", "--strip-v=app-version")
+	if err != nil {
+		t.Fatal("unexpected error pushing with appVersion from env", err)
+	}
+	var result pushResult
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatalf("unexpected error parsing JSON output %q: %s", out, err)
+	}
+	if result.AppVersion != "2.0.1" {
+		t.Errorf("expected appVersion 2.0.1 in output, instead got %q", result.AppVersion)
+	}
+
+	uploads = 0
+	for _, tc := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--app-version-from", "env:HELM_PUSH_TEST_UNSET"}, "HELM_PUSH_TEST_UNSET is not set"},
+		{[]string{"--app-version-from", "tag"}, "invalid --app-version-from"},
+		{[]string{"--app-version-from", "git-tag", "--app-version", "1.0.0"}, "cannot be used together"},
+	} {
+		if _, err := push(tc.args...); err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%v: expected error containing %q, instead got %v", tc.args, tc.expected, err)
+		}
+	}
+	if uploads != 0 {
+		t.Errorf("expected nothing to be uploaded, instead got %d uploads", uploads)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+	"github.com/chartmuseum/helm-push/pkg/helm"
+	"github.com/spf13/cobra"
+)
+
+// stagingSuffix is added to the version of charts staged in the repo
+// itself with --atomic, as a pre-release identifier since a _staging
+// suffix is not valid semver
+const stagingSuffix = "staging"
+
+// validateAtomicFlags checks that --atomic and --atomic-staging-repo are
+// given with flags they can be used with
+func (p *pushCmd) validateAtomicFlags() error {
+	if p.atomicStagingRepo != "" && !p.atomic {
+		return errors.New("--atomic-staging-repo only applies to --atomic")
+	}
+	if !p.atomic {
+		return nil
+	}
+	if p.dryRun || p.signatureOnly {
+		return errors.New("--atomic cannot be used with --dry-run or --signature-only")
+	}
+	if helm.IsOCIRef(p.atomicStagingRepo) {
+		return errors.New("--atomic-staging-repo cannot be an oci:// repo")
+	}
+	return nil
+}
+
+// setupStagingRepo sets up the --atomic-staging-repo charts pushed to
+// the target are staged in, from base, the push command before setup
+func (p *pushCmd) setupStagingRepo(cmd *cobra.Command, base pushCmd) error {
+	if p.atomicStagingRepo == "" || p.oci {
+		return nil
+	}
+	base.repoName = p.atomicStagingRepo
+	if err := base.setup(cmd); err != nil {
+		return err
+	}
+	p.stagingRepo = &base
+	return nil
+}
+
+// stagingVersion returns the version a chart is staged with in the repo
+// itself, e.g. 1.2.3-staging or 1.2.3-rc.1.staging, keeping any build
+// metadata
+func stagingVersion(version string) string {
+	build := ""
+	if i := strings.Index(version, "+"); i >= 0 {
+		version, build = version[:i], version[i:]
+	}
+	sep := "-"
+	if strings.Contains(version, "-") {
+		sep = "."
+	}
+	return version + sep + stagingSuffix + build
+}
+
+// stage pushes the chart to the --atomic-staging-repo, or to the repo
+// itself with a staging version, and checks that the package downloaded
+// back from it is the one pushed, before the chart is pushed for real,
+// to be checked again with verifyPushed.
+// The returned cleanup deletes the staged chart, whether the push
+// succeeds or not, and must always be called, even on error: a failed
+// upload may still have left the chart in the repo
+func (p *pushCmd) stage(client *cm.Client, chart *helm.Chart, chartPackagePath string) (func(), error) {
+	noop := func() {}
+	original := chart.Version()
+	stageRepo, stageClient, version := p.repoName, client, original
+	if p.stagingRepo != nil {
+		var err error
+		stageRepo = p.stagingRepo.repoName
+		if stageClient, err = p.stagingRepo.connect(); err != nil {
+			return noop, fmt.Errorf("failed to connect to staging repo %s: %w", stageRepo, err)
+		}
+	} else {
+		// the chart is repackaged with the staging version
+		tmp, err := p.mkdirTemp()
+		if err != nil {
+			return noop, err
+		}
+		defer os.RemoveAll(tmp)
+		version = stagingVersion(chart.Version())
+		chart.SetVersion(version)
+		chartPackagePath, err = helm.CreateChartPackage(chart, tmp)
+		chart.SetVersion(original)
+		if err != nil {
+			return noop, err
+		}
+	}
+	stagedName := fmt.Sprintf("%s-%s", chart.Name(), version)
+
+	p.log.info(chart.Name(), p.repoName, "Staging %s in %s...", stagedName, stageRepo)
+	cleanup := func() {
+		// deleted even if the push was interrupted
+		if err := stageClient.DeleteChartWithContext(context.Background(), chart.Name(), version); err != nil {
+			p.log.warn(chart.Name(), p.repoName, "Failed to delete staged chart %s from %s: %s", stagedName, stageRepo, err)
+			return
+		}
+		p.log.info(chart.Name(), p.repoName, "Deleted staged chart %s from %s", stagedName, stageRepo)
+	}
+
+	resp, err := stageClient.UploadChartPackageWithContext(p.ctx, chartPackagePath, p.forceUpload)
+	if err != nil {
+		return cleanup, fmt.Errorf("failed to stage %s: %w", stagedName, err)
+	}
+	if err := handlePushResponse(resp, chart.Name(), version, stageClient.URL()); err != nil {
+		return cleanup, fmt.Errorf("failed to stage %s: %w", stagedName, err)
+	}
+	if err := verifyPushed(p.ctx, stageClient, chart.Name(), version, chartPackagePath); err != nil {
+		return cleanup, fmt.Errorf("failed to verify staged chart %s in %s: %w", stagedName, stageRepo, err)
+	}
+	p.log.info(chart.Name(), p.repoName, "Verified staged chart %s", stagedName)
+	return cleanup, nil
+}
+
+// verifyPushed downloads a staged or pushed chart package and checks
+// that its checksum is that of the package pushed. With a staging
+// version, the staged package isn't the final one, so the final push is
+// checked too
+func verifyPushed(ctx context.Context, client *cm.Client, name string, version string, chartPackagePath string) error {
+	expected, err := packageChecksum(chartPackagePath)
+	if err != nil {
+		return err
+	}
+	resp, err := client.DownloadFileWithContext(ctx, fmt.Sprintf("charts/%s-%s.tgz", name, version))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return cm.NewResponseError(resp)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, resp.Body); err != nil {
+		return err
+	}
+	if actual := fmt.Sprintf("%x", h.Sum(nil)); actual != expected {
+		return fmt.Errorf("sha256 checksum %s does not match the pushed package's %s", actual, expected)
+	}
+	return nil
+}
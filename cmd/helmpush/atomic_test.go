@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestStagingVersion(t *testing.T) {
+	for version, expected := range map[string]string{
+		"0.1.0":          "0.1.0-staging",
+		"1.0.0-rc.1":     "1.0.0-rc.1.staging",
+		"1.0.0+build.5":  "1.0.0-staging+build.5",
+		"v2.0.0-beta+sh": "v2.0.0-beta.staging+sh",
+	} {
+		if actual := stagingVersion(version); actual != expected {
+			t.Errorf("%s: expected %s, instead got %s", version, expected, actual)
+		}
+	}
+}
+
+func TestPushCmdAtomic(t *testing.T) {
+	var requests []string
+	var corrupt, corruptFinal bool
+	packages := map[string][]byte{}
+	repo := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, name+r.Method+" "+r.URL.Path)
+			switch {
+			case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/api/charts"):
+				f, header, err := r.FormFile("chart")
+				if err != nil {
+					w.WriteHeader(400)
+					return
+				}
+				b, _ := io.ReadAll(f)
+				packages[name+header.Filename] = b
+				w.WriteHeader(201)
+				w.Write([]byte(`{"saved":true}`))
+			case r.Method == "GET" && strings.Contains(r.URL.Path, "/charts/"):
+				b, ok := packages[name+path.Base(r.URL.Path)]
+				if !ok {
+					w.WriteHeader(404)
+					return
+				}
+				if corrupt || corruptFinal && !strings.Contains(r.URL.Path, stagingSuffix) {
+					b = append(b, 0)
+				}
+				w.Write(b)
+			case r.Method == "DELETE":
+				parts := strings.Split(r.URL.Path, "/")
+				delete(packages, name+parts[len(parts)-2]+"-"+parts[len(parts)-1]+".tgz")
+				w.Write([]byte(`{"deleted":true}`))
+			default:
+				w.WriteHeader(404)
+			}
+		}
+	}
+	ts := httptest.NewServer(repo(""))
+	defer ts.Close()
+	staging := httptest.NewServer(repo("staging "))
+	defer staging.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+
+	push := func(args ...string) (string, error) {
+		requests = nil
+		packages = map[string][]byte{}
+		args = append([]string{testTarballPath, "helm-push-test"}, args...)
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	// staged in the repo itself with a staging version
+	out, err := push("--atomic")
+	if err != nil {
+		t.Fatalf("unexpected error pushing with --atomic: %s\n%s", err, out)
+	}
+	expected := []string{
+		"POST /x/y/z/api/charts",
+		"GET /x/y/z/charts/mychart-0.1.0-staging.tgz",
+		"POST /x/y/z/api/charts",
+		"GET /x/y/z/charts/mychart-0.1.0.tgz",
+		"DELETE /x/y/z/api/charts/mychart/0.1.0-staging",
+	}
+	var actual []string
+	for _, r := range requests {
+		if !strings.HasPrefix(r, "GET /x/y/z/api/") {
+			actual = append(actual, r)
+		}
+	}
+	if strings.Join(actual, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected requests %v, instead got %v", expected, actual)
+	}
+	if _, ok := packages["mychart-0.1.0.tgz"]; !ok || len(packages) != 1 {
+		t.Errorf("expected only the final package to be left, instead got %d packages", len(packages))
+	}
+	if !strings.Contains(out, "Verified staged chart mychart-0.1.0-staging") || !strings.Contains(out, "Verified pushed chart mychart-0.1.0.tgz") || !strings.Contains(out, "Deleted staged chart mychart-0.1.0-staging from helm-push-test") {
+		t.Errorf("expected staging to be reported, instead got:\n%s", out)
+	}
+
+	// staged in another repo with the final version
+	if out, err := push("--atomic", "--atomic-staging-repo", staging.URL); err != nil {
+		t.Errorf("unexpected error pushing with --atomic-staging-repo: %s\n%s", err, out)
+	}
+	if len(requests) < 4 || requests[0] != "staging POST /x/y/z/api/charts" || requests[1] != "staging GET /x/y/z/charts/mychart-0.1.0.tgz" || requests[2] != "POST /x/y/z/api/charts" || requests[3] != "GET /x/y/z/charts/mychart-0.1.0.tgz" || requests[len(requests)-1] != "staging DELETE /x/y/z/api/charts/mychart/0.1.0" {
+		t.Errorf("expected the chart to be staged in the staging repo, instead got %v", requests)
+	}
+	if _, ok := packages["mychart-0.1.0.tgz"]; !ok || len(packages) != 1 {
+		t.Errorf("expected only the final package to be left, instead got %d packages", len(packages))
+	}
+
+	// a staged package which doesn't match isn't pushed, and is deleted
+	corrupt = true
+	out, err = push("--atomic")
+	if err == nil || !strings.Contains(err.Error(), "failed to verify staged chart mychart-0.1.0-staging in helm-push-test: sha256 checksum") {
+		t.Errorf("expected checksum mismatch, instead got %v", err)
+	}
+	if len(packages) != 0 || !strings.Contains(out, "Deleted staged chart") {
+		t.Errorf("expected the staged chart to be deleted and nothing pushed, instead got %d packages:\n%s", len(packages), out)
+	}
+	corrupt = false
+
+	// the final package is checked too, as it isn't the staged one
+	corruptFinal = true
+	out, err = push("--atomic")
+	if err == nil || !strings.Contains(err.Error(), "failed to verify pushed chart mychart-0.1.0.tgz in helm-push-test: sha256 checksum") {
+		t.Errorf("expected checksum mismatch of the final package, instead got %v", err)
+	}
+	if !strings.Contains(out, "Deleted staged chart") {
+		t.Errorf("expected the staged chart to be deleted, instead got:\n%s", out)
+	}
+	corruptFinal = false
+
+	for _, tc := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--atomic-staging-repo", "staging"}, "--atomic-staging-repo only applies to --atomic"},
+		{[]string{"--atomic", "--dry-run"}, "--atomic cannot be used with --dry-run or --signature-only"},
+		{[]string{"--atomic", "--oci"}, "--atomic cannot be used with --oci or oci:// repos"},
+		{[]string{"--atomic", "--atomic-staging-repo", "oci://registry/charts"}, "--atomic-staging-repo cannot be an oci:// repo"},
+	} {
+		if _, err := push(tc.args...); err == nil || err.Error() != tc.err {
+			t.Errorf("%v: expected error %q, instead got %v", tc.args, tc.err, err)
+		}
+	}
+}
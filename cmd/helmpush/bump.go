@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+	"github.com/chartmuseum/helm-push/pkg/helm"
+)
+
+const (
+	bumpPatch = "patch"
+	bumpMinor = "minor"
+	bumpMajor = "major"
+
+	// initialBumpVersion is used when neither the repo nor
+	// Chart.yaml provide a valid semver version to start from
+	initialBumpVersion = "0.1.0"
+)
+
+// validateBump checks the --bump flag value
+func validateBump(part string) error {
+	switch part {
+	case "", bumpPatch, bumpMinor, bumpMajor:
+		return nil
+	}
+	return fmt.Errorf("invalid --bump value %q (must be one of: %s, %s, %s)", part, bumpPatch, bumpMinor, bumpMajor)
+}
+
+// bumpVersion increments the given part of a semver version
+func bumpVersion(version string, part string) (string, error) {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return "", err
+	}
+	var bumped semver.Version
+	switch part {
+	case bumpMajor:
+		bumped = v.IncMajor()
+	case bumpMinor:
+		bumped = v.IncMinor()
+	default:
+		bumped = v.IncPatch()
+	}
+	return bumped.String(), nil
+}
+
+// latestVersion returns the highest semver version, ignoring invalid
+// versions and, unless includePrereleases is set, pre-releases
+func latestVersion(versions []*cm.ChartVersion, includePrereleases bool) *semver.Version {
+	var latest *semver.Version
+	for _, cv := range versions {
+		v, err := semver.NewVersion(cv.Version)
+		if err != nil {
+			continue
+		}
+		if v.Prerelease() != "" && !includePrereleases {
+			continue
+		}
+		if latest == nil || v.GreaterThan(latest) {
+			latest = v
+		}
+	}
+	return latest
+}
+
+// resolveBumpVersion determines the version to push for --bump, based on
+// the highest version of the chart already in the repo
+func (p *pushCmd) resolveBumpVersion(client *cm.Client, chart *helm.Chart) (string, error) {
+	versions, _, err := fetchChartVersions(p.ctx, client, chart.Name())
+	if err != nil {
+		return "", err
+	}
+
+	if latest := latestVersion(versions, p.includePrereleases); latest != nil {
+		return bumpVersion(latest.Original(), p.bump)
+	}
+
+	// Not in the repo yet, so start from the version in Chart.yaml
+	if _, err := semver.NewVersion(chart.Version()); err == nil {
+		return chart.Version(), nil
+	}
+	p.log.warn(chart.Name(), p.repoName, "%s has no valid semver version in the repo or Chart.yaml, starting from %s", chart.Name(), initialBumpVersion)
+	return initialBumpVersion, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+)
+
+func TestBumpVersion(t *testing.T) {
+	for _, tc := range []struct{ version, part, expected string }{
+		{"1.2.3", bumpPatch, "1.2.4"},
+		{"1.2.3", bumpMinor, "1.3.0"},
+		{"1.2.3", bumpMajor, "2.0.0"},
+		{"v1.2.3", bumpPatch, "1.2.4"},
+	} {
+		bumped, err := bumpVersion(tc.version, tc.part)
+		if err != nil {
+			t.Errorf("unexpected error bumping %s %s: %s", tc.version, tc.part, err)
+		}
+		if bumped != tc.expected {
+			t.Errorf("expected %s %s bump to be %s, instead got %s", tc.version, tc.part, tc.expected, bumped)
+		}
+	}
+
+	if _, err := bumpVersion("latest", bumpPatch); err == nil {
+		t.Error("expected error bumping non-semver version, instead got nil")
+	}
+	if err := validateBump("micro"); err == nil {
+		t.Error("expected error with invalid bump value, instead got nil")
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	versions := []*cm.ChartVersion{{Version: "1.0.0"}, {Version: "1.1.0-rc.1"}, {Version: "latest"}, {Version: "0.9.0"}}
+	if v := latestVersion(versions, false); v.String() != "1.0.0" {
+		t.Errorf("expected latest version to be 1.0.0, instead got %s", v)
+	}
+	if v := latestVersion(versions, true); v.String() != "1.1.0-rc.1" {
+		t.Errorf("expected latest version including pre-releases to be 1.1.0-rc.1, instead got %s", v)
+	}
+	if v := latestVersion(nil, false); v != nil {
+		t.Errorf("expected no latest version, instead got %s", v)
+	}
+}
+
+func TestPushCmdBump(t *testing.T) {
+	remoteVersions := `[{"name": "mychart", "version": "0.1.0"}, {"name": "mychart", "version": "0.2.0-rc.1"}]`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/x/y/z/api/charts/mychart" && remoteVersions != "":
+			w.WriteHeader(200)
+			w.Write([]byte(remoteVersions))
+		case r.Method == "POST" && r.URL.Path == "/x/y/z/api/charts":
+			w.WriteHeader(201)
+			w.Write([]byte("{\"saved\": true}"))
+		default:
+			w.WriteHeader(404)
+			w.Write([]byte("{\"error\": \"not found\"}"))
+		}
+	}))
+	defer ts.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+
+	push := func(args ...string) pushResult {
+		out := &bytes.Buffer{}
+		args = append([]string{"-o", "json"}, args...)
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		cmd.SetOut(out)
+		cmd.SetErr(&bytes.Buffer{})
+		if err := cmd.Execute(); err != nil {
+			t.Fatal("unexpected error pushing with --bump", err)
+		}
+		var result pushResult
+		if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+			t.Fatalf("unexpected error parsing json output %q: %s", out.String(), err)
+		}
+		return result
+	}
+
+	if result := push("--bump", "minor", testTarballPath, "helm-push-test"); result.Version != "0.2.0" || result.Package != "mychart-0.2.0.tgz" {
+		t.Errorf("unexpected result bumping minor version: %+v", result)
+	}
+	if result := push("--bump", "patch", "--include-prereleases", testTarballPath, "helm-push-test"); result.Version != "0.2.0" {
+		t.Errorf("unexpected result bumping patch version including pre-releases: %+v", result)
+	}
+
+	// Not in the repo yet, use Chart.yaml version
+	remoteVersions = ""
+	if result := push("--bump", "major", testTarballPath, "helm-push-test"); result.Version != "0.1.0" {
+		t.Errorf("unexpected result bumping chart missing from repo: %+v", result)
+	}
+
+	// Cannot be combined with --version
+	args := []string{"--bump", "patch", "--version", "1.0.0", testTarballPath, "helm-push-test"}
+	cmd := newPushCmd(args)
+	cmd.SetArgs(args)
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	if err := cmd.Execute(); err == nil {
+		t.Error("expected error combining --bump and --version, instead got nil")
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+type (
+	// chartDependencies is the dependencies section of Chart.yaml
+	// (Helm 3) or requirements.yaml (Helm 2)
+	chartDependencies struct {
+		Dependencies []struct {
+			Repository string `json:"repository"`
+		} `json:"dependencies"`
+	}
+)
+
+// selectChangedCharts returns the charts with files changed since ref
+// in their git work tree, either in the chart itself or in one of its
+// local (file://) dependencies. Charts outside a git work tree are
+// always selected, with a warning
+func (p *pushCmd) selectChangedCharts(paths []string, ref string) ([]string, error) {
+	changedByTree := map[string][]string{}
+	var selected []string
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		if abs, err = filepath.EvalSymlinks(abs); err != nil {
+			return nil, err
+		}
+		dir := abs
+		if !isChartDir(abs) {
+			dir = filepath.Dir(abs)
+		}
+
+		top, err := gitTopLevel(dir)
+		if err != nil {
+			p.log.warn("", p.repoName, "%s is not in a git work tree, pushing it anyway", path)
+			selected = append(selected, path)
+			continue
+		}
+		changed, ok := changedByTree[top]
+		if !ok {
+			if changed, err = gitChangedFiles(top, ref); err != nil {
+				return nil, err
+			}
+			changedByTree[top] = changed
+		}
+
+		for _, watched := range append([]string{abs}, localDependencies(abs)...) {
+			if containsChangedFile(top, watched, changed) {
+				selected = append(selected, path)
+				break
+			}
+		}
+	}
+	return selected, nil
+}
+
+// localDependencies returns the absolute paths of the file://
+// dependencies of a chart directory
+func localDependencies(chartDir string) []string {
+	var deps []string
+	for _, name := range []string{"Chart.yaml", "requirements.yaml"} {
+		b, err := os.ReadFile(filepath.Join(chartDir, name))
+		if err != nil {
+			continue
+		}
+		var cd chartDependencies
+		if err := yaml.Unmarshal(b, &cd); err != nil {
+			continue
+		}
+		for _, d := range cd.Dependencies {
+			if strings.HasPrefix(d.Repository, "file://") {
+				deps = append(deps, filepath.Join(chartDir, strings.TrimPrefix(d.Repository, "file://")))
+			}
+		}
+	}
+	return deps
+}
+
+// containsChangedFile reports whether path, or any file within it,
+// is one of the changed files (relative to the work tree top)
+func containsChangedFile(top string, path string, changed []string) bool {
+	rel, err := filepath.Rel(top, path)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, f := range changed {
+		if f == rel || strings.HasPrefix(f, rel+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// gitTopLevel returns the top of the git work tree containing dir
+func gitTopLevel(dir string) (string, error) {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", err
+	}
+	// Resolve symlinks, as git does
+	return filepath.EvalSymlinks(strings.TrimSpace(string(out)))
+}
+
+// gitChangedFiles returns the files changed between ref and HEAD
+func gitChangedFiles(top string, ref string) ([]string, error) {
+	out, err := exec.Command("git", "-C", top, "diff", "--name-only", ref+"...HEAD").Output()
+	if err != nil {
+		if e, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("git diff %s...HEAD failed: %s", ref, strings.TrimSpace(string(e.Stderr)))
+		}
+		return nil, err
+	}
+	var changed []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			changed = append(changed, line)
+		}
+	}
+	return changed, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupTestGitCharts creates a git repo with charts a, b (depending on
+// common) and c, then changes a and common since the "base" tag
+func setupTestGitCharts(t *testing.T) (string, func()) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	cleanup := func() {
+		os.RemoveAll(tmp)
+	}
+
+	git := func(args ...string) {
+		args = append([]string{"-C", tmp, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("unexpected error running git %v: %s", args, out)
+		}
+	}
+	writeChart := func(name string, extra string) {
+		dir := filepath.Join(tmp, "charts", name)
+		os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+		os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte("apiVersion: v2\nname: "+name+"\nversion: 0.1.0\n"+extra), 0644)
+		os.WriteFile(filepath.Join(dir, "templates", "cm.yaml"), []byte("kind: ConfigMap\n"), 0644)
+	}
+
+	writeChart("a", "")
+	writeChart("b", "dependencies:\n- name: common\n  version: 0.1.0\n  repository: file://../common\n")
+	writeChart("c", "")
+	writeChart("common", "")
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+	git("tag", "base")
+
+	os.WriteFile(filepath.Join(tmp, "charts", "a", "templates", "cm.yaml"), []byte("kind: ConfigMap\ndata: {}\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "charts", "common", "values.yaml"), []byte("x: 1\n"), 0644)
+	git("add", "-A")
+	git("commit", "-q", "-m", "change a and common")
+
+	return filepath.Join(tmp, "charts"), cleanup
+}
+
+func TestSelectChangedCharts(t *testing.T) {
+	chartsDir, cleanup := setupTestGitCharts(t)
+	defer cleanup()
+
+	p := &pushCmd{log: newLogger(&bytes.Buffer{}, &bytes.Buffer{})}
+	paths, err := findLocalCharts(chartsDir)
+	if err != nil {
+		t.Fatal("unexpected error finding charts", err)
+	}
+	selected, err := p.selectChangedCharts(paths, "base")
+	if err != nil {
+		t.Fatal("unexpected error selecting changed charts", err)
+	}
+	var names []string
+	for _, path := range selected {
+		names = append(names, filepath.Base(path))
+	}
+	if strings.Join(names, ",") != "a,b,common" {
+		t.Errorf("expected charts a, b and common to be selected, instead got %v", names)
+	}
+
+	if _, err := p.selectChangedCharts(paths, "no-such-ref"); err == nil {
+		t.Error("expected error with bad git ref, instead got nil")
+	}
+
+	// Outside a git work tree, pushed with a warning
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+	b, err := os.ReadFile(testTarballPath)
+	if err != nil {
+		t.Fatal("unexpected error reading test tarball", err)
+	}
+	os.WriteFile(filepath.Join(tmp, "mychart-0.1.0.tgz"), b, 0644)
+	errOut := &bytes.Buffer{}
+	p.log = newLogger(&bytes.Buffer{}, errOut)
+	selected, err = p.selectChangedCharts([]string{filepath.Join(tmp, "mychart-0.1.0.tgz")}, "base")
+	if err != nil {
+		t.Fatal("unexpected error selecting chart outside git", err)
+	}
+	if len(selected) != 1 || !strings.Contains(errOut.String(), "not in a git work tree") {
+		t.Errorf("expected chart outside git to be selected with a warning, instead got %v: %s", selected, errOut.String())
+	}
+}
+
+func TestPushCmdChangedSince(t *testing.T) {
+	chartsDir, cleanup := setupTestGitCharts(t)
+	defer cleanup()
+
+	var pushed []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, header, err := r.FormFile("chart"); err == nil {
+			pushed = append(pushed, filepath.Base(header.Filename))
+		}
+		w.WriteHeader(201)
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+
+	repoCleanup := setupTestRepo(t, ts.URL)
+	defer repoCleanup()
+
+	out := &bytes.Buffer{}
+	args := []string{"-r", "--changed-since", "base", chartsDir, "helm-push-test"}
+	cmd := newPushCmd(args)
+	cmd.SetArgs(args)
+	cmd.SetOut(out)
+	if err := cmd.Execute(); err != nil {
+		t.Fatal("unexpected error pushing changed charts", err)
+	}
+	// b is pushed after common, which it depends on
+	if strings.Join(pushed, ",") != "a-0.1.0.tgz,common-0.1.0.tgz,b-0.1.0.tgz" {
+		t.Errorf("unexpected charts pushed: %v", pushed)
+	}
+	if !strings.HasPrefix(out.String(), "3 of 4 charts changed since base") {
+		t.Errorf("expected selection to be printed first, instead got %s", out.String())
+	}
+}
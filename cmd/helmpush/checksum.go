@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// packageChecksum returns the hex sha256 checksum of the chart package
+func packageChecksum(chartPackagePath string) (string, error) {
+	f, err := os.Open(chartPackagePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// checksumFilePath returns where --checksum-file writes the checksum of
+// the package: the path itself, or NAME-VERSION.tgz.sha256 in it if it
+// is a directory (or ends with a /)
+func checksumFilePath(path string, packageName string) string {
+	if strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(filepath.Separator)) {
+		return filepath.Join(path, packageName+".sha256")
+	}
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		return filepath.Join(path, packageName+".sha256")
+	}
+	return path
+}
+
+// writeChecksumFile writes the checksum of the pushed package for
+// --checksum-file, in the format sha256sum -c reads
+func (p *pushCmd) writeChecksumFile(chartName string, packageName string, sum string) error {
+	path := checksumFilePath(p.checksumFile, packageName)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(fmt.Sprintf("%s  %s\n", sum, packageName)), 0644); err != nil {
+		return err
+	}
+	p.log.info(chartName, p.repoName, "Wrote checksum to %s", path)
+	return nil
+}
+
+// anyPushed reports whether the package was pushed to any repo
+func anyPushed(results []pushResult) bool {
+	for _, r := range results {
+		if r.Error == "" && !r.Skipped {
+			return true
+		}
+	}
+	return false
+}
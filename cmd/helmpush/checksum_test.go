@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chartmuseum/helm-push/pkg/helm"
+)
+
+func TestPushCmdChecksum(t *testing.T) {
+	status := 201
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	push := func(args ...string) (string, string, error) {
+		args = append([]string{testTarballPath, "helm-push-test"}, args...)
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		var out, log bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&log)
+		err := cmd.Execute()
+		return out.String(), log.String(), err
+	}
+
+	// the package is repacked, so its checksum isn't the tarball's
+	chart, err := helm.GetChartByName(testTarballPath)
+	if err != nil {
+		t.Fatal("unexpected error getting chart", err)
+	}
+	repacked, err := helm.CreateChartPackage(chart, filepath.Join(tmp, "repacked"))
+	if err != nil {
+		t.Fatal("unexpected error packaging chart", err)
+	}
+	sum, err := packageChecksum(repacked)
+	if err != nil {
+		t.Fatal("unexpected error reading package checksum", err)
+	}
+
+	out, log, err := push("--checksum-file", tmp+"/")
+	if err != nil || !strings.Contains(out+log, "SHA256:  "+sum) {
+		t.Errorf("expected checksum to be printed, instead got %v:\n%s%s", err, out, log)
+	}
+	written, err := os.ReadFile(filepath.Join(tmp, "mychart-0.1.0.tgz.sha256"))
+	if err != nil || string(written) != sum+"  mychart-0.1.0.tgz\n" {
+		t.Errorf("expected checksum file in the directory, instead got %q (%v)", written, err)
+	}
+
+	checksumFile := filepath.Join(tmp, "out", "chart.sha256")
+	out, _, err = push("--checksum-file", checksumFile, "-o", "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var result pushResult
+	if err := json.Unmarshal([]byte(out), &result); err != nil || result.SHA256 != sum {
+		t.Errorf("expected sha256 in json output, instead got %v:\n%s", err, out)
+	}
+	if written, err := os.ReadFile(checksumFile); err != nil || string(written) != sum+"  mychart-0.1.0.tgz\n" {
+		t.Errorf("expected checksum file at the given path, instead got %q (%v)", written, err)
+	}
+
+	// nothing is written if the push fails
+	status = 500
+	os.RemoveAll(tmp)
+	if _, _, err := push("--checksum-file", tmp+"/"); err == nil {
+		t.Error("expected error when the push fails")
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "mychart-0.1.0.tgz.sha256")); !os.IsNotExist(err) {
+		t.Errorf("expected no checksum file after a failed push, instead got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chartmuseum/helm-push/pkg/helm"
+	"github.com/spf13/cobra"
+)
+
+var completionUsage = `Generate shell completion scripts for the plugin.
+
+Helm 3.2+ automatically uses the plugin's completions as part of its own
+completion script. The scripts generated here can be used with the helmpush
+binary directly:
+
+  $ source <(helmpush completion bash)
+`
+
+func newCompletionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:       "completion [bash|zsh|fish|powershell]",
+		Short:     "Generate shell completion scripts",
+		Long:      completionUsage,
+		Hidden:    true,
+		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+		Args:      cobra.ExactValidArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			root := cmd.Root()
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletion(out)
+			case "zsh":
+				return root.GenZshCompletion(out)
+			case "fish":
+				return root.GenFishCompletion(out, true)
+			default:
+				return root.GenPowerShellCompletion(out)
+			}
+		},
+	}
+	return cmd
+}
+
+// completePushArgs completes the chart path and repo arguments of push.
+// Completions only use the local filesystem and repository file
+func completePushArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return completeChartPaths(toComplete)
+	}
+	return completeRepoNames(1)(cmd, args, toComplete)
+}
+
+// completeRepoNames returns a completion function which offers the locally
+// configured repo names for the positional argument at index pos
+func completeRepoNames(pos int) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != pos {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		if cmd != nil {
+			// completions run without setup, which sets where repos
+			// are looked up
+			dir, _ := cmd.Flags().GetString("helm-home")
+			helm.SetHelmHome(dir)
+			path, _ := cmd.Flags().GetString("repo-config")
+			helm.SetRepoConfig(path)
+		}
+		names, err := helm.GetRepoNames()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeChartPaths offers chart directories (containing Chart.yaml) and
+// .tgz packages, as well as other directories to descend into
+func completeChartPaths(toComplete string) ([]string, cobra.ShellCompDirective) {
+	dir, prefix := filepath.Split(toComplete)
+	readDir := dir
+	if readDir == "" {
+		readDir = "."
+	}
+	entries, err := os.ReadDir(readDir)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var paths []string
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), prefix) {
+			continue
+		}
+		name := dir + e.Name()
+		if e.IsDir() {
+			paths = append(paths, name+string(os.PathSeparator))
+		} else if strings.HasSuffix(e.Name(), ".tgz") {
+			paths = append(paths, name)
+		}
+	}
+
+	// Only directories were found, so don't add a space to allow
+	// descending into them
+	directive := cobra.ShellCompDirectiveNoFileComp
+	for _, path := range paths {
+		if !strings.HasSuffix(path, string(os.PathSeparator)) || isChartDir(path) {
+			return paths, directive
+		}
+	}
+	return paths, directive | cobra.ShellCompDirectiveNoSpace
+}
+
+// isChartDir reports whether dir contains a Chart.yaml
+func isChartDir(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "Chart.yaml"))
+	return err == nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCompletionCmd(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		out := &bytes.Buffer{}
+		args := []string{"completion", shell}
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		cmd.SetOut(out)
+		if err := cmd.Execute(); err != nil {
+			t.Errorf("unexpected error generating %s completion: %s", shell, err)
+		}
+		if out.Len() == 0 {
+			t.Errorf("expected %s completion script, instead got empty output", shell)
+		}
+	}
+
+	args := []string{"completion", "tcsh"}
+	cmd := newPushCmd(args)
+	cmd.SetArgs(args)
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	if err := cmd.Execute(); err == nil {
+		t.Error("expected error generating completion for unsupported shell, instead got nil")
+	}
+}
+
+func TestCompleteRepoNames(t *testing.T) {
+	cleanup := setupTestRepo(t, "http://localhost:8080")
+	defer cleanup()
+
+	complete := completeRepoNames(1)
+
+	// Chart name argument is not completed
+	names, directive := complete(nil, []string{}, "")
+	if names != nil || directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("expected no completion for chart argument, instead got %v %v", names, directive)
+	}
+
+	names, directive = complete(nil, []string{"mychart"}, "")
+	if len(names) != 1 || names[0] != "helm-push-test" {
+		t.Errorf("expected repo names to be completed, instead got %v", names)
+	}
+	if directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("expected no file completion for repo argument, instead got %v", directive)
+	}
+}
+
+func TestCompletePushArgs(t *testing.T) {
+	cleanup := setupTestRepo(t, "http://localhost:8080")
+	defer cleanup()
+
+	// Chart directories and packages
+	names, directive := completePushArgs(nil, []string{}, "../../testdata/charts/helm2/mychart/")
+	expected := []string{
+		"../../testdata/charts/helm2/mychart/charts/",
+		"../../testdata/charts/helm2/mychart/mychart-0.1.0.tgz",
+		"../../testdata/charts/helm2/mychart/templates/",
+	}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("unexpected chart path completions: %v", names)
+	}
+	if directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("unexpected directive for chart path completions: %v", directive)
+	}
+
+	// Only plain directories, allow descending
+	names, directive = completePushArgs(nil, []string{}, "../../testdata/charts/he")
+	if strings.Join(names, ",") != "../../testdata/charts/helm2/,../../testdata/charts/helm3/" {
+		t.Errorf("unexpected directory completions: %v", names)
+	}
+	if directive != cobra.ShellCompDirectiveNoFileComp|cobra.ShellCompDirectiveNoSpace {
+		t.Errorf("unexpected directive for directory completions: %v", directive)
+	}
+
+	// Repo names
+	names, _ = completePushArgs(nil, []string{"mychart/"}, "")
+	if len(names) != 1 || names[0] != "helm-push-test" {
+		t.Errorf("expected repo names to be completed, instead got %v", names)
+	}
+}
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+type (
+	// pluginConfig is the persistent plugin configuration, read from
+	// the file returned by configFilePath
+	pluginConfig struct {
+		repoSettings
+		Repositories map[string]repoSettings `json:"repositories,omitempty"`
+		path         string
+	}
+
+	// repoSettings are the settings which can be set globally or per repo
+	repoSettings struct {
+		Username    string `json:"username,omitempty"`
+		Password    string `json:"password,omitempty"`
+		AccessToken string `json:"accessToken,omitempty"`
+		AuthHeader  string `json:"authHeader,omitempty"`
+		ContextPath string `json:"contextPath,omitempty"`
+		Timeout     int64  `json:"timeout,omitempty"`
+		Retries     *int   `json:"retries,omitempty"`
+		CAFile      string `json:"caFile,omitempty"`
+		CertFile    string `json:"certFile,omitempty"`
+		KeyFile     string `json:"keyFile,omitempty"`
+		Insecure    *bool  `json:"insecure,omitempty"`
+		PlainHTTP   *bool  `json:"plainHTTP,omitempty"`
+		UseHTTP     *bool  `json:"useHTTP,omitempty"` // deprecated alias of plainHTTP
+
+		// VersionPattern is a regular expression the versions of charts
+		// pushed to the repo must match
+		VersionPattern string `json:"versionPattern,omitempty"`
+		// Dev marks a development repo, which helm push --watch can
+		// push to
+		Dev *bool `json:"dev,omitempty"`
+	}
+
+	// setting describes a connection setting for --debug output, and
+	// where its value may come from
+	setting struct {
+		name       string
+		flag       string
+		aliasFlag  string
+		env        string
+		configKeys []string
+		perRepoEnv bool
+		secret     bool
+		// fromVault settings are read from --vault-path when given
+		fromVault bool
+		value     func(p *pushCmd) string
+	}
+
+	configInitCmd struct {
+		force bool
+		out   io.Writer
+	}
+)
+
+const starterConfig = `# helm-push configuration file
+#
+# Values set here are used as defaults. Command line flags and
+# HELM_REPO_* environment variables always take precedence, and
+# per-repository values take precedence over global ones. The global
+# username and password are only used for repos added to helm without
+# credentials.
+
+# username: myuser
+# password: mypass
+# accessToken: <token>
+# authHeader: X-Auth-Token
+# contextPath: /helm/v1
+# timeout: 30
+# retries: 0
+# caFile: /path/to/ca.crt
+# certFile: /path/to/client.crt
+# keyFile: /path/to/client.key
+# insecure: false
+# plainHTTP: false
+# versionPattern: '^\d+\.\d+\.\d+$'
+
+# Per-repository overrides, keyed by repo name (as passed to helm push)
+repositories: {}
+#  chartmuseum:
+#    username: otheruser
+#    contextPath: /charts
+#    versionPattern: '^\d+\.\d+\.\d+(-dev\.\d+)?$'
+#  dev-chartmuseum:
+#    dev: true  # helm push --watch can push to it
+`
+
+// configFilePath returns the location of the plugin configuration file:
+// $HELM_PUSH_CONFIG if set, otherwise config.yaml in the plugin directory
+// ($HELM_PLUGIN_DIR) if it exists, otherwise ~/.config/helm-push/config.yaml
+func configFilePath() (string, error) {
+	if v, ok := os.LookupEnv("HELM_PUSH_CONFIG"); ok && v != "" {
+		return v, nil
+	}
+	if dir, ok := os.LookupEnv("HELM_PLUGIN_DIR"); ok && dir != "" {
+		configPath := filepath.Join(dir, "config.yaml")
+		if _, err := os.Stat(configPath); err == nil {
+			return configPath, nil
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "helm-push", "config.yaml"), nil
+}
+
+// loadPluginConfig reads the plugin configuration file. A missing
+// file is not an error, and results in an empty configuration, but
+// a malformed file or unknown setting is
+func loadPluginConfig() (*pluginConfig, error) {
+	c := &pluginConfig{}
+	configPath, err := configFilePath()
+	if err != nil {
+		return c, nil
+	}
+	b, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	j, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %s", configPath, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(c); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %s", configPath, err)
+	}
+	c.path = configPath
+	return c, nil
+}
+
+// settingsForRepo returns the global settings merged with any overrides
+// configured for the given repo
+func (c *pluginConfig) settingsForRepo(repoName string) repoSettings {
+	s := c.repoSettings
+	r, ok := c.Repositories[repoName]
+	if !ok {
+		return s
+	}
+	if r.Username != "" {
+		s.Username = r.Username
+	}
+	if r.Password != "" {
+		s.Password = r.Password
+	}
+	if r.AccessToken != "" {
+		s.AccessToken = r.AccessToken
+	}
+	if r.AuthHeader != "" {
+		s.AuthHeader = r.AuthHeader
+	}
+	if r.ContextPath != "" {
+		s.ContextPath = r.ContextPath
+	}
+	if r.Timeout != 0 {
+		s.Timeout = r.Timeout
+	}
+	if r.Retries != nil {
+		s.Retries = r.Retries
+	}
+	if r.CAFile != "" {
+		s.CAFile = r.CAFile
+	}
+	if r.CertFile != "" {
+		s.CertFile = r.CertFile
+	}
+	if r.KeyFile != "" {
+		s.KeyFile = r.KeyFile
+	}
+	if r.Insecure != nil {
+		s.Insecure = r.Insecure
+	}
+	if r.PlainHTTP != nil || r.UseHTTP != nil {
+		s.PlainHTTP, s.UseHTTP = r.PlainHTTP, r.UseHTTP
+	}
+	if r.VersionPattern != "" {
+		s.VersionPattern = r.VersionPattern
+	}
+	if r.Dev != nil {
+		s.Dev = r.Dev
+	}
+	return s
+}
+
+// plainHTTP returns the plainHTTP setting, or its older name useHTTP
+func (s repoSettings) plainHTTP() *bool {
+	if s.PlainHTTP != nil {
+		return s.PlainHTTP
+	}
+	return s.UseHTTP
+}
+
+// setFieldsFromConfig fills in any settings not already provided
+// via flags or environment variables from the plugin configuration file
+func (p *pushCmd) setFieldsFromConfig(f *pflag.FlagSet) error {
+	c, err := loadPluginConfig()
+	if err != nil {
+		return err
+	}
+	s := c.settingsForRepo(p.repoName)
+	// the global credentials are only defaults for repos without
+	// their own, see withDefaultCredentials
+	r := c.Repositories[p.repoName]
+	if p.username == "" {
+		p.username = r.Username
+	}
+	if p.password == "" {
+		p.password = r.Password
+	}
+	p.defaultUsername, p.defaultPassword = c.Username, c.Password
+	if p.accessToken == "" {
+		p.accessToken = s.AccessToken
+	}
+	if p.authHeader == "" {
+		p.authHeader = s.AuthHeader
+	}
+	if p.contextPath == "" {
+		p.contextPath = s.ContextPath
+	}
+	if s.Timeout != 0 && !f.Changed("timeout") {
+		p.timeout = s.Timeout
+	}
+	if s.Retries != nil && !isSet(f, "retries", "HELM_REPO_RETRIES") {
+		p.retries = *s.Retries
+	}
+	if p.caFile == "" {
+		p.caFile = s.CAFile
+	}
+	if p.certFile == "" {
+		p.certFile = s.CertFile
+	}
+	if p.keyFile == "" {
+		p.keyFile = s.KeyFile
+	}
+	if s.Insecure != nil && !isSet(f, "insecure", "HELM_REPO_INSECURE") {
+		p.insecureSkipVerify = *s.Insecure
+	}
+	if v := s.plainHTTP(); v != nil && !isSet(f, "", "HELM_REPO_USE_HTTP") {
+		p.useHTTP = *v
+	}
+	if p.versionPattern == "" {
+		p.versionPattern = s.VersionPattern
+	}
+	if s.Dev != nil && !f.Changed("dev-repo") {
+		p.devRepo = *s.Dev
+	}
+	return nil
+}
+
+// withDefaultCredentials fills in the username and password resolved for
+// a repo with the global ones from the config file, if they are unset
+func (p *pushCmd) withDefaultCredentials(username, password string) (string, string) {
+	if username == "" {
+		username = p.defaultUsername
+	}
+	if password == "" {
+		password = p.defaultPassword
+	}
+	return username, password
+}
+
+// isSet reports whether a setting was given explicitly, either
+// via the named flag or the named environment variable
+func isSet(f *pflag.FlagSet, flagName string, envName string) bool {
+	if flagName != "" && f.Changed(flagName) {
+		return true
+	}
+	_, ok := os.LookupEnv(envName)
+	return ok
+}
+
+// connectionSettings are the connection settings reported with --debug
+var connectionSettings = []setting{
+	{name: "username", flag: "username", aliasFlag: "repo-username", perRepoEnv: true, fromVault: true, env: "HELM_REPO_USERNAME", configKeys: []string{"username"},
+		value: func(p *pushCmd) string {
+			username, _ := p.withDefaultCredentials(p.username, p.password)
+			return username
+		}},
+	{name: "password", flag: "password", aliasFlag: "repo-password", perRepoEnv: true, fromVault: true, env: "HELM_REPO_PASSWORD", configKeys: []string{"password"}, secret: true,
+		value: func(p *pushCmd) string {
+			_, password := p.withDefaultCredentials(p.username, p.password)
+			return password
+		}},
+	{name: "accessToken", flag: "access-token", perRepoEnv: true, env: "HELM_REPO_ACCESS_TOKEN", configKeys: []string{"accessToken"}, secret: true,
+		value: func(p *pushCmd) string { return p.accessToken }},
+	{name: "apiKey", flag: "api-key", perRepoEnv: true, env: "HELM_REPO_API_KEY", secret: true,
+		value: func(p *pushCmd) string { return p.apiKey }},
+	{name: "tokenCommand", flag: "token-command", perRepoEnv: true, env: "HELM_REPO_TOKEN_COMMAND", secret: true,
+		value: func(p *pushCmd) string { return p.tokenCommand }},
+	{name: "oauthClientSecret", flag: "oauth-client-secret", perRepoEnv: true, env: "HELM_REPO_OAUTH_CLIENT_SECRET", secret: true,
+		value: func(p *pushCmd) string { return p.oauthClientSecret }},
+	{name: "authHeader", flag: "auth-header", env: "HELM_REPO_AUTH_HEADER", configKeys: []string{"authHeader"},
+		value: func(p *pushCmd) string { return p.authHeader }},
+	{name: "contextPath", flag: "context-path", perRepoEnv: true, env: "HELM_REPO_CONTEXT_PATH", configKeys: []string{"contextPath"},
+		value: func(p *pushCmd) string { return p.contextPath }},
+	{name: "timeout", flag: "timeout", configKeys: []string{"timeout"},
+		value: func(p *pushCmd) string { return strconv.FormatInt(p.timeout, 10) }},
+	{name: "retries", flag: "retries", env: "HELM_REPO_RETRIES", configKeys: []string{"retries"},
+		value: func(p *pushCmd) string { return strconv.Itoa(p.retries) }},
+	{name: "caFile", flag: "ca-file", env: "HELM_REPO_CA_FILE", configKeys: []string{"caFile"},
+		value: func(p *pushCmd) string { return p.caFile }},
+	{name: "certFile", flag: "cert-file", env: "HELM_REPO_CERT_FILE", configKeys: []string{"certFile"},
+		value: func(p *pushCmd) string { return p.certFile }},
+	{name: "keyFile", flag: "key-file", env: "HELM_REPO_KEY_FILE", configKeys: []string{"keyFile"},
+		value: func(p *pushCmd) string { return p.keyFile }},
+	{name: "insecure", flag: "insecure", env: "HELM_REPO_INSECURE", configKeys: []string{"insecure"},
+		value: func(p *pushCmd) string { return strconv.FormatBool(p.insecureSkipVerify) }},
+	{name: "plainHTTP", env: "HELM_REPO_USE_HTTP", configKeys: []string{"plainHTTP", "useHTTP"},
+		value: func(p *pushCmd) string { return strconv.FormatBool(p.useHTTP) }},
+}
+
+// writeSettingSources writes the effective value of each connection
+// setting and where it came from (flag, environment, config file or default)
+func (p *pushCmd) writeSettingSources(w io.Writer, f *pflag.FlagSet) error {
+	c, err := loadPluginConfig()
+	if err != nil {
+		return err
+	}
+	global, err := settingKeys(c.repoSettings)
+	if err != nil {
+		return err
+	}
+	repo, err := settingKeys(c.Repositories[p.repoName])
+	if err != nil {
+		return err
+	}
+	for _, s := range connectionSettings {
+		source := "default"
+		switch {
+		case s.fromVault && p.vaultPath != "":
+			source = "vault " + p.vaultPath
+		case s.flag != "" && f.Changed(s.flag):
+			source = "flag --" + s.flag
+		case s.aliasFlag != "" && f.Changed(s.aliasFlag):
+			source = "flag --" + s.aliasFlag
+		case s.perRepoEnv && isSet(f, "", repoEnvName(p.repoName, s.env)):
+			source = "env " + repoEnvName(p.repoName, s.env)
+		case s.env != "" && isSet(f, "", s.env):
+			source = "env " + s.env
+		case hasAnyKey(repo, s.configKeys):
+			source = fmt.Sprintf("config %s (repositories.%s)", c.path, p.repoName)
+		case hasAnyKey(global, s.configKeys):
+			source = fmt.Sprintf("config %s", c.path)
+		case s.name == "accessToken" && p.accessToken != "":
+			source = "~/.cfconfig"
+		}
+		value := s.value(p)
+		if s.secret && value != "" {
+			value = "***"
+		}
+		fmt.Fprintf(w, "[debug] %s=%s (%s)\n", s.name, value, source)
+	}
+	return nil
+}
+
+// settingKeys returns the names of the settings set in s
+func settingKeys(s repoSettings) (map[string]bool, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool, len(m))
+	for k := range m {
+		keys[k] = true
+	}
+	return keys, nil
+}
+
+// hasAnyKey reports whether any of the given keys is in keys
+func hasAnyKey(keys map[string]bool, names []string) bool {
+	for _, name := range names {
+		if keys[name] {
+			return true
+		}
+	}
+	return false
+}
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the helm-push configuration file",
+	}
+	cmd.AddCommand(newConfigInitCmd())
+	return cmd
+}
+
+func newConfigInitCmd() *cobra.Command {
+	c := &configInitCmd{}
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Write a starter configuration file [$HELM_PUSH_CONFIG]",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c.out = cmd.OutOrStdout()
+			return c.run()
+		},
+	}
+	f := cmd.Flags()
+	f.BoolVarP(&c.force, "force", "f", false, "Overwrite the configuration file if it already exists")
+	return cmd
+}
+
+func (c *configInitCmd) run() error {
+	configPath, err := configFilePath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(configPath); err == nil && !c.force {
+		return fmt.Errorf("config file %s already exists (use --force to overwrite)", configPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(configPath, []byte(starterConfig), 0600); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "Wrote %s\n", configPath)
+	return nil
+}
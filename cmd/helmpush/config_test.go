@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/helm/pkg/repo"
+)
+
+var testConfig = `
+username: globaluser
+contextPath: /global
+timeout: 45
+retries: 2
+plainHTTP: true
+versionPattern: '^\d+\.\d+\.\d+$'
+repositories:
+  myrepo:
+    username: repouser
+    versionPattern: '-dev\.\d+$'
+    insecure: true
+    retries: 0
+    dev: true
+`
+
+func TestLoadPluginConfig(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	configPath := filepath.Join(tmp, "config.yaml")
+	os.Setenv("HELM_PUSH_CONFIG", configPath)
+	defer os.Unsetenv("HELM_PUSH_CONFIG")
+
+	// Missing file is not an error
+	c, err := loadPluginConfig()
+	if err != nil {
+		t.Error("unexpected error loading missing config file", err)
+	}
+	if c.Username != "" {
+		t.Errorf("expected empty username, instead got %s", c.Username)
+	}
+
+	// Malformed file
+	os.WriteFile(configPath, []byte("username: [a"), 0600)
+	_, err = loadPluginConfig()
+	if err == nil {
+		t.Error("expected error loading malformed config file, instead got nil")
+	}
+
+	// Unknown setting
+	os.WriteFile(configPath, []byte("contexPath: /typo\n"), 0600)
+	_, err = loadPluginConfig()
+	if err == nil || !strings.Contains(err.Error(), "contexPath") {
+		t.Errorf("expected error loading config file with unknown setting, instead got %v", err)
+	}
+
+	os.WriteFile(configPath, []byte(testConfig), 0600)
+	c, err = loadPluginConfig()
+	if err != nil {
+		t.Fatal("unexpected error loading config file", err)
+	}
+
+	s := c.settingsForRepo("otherrepo")
+	if s.Username != "globaluser" {
+		t.Errorf("expected username to be globaluser, instead got %s", s.Username)
+	}
+	if s.Insecure != nil {
+		t.Errorf("expected insecure to be unset, instead got %v", *s.Insecure)
+	}
+	if s.Retries == nil || *s.Retries != 2 {
+		t.Errorf("expected retries to be 2, instead got %v", s.Retries)
+	}
+	if s.VersionPattern != `^\d+\.\d+\.\d+$` {
+		t.Errorf("expected global version pattern, instead got %s", s.VersionPattern)
+	}
+
+	s = c.settingsForRepo("myrepo")
+	if s.Username != "repouser" {
+		t.Errorf("expected username to be repouser, instead got %s", s.Username)
+	}
+	if s.ContextPath != "/global" {
+		t.Errorf("expected context path to be /global, instead got %s", s.ContextPath)
+	}
+	if s.Insecure == nil || !*s.Insecure {
+		t.Error("expected insecure to be true")
+	}
+	if s.Retries == nil || *s.Retries != 0 {
+		t.Errorf("expected per-repo retries to override global, instead got %v", s.Retries)
+	}
+	if v := s.plainHTTP(); v == nil || !*v {
+		t.Error("expected plainHTTP to be true")
+	}
+	if s.VersionPattern != `-dev\.\d+$` {
+		t.Errorf("expected per-repo version pattern to override global, instead got %s", s.VersionPattern)
+	}
+}
+
+func TestConfigFilePath(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	os.Unsetenv("HELM_PUSH_CONFIG")
+	os.Setenv("HELM_PLUGIN_DIR", tmp)
+	defer os.Unsetenv("HELM_PLUGIN_DIR")
+	home, _ := os.UserHomeDir()
+
+	// Plugin dir is only used if it has a config file
+	configPath, err := configFilePath()
+	if err != nil {
+		t.Fatal("unexpected error getting config file path", err)
+	}
+	if expected := filepath.Join(home, ".config", "helm-push", "config.yaml"); configPath != expected {
+		t.Errorf("expected config file path to be %s, instead got %s", expected, configPath)
+	}
+
+	pluginConfigPath := filepath.Join(tmp, "config.yaml")
+	os.WriteFile(pluginConfigPath, []byte(testConfig), 0600)
+	if configPath, _ = configFilePath(); configPath != pluginConfigPath {
+		t.Errorf("expected config file path to be %s, instead got %s", pluginConfigPath, configPath)
+	}
+
+	os.Setenv("HELM_PUSH_CONFIG", "/other/config.yaml")
+	defer os.Unsetenv("HELM_PUSH_CONFIG")
+	if configPath, _ = configFilePath(); configPath != "/other/config.yaml" {
+		t.Errorf("expected config file path to be /other/config.yaml, instead got %s", configPath)
+	}
+}
+
+func TestSetFieldsFromConfig(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	configPath := filepath.Join(tmp, "config.yaml")
+	os.WriteFile(configPath, []byte(testConfig), 0600)
+	os.Setenv("HELM_PUSH_CONFIG", configPath)
+	defer os.Unsetenv("HELM_PUSH_CONFIG")
+	os.Unsetenv("HELM_REPO_USERNAME")
+	os.Unsetenv("HELM_REPO_CONTEXT_PATH")
+	os.Unsetenv("HELM_REPO_INSECURE")
+	os.Unsetenv("HELM_REPO_RETRIES")
+	os.Unsetenv("HELM_REPO_USE_HTTP")
+
+	// Config values used as defaults
+	args := []string{"mychart", "myrepo"}
+	cmd := newPushCmd(args)
+	p := &pushCmd{repoName: "myrepo", timeout: 30}
+	if err := p.setFieldsFromConfig(cmd.Flags()); err != nil {
+		t.Fatal("unexpected error setting fields from config", err)
+	}
+	if p.username != "repouser" || p.contextPath != "/global" || p.timeout != 45 || !p.insecureSkipVerify || !p.useHTTP || !p.devRepo {
+		t.Errorf("unexpected fields from config: %+v", p)
+	}
+	p = &pushCmd{repoName: "otherrepo"}
+	if err := p.setFieldsFromConfig(cmd.Flags()); err != nil {
+		t.Fatal("unexpected error setting fields from config", err)
+	}
+	if p.retries != 2 || p.devRepo {
+		t.Errorf("expected retries from global config and not a dev repo, instead got %d %v", p.retries, p.devRepo)
+	}
+
+	// Flags and environment take precedence
+	os.Setenv("HELM_REPO_INSECURE", "false")
+	defer os.Unsetenv("HELM_REPO_INSECURE")
+	cmd.Flags().Set("timeout", "10")
+	p = &pushCmd{repoName: "myrepo", username: "flaguser", timeout: 10}
+	if err := p.setFieldsFromConfig(cmd.Flags()); err != nil {
+		t.Fatal("unexpected error setting fields from config", err)
+	}
+	if p.username != "flaguser" || p.timeout != 10 || p.insecureSkipVerify {
+		t.Errorf("expected flags and env to take precedence over config: %+v", p)
+	}
+}
+
+func TestGlobalConfigCredentials(t *testing.T) {
+	var uploads []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cm/api/charts" {
+			username, password, _ := r.BasicAuth()
+			uploads = append(uploads, fmt.Sprintf("%s:%s", username, password))
+			w.WriteHeader(201)
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	configPath := filepath.Join(tmp, "config.yaml")
+	os.WriteFile(configPath, []byte(`
+username: globaluser
+password: globalpass
+repositories:
+  overridden:
+    username: configuser
+    password: configpass
+`), 0600)
+	os.Setenv("HELM_PUSH_CONFIG", configPath)
+	defer os.Unsetenv("HELM_PUSH_CONFIG")
+	os.Unsetenv("HELM_REPO_USERNAME")
+	os.Unsetenv("HELM_REPO_PASSWORD")
+
+	repoConfig := filepath.Join(tmp, "repositories.yaml")
+	f := repo.NewRepoFile()
+	f.Update(
+		&repo.Entry{Name: "withcreds", URL: ts.URL, Username: "repouser", Password: "repopass"},
+		&repo.Entry{Name: "nocreds", URL: ts.URL},
+		&repo.Entry{Name: "overridden", URL: ts.URL, Username: "repouser", Password: "repopass"},
+	)
+	f.WriteFile(repoConfig, 0644)
+
+	for repoName, expected := range map[string]string{
+		"withcreds":  "repouser:repopass",
+		"nocreds":    "globaluser:globalpass",
+		"overridden": "configuser:configpass",
+	} {
+		uploads = nil
+		args := []string{testTarballPath, repoName, "--repo-config", repoConfig, "--context-path", "/cm"}
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("unexpected error pushing to %s: %s", repoName, err)
+		}
+		if len(uploads) != 1 || uploads[0] != expected {
+			t.Errorf("expected push to %s with %s, instead got %v", repoName, expected, uploads)
+		}
+	}
+}
+
+func TestWriteSettingSources(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	configPath := filepath.Join(tmp, "config.yaml")
+	os.WriteFile(configPath, []byte(testConfig), 0600)
+	os.Setenv("HELM_PUSH_CONFIG", configPath)
+	defer os.Unsetenv("HELM_PUSH_CONFIG")
+	os.Setenv("HELM_REPO_CONTEXT_PATH", "/env")
+	defer os.Unsetenv("HELM_REPO_CONTEXT_PATH")
+	os.Unsetenv("HELM_REPO_USERNAME")
+	os.Unsetenv("HELM_REPO_PASSWORD")
+	os.Unsetenv("HELM_REPO_INSECURE")
+	os.Unsetenv("HELM_REPO_RETRIES")
+	os.Unsetenv("HELM_REPO_USE_HTTP")
+
+	cmd := newPushCmd([]string{"mychart", "myrepo"})
+	cmd.Flags().Set("timeout", "10")
+	cmd.Flags().Set("password", "secret")
+	p := &pushCmd{repoName: "myrepo", timeout: 10, password: "secret"}
+	p.setFieldsFromEnv(cmd.Flags())
+	if err := p.setFieldsFromConfig(cmd.Flags()); err != nil {
+		t.Fatal("unexpected error setting fields from config", err)
+	}
+
+	var out bytes.Buffer
+	if err := p.writeSettingSources(&out, cmd.Flags()); err != nil {
+		t.Fatal("unexpected error writing setting sources", err)
+	}
+	for _, expected := range []string{
+		"[debug] timeout=10 (flag --timeout)",
+		"[debug] password=*** (flag --password)",
+		"[debug] contextPath=/env (env HELM_REPO_CONTEXT_PATH)",
+		"[debug] username=repouser (config " + configPath + " (repositories.myrepo))",
+		"[debug] retries=0 (config " + configPath + " (repositories.myrepo))",
+		"[debug] plainHTTP=true (config " + configPath + ")",
+		"[debug] caFile= (default)",
+	} {
+		if !strings.Contains(out.String(), expected+"\n") {
+			t.Errorf("expected output to contain %q, instead got:\n%s", expected, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "secret") {
+		t.Errorf("expected password to be hidden, instead got:\n%s", out.String())
+	}
+
+	// credentials read from Vault
+	out.Reset()
+	p = &pushCmd{repoName: "myrepo", username: "ci", vaultPath: "secret/chartmuseum"}
+	if err := p.writeSettingSources(&out, newPushCmd(nil).Flags()); err != nil {
+		t.Fatal("unexpected error writing setting sources", err)
+	}
+	if !strings.Contains(out.String(), "[debug] username=ci (vault secret/chartmuseum)\n") {
+		t.Errorf("expected username from Vault, instead got:\n%s", out.String())
+	}
+}
+
+func TestConfigInitCmd(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	configPath := filepath.Join(tmp, "helm-push", "config.yaml")
+	os.Setenv("HELM_PUSH_CONFIG", configPath)
+	defer os.Unsetenv("HELM_PUSH_CONFIG")
+
+	c := &configInitCmd{out: &bytes.Buffer{}}
+	if err := c.run(); err != nil {
+		t.Fatal("unexpected error writing config file", err)
+	}
+	if _, err := loadPluginConfig(); err != nil {
+		t.Error("unexpected error loading starter config file", err)
+	}
+
+	// Refuse to overwrite without --force
+	if err := c.run(); err == nil {
+		t.Error("expected error overwriting existing config file, instead got nil")
+	}
+	c.force = true
+	if err := c.run(); err != nil {
+		t.Error("unexpected error overwriting config file with force", err)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+	"github.com/spf13/cobra"
+)
+
+// errMissingAtSource is returned when a file to copy isn't in the source repo
+var errMissingAtSource = errors.New("missing at source")
+
+type (
+	copyCmd struct {
+		*pushCmd
+		force    bool
+		withProv bool
+	}
+)
+
+func newCopyCmd(p *pushCmd) *cobra.Command {
+	c := &copyCmd{pushCmd: p}
+	cmd := &cobra.Command{
+		Use:   "copy CHART VERSION SOURCE_REPO DEST_REPO",
+		Short: "Copy a chart version (and its provenance file, if any) between ChartMuseum repositories, without saving it locally",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 4 {
+				return errors.New("This command needs 4 arguments: name of chart, chart version, name of source chart repository, name of destination chart repository (or repo URLs)")
+			}
+			return nil
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 2 || len(args) == 3 {
+				return completeRepoNames(len(args))(cmd, args, toComplete)
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c.chartName = args[0]
+			c.chartVersion = args[1]
+
+			// Each repo is set up with its own settings
+			targets, err := c.setupTargets(cmd, args[2:])
+			if err != nil {
+				return err
+			}
+			return c.copy(targets[0], targets[1])
+		},
+	}
+	f := cmd.Flags()
+	f.BoolVarP(&c.force, "force", "f", false, "Force upload even if chart version exists in the destination repo")
+	f.BoolVar(&c.withProv, "with-prov", false, "Require the provenance file of the chart version, failing if the source repo doesn't have it. It is copied without this flag too, if present")
+	return cmd
+}
+
+// copy streams the chart package, and its provenance file if present,
+// from the source repo's download URL straight to the destination repo,
+// without saving it locally
+func (c *copyCmd) copy(source *pushCmd, dest *pushCmd) error {
+	sourceClient, err := source.connect()
+	if err != nil {
+		return err
+	}
+	destClient, err := dest.connect()
+	if err != nil {
+		return err
+	}
+	if err := c.copyVersion(sourceClient, destClient, source.repoName, dest.repoName, c.chartName, c.chartVersion); err != nil {
+		return err
+	}
+	c.log.info(c.chartName, dest.repoName, "Done.")
+	return nil
+}
+
+// copyVersion streams a single chart version between connected repos,
+// with its provenance file if the source repo has one. With --with-prov,
+// a missing provenance file fails the copy
+func (c *copyCmd) copyVersion(sourceClient, destClient *cm.Client, sourceRepo, destRepo, name, version string) error {
+	// The package is copied as is, so its digest is the same in both repos
+	packageName := fmt.Sprintf("%s-%s.tgz", name, version)
+	// read first, so that the package isn't copied without it
+	var prov []byte
+	body, err := c.openChartFile(sourceClient, sourceRepo, packageName+".prov")
+	switch {
+	case errors.Is(err, errMissingAtSource) && !c.withProv:
+	case err != nil:
+		return err
+	default:
+		prov, err = io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	body, err = c.openChartFile(sourceClient, sourceRepo, packageName)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	c.log.info(name, destRepo, "Copying %s from %s to %s...", packageName, sourceRepo, destRepo)
+	resp, err := destClient.UploadChartPackageStreamWithContext(c.ctx, packageName, body, c.force)
+	if err != nil {
+		return err
+	}
+	if err := c.handleCopyResponse(resp, packageName, destRepo); err != nil {
+		return err
+	}
+
+	if prov != nil {
+		resp, err := destClient.UploadProvenanceFileStreamWithContext(c.ctx, packageName+".prov", bytes.NewReader(prov), c.force)
+		if err != nil {
+			return err
+		}
+		if err := handleFileUploadResponse(resp, "provenance file"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *copyCmd) handleCopyResponse(resp *http.Response, fileName string, repoName string) error {
+	defer resp.Body.Close()
+	if resp.StatusCode == 409 {
+		return fmt.Errorf("conflict at destination: %s already exists in %s (use --force to overwrite)", fileName, repoName)
+	}
+	if resp.StatusCode != 201 {
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		return getChartmuseumError(b, resp.StatusCode)
+	}
+	return nil
+}
+
+// openChartFile starts downloading a file from the repo's charts/
+// directory, returning its body to read it from
+func (c *copyCmd) openChartFile(client *cm.Client, repoName string, fileName string) (io.ReadCloser, error) {
+	resp, err := client.DownloadFileWithContext(c.ctx, "charts/"+fileName)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == 404 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s not found in %s", errMissingAtSource, fileName, repoName)
+	}
+	if resp.StatusCode != 200 {
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return nil, getChartmuseumError(b, resp.StatusCode)
+	}
+	return resp.Body, nil
+}
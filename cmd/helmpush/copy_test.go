@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCopyCmd(t *testing.T) {
+	tarball, err := os.ReadFile(testTarballPath)
+	if err != nil {
+		t.Fatal("unexpected error reading test tarball", err)
+	}
+	prov := []byte("-----BEGIN PGP SIGNED MESSAGE-----")
+
+	hasProv := false
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/x/y/z/charts/mychart-0.1.0.tgz":
+			w.Write(tarball)
+		case r.URL.Path == "/x/y/z/charts/mychart-0.1.0.tgz.prov" && hasProv:
+			w.Write(prov)
+		default:
+			w.WriteHeader(404)
+			w.Write([]byte("{\"error\": \"not found\"}"))
+		}
+	}))
+	defer source.Close()
+
+	var uploaded, provUploaded []byte
+	provStatus := 201
+	dest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, force := r.URL.Query()["force"]
+		switch r.URL.Path {
+		case "/x/y/z/api/charts":
+			if uploaded != nil && !force {
+				w.WriteHeader(409)
+				w.Write([]byte("{\"error\": \"file already exists\"}"))
+				return
+			}
+			f, _, _ := r.FormFile("chart")
+			uploaded, _ = io.ReadAll(f)
+		case "/x/y/z/api/prov":
+			if provStatus != 201 {
+				w.WriteHeader(provStatus)
+				w.Write([]byte("{\"error\": \"invalid provenance file\"}"))
+				return
+			}
+			f, _, _ := r.FormFile("prov")
+			provUploaded, _ = io.ReadAll(f)
+		}
+		w.WriteHeader(201)
+		w.Write([]byte("{\"saved\": true}"))
+	}))
+	defer dest.Close()
+
+	cleanup := setupTestRepo(t, source.URL)
+	defer cleanup()
+
+	copyChart := func(args ...string) error {
+		args = append([]string{"copy"}, args...)
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		return cmd.Execute()
+	}
+
+	// Missing at source
+	err = copyChart("mychart", "0.2.0", "helm-push-test", dest.URL)
+	if err == nil || !strings.Contains(err.Error(), "missing at source") {
+		t.Errorf("expected missing at source error, instead got %v", err)
+	}
+
+	// Happy path, package is copied unchanged
+	if err := copyChart("mychart", "0.1.0", "helm-push-test", dest.URL); err != nil {
+		t.Fatal("unexpected error copying chart", err)
+	}
+	if !bytes.Equal(uploaded, tarball) {
+		t.Error("expected package to be copied unchanged")
+	}
+	if provUploaded != nil {
+		t.Error("expected no provenance file to be copied")
+	}
+
+	// Conflict at destination
+	err = copyChart("mychart", "0.1.0", "helm-push-test", dest.URL)
+	if err == nil || !strings.Contains(err.Error(), "conflict at destination") {
+		t.Errorf("expected conflict at destination error, instead got %v", err)
+	}
+
+	// Provenance file missing at source
+	uploaded = nil
+	err = copyChart("--with-prov", "mychart", "0.1.0", "helm-push-test", dest.URL)
+	if err == nil || err.Error() != "missing at source: mychart-0.1.0.tgz.prov not found in helm-push-test" {
+		t.Errorf("expected missing provenance file error, instead got %v", err)
+	}
+	if uploaded != nil {
+		t.Error("expected package not to be copied without its provenance file")
+	}
+
+	// Provenance file is copied when present, without --with-prov
+	hasProv = true
+	if err := copyChart("mychart", "0.1.0", "helm-push-test", dest.URL); err != nil || !bytes.Equal(provUploaded, prov) {
+		t.Errorf("expected the provenance file to be copied, instead got %q (%v)", provUploaded, err)
+	}
+	provUploaded = nil
+
+	// Forced, with provenance file
+	if err := copyChart("--force", "--with-prov", "mychart", "0.1.0", "helm-push-test", dest.URL); err != nil {
+		t.Fatal("unexpected error copying chart with --force", err)
+	}
+	if !bytes.Equal(uploaded, tarball) || !bytes.Equal(provUploaded, prov) {
+		t.Errorf("expected package and provenance file to be copied, instead got %q", provUploaded)
+	}
+
+	// Provenance file rejected
+	provStatus = 400
+	err = copyChart("--force", "--with-prov", "mychart", "0.1.0", "helm-push-test", dest.URL)
+	if err == nil || !strings.Contains(err.Error(), "failed to upload provenance file: 400: invalid provenance file") {
+		t.Errorf("expected provenance file upload error, instead got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+	"github.com/spf13/cobra"
+)
+
+type (
+	deleteCmd struct {
+		*pushCmd
+		ignoreMissing bool
+		yes           bool
+		in            io.Reader
+	}
+)
+
+func newDeleteCmd(p *pushCmd) *cobra.Command {
+	d := &deleteCmd{pushCmd: p}
+	cmd := &cobra.Command{
+		Use:   "delete CHART VERSION REPO",
+		Short: "Delete a chart version from ChartMuseum",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 3 {
+				return errors.New("This command needs 3 arguments: name of chart, chart version, name of chart repository (or repo URL)")
+			}
+			return nil
+		},
+		ValidArgsFunction: completeRepoNames(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			d.in = cmd.InOrStdin()
+			d.chartName = args[0]
+			d.chartVersion = args[1]
+			d.repoName = args[2]
+			if err := d.setup(cmd); err != nil {
+				return err
+			}
+			return d.delete()
+		},
+	}
+	f := cmd.Flags()
+	f.BoolVarP(&d.ignoreMissing, "ignore-missing", "", false, "Do not fail if the chart version does not exist")
+	f.BoolVarP(&d.yes, "yes", "y", false, "Do not prompt for confirmation")
+	return cmd
+}
+
+func (d *deleteCmd) delete() error {
+	repo, err := d.getRepo()
+	if err != nil {
+		return err
+	}
+
+	if !d.yes && isTerminal(d.in) {
+		prompt := fmt.Sprintf("Delete %s-%s from %s?", d.chartName, d.chartVersion, d.repoName)
+		ok, err := confirm(d.in, d.out, prompt)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("aborted")
+		}
+	}
+
+	client, err := d.newClientFromRepo(repo)
+	if err != nil {
+		return err
+	}
+
+	err = client.DeleteChartWithContext(d.ctx, d.chartName, d.chartVersion)
+	if errors.Is(err, cm.ErrNotFound) {
+		if d.ignoreMissing {
+			d.log.info(d.chartName, d.repoName, "%s-%s not found in %s, nothing to delete.", d.chartName, d.chartVersion, d.repoName)
+			return nil
+		}
+		return fmt.Errorf("version not found: %s-%s", d.chartName, d.chartVersion)
+	}
+	if err != nil {
+		return err
+	}
+	d.log.info(d.chartName, d.repoName, "Deleted %s-%s from %s.", d.chartName, d.chartVersion, d.repoName)
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDeleteCmd(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" && r.URL.Path == "/x/y/z/api/charts/mychart/0.1.0" {
+			w.WriteHeader(200)
+			w.Write([]byte("{\"deleted\": true}"))
+		} else {
+			w.WriteHeader(404)
+			w.Write([]byte("{\"error\": \"not found\"}"))
+		}
+	}))
+	defer ts.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+
+	// Wrong number of args
+	args := []string{"delete", "mychart", "helm-push-test"}
+	cmd := newPushCmd(args)
+	cmd.SetArgs(args)
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	if err := cmd.Execute(); err == nil {
+		t.Error("expecting error with missing args, instead got nil")
+	}
+
+	// Happy path
+	out := &bytes.Buffer{}
+	args = []string{"delete", "mychart", "0.1.0", "helm-push-test"}
+	cmd = newPushCmd(args)
+	cmd.SetArgs(args)
+	cmd.SetOut(out)
+	if err := cmd.Execute(); err != nil {
+		t.Error("unexpected error deleting chart", err)
+	}
+	if !strings.Contains(out.String(), "Deleted mychart-0.1.0") {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+
+	// Missing version
+	args = []string{"delete", "mychart", "0.2.0", "helm-push-test"}
+	cmd = newPushCmd(args)
+	cmd.SetArgs(args)
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "version not found") {
+		t.Errorf("expecting version not found error, instead got %v", err)
+	}
+
+	// Missing version, ignored
+	args = []string{"delete", "--ignore-missing", "mychart", "0.2.0", "helm-push-test"}
+	cmd = newPushCmd(args)
+	cmd.SetArgs(args)
+	cmd.SetOut(&bytes.Buffer{})
+	if err := cmd.Execute(); err != nil {
+		t.Error("unexpected error deleting missing chart with --ignore-missing", err)
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/chartmuseum/helm-push/pkg/helm"
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+const (
+	diffIdentical       = "identical"
+	diffLocalOnly       = "local-only"
+	diffMissingRemotely = "missing remotely"
+	diffVersionMismatch = "version mismatch"
+	diffDigestMismatch  = "digest mismatch"
+)
+
+type (
+	diffCmd struct {
+		*pushCmd
+		dir    string
+		digest bool
+		output string
+	}
+
+	// chartDiff compares a local chart with the repo
+	chartDiff struct {
+		Name          string `json:"name"`
+		Path          string `json:"path"`
+		LocalVersion  string `json:"localVersion"`
+		RemoteVersion string `json:"remoteVersion,omitempty"`
+		Status        string `json:"status"`
+	}
+)
+
+func newDiffCmd(p *pushCmd) *cobra.Command {
+	d := &diffCmd{pushCmd: p}
+	cmd := &cobra.Command{
+		Use:   "diff DIR REPO",
+		Short: "Compare the charts in a local directory with a ChartMuseum repository",
+		Long: `Compare the charts (directories and .tgz packages) found in DIR with the
+index of a ChartMuseum repository. Exits non-zero if any chart differs.
+
+Statuses:
+
+  identical         the local version is in the repo
+  missing remotely  the local version is newer than any version in the repo
+  version mismatch  the local version is not in the repo, and older than its latest
+  digest mismatch   the local version is in the repo with a different package (--digest)
+  local-only        the chart is not in the repo at all
+`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return errors.New("This command needs 2 arguments: path to charts directory, name of chart repository (or repo URL)")
+			}
+			return nil
+		},
+		ValidArgsFunction: completePushArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutputFormat(d.output, outputTable, outputJSON); err != nil {
+				return err
+			}
+			d.dir = args[0]
+			d.repoName = args[1]
+			if err := d.setup(cmd); err != nil {
+				return err
+			}
+			return d.diff()
+		},
+	}
+	f := cmd.Flags()
+	f.BoolVar(&d.digest, "digest", false, "Also compare package digests of charts whose version is in the repo")
+	f.StringVarP(&d.output, "output", "o", outputTable, "Output format (table, json)")
+	return cmd
+}
+
+func (d *diffCmd) diff() error {
+	paths, err := findLocalCharts(d.dir)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no charts found in %s", d.dir)
+	}
+
+	client, err := d.connect()
+	if err != nil {
+		return err
+	}
+
+	// Always fetch a fresh index, rather than the one cached by helm repo update
+	index, err := helm.GetIndexByDownloader(getIndexDownloader(d.ctx, client))
+	if err != nil {
+		return err
+	}
+
+	tmp, err := d.mkdirTemp()
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	diffs := []chartDiff{}
+	differ := 0
+	for _, path := range paths {
+		chart, err := helm.GetChartByName(path)
+		if err != nil {
+			return err
+		}
+		cd, err := d.compare(chart, path, index.Entries[chart.Name()], tmp)
+		if err != nil {
+			return err
+		}
+		if cd.Status != diffIdentical {
+			differ++
+		}
+		diffs = append(diffs, cd)
+	}
+
+	if d.output == outputJSON {
+		if err := printJSON(d.out, diffs); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(d.out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tLOCAL\tREMOTE\tSTATUS\tPATH")
+		for _, cd := range diffs {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", cd.Name, cd.LocalVersion, cd.RemoteVersion, cd.Status, cd.Path)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if differ > 0 {
+		return fmt.Errorf("%d of %d charts differ from %s", differ, len(diffs), d.repoName)
+	}
+	return nil
+}
+
+// compare compares a local chart with its versions in the repo index
+// (newest first), packaging it in tmp to compare digests with --digest
+func (d *diffCmd) compare(chart *helm.Chart, path string, remote repo.ChartVersions, tmp string) (chartDiff, error) {
+	cd := chartDiff{Name: chart.Name(), Path: path, LocalVersion: chart.Version()}
+	if len(remote) == 0 {
+		cd.Status = diffLocalOnly
+		return cd, nil
+	}
+	cd.RemoteVersion = remote[0].Version
+
+	for _, cv := range remote {
+		if cv.Version != chart.Version() {
+			continue
+		}
+		cd.RemoteVersion = cv.Version
+		cd.Status = diffIdentical
+		if d.digest {
+			digest, err := packageDigest(chart, path, tmp)
+			if err != nil {
+				return cd, err
+			}
+			if digest != cv.Digest {
+				cd.Status = diffDigestMismatch
+			}
+		}
+		return cd, nil
+	}
+
+	cd.Status = diffVersionMismatch
+	local, err := semver.NewVersion(chart.Version())
+	if err != nil {
+		return cd, nil
+	}
+	if latest, err := semver.NewVersion(remote[0].Version); err == nil && local.GreaterThan(latest) {
+		cd.Status = diffMissingRemotely
+	}
+	return cd, nil
+}
+
+// packageDigest returns the sha256 digest of the chart at path: that of
+// the package itself for a .tgz package, which is pushed as it is, or of
+// the package push builds for a chart directory
+func packageDigest(chart *helm.Chart, path string, tmp string) (string, error) {
+	if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+		return packageChecksum(path)
+	}
+	chartPackagePath, err := helm.CreateChartPackage(chart, tmp)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(chartPackagePath)
+	b, err := os.ReadFile(chartPackagePath)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(b)), nil
+}
+
+// findLocalCharts returns the paths of the chart directories and .tgz
+// packages in dir (or dir itself, if it is a chart), skipping hidden
+// directories and the contents of charts
+func findLocalCharts(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			if strings.HasSuffix(path, ".tgz") {
+				paths = append(paths, path)
+			}
+			return nil
+		}
+		if path != dir && strings.HasPrefix(fi.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if isChartDir(path) {
+			paths = append(paths, path)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return paths, err
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/chartmuseum/helm-push/pkg/helm"
+)
+
+func TestFindLocalCharts(t *testing.T) {
+	paths, err := findLocalCharts("../../testdata/charts")
+	if err != nil {
+		t.Fatal("unexpected error finding local charts", err)
+	}
+	if len(paths) != 2 || paths[0] != "../../testdata/charts/helm2/mychart" || paths[1] != "../../testdata/charts/helm3/my-v3-chart" {
+		t.Errorf("unexpected local charts: %v", paths)
+	}
+
+	paths, err = findLocalCharts(testTarballPath)
+	if err != nil || len(paths) != 1 {
+		t.Errorf("expected a .tgz package to be found, instead got %v (%v)", paths, err)
+	}
+}
+
+func TestDiffCmd(t *testing.T) {
+	var index string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/x/y/z/index.yaml" {
+			w.WriteHeader(200)
+			w.Write([]byte(index))
+			return
+		}
+		w.WriteHeader(404)
+		w.Write([]byte("{\"error\": \"not found\"}"))
+	}))
+	defer ts.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+
+	diff := func(args ...string) ([]chartDiff, error) {
+		out := &bytes.Buffer{}
+		args = append([]string{"diff", "-o", "json"}, args...)
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		cmd.SetOut(out)
+		cmd.SetErr(&bytes.Buffer{})
+		err := cmd.Execute()
+		var diffs []chartDiff
+		// usage is printed after the results on error
+		json.NewDecoder(out).Decode(&diffs)
+		return diffs, err
+	}
+
+	// Local mychart-0.1.0 and my-v3-chart-0.1.0
+	for _, tc := range []struct {
+		versions string
+		status   string
+	}{
+		{"[]", diffLocalOnly},
+		{`[{"version": "0.0.9"}]`, diffMissingRemotely},
+		{`[{"version": "0.2.0"}, {"version": "0.0.9"}]`, diffVersionMismatch},
+		{`[{"version": "0.2.0"}, {"version": "0.1.0"}]`, diffIdentical},
+	} {
+		index = fmt.Sprintf(`{"apiVersion": "v1", "entries": {"mychart": %s}}`, tc.versions)
+		diffs, err := diff("../../testdata/charts", "helm-push-test")
+		if err == nil {
+			t.Error("expected error with my-v3-chart missing from the repo, instead got nil")
+		}
+		if len(diffs) != 2 || diffs[0].Status != tc.status || diffs[1].Status != diffLocalOnly {
+			t.Errorf("expected mychart status to be %q with remote versions %s, instead got %+v", tc.status, tc.versions, diffs)
+		}
+	}
+
+	// No differences
+	if _, err := diff("../../testdata/charts/helm2", "helm-push-test"); err != nil {
+		t.Error("unexpected error with no differences", err)
+	}
+
+	// Digests
+	chart, err := helm.GetChartByName("../../testdata/charts/helm2/mychart")
+	if err != nil {
+		t.Fatal("unexpected error loading test chart", err)
+	}
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+	digest, err := packageDigest(chart, "../../testdata/charts/helm2/mychart", tmp)
+	if err != nil {
+		t.Fatal("unexpected error packaging test chart", err)
+	}
+
+	index = `{"apiVersion": "v1", "entries": {"mychart": [{"version": "0.1.0", "digest": "abc"}]}}`
+	diffs, err := diff("--digest", "../../testdata/charts/helm2", "helm-push-test")
+	if err == nil || len(diffs) != 1 || diffs[0].Status != diffDigestMismatch {
+		t.Errorf("expected digest mismatch, instead got %+v (%v)", diffs, err)
+	}
+
+	index = fmt.Sprintf(`{"apiVersion": "v1", "entries": {"mychart": [{"version": "0.1.0", "digest": "%s"}]}}`, digest)
+	if _, err := diff("--digest", "../../testdata/charts/helm2", "helm-push-test"); err != nil {
+		t.Error("unexpected error with matching digest", err)
+	}
+
+	// .tgz packages are compared as they are, not packaged again
+	tgzDigest, err := packageChecksum(testTarballPath)
+	if err != nil {
+		t.Fatal("unexpected error reading test package checksum", err)
+	}
+	index = fmt.Sprintf(`{"apiVersion": "v1", "entries": {"mychart": [{"version": "0.1.0", "digest": "%s"}]}}`, tgzDigest)
+	if diffs, err := diff("--digest", testTarballPath, "helm-push-test"); err != nil || len(diffs) != 1 || diffs[0].Status != diffIdentical {
+		t.Errorf("expected the .tgz package to match its own digest, instead got %+v (%v)", diffs, err)
+	}
+}
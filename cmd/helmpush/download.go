@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+	"github.com/spf13/cobra"
+)
+
+type (
+	downloadCmd struct {
+		*pushCmd
+		dest  string
+		force bool
+	}
+)
+
+func newDownloadCmd(p *pushCmd) *cobra.Command {
+	d := &downloadCmd{pushCmd: p}
+	cmd := &cobra.Command{
+		Use:   "download CHART VERSION REPO",
+		Short: "Download a chart version from a ChartMuseum repository by name and version",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 3 {
+				return errors.New("This command needs 3 arguments: name of chart, chart version, name of chart repository (or repo URL)")
+			}
+			return nil
+		},
+		ValidArgsFunction: completeRepoNames(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			d.chartName = args[0]
+			d.chartVersion = args[1]
+			d.repoName = args[2]
+			if err := d.setup(cmd); err != nil {
+				return err
+			}
+			return d.download()
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&d.dest, "dest", ".", "Directory to save the chart package in, created if missing")
+	f.BoolVarP(&d.force, "force", "f", false, "Overwrite the chart package if it already exists in the directory")
+	return cmd
+}
+
+// download looks up the chart version with the chart API and saves the
+// package at its URL in the index to --dest
+func (d *downloadCmd) download() error {
+	client, err := d.connect()
+	if err != nil {
+		return err
+	}
+
+	cv, err := client.GetChartInfoWithContext(d.ctx, d.chartName, d.chartVersion)
+	if errors.Is(err, cm.ErrNotFound) {
+		return fmt.Errorf("chart not found: no version %s of chart %q in %s", d.chartVersion, d.chartName, d.repoName)
+	}
+	if err != nil {
+		return err
+	}
+	filePath := fmt.Sprintf("charts/%s-%s.tgz", d.chartName, d.chartVersion)
+	if len(cv.URLs) > 0 {
+		if filePath, err = d.repoFilePath(client.URL(), cv.URLs[0]); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(d.dest, 0755); err != nil {
+		return err
+	}
+	d.outputFile = filepath.Join(d.dest, path.Base(filePath))
+	d.forceUpload = d.force
+	if err := d.checkOutputFile(); err != nil {
+		return err
+	}
+
+	d.log.info(d.chartName, d.repoName, "Downloading %s from %s...", path.Base(filePath), d.repoName)
+	resp, err := client.DownloadFileWithContext(d.ctx, filePath)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != 200 {
+		return getChartmuseumError(b, resp.StatusCode)
+	}
+	if err := d.writeOutputFile(b); err != nil {
+		return err
+	}
+	d.log.info(d.chartName, d.repoName, "Saved %s", d.outputFile)
+	return nil
+}
+
+// repoFilePath returns the path, relative to the repo, of a chart package
+// from its URL in the index. ChartMuseum makes the URL relative unless it
+// runs with --chart-url, in which case it must still point into the
+// repo, as the repo credentials are sent with the download
+func (d *downloadCmd) repoFilePath(repoURL string, packageURL string) (string, error) {
+	u, err := url.Parse(packageURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid chart URL %q: %s", packageURL, err)
+	}
+	if !u.IsAbs() {
+		return strings.TrimPrefix(u.Path, "/"), nil
+	}
+	base, err := url.Parse(repoURL)
+	if err != nil {
+		return "", err
+	}
+	prefix := path.Join("/", d.contextPath, strings.TrimPrefix(base.Path, d.contextPath))
+	if prefix != "/" {
+		prefix += "/"
+	}
+	if u.Scheme != base.Scheme || u.Host != base.Host || !strings.HasPrefix(u.Path, prefix) {
+		return "", fmt.Errorf("chart URL %s is outside %s, download it with helm pull instead", cm.RedactURL(packageURL), d.repoName)
+	}
+	return strings.TrimPrefix(u.Path, prefix), nil
+}
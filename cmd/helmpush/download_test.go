@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDownloadCmd(t *testing.T) {
+	tarball, err := os.ReadFile(testTarballPath)
+	if err != nil {
+		t.Fatal("unexpected error reading test tarball", err)
+	}
+
+	var chartURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/x/y/z/api/charts/mychart/0.1.0":
+			w.Write([]byte(`{"name":"mychart","version":"0.1.0","urls":["` + chartURL + `"]}`))
+		case "/x/y/z/charts/mychart-0.1.0.tgz":
+			w.Write(tarball)
+		default:
+			w.WriteHeader(404)
+			w.Write([]byte(`{"error":"not found"}`))
+		}
+	}))
+	defer ts.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+	dest := filepath.Join(tmp, "charts")
+	saved := filepath.Join(dest, "mychart-0.1.0.tgz")
+
+	download := func(args ...string) error {
+		args = append([]string{"download"}, args...)
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		return cmd.Execute()
+	}
+
+	// URL relative to the repo, as ChartMuseum returns by default
+	chartURL = "charts/mychart-0.1.0.tgz"
+	if err := download("mychart", "0.1.0", "helm-push-test", "--dest", dest); err != nil {
+		t.Fatal("unexpected error downloading chart", err)
+	}
+	if b, err := os.ReadFile(saved); err != nil || !bytes.Equal(b, tarball) {
+		t.Errorf("expected the chart package to be saved in %s (%v)", dest, err)
+	}
+
+	// An existing package is only overwritten with --force
+	if err := download("mychart", "0.1.0", "helm-push-test", "--dest", dest); err == nil || !strings.Contains(err.Error(), "already exists (use --force to overwrite)") {
+		t.Errorf("expected already exists error, instead got %v", err)
+	}
+	// Absolute URL in the repo, as with chartmuseum --chart-url
+	chartURL = ts.URL + "/x/y/z/charts/mychart-0.1.0.tgz"
+	if err := download("mychart", "0.1.0", "helm-push-test", "--dest", dest, "--force"); err != nil {
+		t.Error("unexpected error downloading chart with --force", err)
+	}
+
+	// Absolute URL outside the repo, which would be sent the repo credentials
+	chartURL = "https://cdn.example.com/mychart-0.1.0.tgz"
+	if err := download("mychart", "0.1.0", "helm-push-test", "--dest", dest, "--force"); err == nil || !strings.Contains(err.Error(), "chart URL https://cdn.example.com/mychart-0.1.0.tgz is outside helm-push-test") {
+		t.Errorf("expected outside repo error, instead got %v", err)
+	}
+
+	// Missing chart version
+	if err := download("mychart", "0.2.0", "helm-push-test"); err == nil || err.Error() != `chart not found: no version 0.2.0 of chart "mychart" in helm-push-test` {
+		t.Errorf("expected chart not found error, instead got %v", err)
+	}
+
+	// Defaults to the current directory
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(tmp)
+	chartURL = "charts/mychart-0.1.0.tgz"
+	if err := download("mychart", "0.1.0", "helm-push-test"); err != nil {
+		t.Fatal("unexpected error downloading chart", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "mychart-0.1.0.tgz")); err != nil {
+		t.Errorf("expected the chart package to be saved in the current directory (%v)", err)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const fromFileArgsError = "With --from-file, this command needs at most 1 argument: name of chart repository (or repo URL)"
+
+// validateFromFileFlags checks the flags given with --from-file, which
+// pushes every chart listed in a file
+func (p *pushCmd) validateFromFileFlags() error {
+	if p.recursive {
+		return errors.New("--from-file cannot be used with --recursive")
+	}
+	if p.chartVersion != "" || p.nameOverride != "" {
+		return errors.New("--version and --name cannot be used with --from-file")
+	}
+	return nil
+}
+
+// readChartList returns the charts listed in the --from-file file, or in
+// stdin for "-": one chart directory or .tgz package per line, relative
+// to the current directory. Blank lines and lines starting with # are
+// ignored. Every line naming a path which doesn't exist is reported,
+// with its line number, before anything is pushed
+func (p *pushCmd) readChartList(stdin io.Reader) ([]string, error) {
+	name, in := p.fromFile, stdin
+	if p.fromFile == stdinChartName {
+		name = "stdin"
+	} else {
+		f, err := os.Open(p.fromFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var paths, missing []string
+	s := bufio.NewScanner(in)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := os.Stat(line); err != nil {
+			missing = append(missing, fmt.Sprintf("%s:%d: %s", name, n, err))
+			continue
+		}
+		paths = append(paths, line)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", name, err)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("charts not found:\n%s", strings.Join(missing, "\n"))
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no charts listed in %s", name)
+	}
+	return paths, nil
+}
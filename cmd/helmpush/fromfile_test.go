@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadChartList(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+	list := filepath.Join(tmp, "charts.txt")
+
+	for _, tc := range []struct {
+		content  string
+		expected []string
+		err      string
+	}{
+		{"# charts\n\n" + testTarballPath + "\n  ../../testdata/charts/helm3/my-v3-chart  \n", []string{testTarballPath, "../../testdata/charts/helm3/my-v3-chart"}, ""},
+		{"# nothing to push\n\n", nil, "no charts listed in " + list},
+		{testTarballPath + "\nmissing\n# missing-too\nmissing-too\n", nil, list + ":2: stat missing: no such file or directory\n" + list + ":4: stat missing-too: no such file or directory"},
+	} {
+		os.WriteFile(list, []byte(tc.content), 0644)
+		p := &pushCmd{fromFile: list}
+		paths, err := p.readChartList(nil)
+		if strings.Join(paths, ",") != strings.Join(tc.expected, ",") || (err == nil && tc.err != "") || (err != nil && !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("expected %v (error %q), instead got %v (%v)", tc.expected, tc.err, paths, err)
+		}
+	}
+
+	p := &pushCmd{fromFile: stdinChartName}
+	if paths, err := p.readChartList(strings.NewReader(testTarballPath + "\n")); err != nil || len(paths) != 1 || paths[0] != testTarballPath {
+		t.Errorf("expected chart list from stdin, instead got %v (%v)", paths, err)
+	}
+	if _, err := p.readChartList(strings.NewReader("missing\n")); err == nil || !strings.Contains(err.Error(), "stdin:1: ") {
+		t.Errorf("expected error naming stdin, instead got %v", err)
+	}
+}
+
+func TestPushCmdFromFile(t *testing.T) {
+	chartsDir, cleanup := setupTestGitCharts(t)
+	defer cleanup()
+
+	var pushed []string
+	ts := httptest.NewServer(uploadsOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, header, err := r.FormFile("chart")
+		if err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		pushed = append(pushed, filepath.Base(header.Filename))
+		w.WriteHeader(201)
+		w.Write([]byte("{}"))
+	})))
+	defer ts.Close()
+
+	repoCleanup := setupTestRepo(t, ts.URL)
+	defer repoCleanup()
+
+	list := filepath.Join(filepath.Dir(chartsDir), "charts.txt")
+	os.WriteFile(list, []byte("# b depends on common\n"+filepath.Join(chartsDir, "b")+"\n\n"+filepath.Join(chartsDir, "common")+"\n"), 0644)
+
+	push := func(stdin string, args ...string) (string, error) {
+		pushed = nil
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		cmd.SetIn(strings.NewReader(stdin))
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := push("", "--from-file", list, "helm-push-test")
+	if err != nil {
+		t.Fatalf("unexpected error pushing charts from a file: %v\n%s", err, out)
+	}
+	if strings.Join(pushed, ",") != "common-0.1.0.tgz,b-0.1.0.tgz" {
+		t.Errorf("expected listed charts to be pushed in dependency order, instead got %v", pushed)
+	}
+	if !strings.Contains(out, "CHART") || !strings.Contains(out, "pushed") {
+		t.Errorf("expected summary, instead got:\n%s", out)
+	}
+
+	if _, err := push(filepath.Join(chartsDir, "c")+"\n", "--from-file", "-", "helm-push-test"); err != nil || strings.Join(pushed, ",") != "c-0.1.0.tgz" {
+		t.Errorf("expected chart listed in stdin to be pushed, instead got %v (%v)", pushed, err)
+	}
+
+	os.WriteFile(list, []byte(filepath.Join(chartsDir, "a")+"\n"+filepath.Join(chartsDir, "nope")+"\n"), 0644)
+	if _, err := push("", "--from-file", list, "helm-push-test"); err == nil || !strings.Contains(err.Error(), list+":2: ") || len(pushed) != 0 {
+		t.Errorf("expected error for a missing chart and nothing pushed, instead got %v (%v)", err, pushed)
+	}
+
+	for _, tc := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--from-file", list, chartsDir, "helm-push-test"}, fromFileArgsError},
+		{[]string{"--from-file", list, "-r", "helm-push-test"}, "--from-file cannot be used with --recursive"},
+		{[]string{"--from-file", list, "--version", "1.0.0", "helm-push-test"}, "--version and --name cannot be used with --from-file"},
+		{[]string{"--from-file", list, "--watch", "helm-push-test"}, "--watch cannot be used with --from-file, it watches a single chart"},
+	} {
+		if _, err := push("", tc.args...); err == nil || err.Error() != tc.expected {
+			t.Errorf("%v: expected error %q, instead got %v", tc.args, tc.expected, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/chartmuseum/helm-push/pkg/helm"
+)
+
+// runPrePushHooks runs each --pre-push-hook in order before the chart
+// package is uploaded, unless --no-hooks is given. The first one to
+// fail or time out aborts the push, with its stderr in the error
+func (p *pushCmd) runPrePushHooks(chart *helm.Chart, chartPackagePath string) error {
+	if p.noHooks {
+		return nil
+	}
+	for _, hook := range p.prePushHooks {
+		p.log.info(chart.Name(), p.repoName, "Running pre-push hook %s", hook)
+		if err := p.runHook(hook, chart, chartPackagePath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runHook runs a hook command with the shell, with the chart package
+// path, name, version and repo in its environment
+func (p *pushCmd) runHook(hook string, chart *helm.Chart, chartPackagePath string) error {
+	ctx, cancel := context.WithTimeout(p.ctx, time.Duration(p.hookTimeout)*time.Second)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", hook)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", hook)
+	}
+	cmd.Env = append(os.Environ(),
+		"CHART_PACKAGE="+chartPackagePath,
+		"CHART_NAME="+chart.Name(),
+		"CHART_VERSION="+chart.Version(),
+		"CHART_REPO="+p.repoName,
+	)
+	// the output goes to files rather than pipes, so a timeout isn't
+	// held up by processes the hook left running
+	stdout, err := os.CreateTemp(p.tempDir, "helm-push-hook-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(stdout.Name())
+	defer stdout.Close()
+	stderr, err := os.CreateTemp(p.tempDir, "helm-push-hook-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(stderr.Name())
+	defer stderr.Close()
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err = cmd.Run()
+
+	stdout.Seek(0, io.SeekStart)
+	io.Copy(p.log.out, stdout)
+	stderr.Seek(0, io.SeekStart)
+	errOutput, _ := io.ReadAll(stderr)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("pre-push hook %q timed out after %ds", hook, p.hookTimeout)
+	}
+	if err != nil {
+		msg := fmt.Sprintf("pre-push hook %q failed: %s", hook, err)
+		if s := strings.TrimSpace(string(errOutput)); s != "" {
+			msg += ": " + s
+		}
+		return errors.New(msg)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestPushCmdPrePushHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks in this test are shell scripts")
+	}
+
+	var uploads int
+	ts := httptest.NewServer(uploadsOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uploads++
+		w.WriteHeader(201)
+		w.Write([]byte("{}"))
+	})))
+	defer ts.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+	logPath := filepath.Join(tmp, "hooks.log")
+
+	push := func(args ...string) (string, error) {
+		uploads = 0
+		os.Remove(logPath)
+		args = append([]string{testTarballPath, "helm-push-test", "--version", "0.2.0"}, args...)
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&bytes.Buffer{})
+		err := cmd.Execute()
+		return out.String(), err
+	}
+	hookLog := func() string {
+		b, _ := os.ReadFile(logPath)
+		return string(b)
+	}
+
+	scan := `test -f "$CHART_PACKAGE" && echo "scan $(basename "$CHART_PACKAGE") $CHART_NAME $CHART_VERSION $CHART_REPO" >> ` + logPath
+	out, err := push("--pre-push-hook", scan, "--pre-push-hook", "echo notify >> "+logPath+"; echo scanned")
+	if err != nil {
+		t.Fatal("unexpected error pushing with hooks", err)
+	}
+	if hookLog() != "scan mychart-0.2.0.tgz mychart 0.2.0 helm-push-test\nnotify\n" || uploads != 1 {
+		t.Errorf("expected hooks to run in order then the upload, instead got %q (%d uploads)", hookLog(), uploads)
+	}
+	if !strings.Contains(out, "Running pre-push hook echo notify") || !strings.Contains(out, "scanned\n") {
+		t.Errorf("expected hooks and their output to be printed, instead got:\n%s", out)
+	}
+
+	// a hook given twice runs twice
+	notify := "echo notify >> " + logPath
+	if _, err := push("--pre-push-hook", notify, "--pre-push-hook", notify); err != nil {
+		t.Fatal("unexpected error pushing with a repeated hook", err)
+	}
+	if hookLog() != "notify\nnotify\n" {
+		t.Errorf("expected the repeated hook to run twice, instead got %q", hookLog())
+	}
+
+	// a failing hook aborts the push, and the following hooks
+	_, err = push("--pre-push-hook", "echo policy violation: privileged container >&2; exit 3", "--pre-push-hook", "echo notify >> "+logPath)
+	if err == nil || !strings.Contains(err.Error(), "failed: exit status 3: policy violation: privileged container") {
+		t.Errorf("expected hook error with its stderr, instead got %v", err)
+	}
+	if uploads != 0 || hookLog() != "" {
+		t.Errorf("expected no upload or later hook, instead got %d uploads and %q", uploads, hookLog())
+	}
+
+	if _, err := push("--pre-push-hook", "exit 1", "--no-hooks"); err != nil || uploads != 1 {
+		t.Errorf("expected hooks to be skipped with --no-hooks, instead got %v (%d uploads)", err, uploads)
+	}
+
+	if _, err := push("--pre-push-hook", "sleep 5", "--hook-timeout", "1"); err == nil || !strings.Contains(err.Error(), `pre-push hook "sleep 5" timed out after 1s`) || uploads != 0 {
+		t.Errorf("expected hook timeout, instead got %v (%d uploads)", err, uploads)
+	}
+	if _, err := push("--hook-timeout", "0"); err == nil || !strings.Contains(err.Error(), "--hook-timeout must be greater than 0") {
+		t.Errorf("expected invalid --hook-timeout error, instead got %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// newIdempotencyKey returns a random (version 4) UUID to identify a push
+// and its retries
+func newIdempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// pushIdempotencyKey returns the --idempotency-key, or a new key for
+// each push of a chart to a repo
+func (p *pushCmd) pushIdempotencyKey() (string, error) {
+	if p.idempotencyKey != "" {
+		return p.idempotencyKey, nil
+	}
+	return newIdempotencyKey()
+}
+
+// validateIdempotencyKeyFlags checks that a given --idempotency-key
+// only identifies a single push, since a gateway would take another
+// push with the same key for a retry of the first
+func (p *pushCmd) validateIdempotencyKeyFlags(repoNames []string) error {
+	if p.idempotencyKey == "" {
+		return nil
+	}
+	if p.recursive || p.changedSince != "" || p.withSubcharts || p.fromFile != "" || p.watch {
+		return errors.New("--idempotency-key can only be used to push a single chart")
+	}
+	if len(repoNames) > 1 {
+		return errors.New("--idempotency-key can only be used to push to a single repository")
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestNewIdempotencyKey(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	a, err := newIdempotencyKey()
+	if err != nil || !uuid.MatchString(a) {
+		t.Errorf("expected a version 4 UUID, instead got %q (%v)", a, err)
+	}
+	if b, _ := newIdempotencyKey(); a == b {
+		t.Errorf("expected a new key each time, instead got %s twice", a)
+	}
+}
+
+func TestPushCmdIdempotencyKey(t *testing.T) {
+	var keys []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			keys = append(keys, r.Header.Get("Idempotency-Key"))
+		}
+		w.WriteHeader(201)
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+
+	push := func(args ...string) (pushResult, error) {
+		keys = nil
+		args = append([]string{testTarballPath, "helm-push-test", "-o", "json"}, args...)
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&bytes.Buffer{})
+		var result pushResult
+		if err := cmd.Execute(); err != nil {
+			return result, err
+		}
+		return result, json.Unmarshal(out.Bytes(), &result)
+	}
+
+	result, err := push()
+	if err != nil {
+		t.Fatal("unexpected error pushing", err)
+	}
+	if len(keys) != 1 || keys[0] == "" || result.IdempotencyKey != keys[0] {
+		t.Errorf("expected the key sent to be in the json output, instead got %q and %q", keys, result.IdempotencyKey)
+	}
+	first := keys[0]
+	if _, err := push(); err != nil || keys[0] == first {
+		t.Errorf("expected a new key for another push, instead got %q (%v)", keys, err)
+	}
+
+	result, err = push("--idempotency-key", "release-42-mychart")
+	if err != nil || len(keys) != 1 || keys[0] != "release-42-mychart" || result.IdempotencyKey != "release-42-mychart" {
+		t.Errorf("expected the given key to be sent, instead got %q (%v)", keys, err)
+	}
+
+	for _, tc := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--idempotency-key", "k", "--also-repo", "other"}, "--idempotency-key can only be used to push to a single repository"},
+		{[]string{"--idempotency-key", "k", "--recursive"}, "--idempotency-key can only be used to push a single chart"},
+	} {
+		if _, err := push(tc.args...); err == nil || err.Error() != tc.err {
+			t.Errorf("%v: expected error %q, instead got %v", tc.args, tc.err, err)
+		}
+	}
+}
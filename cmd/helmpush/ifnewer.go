@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+	"github.com/chartmuseum/helm-push/pkg/helm"
+)
+
+// remoteVersionNotOlder returns the highest version of the chart in the
+// repo if it is greater than or equal to the local version, or nil if the
+// local version is newer (or the chart is not in the repo), for --if-newer
+func remoteVersionNotOlder(ctx context.Context, client *cm.Client, chart *helm.Chart) (*semver.Version, error) {
+	local, err := semver.NewVersion(chart.Version())
+	if err != nil {
+		return nil, fmt.Errorf("--if-newer needs a semver chart version, instead got %q", chart.Version())
+	}
+
+	versions, _, err := fetchChartVersions(ctx, client, chart.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	// Pre-releases count, they sort before the release they precede
+	remote := latestVersion(versions, true)
+	if remote == nil || local.GreaterThan(remote) {
+		return nil, nil
+	}
+	return remote, nil
+}
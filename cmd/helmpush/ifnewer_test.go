@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPushCmdIfNewer(t *testing.T) {
+	var remoteVersions string
+	var uploaded bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/x/y/z/api/charts/mychart" && remoteVersions != "":
+			w.WriteHeader(200)
+			w.Write([]byte(remoteVersions))
+		case r.Method == "POST" && r.URL.Path == "/x/y/z/api/charts":
+			uploaded = true
+			w.WriteHeader(201)
+			w.Write([]byte("{\"saved\": true}"))
+		default:
+			w.WriteHeader(404)
+			w.Write([]byte("{\"error\": \"not found\"}"))
+		}
+	}))
+	defer ts.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+
+	// Local version is 0.1.0
+	for _, tc := range []struct {
+		remoteVersions string
+		expectSkipped  bool
+	}{
+		{"", false},
+		{`[{"name": "mychart", "version": "0.0.9"}]`, false},
+		{`[{"name": "mychart", "version": "0.1.0-rc.1"}]`, false},
+		{`[{"name": "mychart", "version": "0.0.9"}, {"name": "mychart", "version": "0.1.0"}]`, true},
+		{`[{"name": "mychart", "version": "0.2.0-alpha"}]`, true},
+	} {
+		remoteVersions = tc.remoteVersions
+		uploaded = false
+
+		out := &bytes.Buffer{}
+		args := []string{"--if-newer", "-o", "json", testTarballPath, "helm-push-test"}
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		cmd.SetOut(out)
+		cmd.SetErr(&bytes.Buffer{})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("unexpected error pushing with remote versions %s: %s", tc.remoteVersions, err)
+		}
+		var result pushResult
+		json.Unmarshal(out.Bytes(), &result)
+		if result.Skipped != tc.expectSkipped || uploaded == tc.expectSkipped {
+			t.Errorf("expected skipped to be %v with remote versions %s, instead got %+v", tc.expectSkipped, tc.remoteVersions, result)
+		}
+	}
+}
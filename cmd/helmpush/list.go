@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+	"github.com/spf13/cobra"
+)
+
+type (
+	listCmd struct {
+		*pushCmd
+		output string
+	}
+
+	// chartSummary is a single row of list output
+	chartSummary struct {
+		Name          string `json:"name"`
+		LatestVersion string `json:"latestVersion"`
+		Versions      int    `json:"versions"`
+	}
+)
+
+func newListCmd(p *pushCmd) *cobra.Command {
+	l := &listCmd{pushCmd: p}
+	cmd := &cobra.Command{
+		Use:   "list REPO",
+		Short: "List the charts in a ChartMuseum repository",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("This command needs 1 argument: name of chart repository (or repo URL)")
+			}
+			return nil
+		},
+		ValidArgsFunction: completeRepoNames(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutputFormat(l.output, outputTable, outputJSON); err != nil {
+				return err
+			}
+			l.repoName = args[0]
+			if err := l.setup(cmd); err != nil {
+				return err
+			}
+			return l.list()
+		},
+	}
+	f := cmd.Flags()
+	f.StringVarP(&l.output, "output", "o", outputTable, "Output format (table, json)")
+	return cmd
+}
+
+func (l *listCmd) list() error {
+	repo, err := l.getRepo()
+	if err != nil {
+		return err
+	}
+
+	client, err := l.newClientFromRepo(repo)
+	if err != nil {
+		return err
+	}
+
+	charts, err := client.ListChartsWithContext(l.ctx)
+	if errors.Is(err, cm.ErrNotFound) {
+		return fmt.Errorf("%s does not appear to be a ChartMuseum server (chart API not found)", l.repoName)
+	}
+	if errors.Is(err, cm.ErrInvalidResponse) {
+		return fmt.Errorf("%s does not appear to be a ChartMuseum server (%s)", l.repoName, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	summaries := summarizeCharts(charts)
+	if l.output == outputJSON {
+		return printJSON(l.out, summaries)
+	}
+
+	w := tabwriter.NewWriter(l.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tLATEST VERSION\tVERSIONS")
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", s.Name, s.LatestVersion, s.Versions)
+	}
+	return w.Flush()
+}
+
+// summarizeCharts returns the latest version and number of
+// versions of each chart, sorted by chart name
+func summarizeCharts(charts map[string][]*cm.ChartVersion) []chartSummary {
+	summaries := make([]chartSummary, 0, len(charts))
+	for name, versions := range charts {
+		s := chartSummary{Name: name, Versions: len(versions)}
+		if len(versions) > 0 {
+			cm.SortVersions(versions)
+			s.LatestVersion = versions[0].Version
+		}
+		summaries = append(summaries, s)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+	return summaries
+}
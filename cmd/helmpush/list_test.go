@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var testChartsResponse = `{
+  "mychart": [
+    {"name": "mychart", "version": "0.1.0", "created": "2020-01-01T00:00:00Z"},
+    {"name": "mychart", "version": "0.10.0", "created": "2020-01-03T00:00:00Z"},
+    {"name": "mychart", "version": "0.2.0", "created": "2020-01-02T00:00:00Z"}
+  ],
+  "another": [
+    {"name": "another", "version": "1.0.0", "created": "2020-01-01T00:00:00Z"}
+  ]
+}`
+
+func TestListCmd(t *testing.T) {
+	statusCode := 200
+	body := testChartsResponse
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/x/y/z/api/charts" {
+			w.WriteHeader(404)
+			return
+		}
+		w.WriteHeader(statusCode)
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+
+	// Table output
+	out := &bytes.Buffer{}
+	args := []string{"list", "helm-push-test"}
+	cmd := newPushCmd(args)
+	cmd.SetArgs(args)
+	cmd.SetOut(out)
+	if err := cmd.Execute(); err != nil {
+		t.Fatal("unexpected error listing charts", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines of output, instead got %q", out.String())
+	}
+	if fields := strings.Fields(lines[2]); fields[0] != "mychart" || fields[1] != "0.10.0" || fields[2] != "3" {
+		t.Errorf("unexpected row for mychart: %s", lines[2])
+	}
+
+	// JSON output
+	out.Reset()
+	args = []string{"list", "-o", "json", "helm-push-test"}
+	cmd = newPushCmd(args)
+	cmd.SetArgs(args)
+	cmd.SetOut(out)
+	if err := cmd.Execute(); err != nil {
+		t.Fatal("unexpected error listing charts", err)
+	}
+	var summaries []chartSummary
+	if err := json.Unmarshal(out.Bytes(), &summaries); err != nil {
+		t.Fatal("unexpected error parsing json output", err)
+	}
+	if len(summaries) != 2 || summaries[0].Name != "another" || summaries[1].LatestVersion != "0.10.0" {
+		t.Errorf("unexpected json output: %+v", summaries)
+	}
+
+	// Not a ChartMuseum server
+	statusCode = 404
+	body = "404 page not found"
+	args = []string{"list", "helm-push-test"}
+	cmd = newPushCmd(args)
+	cmd.SetArgs(args)
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "does not appear to be a ChartMuseum server") {
+		t.Errorf("expected error for non-ChartMuseum server, instead got %v", err)
+	}
+
+	// Bad output format
+	args = []string{"list", "-o", "yaml", "helm-push-test"}
+	cmd = newPushCmd(args)
+	cmd.SetArgs(args)
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	if err := cmd.Execute(); err == nil {
+		t.Error("expected error with bad output format, instead got nil")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+type (
+	// logger writes progress messages, either as plain text or as
+	// JSON lines on stderr when $HELM_PUSH_LOG_FORMAT is "json"
+	logger struct {
+		out    io.Writer
+		errOut io.Writer
+		format string
+		// quiet drops informational messages, for --quiet
+		quiet bool
+		// redact replaces the credentials in messages, if set
+		redact func(string) string
+		mu     sync.Mutex
+	}
+
+	// logEntry is a single structured log line
+	logEntry struct {
+		Level string `json:"level"`
+		Msg   string `json:"msg"`
+		Chart string `json:"chart,omitempty"`
+		Repo  string `json:"repo,omitempty"`
+		TS    string `json:"ts"`
+	}
+)
+
+func newLogger(out io.Writer, errOut io.Writer) *logger {
+	format := logFormatText
+	if os.Getenv("HELM_PUSH_LOG_FORMAT") == logFormatJSON {
+		format = logFormatJSON
+	}
+	return &logger{out: out, errOut: errOut, format: format}
+}
+
+// info logs an informational message about the given chart and repo
+func (l *logger) info(chart string, repo string, format string, a ...interface{}) {
+	l.log("info", chart, repo, fmt.Sprintf(format, a...))
+}
+
+// warn logs a warning about the given chart and repo
+func (l *logger) warn(chart string, repo string, format string, a ...interface{}) {
+	l.log("warning", chart, repo, fmt.Sprintf(format, a...))
+}
+
+// error logs an error about the given chart and repo
+func (l *logger) error(chart string, repo string, format string, a ...interface{}) {
+	l.log("error", chart, repo, fmt.Sprintf(format, a...))
+}
+
+func (l *logger) log(level string, chart string, repo string, msg string) {
+	if l.quiet && level == "info" {
+		return
+	}
+	if l.redact != nil {
+		msg = l.redact(msg)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.format == logFormatJSON {
+		b, _ := json.Marshal(logEntry{
+			Level: level,
+			Msg:   msg,
+			Chart: chart,
+			Repo:  repo,
+			TS:    time.Now().UTC().Format(time.RFC3339),
+		})
+		fmt.Fprintf(l.errOut, "%s\n", b)
+		return
+	}
+	if level != "info" {
+		fmt.Fprintf(l.errOut, "%s: %s\n", strings.ToUpper(level), msg)
+		return
+	}
+	fmt.Fprintln(l.out, msg)
+}
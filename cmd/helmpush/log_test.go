@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	// Text
+	os.Unsetenv("HELM_PUSH_LOG_FORMAT")
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	l := newLogger(out, errOut)
+	l.info("mychart", "myrepo", "Pushing %s...", "mychart-0.1.0.tgz")
+	l.warn("mychart", "myrepo", "careful")
+	if out.String() != "Pushing mychart-0.1.0.tgz...\n" {
+		t.Errorf("unexpected text output: %q", out.String())
+	}
+	if errOut.String() != "WARNING: careful\n" {
+		t.Errorf("unexpected text warning output: %q", errOut.String())
+	}
+
+	// JSON
+	os.Setenv("HELM_PUSH_LOG_FORMAT", "json")
+	defer os.Unsetenv("HELM_PUSH_LOG_FORMAT")
+	out, errOut = &bytes.Buffer{}, &bytes.Buffer{}
+	l = newLogger(out, errOut)
+	l.info("mychart", "myrepo", "Pushing %s...", "mychart-0.1.0.tgz")
+	if out.Len() != 0 {
+		t.Errorf("expected no stdout output in json mode, instead got %q", out.String())
+	}
+	var entry logEntry
+	if err := json.Unmarshal(errOut.Bytes(), &entry); err != nil {
+		t.Fatal("unexpected error parsing json log line", err)
+	}
+	if entry.Level != "info" || entry.Msg != "Pushing mychart-0.1.0.tgz..." || entry.Chart != "mychart" || entry.Repo != "myrepo" || entry.TS == "" {
+		t.Errorf("unexpected json log entry: %+v", entry)
+	}
+}
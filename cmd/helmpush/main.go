@@ -1,27 +1,34 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"os/user"
 	"path"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+	"github.com/chartmuseum/helm-push/pkg/chartmuseum/auth"
 	"github.com/chartmuseum/helm-push/pkg/helm"
 	"github.com/ghodss/yaml"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/oauth2"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/cli/values"
 	"helm.sh/helm/v3/pkg/downloader"
 	"helm.sh/helm/v3/pkg/getter"
 	v2downloader "k8s.io/helm/pkg/downloader"
@@ -33,12 +40,29 @@ type (
 	pushCmd struct {
 		chartName          string
 		chartVersion       string
+		nameOverride       string
+		appVersion         string
+		appVersionFrom     string
+		stripV             []string
+		annotationArgs     []string
+		annotations        map[string]string
+		annotateVCS        bool
+		ignore             []string
+		includes           []string
+		includeOverwrite   bool
+		ignoreFiles        []string
+		forbid             []string
+		forbidNone         bool
 		repoName           string
 		username           string
 		password           string
 		accessToken        string
+		apiKey             string
 		authHeader         string
 		contextPath        string
+		timeout            int64
+		retries            int
+		retryMaxWait       int64
 		forceUpload        bool
 		useHTTP            bool
 		checkHelmVersion   bool
@@ -46,23 +70,138 @@ type (
 		certFile           string
 		keyFile            string
 		insecureSkipVerify bool
+		proxy              string
+		noProxy            string
+		maxIdleConns       int
+		idleConnTimeout    int64
 		keyring            string
+		awsAccessKeyID     string
+		awsSecretAccessKey string
+		awsSessionToken    string
+		awsRegion          string
+		awsService         string
+		gcpKeyFile         string
+		googleIAPAudience  string
+		oauthTokenURL      string
+		oauthClientID      string
+		oauthClientSecret  string
+		oauthSecretFile    string
+		oauthScopes        []string
+		vaultAddr          string
+		vaultToken         string
+		vaultPath          string
+		vaultKeys          string
+		azureIdentity      bool
+		azureClientID      string
+		azureAudience      string
+		noTokenCache       bool
+		helmHome           string
+		repoConfig         string
+		watch              bool
+		watchVersion       string
+		devRepo            bool
+		// tokens are the OAuth2 tokens of a GCP service account or
+		// Azure managed identity
+		tokens             oauth2.TokenSource
+		tokenCommand       string
+		tempDir            string
+		requireProv        bool
+		verify             bool
+		signature          string
+		signCmd            string
+		signaturePath      string
+		signatureOnly      bool
+		installHint        bool
 		dependencyUpdate   bool
+		validateValues     bool
+		validateRender     bool
+		valueFiles         []string
+		setValues          []string
+		progress           bool
+		bump               string
+		includePrereleases bool
+		allowPrerelease    bool
+		versionPattern     string
+		versionRegexp      *regexp.Regexp
+		output             string
+		alsoRepos          []string
+		failFast           bool
+		continueOnError    bool
+		quiet              bool
+		ifNewer            bool
+		dryRun             bool
+		skipExisting       bool
+		maxSize            string
+		maxSizeBytes       int64
+		showSize           bool
+		checksumFile       string
+		atomic             bool
+		atomicStagingRepo  string
+		stagingRepo        *pushCmd
+		wait               time.Duration
+		idempotencyKey     string
+		oci                bool
+		recursive          bool
+		changedSince       string
+		withSubcharts      bool
+		fromFile           string
+		outputFile         string
+		prePushHooks       []string
+		hookTimeout        int64
+		noHooks            bool
+		ownSubcharts       []string
+		ctx                context.Context
 		out                io.Writer
+		log                *logger
+
+		// secrets are the credentials of the run, redacted from its
+		// output and errors
+		secrets *secretSet
+
+		// defaultUsername and defaultPassword are the global credentials
+		// from the config file, only used for repos without their own
+		defaultUsername string
+		defaultPassword string
+
+		// chartPaths are the charts listed in the --from-file file
+		chartPaths []string
+
+		// pushed is the chart last pushed by push, and results its
+		// results for each repo, for the summary of --recursive
+		pushed  *helm.Chart
+		results []pushResult
 	}
 
 	config struct {
-		CurrentContext string             `json:"current-context"`
-		Contexts       map[string]context `json:"contexts"`
+		CurrentContext string                   `json:"current-context"`
+		Contexts       map[string]configContext `json:"contexts"`
 	}
 
-	context struct {
+	configContext struct {
 		Name  string `json:"name"`
 		Token string `json:"token"`
 	}
 )
 
+const (
+	// repoAnnotation is the Chart.yaml annotation naming the repo to push
+	// a chart to when no repo is given
+	repoAnnotation = "helm-push/repo"
+
+	// --strip-v modes
+	stripVersion    = "version"
+	stripAppVersion = "app-version"
+
+	// stdinChartName is the chart name to read a .tgz package from stdin
+	stdinChartName = "-"
+
+	pushArgsError = "This command needs 2 arguments: name of chart, name of chart repository (or repo URL)"
+)
+
 var (
+	repoEnvNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	chartNameRegexp   = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
 	v2settings  v2environment.EnvSettings
 	settings    = cli.New()
 	globalUsage = `Helm plugin to push chart package to ChartMuseum
@@ -71,18 +210,25 @@ Examples:
 
   $ helm push mychart-0.1.0.tgz chartmuseum       # push .tgz from "helm package"
   $ helm push . chartmuseum                       # package and push chart directory
+  $ helm push - chartmuseum < mychart-0.1.0.tgz   # push .tgz piped to stdin
   $ helm push . --version="7c4d121" chartmuseum   # override version in Chart.yaml
+  $ helm push . --name="acme-nginx" chartmuseum   # override name in Chart.yaml
   $ helm push . https://my.chart.repo.com         # push directly to chart repo URL
+  $ helm push . chartmuseum,mirror                # push the same package to several repos
+  $ helm push . oci://registry.example.com/charts # push to an OCI registry
+  $ helm push .                                   # push to the repo in the helm-push/repo annotation
 `
 )
 
 func newPushCmd(args []string) *cobra.Command {
-	p := &pushCmd{}
+	p := &pushCmd{secrets: &secretSet{}}
 	cmd := &cobra.Command{
-		Use:          "helm push",
-		Short:        "Helm plugin to push chart package to ChartMuseum",
-		Long:         globalUsage,
-		SilenceUsage: false,
+		Use:               "helm push",
+		Short:             "Helm plugin to push chart package to ChartMuseum",
+		Long:              globalUsage,
+		SilenceUsage:      false,
+		Args:              cobra.ArbitraryArgs,
+		ValidArgsFunction: completePushArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 
 			// If the --check-helm-version flag is provided, short circuit
@@ -91,186 +237,1733 @@ func newPushCmd(args []string) *cobra.Command {
 				return nil
 			}
 
-			p.out = cmd.OutOrStdout()
+			// If there are 4 args, this is likely being used as a downloader for cm:// protocol
+			if len(args) == 4 && strings.HasPrefix(args[3], "cm://") {
+				if err := p.setup(cmd); err != nil {
+					return err
+				}
+				return p.download(args[3])
+			}
+			if p.outputFile != "" {
+				return errors.New("--output-file only applies to downloading a cm:// URL")
+			}
+			if err := validateOptionalFlagValues(cmd.Flags(), args); err != nil {
+				return err
+			}
+
+			if p.fromFile != "" {
+				if len(args) > 1 {
+					return errors.New(fromFileArgsError)
+				}
+			} else if len(args) != 1 && len(args) != 2 {
+				return errors.New(pushArgsError)
+			}
+			if err := validateOutputFormat(p.output, outputText, outputJSON); err != nil {
+				return err
+			}
+			if err := validateBump(p.bump); err != nil {
+				return err
+			}
+			if p.bump != "" && p.chartVersion != "" {
+				return errors.New("--bump and --version cannot be used together")
+			}
+			if p.skipExisting && p.forceUpload {
+				return errors.New("--skip-existing and --force cannot be used together")
+			}
+			if p.failFast && p.continueOnError {
+				return errors.New("--fail-fast and --continue-on-error cannot be used together")
+			}
+			if p.oci {
+				if err := p.validateOCIFlags(); err != nil {
+					return err
+				}
+			}
+			if err := validateStripV(p.stripV); err != nil {
+				return err
+			}
+			if p.appVersionFrom != "" {
+				if p.appVersion != "" {
+					return errors.New("--app-version and --app-version-from cannot be used together")
+				}
+				if err := validateAppVersionFrom(p.appVersionFrom); err != nil {
+					return err
+				}
+			}
+			if !p.validateRender && (len(p.valueFiles) > 0 || len(p.setValues) > 0) {
+				return errors.New("--values and --set only apply to --validate-render")
+			}
+			if err := p.validateSignatureFlags(); err != nil {
+				return err
+			}
+			if p.dryRun && p.signatureOnly {
+				return errors.New("--dry-run cannot be used with --signature-only")
+			}
+			if err := p.validateAtomicFlags(); err != nil {
+				return err
+			}
+			if err := p.validateIdempotencyKeyFlags(nil); err != nil {
+				return err
+			}
+			for _, pattern := range p.forbid {
+				if err := helm.ValidateForbidPattern(pattern); err != nil {
+					return fmt.Errorf("invalid --forbid: %s", err)
+				}
+			}
+			annotations, err := parseAnnotations(p.annotationArgs)
+			if err != nil {
+				return err
+			}
+			p.annotations = annotations
+			if p.recursive && p.chartVersion != "" {
+				return errors.New("--version cannot be used with --recursive")
+			}
+			if p.nameOverride != "" {
+				if p.recursive {
+					return errors.New("--name cannot be used with --recursive")
+				}
+				if !chartNameRegexp.MatchString(p.nameOverride) {
+					return fmt.Errorf("invalid chart name %q", p.nameOverride)
+				}
+			}
+			if p.hookTimeout <= 0 {
+				return errors.New("--hook-timeout must be greater than 0")
+			}
+			if len(p.ownSubcharts) > 0 && !p.withSubcharts {
+				return errors.New("--own-subcharts-only only applies to --with-subcharts")
+			}
+			if err := validateOwnSubchartsFilters(p.ownSubcharts); err != nil {
+				return err
+			}
+			if p.fromFile != "" {
+				if err := p.validateFromFileFlags(); err != nil {
+					return err
+				}
+				if err := p.validateWatchFlags(""); err != nil {
+					return err
+				}
+				paths, err := p.readChartList(cmd.InOrStdin())
+				if err != nil {
+					return err
+				}
+				p.chartName, p.chartPaths = ".", paths
+				if len(args) == 0 {
+					// each chart is pushed to the repo in its annotation
+					return p.pushCharts(cmd, nil)
+				}
+				repoNames := splitRepoNames(args[0], p.alsoRepos)
+				if len(repoNames) == 0 {
+					return errors.New(fromFileArgsError)
+				}
+				targets, err := p.setupTargets(cmd, repoNames)
+				if err != nil {
+					return err
+				}
+				return p.pushCharts(cmd, targets)
+			}
+			if p.withSubcharts && !p.recursive && !isChartDir(args[0]) {
+				return errors.New("--with-subcharts needs a chart directory")
+			}
+			if err := p.validateWatchFlags(args[0]); err != nil {
+				return err
+			}
+			if p.changedSince != "" && helm.IsChartURL(args[0]) {
+				return errors.New("--changed-since needs a local chart or directory")
+			}
+			p.chartName = args[0]
+			if p.chartName == stdinChartName {
+				if p.recursive || p.changedSince != "" {
+					return errors.New("--recursive and --changed-since cannot be used with a chart from stdin")
+				}
+				path, cleanup, err := p.readStdinChart(cmd.InOrStdin())
+				if err != nil {
+					return err
+				}
+				defer cleanup()
+				p.chartName = path
+			}
+			multiple := p.recursive || p.changedSince != "" || p.withSubcharts
+			if len(args) == 1 {
+				if multiple {
+					// each chart is pushed to the repo in its annotation
+					return p.pushCharts(cmd, nil)
+				}
+				repoName := annotatedRepo(p.chartName)
+				if repoName == "" {
+					return errors.New(pushArgsError)
+				}
+				args = append(args, repoName)
+			}
+			repoNames := splitRepoNames(args[1], p.alsoRepos)
+			if len(repoNames) == 0 {
+				return errors.New(pushArgsError)
+			}
+			targets, err := p.setupTargets(cmd, repoNames)
+			if err != nil {
+				return err
+			}
+			if multiple {
+				return p.pushCharts(cmd, targets)
+			}
+			if p.watch {
+				return p.watchAndPush(targets)
+			}
+			return p.push(targets)
+		},
+	}
+	cmd.RunE = reportCancelled(cmd.RunE)
+	// Flags for connecting to the chart repository are shared with subcommands
+	pf := cmd.PersistentFlags()
+	pf.StringVarP(&p.username, "username", "u", "", "Override HTTP basic auth username [$HELM_REPO_USERNAME]")
+	pf.StringVarP(&p.password, "password", "p", "", "Override HTTP basic auth password [$HELM_REPO_PASSWORD]")
+	pf.StringVar(&p.username, "repo-username", "", "Same as --username")
+	pf.StringVar(&p.password, "repo-password", "", "Same as --password")
+	pf.StringVarP(&p.accessToken, "access-token", "", "", "Send token in Authorization header [$HELM_REPO_ACCESS_TOKEN]")
+	pf.StringVar(&p.apiKey, "api-key", "", "Send this Artifactory API key in the X-JFrog-Art-Api header, instead of the username and password [$HELM_REPO_API_KEY]")
+	pf.StringVar(&p.tokenCommand, "token-command", "", "Run this shell command before every request, sending the token it prints like --access-token, e.g. for short-lived tokens [$HELM_REPO_TOKEN_COMMAND]")
+	pf.StringVarP(&p.authHeader, "auth-header", "", "", "Alternative header to use for token auth [$HELM_REPO_AUTH_HEADER]")
+	pf.StringVarP(&p.contextPath, "context-path", "", "", "ChartMuseum context path [$HELM_REPO_CONTEXT_PATH]")
+	pf.Int64VarP(&p.timeout, "timeout", "t", 30, "Timeout (in seconds) for requests to the chart repository")
+	pf.IntVar(&p.retries, "retries", 0, "Retry requests this many times when the connection fails or the server is temporarily unavailable or throttling requests [$HELM_REPO_RETRIES]")
+	pf.Int64Var(&p.retryMaxWait, "retry-max-wait", 60, "Longest time (in seconds) to wait before a retry, even if the server's Retry-After asks for longer")
+	pf.StringVar(&p.awsAccessKeyID, "aws-access-key-id", "", "Sign requests with AWS Signature V4 using this access key, for ChartMuseum behind IAM auth [$AWS_ACCESS_KEY_ID]")
+	pf.StringVar(&p.awsSecretAccessKey, "aws-secret-access-key", "", "Secret key to sign requests with AWS Signature V4 [$AWS_SECRET_ACCESS_KEY]")
+	pf.StringVar(&p.awsRegion, "aws-region", "", "AWS region to sign requests for, turns on AWS Signature V4 signing [$AWS_REGION]")
+	pf.StringVar(&p.awsService, "aws-service", "execute-api", `AWS service to sign requests for, e.g. "s3" or "es"`)
+	pf.StringVar(&p.gcpKeyFile, "gcp-service-account-file", "", "Authenticate with OAuth2 access tokens for the GCP service account in this JSON key file, for ChartMuseum behind IAP or Cloud Endpoints")
+	pf.StringVar(&p.googleIAPAudience, "google-iap-audience", "", "Authenticate with Google-signed ID tokens for this audience (the OAuth client ID of the Identity-Aware Proxy), minted with the Application Default Credentials")
+	pf.StringVar(&p.oauthTokenURL, "oauth-token-url", "", "Authenticate with access tokens from this OAuth2 token endpoint (e.g. of a Keycloak realm), with the client credentials grant")
+	pf.StringVar(&p.oauthClientID, "oauth-client-id", "", "Client ID to get --oauth-token-url tokens for")
+	pf.StringVar(&p.oauthClientSecret, "oauth-client-secret", "", "Client secret to get --oauth-token-url tokens with [$HELM_REPO_OAUTH_CLIENT_SECRET]")
+	pf.StringVar(&p.oauthSecretFile, "oauth-client-secret-file", "", "Read the --oauth-client-secret from this file")
+	pf.StringSliceVar(&p.oauthScopes, "oauth-scopes", nil, "Scopes to request --oauth-token-url tokens for (comma-separated)")
+	pf.StringVar(&p.vaultAddr, "vault-addr", "", "Address of the Vault server to read --vault-path from [$VAULT_ADDR]")
+	pf.StringVar(&p.vaultToken, "vault-token", "", "Vault token to read --vault-path with [$VAULT_TOKEN, or ~/.vault-token]")
+	pf.StringVar(&p.vaultPath, "vault-path", "", "Read the username and password from this Vault KV (version 1 or 2) secret, e.g. secret/chartmuseum")
+	pf.StringVar(&p.vaultKeys, "vault-keys", "username,password", "Keys of the username and password in the --vault-path secret")
+	pf.BoolVar(&p.azureIdentity, "azure-managed-identity", false, "Authenticate with access tokens for the Azure managed identity of the VM or pod, from the Instance Metadata Service")
+	pf.StringVar(&p.azureClientID, "azure-client-id", "", "Client ID of the user-assigned managed identity to use with --azure-managed-identity")
+	pf.StringVar(&p.azureAudience, "azure-audience", "", "Audience (application ID URI) of ChartMuseum to get tokens for with --azure-managed-identity")
+	pf.BoolVar(&p.noTokenCache, "no-token-cache", false, "Don't cache the --gcp-service-account-file, --google-iap-audience and --azure-managed-identity tokens between runs, in $HELM_PUSH_TOKEN_CACHE or ~/.config/helm-push/tokens.json")
+	pf.StringVar(&p.helmHome, "helm-home", "", "Look up repos only in the repositories.yaml of this directory, in the Helm 2 (repository/repositories.yaml) or Helm 3 layout, instead of $HELM_HOME, $XDG_CONFIG_HOME/helm and the default Helm 3 and Helm 2 locations")
+	pf.StringVar(&p.repoConfig, "repo-config", "", "Look up repos only in this repositories file (e.g. an isolated CI Helm configuration), instead of --helm-home or the default locations")
+	pf.StringVarP(&p.caFile, "ca-file", "", "", "Verify certificates of HTTPS-enabled servers using this CA bundle [$HELM_REPO_CA_FILE]")
+	pf.StringVarP(&p.certFile, "cert-file", "", "", "Identify HTTPS client using this SSL certificate file [$HELM_REPO_CERT_FILE]")
+	pf.StringVarP(&p.keyFile, "key-file", "", "", "Identify HTTPS client using this SSL key file [$HELM_REPO_KEY_FILE]")
+	pf.BoolVarP(&p.insecureSkipVerify, "insecure", "", false, "Connect to server with an insecure way by skipping certificate verification [$HELM_REPO_INSECURE]")
+	pf.StringVar(&p.proxy, "proxy", "", "Connect through this HTTP proxy URL (default from $HTTPS_PROXY/$HTTP_PROXY)")
+	pf.StringVar(&p.noProxy, "no-proxy", "", "Comma-separated list of hosts to connect to without the proxy")
+	pf.StringVar(&p.tempDir, "temp-dir", "", "Directory to create chart packages in, instead of the system temp directory [$HELM_PUSH_TEMP_DIR]")
+	pf.IntVar(&p.maxIdleConns, "max-idle-conns", 10, "Maximum idle connections kept open for reuse, saving a TCP/TLS handshake per request when pushing many charts")
+	pf.Int64Var(&p.idleConnTimeout, "idle-conn-timeout", 90, "Seconds an idle connection is kept open for reuse")
+
+	f := cmd.Flags()
+	f.StringVarP(&p.chartVersion, "version", "v", "", "Override chart version pre-push")
+	f.StringVar(&p.nameOverride, "name", "", "Override chart name pre-push")
+	f.StringVar(&p.appVersion, "app-version", "", "Override chart appVersion pre-push")
+	f.StringVar(&p.appVersionFrom, "app-version-from", "", `Set the chart appVersion pre-push from the latest git tag of the chart directory ("git-tag") or an environment variable ("env:VAR")`)
+	f.StringSliceVar(&p.stripV, "strip-v", nil, `Remove a leading "v" from the version, or from the appVersion with --strip-v=app-version (or both with --strip-v=version,app-version)`)
+	f.Lookup("strip-v").NoOptDefVal = stripVersion
+	f.StringArrayVar(&p.annotationArgs, "set-annotation", nil, "Set a Chart.yaml annotation (key=value) pre-push, an empty value removes it. Can be repeated")
+	f.BoolVar(&p.annotateVCS, "annotate-vcs", false, "Annotate the chart pre-push with the git revision, remote URL and branch of its work tree")
+	f.StringArrayVar(&p.ignore, "ignore", nil, "Leave files matching this .helmignore pattern out of the package, on top of the chart's .helmignore. Can be repeated")
+	f.StringArrayVar(&p.ignoreFiles, "ignore-file", nil, "Leave files matching the patterns in this file out of the package, on top of the chart's .helmignore. Can be repeated")
+	f.StringArrayVar(&p.forbid, "forbid", nil, "Refuse to push charts with files matching this pattern (a file name glob, or a path in the chart if it has a /). Can be repeated")
+	f.BoolVar(&p.forbidNone, "forbid-none", false, "Allow files which usually hold secrets (private keys, *.p12, .env, id_rsa), refused by default")
+	f.StringArrayVar(&p.includes, "include", nil, "Add a file to the package (src[:dest], dest defaults to the chart root). Can be repeated")
+	f.BoolVar(&p.includeOverwrite, "include-overwrite", false, "Allow --include to replace files already in the chart")
+	f.StringVar(&p.keyring, "keyring", defaultKeyring(), "location of a public keyring")
+	f.BoolVar(&p.requireProv, "require-prov", false, "Only push chart packages signed with a provenance (.prov) file, and push it along with the package")
+	f.BoolVar(&p.verify, "verify", false, "Verify the provenance file of the chart package against --keyring before pushing (implies --require-prov)")
+	f.StringVar(&p.signature, "signature", "", "Push this detached signature of the chart package (e.g. from cosign sign-blob) after it, as <chart>-<version>.tgz.sig")
+	f.StringVar(&p.signCmd, "sign-cmd", "", `Sign the final chart package with this command, called with the package path, and push the signature it prints after the package (e.g. "cosign sign-blob --yes --key cosign.key")`)
+	f.StringVar(&p.signaturePath, "signature-path", "", "Path of the endpoint to push signatures to, relative to the context path (default /api/prov)")
+	f.BoolVar(&p.signatureOnly, "signature-only", false, "Only push the --signature of a chart package already in the repo, e.g. to retry a failed signature upload")
+	f.BoolVarP(&p.forceUpload, "force", "f", false, "Force upload even if chart version exists")
+	f.StringVarP(&p.outputFile, "output-file", "O", "", "Write the file downloaded from a cm:// URL to this path instead of stdout, failing if it exists unless --force is set")
+	f.BoolVarP(&p.dependencyUpdate, "dependency-update", "d", false, `update dependencies from "requirements.yaml" to dir "charts/" before packaging`)
+	f.BoolVar(&p.validateValues, "validate-values", true, "Validate values.yaml against values.schema.json, in the chart and its subcharts, before pushing")
+	f.BoolVar(&p.validateRender, "validate-render", false, "Render the templates of the chart and its subcharts before pushing, as helm template would, and fail on template errors")
+	f.StringArrayVar(&p.valueFiles, "values", nil, "Values file to render the templates with when using --validate-render. Can be repeated")
+	f.StringArrayVar(&p.setValues, "set", nil, "Value (key=val) to render the templates with when using --validate-render. Can be repeated")
+	f.StringVar(&p.bump, "bump", "", "Push the next patch, minor or major version after the highest version in the repo")
+	f.BoolVar(&p.includePrereleases, "include-prereleases", false, "Consider pre-release versions in the repo when using --bump")
+	f.StringVar(&p.versionPattern, "version-pattern", "", `Only push if the final chart version matches this regular expression (e.g. "^\d+\.\d+\.\d+$") [$HELM_REPO_VERSION_PATTERN]`)
+	f.BoolVar(&p.allowPrerelease, "allow-prerelease", false, "Allow pushing pre-release versions (e.g. 1.0.0-rc.1) [$HELM_REPO_ALLOW_PRERELEASE]")
+	f.StringVarP(&p.output, "output", "o", outputText, "Output format (text, json)")
+	f.BoolVar(&p.installHint, "print-install-hint", false, "Print the helm commands to install the pushed chart")
+	f.StringArrayVar(&p.alsoRepos, "also-repo", nil, "Also push to this chart repository (or repo URL), can be repeated")
+	f.BoolVar(&p.failFast, "fail-fast", false, "Stop after the first chart or repository that fails to push")
+	f.BoolVar(&p.continueOnError, "continue-on-error", false, "Keep pushing the other charts and repositories after a failure, and fail at the end (default)")
+	f.BoolVarP(&p.quiet, "quiet", "q", false, "Only print warnings and errors, and the --recursive summary if a chart failed to push")
+	f.BoolVar(&p.skipExisting, "skip-existing", false, "Skip chart versions already in the repo instead of failing with a conflict, checking before packaging when the repo allows it")
+	f.BoolVar(&p.atomic, "atomic", false, "Stage the chart first, with a -staging version or in --atomic-staging-repo, and only push it once the staged package downloads with the same checksum, then check the pushed package the same way. The staged chart is deleted afterwards")
+	f.StringVar(&p.atomicStagingRepo, "atomic-staging-repo", "", "Chart repository (or repo URL) to stage charts in with --atomic, with their final version, instead of the repo itself")
+	f.DurationVar(&p.wait, "wait", 0, "After pushing, wait up to this long (1m if no duration is given, e.g. --wait=5m) for the chart to appear in the repo index, failing if it doesn't")
+	f.Lookup("wait").NoOptDefVal = defaultWait
+	f.StringVar(&p.idempotencyKey, "idempotency-key", "", "Send this key in the Idempotency-Key header of the upload and its retries, instead of a new UUID for each push, for orchestrators managing their own keys")
+	f.BoolVar(&p.dryRun, "dry-run", false, "Build and check the chart package and print the upload request as a curl command, without pushing. The package is kept for the command")
+	f.StringVar(&p.maxSize, "max-size", "", "Refuse to push chart packages larger than this (e.g. 20MB or 512KiB), listing their largest files [$HELM_PUSH_MAX_SIZE]")
+	f.StringVar(&p.maxSize, "max-chart-size", "", "Same as --max-size")
+	f.BoolVar(&p.showSize, "show-size", false, "Print the size of the chart package and its largest files")
+	f.StringVar(&p.checksumFile, "checksum-file", "", "Write the sha256 checksum of the pushed package to this file, or to NAME-VERSION.tgz.sha256 in it if it is a directory")
+	f.BoolVar(&p.ifNewer, "if-newer", false, "Only push if the chart version is greater than the highest version in the repo")
+	f.BoolVarP(&p.recursive, "recursive", "r", false, "Push every chart (directory or .tgz package) found in the given directory")
+	f.StringVar(&p.changedSince, "changed-since", "", "Only push charts with files changed in git since this ref (e.g. origin/main)")
+	f.BoolVar(&p.withSubcharts, "with-subcharts", false, "Also push each subchart (directory or .tgz package) in the charts/ directory of the chart, after it")
+	f.StringVar(&p.fromFile, "from-file", "", `Push every chart (directory or .tgz package) listed in this file, one per line, or in stdin with "-". Blank lines and # comments are ignored`)
+	f.StringArrayVar(&p.ownSubcharts, "own-subcharts-only", nil, "Only push the subcharts matching this filter with --with-subcharts, either maintainer=<name or email> or prefix=<chart name prefix>. Can be repeated")
+	f.StringArrayVar(&p.prePushHooks, "pre-push-hook", nil, "Run this shell command before uploading the chart package, with $CHART_PACKAGE, $CHART_NAME, $CHART_VERSION and $CHART_REPO set. A non-zero exit aborts the push. Can be repeated, hooks run in order")
+	f.Int64Var(&p.hookTimeout, "hook-timeout", 300, "Timeout (in seconds) for each --pre-push-hook")
+	f.BoolVar(&p.noHooks, "no-hooks", false, "Don't run the --pre-push-hook commands")
+	f.BoolVar(&p.watch, "watch", false, "Push the chart directory again after every change to it, with a new development version each time, until Ctrl-C. Only pushes to repos marked with --dev-repo")
+	f.StringVar(&p.watchVersion, "watch-version", watchVersionDev, `Version suffix of the pushes of --watch: "dev" for -dev.1, -dev.2... or "timestamp" for -dev.<UTC timestamp>, which doesn't conflict with an earlier --watch`)
+	f.BoolVar(&p.devRepo, "dev-repo", false, "Mark the repo as a development repo, which --watch can push to (or set dev: true for the repo in the config file)")
+	f.BoolVar(&p.oci, "oci", false, "Push to the repo as an OCI registry with helm registry login and helm push (needs Helm 3.8+), implied for oci:// repos")
+	f.BoolVarP(&p.progress, "progress", "", false, "Show upload progress (only when stdout is a terminal)")
+	f.BoolVarP(&p.checkHelmVersion, "check-helm-version", "", false, `outputs either "2" or "3" indicating the current Helm major version`)
+
+	f.AddFlagSet(pf)
+
+	cmd.AddCommand(
+		newCompletionCmd(),
+		newConfigCmd(),
+		newCopyCmd(p),
+		newDeleteCmd(p),
+		newDiffCmd(p),
+		newDownloadCmd(p),
+		newListCmd(p),
+		newMirrorCmd(p),
+		newPruneCmd(p),
+		newReindexCmd(p),
+		newSelfUpdateCmd(),
+		newSyncCmd(p),
+		newVersionCmd(),
+		newVersionsCmd(p),
+	)
+	p.redactCommandErrors(cmd)
+
+	v2settings.AddFlags(pf)
+	v2settings.Init(pf)
+
+	return cmd
+}
+
+// setup prepares the command to run once its arguments have been
+// read, applying settings from the environment and config file
+func (p *pushCmd) setup(cmd *cobra.Command) error {
+	p.ctx = cmd.Context()
+	if p.ctx == nil {
+		p.ctx = context.Background()
+	}
+	p.out = cmd.OutOrStdout()
+	if p.output == outputJSON {
+		// keep stdout clean for the JSON result
+		p.log = newLogger(cmd.ErrOrStderr(), cmd.ErrOrStderr())
+	} else {
+		p.log = newLogger(p.out, cmd.ErrOrStderr())
+	}
+	p.log.quiet = p.quiet
+	p.log.redact = p.redact
+	helm.SetHelmHome(p.helmHome)
+	helm.SetRepoConfig(p.repoConfig)
+	p.setFieldsFromEnv(cmd.Flags())
+	if err := p.setFieldsFromConfig(cmd.Flags()); err != nil {
+		return err
+	}
+	if (p.awsAccessKeyID != "" || p.awsRegion != "") && (p.awsAccessKeyID == "" || p.awsSecretAccessKey == "" || p.awsRegion == "") {
+		return errors.New("AWS Signature V4 signing needs an access key, a secret key and a region (--aws-access-key-id, --aws-secret-access-key and --aws-region, or $AWS_ACCESS_KEY_ID, $AWS_SECRET_ACCESS_KEY and $AWS_REGION)")
+	}
+	if err := p.setupTokens(); err != nil {
+		return err
+	}
+	if err := p.setVaultCredentials(cmd.Flags()); err != nil {
+		return err
+	}
+	if p.versionPattern != "" {
+		re, err := regexp.Compile(p.versionPattern)
+		if err != nil {
+			return fmt.Errorf("invalid version pattern %q: %s", p.versionPattern, err)
+		}
+		p.versionRegexp = re
+	}
+	if p.maxSize != "" {
+		n, err := cm.ParseSize(p.maxSize)
+		if err != nil {
+			return fmt.Errorf("invalid maximum chart size: %s", err)
+		}
+		p.maxSizeBytes = n
+	}
+	if debugOutput() != nil {
+		return p.writeSettingSources(cmd.ErrOrStderr(), cmd.Flags())
+	}
+	return nil
+}
+
+// setFieldsFromEnv fills in any settings not already provided via flags
+// from HELM_REPO_* environment variables. Credentials and the context path
+// can also be set per repo, see lookupRepoEnv
+func (p *pushCmd) setFieldsFromEnv(f *pflag.FlagSet) {
+	if v, _, ok := lookupRepoEnv(p.repoName, "HELM_REPO_USERNAME"); ok && p.username == "" {
+		p.username = v
+	}
+	if v, _, ok := lookupRepoEnv(p.repoName, "HELM_REPO_PASSWORD"); ok && p.password == "" {
+		p.password = v
+	}
+	if v, _, ok := lookupRepoEnv(p.repoName, "HELM_REPO_ACCESS_TOKEN"); ok && p.accessToken == "" {
+		p.accessToken = v
+	}
+	if v, _, ok := lookupRepoEnv(p.repoName, "HELM_REPO_API_KEY"); ok && p.apiKey == "" {
+		p.apiKey = v
+	}
+	if v, _, ok := lookupRepoEnv(p.repoName, "HELM_REPO_TOKEN_COMMAND"); ok && p.tokenCommand == "" {
+		p.tokenCommand = v
+	}
+	if v, _, ok := lookupRepoEnv(p.repoName, "HELM_REPO_OAUTH_CLIENT_SECRET"); ok && p.oauthClientSecret == "" && p.oauthSecretFile == "" {
+		p.oauthClientSecret = v
+	}
+	if v, ok := os.LookupEnv("HELM_REPO_AUTH_HEADER"); ok && p.authHeader == "" {
+		p.authHeader = v
+	}
+	if v, _, ok := lookupRepoEnv(p.repoName, "HELM_REPO_CONTEXT_PATH"); ok && p.contextPath == "" {
+		p.contextPath = v
+	}
+	if v, ok := os.LookupEnv("HELM_REPO_USE_HTTP"); ok {
+		useHTTP, valid := parseBoolEnv(v)
+		if !valid {
+			p.log.warn("", p.repoName, "ignoring HELM_REPO_USE_HTTP=%q, expected true/false, 1/0, yes/no or on/off", v)
+		}
+		p.useHTTP = useHTTP
+	}
+	if v, ok := os.LookupEnv("HELM_REPO_RETRIES"); ok && !f.Changed("retries") {
+		p.retries, _ = strconv.Atoi(v)
+	}
+	if v, _, ok := lookupRepoEnv(p.repoName, "HELM_REPO_ALLOW_PRERELEASE"); ok && !f.Changed("allow-prerelease") {
+		p.allowPrerelease, _ = strconv.ParseBool(v)
+	}
+	if v, _, ok := lookupRepoEnv(p.repoName, "HELM_REPO_VERSION_PATTERN"); ok && p.versionPattern == "" {
+		p.versionPattern = v
+	}
+	if v, ok := os.LookupEnv("HELM_PUSH_TEMP_DIR"); ok && p.tempDir == "" {
+		p.tempDir = v
+	}
+	if v, ok := os.LookupEnv("HELM_PUSH_MAX_SIZE"); ok && p.maxSize == "" {
+		p.maxSize = v
+	}
+	// the AWS variables are often set for other tools, so only the
+	// flags turn on signing
+	if p.awsAccessKeyID != "" || p.awsRegion != "" {
+		if p.awsAccessKeyID == "" {
+			p.awsAccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+			// a session token only goes with the key it was issued for
+			p.awsSessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		}
+		if p.awsSecretAccessKey == "" {
+			p.awsSecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		}
+		if p.awsRegion == "" {
+			p.awsRegion = os.Getenv("AWS_REGION")
+		}
+		if p.awsRegion == "" {
+			p.awsRegion = os.Getenv("AWS_DEFAULT_REGION")
+		}
+	}
+	// likewise, only --vault-path turns on reading from Vault
+	if p.vaultPath != "" {
+		if p.vaultAddr == "" {
+			p.vaultAddr = os.Getenv("VAULT_ADDR")
+		}
+		if p.vaultToken == "" {
+			p.vaultToken = os.Getenv("VAULT_TOKEN")
+		}
+	}
+	if v, ok := os.LookupEnv("HELM_REPO_CA_FILE"); ok && p.caFile == "" {
+		p.caFile = v
+	}
+	if v, ok := os.LookupEnv("HELM_REPO_CERT_FILE"); ok && p.certFile == "" {
+		p.certFile = v
+	}
+	if v, ok := os.LookupEnv("HELM_REPO_KEY_FILE"); ok && p.keyFile == "" {
+		p.keyFile = v
+	}
+	if v, ok := os.LookupEnv("HELM_REPO_INSECURE"); ok {
+		p.insecureSkipVerify, _ = strconv.ParseBool(v)
+	}
+
+	if p.accessToken == "" {
+		p.setAccessTokenFromConfigFile()
+	}
+}
+
+// parseBoolEnv parses a boolean environment variable, accepting yes/no
+// and on/off besides what strconv.ParseBool does. The second result is
+// false for unrecognized values, which are parsed as false
+func parseBoolEnv(v string) (bool, bool) {
+	if b, err := strconv.ParseBool(v); err == nil {
+		return b, true
+	}
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "yes", "y", "on":
+		return true, true
+	case "no", "n", "off":
+		return false, true
+	}
+	return false, false
+}
+
+// lookupRepoEnv looks up a HELM_REPO_* environment variable, preferring
+// its per-repo variant (see repoEnvName) when set. It returns the value
+// and the name of the variable it came from
+func lookupRepoEnv(repoName string, env string) (string, string, bool) {
+	if name := repoEnvName(repoName, env); name != "" {
+		if v, ok := os.LookupEnv(name); ok {
+			return v, name, true
+		}
+	}
+	v, ok := os.LookupEnv(env)
+	return v, env, ok
+}
+
+// repoEnvName returns the per-repo variant of a HELM_REPO_* environment
+// variable, HELM_REPO_<NAME>_<KEY>, where NAME is the upper-cased repo name
+// with dashes mapped to underscores. Returns "" for repo names which can't
+// be used in a variable name, such as repo URLs
+func repoEnvName(repoName string, env string) string {
+	if !repoEnvNameRegexp.MatchString(repoName) {
+		return ""
+	}
+	name := strings.ToUpper(strings.Replace(repoName, "-", "_", -1))
+	return "HELM_REPO_" + name + "_" + strings.TrimPrefix(env, "HELM_REPO_")
+}
+
+func (p *pushCmd) setAccessTokenFromConfigFile() {
+	usr, err := user.Current()
+	if err != nil {
+		return
+	}
+	configPath := path.Join(usr.HomeDir, ".cfconfig")
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return
+	}
+	var c config
+	yamlFile, err := os.ReadFile(configPath)
+	if err != nil {
+		return
+	}
+	if err = yaml.Unmarshal(yamlFile, &c); err != nil {
+		return
+	}
+	for _, context := range c.Contexts {
+		if context.Name == c.CurrentContext {
+			p.accessToken = context.Token
+			break
+		}
+	}
+}
+
+// setupTargets prepares a copy of the command for each repo the chart
+// is pushed to, so each one gets its own settings from the config file.
+// The command itself is used for the first repo
+func (p *pushCmd) setupTargets(cmd *cobra.Command, repoNames []string) ([]*pushCmd, error) {
+	if err := p.validateIdempotencyKeyFlags(repoNames); err != nil {
+		return nil, err
+	}
+	base := *p
+	targets := make([]*pushCmd, len(repoNames))
+	for i, repoName := range repoNames {
+		t := p
+		if i > 0 {
+			c := base
+			t = &c
+		}
+		t.repoName = repoName
+		if helm.IsOCIRef(repoName) {
+			t.oci = true
+			if err := t.validateOCIFlags(); err != nil {
+				return nil, err
+			}
+		}
+		if err := t.setup(cmd); err != nil {
+			return nil, err
+		}
+		if err := t.setupStagingRepo(cmd, base); err != nil {
+			return nil, err
+		}
+		targets[i] = t
+	}
+	return targets, nil
+}
+
+// splitRepoNames returns the repos given as a comma-separated list,
+// followed by any given with --also-repo. Repos given more than once
+// are only pushed to once
+func splitRepoNames(arg string, alsoRepos []string) []string {
+	var repoNames []string
+	seen := map[string]bool{}
+	for _, repoName := range append(strings.Split(arg, ","), alsoRepos...) {
+		repoName = strings.TrimSpace(repoName)
+		if repoName == "" || seen[repoName] {
+			continue
+		}
+		seen[repoName] = true
+		repoNames = append(repoNames, repoName)
+	}
+	return repoNames
+}
+
+// pushCharts pushes each chart found with --recursive or listed with
+// --from-file and/or selected with --changed-since, followed by its
+// subcharts with --with-subcharts, to targets or, if targets is nil, to
+// the repo in each chart's helm-push/repo annotation. Charts are pushed
+// after the charts they depend on. Unless --fail-fast is given, every
+// chart is pushed and the failures are reported at the end
+func (p *pushCmd) pushCharts(cmd *cobra.Command, targets []*pushCmd) error {
+	base := *p
+	if targets == nil {
+		// only used for logging, each chart gets its own targets
+		if err := p.setup(cmd); err != nil {
+			return err
+		}
+	}
+
+	paths := []string{p.chartName}
+	if p.chartPaths != nil {
+		paths = p.chartPaths
+	}
+	if p.recursive {
+		var err error
+		if paths, err = findLocalCharts(p.chartName); err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no charts found in %s", p.chartName)
+		}
+	}
+
+	if p.changedSince != "" {
+		selected, err := p.selectChangedCharts(paths, p.changedSince)
+		if err != nil {
+			return err
+		}
+		p.log.info("", p.repoName, "%d of %d charts changed since %s", len(selected), len(paths), p.changedSince)
+		for _, path := range selected {
+			p.log.info("", p.repoName, "  %s", path)
+		}
+		paths = selected
+	}
+
+	if len(paths) > 1 {
+		ordered, err := orderByDependencies(paths)
+		if err != nil {
+			return err
+		}
+		if strings.Join(ordered, "\n") != strings.Join(paths, "\n") {
+			p.log.info("", p.repoName, "Pushing charts in dependency order:")
+			for _, path := range ordered {
+				p.log.info("", p.repoName, "  %s", path)
+			}
+		}
+		paths = ordered
+	}
+
+	dir := p.chartName
+	var jobs []batchJob
+	for _, path := range paths {
+		jobs = append(jobs, batchJob{path: path})
+		if p.withSubcharts {
+			subcharts, err := p.findSubcharts(path)
+			if err != nil {
+				return err
+			}
+			for _, subchart := range subcharts {
+				jobs = append(jobs, batchJob{path: subchart, parent: path})
+			}
+		}
+	}
+
+	var failed []string
+	batch := make([]batchResult, 0, len(jobs))
+	for _, job := range jobs {
+		name, parent := batchChartName(dir, job.path), ""
+		if job.parent != "" {
+			name, parent = filepath.Base(job.path), batchChartName(dir, job.parent)
+		}
+		if len(failed) > 0 && p.failFast {
+			batch = append(batch, batchResult{Chart: name, Parent: parent, Status: batchAborted})
+			continue
+		}
+		start := time.Now()
+		pushed, err := p.pushChart(cmd, &base, targets, job)
+		r := newBatchResult(name, pushed, err, time.Since(start))
+		r.Error = p.redact(r.Error)
+		r.Parent = parent
+		batch = append(batch, r)
+		if err != nil {
+			p.log.error("", p.repoName, "Failed to push %s: %s", job.path, err)
+			failed = append(failed, job.path)
+		}
+	}
+	if p.recursive || p.withSubcharts || p.chartPaths != nil {
+		if err := p.printBatchSummary(cmd.ErrOrStderr(), batch, len(failed) > 0); err != nil {
+			return err
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to push %d of %d charts: %s", len(failed), len(jobs), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// pushChart pushes the chart of job to targets or, if targets is nil,
+// to the repo in its helm-push/repo annotation (or its parent's, for a
+// subchart) using a fresh copy of base, so settings for one repo never
+// leak into another. Subcharts are pushed without the flags changing
+// their parent. It returns the command which pushed the chart, if any
+func (p *pushCmd) pushChart(cmd *cobra.Command, base *pushCmd, targets []*pushCmd, job batchJob) (*pushCmd, error) {
+	if targets != nil {
+		c := p
+		if job.parent != "" {
+			c = p.subchartCmd()
+		}
+		c.chartName = job.path
+		return c, c.push(targets)
+	}
+
+	repoName := annotatedRepo(job.path)
+	if repoName == "" && job.parent != "" {
+		repoName = annotatedRepo(job.parent)
+	}
+	if repoName == "" {
+		return nil, fmt.Errorf("no repo given and chart has no %s annotation", repoAnnotation)
+	}
+	c := *base
+	if job.parent != "" {
+		c = *base.subchartCmd()
+	}
+	c.chartName = job.path
+	targets, err := c.setupTargets(cmd, splitRepoNames(repoName, c.alsoRepos))
+	if err != nil {
+		return nil, err
+	}
+	return &c, c.push(targets)
+}
+
+// checkProvenance returns the provenance file of the chart package with
+// --require-prov or --verify, verifying it against the keyring with
+// --verify. Signed packages are pushed unchanged, as any change would
+// break their signature
+func (p *pushCmd) checkProvenance() (string, error) {
+	if !p.requireProv && !p.verify {
+		return "", nil
+	}
+	if flag := p.chartOverrideFlag(); flag != "" {
+		return "", fmt.Errorf("--%s can't be used with --require-prov or --verify, signed chart packages are pushed unchanged", flag)
+	}
+
+	var provPath string
+	if fi, err := os.Stat(p.chartName); err == nil && !fi.IsDir() && !helm.IsChartURL(p.chartName) {
+		provPath = helm.ProvenanceFile(p.chartName)
+	}
+	if provPath == "" {
+		return "", fmt.Errorf("no provenance file found for %s, only signed chart packages (.tgz with a .prov file next to it) can be pushed with --require-prov or --verify", p.chartName)
+	}
+
+	if p.verify {
+		if err := helm.VerifyChartPackage(p.chartName, provPath, p.keyring); err != nil {
+			return "", fmt.Errorf("failed to verify %s: %w", filepath.Base(p.chartName), err)
+		}
+		p.log.info("", p.repoName, "Verified %s against %s", filepath.Base(provPath), p.keyring)
+	}
+	return provPath, nil
+}
+
+// chartOverrideFlag returns the name of the first flag given that
+// changes the chart before pushing it, or "" if there is none
+func (p *pushCmd) chartOverrideFlag() string {
+	switch {
+	case p.nameOverride != "":
+		return "name"
+	case p.chartVersion != "":
+		return "version"
+	case p.appVersion != "":
+		return "app-version"
+	case p.appVersionFrom != "":
+		return "app-version-from"
+	case len(p.stripV) > 0:
+		return "strip-v"
+	case len(p.annotationArgs) > 0:
+		return "set-annotation"
+	case p.annotateVCS:
+		return "annotate-vcs"
+	case len(p.includes) > 0:
+		return "include"
+	case p.bump != "":
+		return "bump"
+	case p.dependencyUpdate:
+		return "dependency-update"
+	}
+	return ""
+}
+
+// ignorePatterns returns the patterns from --ignore-file followed by
+// those from --ignore, to apply on top of the chart's .helmignore
+func (p *pushCmd) ignorePatterns() ([]string, error) {
+	var patterns []string
+	for _, path := range p.ignoreFiles {
+		filePatterns, err := helm.ReadIgnoreFile(path)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, filePatterns...)
+	}
+	return append(patterns, p.ignore...), nil
+}
+
+// logExcluded returns a function printing the files left out of the
+// package by --ignore and --ignore-file, or nil if debugging is disabled
+func (p *pushCmd) logExcluded() func(string) {
+	out := debugOutput()
+	if out == nil {
+		return nil
+	}
+	return func(name string) {
+		fmt.Fprintf(out, "[debug] excluded %s\n", name)
+	}
+}
+
+// include is a file added to the chart with --include
+type include struct {
+	src  string
+	dest string
+}
+
+// parseIncludes parses --include src[:dest] args. dest is a path relative
+// to the chart root, defaulting to the file name of src, and a dest ending
+// in "/" is the directory to put the file in
+func parseIncludes(args []string) ([]include, error) {
+	var includes []include
+	for _, arg := range args {
+		// leave the colon of Windows drive letters alone
+		vol := filepath.VolumeName(arg)
+		src, dest := arg, ""
+		if i := strings.LastIndex(arg[len(vol):], ":"); i >= 0 {
+			src, dest = arg[:len(vol)+i], arg[len(vol)+i+1:]
+		}
+		if src == "" {
+			return nil, fmt.Errorf("invalid --include %q (must be src[:dest])", arg)
+		}
+		dest = filepath.ToSlash(dest)
+		if dest == "" || strings.HasSuffix(dest, "/") {
+			dest += filepath.Base(src)
+		}
+		includes = append(includes, include{src: src, dest: dest})
+	}
+	return includes, nil
+}
+
+// addIncludes adds the files given with --include to the chart
+func (p *pushCmd) addIncludes(chart *helm.Chart) error {
+	includes, err := parseIncludes(p.includes)
+	if err != nil {
+		return err
+	}
+	for _, inc := range includes {
+		fi, err := os.Stat(inc.src)
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return fmt.Errorf("can't include %s, it is a directory (only files can be included)", inc.src)
+		}
+		data, err := os.ReadFile(inc.src)
+		if err != nil {
+			return err
+		}
+		if err := chart.AddFile(inc.dest, data, p.includeOverwrite); err != nil {
+			if errors.Is(err, helm.ErrFileExists) {
+				return fmt.Errorf("can't include %s: %s (use --include-overwrite to replace it)", inc.src, err)
+			}
+			return fmt.Errorf("can't include %s: %s", inc.src, err)
+		}
+		p.log.info(chart.Name(), p.repoName, "Including %s as %s", inc.src, inc.dest)
+	}
+	return nil
+}
+
+// checkForbiddenFiles refuses charts with files which look like secrets,
+// matching the default patterns (unless --forbid-none) or --forbid
+func (p *pushCmd) checkForbiddenFiles(chart *helm.Chart) error {
+	var patterns []string
+	if !p.forbidNone {
+		patterns = append(patterns, helm.DefaultForbiddenPatterns...)
+	}
+	patterns = append(patterns, p.forbid...)
+	forbidden, err := chart.ForbiddenFiles(patterns, !p.forbidNone)
+	if err != nil {
+		return err
+	}
+	if len(forbidden) == 0 {
+		return nil
+	}
+	lines := make([]string, len(forbidden))
+	for i, f := range forbidden {
+		lines[i] = "  " + f.String()
+	}
+	return fmt.Errorf("refusing to push %s, it contains files which look like secrets (leave them out with --ignore):\n%s", chart.Name(), strings.Join(lines, "\n"))
+}
+
+// validateValues checks the values of the chart and its subcharts
+// against their values.schema.json, listing every violation
+func validateValues(chart *helm.Chart) error {
+	violations, err := chart.ValidateValues()
+	if err != nil {
+		return err
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	lines := make([]string, len(violations))
+	for i, v := range violations {
+		lines[i] = "  " + v.String()
+	}
+	return fmt.Errorf("values.yaml does not match values.schema.json (use --validate-values=false to push anyway):\n%s", strings.Join(lines, "\n"))
+}
+
+// renderChart renders the templates of the chart with the --values and
+// --set overrides, failing on the first template error. Library charts
+// are skipped
+func (p *pushCmd) renderChart(chart *helm.Chart) error {
+	opts := &values.Options{ValueFiles: p.valueFiles, Values: p.setValues}
+	vals, err := opts.MergeValues(getter.All(settings))
+	if err != nil {
+		return err
+	}
+	err = chart.Render(vals)
+	if errors.Is(err, helm.ErrLibraryChart) {
+		p.log.info(chart.Name(), p.repoName, "Skipping render check, %s is a library chart", chart.Name())
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("templates of %s failed to render: %s", chart.Name(), err)
+	}
+	return nil
+}
+
+// mkdirTemp creates a temp directory for chart packages in --temp-dir,
+// or in the system temp directory
+func (p *pushCmd) mkdirTemp() (string, error) {
+	return os.MkdirTemp(p.tempDir, "helm-push-")
+}
+
+// checkVersionPattern checks the final version of the chart against
+// the repo's --version-pattern, if any
+func (p *pushCmd) checkVersionPattern(chart *helm.Chart) error {
+	if p.versionRegexp == nil || p.versionRegexp.MatchString(chart.Version()) {
+		return nil
+	}
+	return fmt.Errorf("version %s of %s does not match the version pattern %q of %s", chart.Version(), chart.Name(), p.versionPattern, p.repoName)
+}
+
+// validateStripV checks the --strip-v modes
+func validateStripV(modes []string) error {
+	for _, mode := range modes {
+		if mode != stripVersion && mode != stripAppVersion {
+			return fmt.Errorf("invalid --strip-v %q (must be %s or %s)", mode, stripVersion, stripAppVersion)
+		}
+	}
+	return nil
+}
+
+// validateOptionalFlagValues fails with a clear error when the value of
+// --wait or --strip-v, which is optional, is given after a space instead
+// of "=", and so is taken as a chart or repo argument
+func validateOptionalFlagValues(f *pflag.FlagSet, args []string) error {
+	for _, arg := range args {
+		if _, err := os.Stat(arg); err == nil {
+			continue
+		}
+		if _, err := time.ParseDuration(arg); err == nil && f.Changed("wait") {
+			return optionalFlagValueError("wait", arg)
+		}
+		if validateStripV(strings.Split(arg, ",")) == nil && f.Changed("strip-v") {
+			return optionalFlagValueError("strip-v", arg)
+		}
+	}
+	return nil
+}
+
+func optionalFlagValueError(flag string, value string) error {
+	return fmt.Errorf("the value of --%s must be given after \"=\", e.g. --%s=%s (%q was taken as an argument)", flag, flag, value, value)
+}
+
+// normalizeVersions removes a leading "v" from the chart version and/or
+// appVersion as requested with --strip-v, printing the final values
+func (p *pushCmd) normalizeVersions(chart *helm.Chart) {
+	modes := map[string]bool{}
+	for _, mode := range p.stripV {
+		modes[mode] = true
+	}
+	if modes[stripVersion] {
+		if v := stripV(chart.Version()); v != chart.Version() {
+			p.log.info(chart.Name(), p.repoName, "Using version %s (normalized from %s)", v, chart.Version())
+			chart.SetVersion(v)
+		} else {
+			p.log.info(chart.Name(), p.repoName, "Using version %s", v)
+		}
+	}
+	if modes[stripAppVersion] {
+		if v := stripV(chart.AppVersion()); v != chart.AppVersion() {
+			p.log.info(chart.Name(), p.repoName, "Using appVersion %s (normalized from %s)", v, chart.AppVersion())
+			chart.SetAppVersion(v)
+		} else {
+			p.log.info(chart.Name(), p.repoName, "Using appVersion %s", v)
+		}
+	}
+}
+
+// stripV removes a single leading "v" or "V" from a version
+func stripV(version string) string {
+	if len(version) > 1 && (version[0] == 'v' || version[0] == 'V') {
+		return version[1:]
+	}
+	return version
+}
+
+// parseAnnotations parses --set-annotation key=value pairs
+func parseAnnotations(args []string) (map[string]string, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	annotations := make(map[string]string, len(args))
+	for _, arg := range args {
+		i := strings.Index(arg, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("invalid --set-annotation %q (must be key=value)", arg)
+		}
+		annotations[arg[:i]] = arg[i+1:]
+	}
+	return annotations, nil
+}
+
+// readStdinChart writes the chart package piped to stdin to a temp file,
+// returning its path and a function removing it
+func (p *pushCmd) readStdinChart(in io.Reader) (string, func(), error) {
+	if isTerminal(in) {
+		return "", nil, errors.New("expected a .tgz chart package to be piped to stdin")
+	}
+	// only set up for the repo later, see setFieldsFromEnv
+	if p.tempDir == "" {
+		p.tempDir = os.Getenv("HELM_PUSH_TEMP_DIR")
+	}
+	tmp, err := p.mkdirTemp()
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmp) }
+
+	path := filepath.Join(tmp, "stdin.tgz")
+	f, err := os.Create(path)
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	n, err := io.Copy(f, in)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n == 0 {
+		err = errors.New("no chart package on stdin")
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("could not read chart from stdin: %s", err)
+	}
+	return path, cleanup, nil
+}
+
+// annotatedRepo returns the repo named in the helm-push/repo annotation
+// of a chart, or "" if it has none or can't be loaded
+func annotatedRepo(chartName string) string {
+	c, err := helm.GetChartByName(chartName)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(c.Annotation(repoAnnotation))
+}
+
+func (p *pushCmd) push(targets []*pushCmd) error {
+	p.pushed, p.results = nil, nil
+	if p.dependencyUpdate {
+		if err := p.updateDependencies(); err != nil {
+			return err
+		}
+	}
+
+	// Catch broken charts before any network round trip. Packages
+	// from URLs only have their Chart.yaml checked when loaded
+	if !helm.IsChartURL(p.chartName) {
+		if err := helm.ValidateChart(p.chartName); err != nil {
+			return err
+		}
+	}
+
+	provPath, err := p.checkProvenance()
+	if err != nil {
+		return err
+	}
+	if err := p.checkSignature(); err != nil {
+		return err
+	}
+
+	patterns, err := p.ignorePatterns()
+	if err != nil {
+		return err
+	}
+	// Packages from URLs are downloaded with the settings of the
+	// first repo, which is connected to early for it
+	clients := make([]*cm.Client, len(targets))
+	chartPath := p.chartName
+	if helm.IsChartURL(p.chartName) {
+		client, err := p.chartURLClient()
+		if err != nil {
+			return err
+		}
+		if !p.oci {
+			clients[0] = client
+		}
+		if chartPath, err = helm.DownloadChart(p.ctx, client.HTTPClient(), p.chartName); err != nil {
+			return err
+		}
+		defer os.Remove(chartPath)
+	}
+	chart, err := helm.GetChartByNameIgnoring(chartPath, patterns, p.logExcluded())
+	if err != nil {
+		return err
+	}
+	p.pushed = chart
+
+	// name override
+	if p.nameOverride != "" {
+		oldName := chart.Name()
+		chart.SetName(p.nameOverride)
+		if deps := chart.DependenciesReferencing(oldName); len(deps) > 0 {
+			p.log.warn(p.nameOverride, p.repoName, "dependencies of %s still refer to its old name %s: %s", p.nameOverride, oldName, strings.Join(deps, ", "))
+		}
+	}
+
+	// library charts are not installed, so they have no appVersion
+	if chart.IsLibrary() && (p.appVersion != "" || p.appVersionFrom != "") {
+		return fmt.Errorf("%s is a library chart, --app-version and --app-version-from cannot be used with it", chart.Name())
+	}
+
+	// version override
+	if p.chartVersion != "" {
+		chart.SetVersion(p.chartVersion)
+	}
+	if p.appVersion != "" {
+		chart.SetAppVersion(p.appVersion)
+	}
+	if p.appVersionFrom != "" {
+		appVersion, err := resolveAppVersion(p.appVersionFrom, p.chartName)
+		if err != nil {
+			return err
+		}
+		chart.SetAppVersion(appVersion)
+		p.log.info(chart.Name(), p.repoName, "Using appVersion %s from %s", appVersion, p.appVersionFrom)
+	}
+	p.normalizeVersions(chart)
+
+	// --set-annotation takes precedence over VCS metadata
+	if p.annotateVCS {
+		vcs, err := vcsAnnotations(p.chartName)
+		if err != nil {
+			return err
+		}
+		chart.SetAnnotations(vcs)
+		p.log.info(chart.Name(), p.repoName, "Annotating %s with revision %s", chart.Name(), vcs[vcsRevisionAnnotation])
+	}
+	if len(p.annotations) > 0 {
+		chart.SetAnnotations(p.annotations)
+	}
+
+	if err := p.addIncludes(chart); err != nil {
+		return err
+	}
+	if err := p.checkForbiddenFiles(chart); err != nil {
+		return err
+	}
+
+	if p.validateValues {
+		if err := validateValues(chart); err != nil {
+			return err
+		}
+	}
+	if p.validateRender {
+		if err := p.renderChart(chart); err != nil {
+			return err
+		}
+	}
+
+	// --bump resolves the version against the first repo
+	if p.bump != "" {
+		if clients[0] == nil {
+			if clients[0], err = p.connect(); err != nil {
+				return err
+			}
+		}
+		version, err := p.resolveBumpVersion(clients[0], chart)
+		if err != nil {
+			return err
+		}
+		chart.SetVersion(version)
+		p.log.info(chart.Name(), p.repoName, "Resolved %s version to %s", chart.Name(), version)
+	}
+
+	for _, t := range targets {
+		if err := t.checkVersionPattern(chart); err != nil {
+			return err
+		}
+	}
+
+	existing := p.findExisting(targets, clients, chart)
+	skipPackage := allExisting(existing)
+
+	tmp, err := p.mkdirTemp()
+	if err != nil {
+		return err
+	}
+	if !p.dryRun {
+		// the package is left for the curl command --dry-run prints
+		defer os.RemoveAll(tmp)
+	}
+
+	// The package is only built once, so every repo gets the same
+	// digest. Signed packages are pushed as they are
+	chartPackagePath := p.chartName
+	if skipPackage {
+		chartPackagePath = fmt.Sprintf("%s-%s.tgz", chart.Name(), chart.Version())
+	} else if provPath == "" && p.signature == "" {
+		if chartPackagePath, err = helm.CreateChartPackage(chart, tmp); err != nil {
+			return err
+		}
+	}
+	if !skipPackage {
+		if err := p.checkPackageSize(chart.Name(), chartPackagePath); err != nil {
+			return err
+		}
+	}
+	var sum string
+	if !skipPackage {
+		if sum, err = packageChecksum(chartPackagePath); err != nil {
+			return err
+		}
+	}
+	sigPath := p.signature
+	if p.signCmd != "" && !skipPackage {
+		if sigPath, err = p.signPackage(chartPackagePath, tmp); err != nil {
+			return err
+		}
+	}
+
+	var results []pushResult
+	var failed, sigFailed []string
+	for i, t := range targets {
+		result := pushResult{
+			Chart:       chart.Name(),
+			Version:     chart.Version(),
+			AppVersion:  chart.AppVersion(),
+			Type:        chart.Type(),
+			Annotations: chart.Annotations(),
+			Repo:        t.repoName,
+			Package:     filepath.Base(chartPackagePath),
+			Provenance:  filepath.Base(provPath),
+			SHA256:      sum,
+		}
+		var err error
+		if existing[i] {
+			t.skipExistingVersion(chart, &result)
+		} else if !p.signatureOnly {
+			err = t.pushPackage(clients[i], chart, chartPackagePath, provPath, &result)
+		}
+		if err != nil && len(targets) == 1 {
+			return err
+		}
+		if err != nil {
+			t.log.error(chart.Name(), t.repoName, "Failed to push %s to %s: %s", result.Package, t.repoName, err)
+			result.Error = t.redact(err.Error())
+			failed = append(failed, t.repoName)
+		} else if sigPath != "" && !result.Skipped {
+			// reported on its own, so only the signature needs pushing again
+			result.Signature = signatureName(chart)
+			if err = t.pushSignature(clients[i], chart, sigPath); err != nil {
+				t.log.error(chart.Name(), t.repoName, "Pushed %s to %s but failed to push its signature: %s", result.Package, t.repoName, err)
+				result.SignatureError = t.redact(err.Error())
+				sigFailed = append(sigFailed, t.repoName)
+			}
+		}
+		results = append(results, result)
+		if err != nil && p.failFast {
+			break
+		}
+	}
+	p.results = results
+
+	if p.checksumFile != "" && sum != "" && !p.signatureOnly && anyPushed(results) {
+		if err := p.writeChecksumFile(chart.Name(), filepath.Base(chartPackagePath), sum); err != nil {
+			return err
+		}
+	}
+
+	if p.output == outputJSON {
+		var err error
+		if len(targets) == 1 {
+			err = printJSON(p.out, results[0])
+		} else {
+			err = printJSON(p.out, results)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to push to %d of %d repositories: %s", len(failed), len(targets), strings.Join(failed, ", "))
+	}
+	if len(sigFailed) > 0 {
+		err := fmt.Errorf("pushed %s-%s but failed to push its signature to %d of %d repositories: %s", chart.Name(), chart.Version(), len(sigFailed), len(targets), strings.Join(sigFailed, ", "))
+		if p.signature != "" {
+			err = fmt.Errorf("%s (retry with --signature-only)", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// pushPackage uploads the chart package, and its provenance file if
+// provPath is set, to the command's repo, connecting to it first if
+// client is nil, and records where it was pushed to in result. With
+// --if-newer, the upload is skipped unless the chart is newer than the
+// repo's. The --pre-push-hook commands run just before the upload. With
+// --dry-run, the upload is only printed as a curl command, as it is for
+// --debug before uploading. With --atomic, the chart is staged and
+// verified first, and with --wait the push is only done once the chart
+// is in the repo index
+func (p *pushCmd) pushPackage(client *cm.Client, chart *helm.Chart, chartPackagePath string, provPath string, result *pushResult) error {
+	if !p.allowPrerelease && isPrerelease(chart.Version()) {
+		return fmt.Errorf("%s-%s is a pre-release version, refusing to push it to %s (use --allow-prerelease to push it anyway)", chart.Name(), chart.Version(), p.repoName)
+	}
+
+	if p.oci {
+		if p.dryRun {
+			p.log.info(chart.Name(), p.repoName, "Dry run, not pushing %s to %s", chartPackagePath, p.repoName)
+			result.Skipped = true
+			return nil
+		}
+		if err := p.runPrePushHooks(chart, chartPackagePath); err != nil {
+			return err
+		}
+		return p.pushOCI(chart, chartPackagePath, result)
+	}
+
+	if client == nil {
+		var err error
+		if client, err = p.connect(); err != nil {
+			return err
+		}
+	}
 
-			// If there are 4 args, this is likely being used as a downloader for cm:// protocol
-			if len(args) == 4 && strings.HasPrefix(args[3], "cm://") {
-				p.setFieldsFromEnv()
-				return p.download(args[3])
-			}
+	packageName := filepath.Base(chartPackagePath)
+	if p.ifNewer {
+		remote, err := remoteVersionNotOlder(p.ctx, client, chart)
+		if err != nil {
+			return err
+		}
+		if remote != nil {
+			p.log.info(chart.Name(), p.repoName, "%s skipped, remote %s >= local %s", packageName, remote.Original(), chart.Version())
+			result.Skipped = true
+			return nil
+		}
+	}
 
-			if len(args) != 2 {
-				return errors.New("This command needs 2 arguments: name of chart, name of chart repository (or repo URL)")
-			}
-			p.chartName = args[0]
-			p.repoName = args[1]
-			p.setFieldsFromEnv()
-			return p.push()
-		},
+	if p.dryRun || debugOutput() != nil {
+		curl, err := client.UploadCurlCommand(chartPackagePath, p.forceUpload)
+		if err != nil {
+			return err
+		}
+		if p.dryRun {
+			p.log.info(chart.Name(), p.repoName, "Dry run, not pushing %s to %s. To push it with curl:\n%s", packageName, p.repoName, curl)
+			result.Skipped = true
+			return nil
+		}
+		fmt.Fprintf(debugOutput(), "# the same upload with curl:\n%s\n", p.redact(curl))
 	}
-	f := cmd.Flags()
-	f.StringVarP(&p.chartVersion, "version", "v", "", "Override chart version pre-push")
-	f.StringVarP(&p.username, "username", "u", "", "Override HTTP basic auth username [$HELM_REPO_USERNAME]")
-	f.StringVarP(&p.password, "password", "p", "", "Override HTTP basic auth password [$HELM_REPO_PASSWORD]")
-	f.StringVarP(&p.accessToken, "access-token", "", "", "Send token in Authorization header [$HELM_REPO_ACCESS_TOKEN]")
-	f.StringVarP(&p.authHeader, "auth-header", "", "", "Alternative header to use for token auth [$HELM_REPO_AUTH_HEADER]")
-	f.StringVarP(&p.contextPath, "context-path", "", "", "ChartMuseum context path [$HELM_REPO_CONTEXT_PATH]")
-	f.StringVarP(&p.caFile, "ca-file", "", "", "Verify certificates of HTTPS-enabled servers using this CA bundle [$HELM_REPO_CA_FILE]")
-	f.StringVarP(&p.certFile, "cert-file", "", "", "Identify HTTPS client using this SSL certificate file [$HELM_REPO_CERT_FILE]")
-	f.StringVarP(&p.keyFile, "key-file", "", "", "Identify HTTPS client using this SSL key file [$HELM_REPO_KEY_FILE]")
-	f.StringVar(&p.keyring, "keyring", defaultKeyring(), "location of a public keyring")
-	f.BoolVarP(&p.insecureSkipVerify, "insecure", "", false, "Connect to server with an insecure way by skipping certificate verification [$HELM_REPO_INSECURE]")
-	f.BoolVarP(&p.forceUpload, "force", "f", false, "Force upload even if chart version exists")
-	f.BoolVarP(&p.dependencyUpdate, "dependency-update", "d", false, `update dependencies from "requirements.yaml" to dir "charts/" before packaging`)
-	f.BoolVarP(&p.checkHelmVersion, "check-helm-version", "", false, `outputs either "2" or "3" indicating the current Helm major version`)
 
-	f.Parse(args)
+	if p.atomic {
+		cleanup, err := p.stage(client, chart, chartPackagePath)
+		defer cleanup()
+		if err != nil {
+			return err
+		}
+	}
 
-	v2settings.AddFlags(f)
-	v2settings.Init(f)
+	if err := p.runPrePushHooks(chart, chartPackagePath); err != nil {
+		return err
+	}
+	key, err := p.pushIdempotencyKey()
+	if err != nil {
+		return err
+	}
+	result.IdempotencyKey = key
+	p.log.info(chart.Name(), p.repoName, "Pushing %s to %s...", packageName, p.repoName)
+	resp, err := client.UploadChartPackageWithContext(cm.WithIdempotencyKey(p.ctx, key), chartPackagePath, p.forceUpload)
+	if err != nil {
+		return err
+	}
 
-	return cmd
-}
+	if err := handlePushResponse(resp, chart.Name(), chart.Version(), client.URL()); err != nil {
+		if p.skipExisting && errors.Is(err, cm.ErrChartAlreadyExists) {
+			p.skipExistingVersion(chart, result)
+			return nil
+		}
+		return err
+	}
+	if p.atomic {
+		if err := verifyPushed(p.ctx, client, chart.Name(), chart.Version(), chartPackagePath); err != nil {
+			return fmt.Errorf("failed to verify pushed chart %s in %s: %w", packageName, p.repoName, err)
+		}
+		p.log.info(chart.Name(), p.repoName, "Verified pushed chart %s", packageName)
+	}
 
-func (p *pushCmd) setFieldsFromEnv() {
-	if v, ok := os.LookupEnv("HELM_REPO_USERNAME"); ok && p.username == "" {
-		p.username = v
+	if provPath != "" {
+		p.log.info(chart.Name(), p.repoName, "Pushing %s to %s...", filepath.Base(provPath), p.repoName)
+		if _, err := client.UploadProvenanceFileWithContext(p.ctx, provPath, p.forceUpload); err != nil {
+			return err
+		}
 	}
-	if v, ok := os.LookupEnv("HELM_REPO_PASSWORD"); ok && p.password == "" {
-		p.password = v
+	if err := p.waitForIndex(client, chart); err != nil {
+		return err
 	}
-	if v, ok := os.LookupEnv("HELM_REPO_ACCESS_TOKEN"); ok && p.accessToken == "" {
-		p.accessToken = v
+	p.log.info(chart.Name(), p.repoName, "Done (%s chart).", chart.Type())
+	p.describePushed(client, chart, result)
+	return nil
+}
+
+// validateOCIFlags checks that no flag needing the ChartMuseum API is
+// given when pushing to an OCI registry
+func (p *pushCmd) validateOCIFlags() error {
+	if p.bump != "" || p.ifNewer {
+		return errors.New("--bump and --if-newer cannot be used with --oci or oci:// repos")
 	}
-	if v, ok := os.LookupEnv("HELM_REPO_AUTH_HEADER"); ok && p.authHeader == "" {
-		p.authHeader = v
+	if p.signature != "" || p.signCmd != "" {
+		return errors.New("--signature and --sign-cmd cannot be used with --oci or oci:// repos")
 	}
-	if v, ok := os.LookupEnv("HELM_REPO_CONTEXT_PATH"); ok && p.contextPath == "" {
-		p.contextPath = v
+	if p.skipExisting {
+		return errors.New("--skip-existing cannot be used with --oci or oci:// repos")
 	}
-	if v, ok := os.LookupEnv("HELM_REPO_USE_HTTP"); ok {
-		p.useHTTP, _ = strconv.ParseBool(v)
+	if p.atomic {
+		return errors.New("--atomic cannot be used with --oci or oci:// repos")
 	}
-	if v, ok := os.LookupEnv("HELM_REPO_CA_FILE"); ok && p.caFile == "" {
-		p.caFile = v
+	if p.wait > 0 {
+		return errors.New("--wait cannot be used with --oci or oci:// repos")
 	}
-	if v, ok := os.LookupEnv("HELM_REPO_CERT_FILE"); ok && p.certFile == "" {
-		p.certFile = v
+	return nil
+}
+
+// pushOCI pushes the chart package to the command's repo as an OCI
+// registry, logging in first if credentials are available. Without
+// them, helm push uses the credentials stored by helm registry login
+// or docker credential helpers
+func (p *pushCmd) pushOCI(chart *helm.Chart, chartPackagePath string, result *pushResult) error {
+	var ref, username, password string
+	if helm.IsOCIRef(p.repoName) {
+		ref = strings.TrimSuffix(p.repoName, "/")
+	} else {
+		repo, err := p.getRepo()
+		if err != nil {
+			return err
+		}
+		if ref, err = helm.OCIRef(repo.Config.URL); err != nil {
+			return err
+		}
+		username = repo.Config.Username
+		password = repo.Config.Password
+		p.addSecrets(password)
 	}
-	if v, ok := os.LookupEnv("HELM_REPO_KEY_FILE"); ok && p.keyFile == "" {
-		p.keyFile = v
+	if p.username != "" {
+		username = p.username
 	}
-	if v, ok := os.LookupEnv("HELM_REPO_INSECURE"); ok {
-		p.insecureSkipVerify, _ = strconv.ParseBool(v)
+	if p.password != "" {
+		password = p.password
+	}
+	username, password = p.withDefaultCredentials(username, password)
+	if username != "" && password != "" {
+		if err := helm.RegistryLogin(ref, username, password, p.log.out); err != nil {
+			return err
+		}
 	}
 
-	if p.accessToken == "" {
-		p.setAccessTokenFromConfigFile()
+	p.log.info(chart.Name(), p.repoName, "Pushing %s to %s...", filepath.Base(chartPackagePath), ref)
+	digest, err := helm.PushOCI(chartPackagePath, ref, p.log.out)
+	if err != nil {
+		return err
 	}
+	p.log.info(chart.Name(), p.repoName, "Pushed %s", helm.OCIChartRef(ref, chart.Name(), chart.Version(), digest))
+	result.URL = helm.OCIChartRef(ref, chart.Name(), chart.Version(), "")
+	result.Digest = digest
+	p.log.info(chart.Name(), p.repoName, "Done (%s chart).", chart.Type())
+	if result.SHA256 != "" {
+		p.log.info(chart.Name(), p.repoName, "SHA256:  %s", result.SHA256)
+	}
+	p.printInstallHint(chart, result)
+	return nil
 }
 
-func (p *pushCmd) setAccessTokenFromConfigFile() {
-	usr, err := user.Current()
+// updateDependencies updates the dependencies of a chart directory
+// before it is packaged
+func (p *pushCmd) updateDependencies() error {
+	if helm.IsChartURL(p.chartName) {
+		return nil
+	}
+	name := filepath.FromSlash(p.chartName)
+	fi, err := os.Stat(name)
 	if err != nil {
-		return
+		return err
 	}
-	configPath := path.Join(usr.HomeDir, ".cfconfig")
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return
+	if !fi.IsDir() {
+		return nil
 	}
-	var c config
-	yamlFile, err := ioutil.ReadFile(configPath)
-	if err != nil {
-		return
+	if validChart, err := chartutil.IsChartDir(name); !validChart {
+		return err
 	}
-	if err = yaml.Unmarshal(yamlFile, &c); err != nil {
-		return
+	chartPath, err := filepath.Abs(p.chartName)
+	if err != nil {
+		return err
 	}
-	for _, context := range c.Contexts {
-		if context.Name == c.CurrentContext {
-			p.accessToken = context.Token
-			break
+	if helm.HelmMajorVersionCurrent() == helm.HelmMajorVersion2 {
+		v2downloadManager := &v2downloader.Manager{
+			Out:       p.log.out,
+			ChartPath: chartPath,
+			HelmHome:  v2settings.Home,
+			Keyring:   p.keyring,
+			Getters:   v2getter.All(v2settings),
+			Debug:     v2settings.Debug,
 		}
+		return v2downloadManager.Update()
+	}
+	downloadManager := &downloader.Manager{
+		Out:       p.log.out,
+		ChartPath: chartPath,
+		Keyring:   p.keyring,
+		Getters:   getter.All(settings),
+		Debug:     v2settings.Debug,
+	}
+	return downloadManager.Update()
+}
+
+// connect creates a client for the command's repo
+func (p *pushCmd) connect() (*cm.Client, error) {
+	repo, err := p.getRepo()
+	if err != nil {
+		return nil, err
 	}
+	return p.newClientFromRepo(repo)
 }
 
-func (p *pushCmd) push() error {
-	var repo *helm.Repo
-	var err error
+// chartURLClient returns the client to download a chart package given
+// by URL with: a client for the command's repo, so its TLS, proxy and
+// timeout settings and its credentials apply. OCI registries have no
+// such client, so only the TLS, proxy and timeout settings apply then
+func (p *pushCmd) chartURLClient() (*cm.Client, error) {
+	if !p.oci {
+		return p.connect()
+	}
+	return cm.NewClient(
+		cm.URL(p.chartName),
+		cm.Timeout(p.timeout),
+		cm.CAFile(p.caFile),
+		cm.CertFile(p.certFile),
+		cm.KeyFile(p.keyFile),
+		cm.InsecureSkipVerify(p.insecureSkipVerify),
+		cm.Proxy(p.proxy),
+		cm.NoProxy(p.noProxy),
+		cm.Debug(debugOutput()),
+	)
+}
 
-	// If the argument looks like a URL, just create a temp repo object
-	// instead of looking for the entry in the local repository list
-	if regexp.MustCompile(`^https?://`).MatchString(p.repoName) {
-		repo, err = helm.TempRepoFromURL(p.repoName)
-		p.repoName = repo.Config.URL
-	} else {
-		repo, err = helm.GetRepoByName(p.repoName)
+// setupTokens creates the source of the OAuth2 tokens sent with
+// --gcp-service-account-file or --azure-managed-identity, or the ID
+// tokens sent with --google-iap-audience, cached between runs unless
+// --no-token-cache is set, the tokens of the --oauth-token-url client
+// credentials grant, cached for the run, or printed by --token-command,
+// which runs before every request
+func (p *pushCmd) setupTokens() error {
+	if (p.azureClientID != "" || p.azureAudience != "") && !p.azureIdentity {
+		return errors.New("--azure-client-id and --azure-audience only apply to --azure-managed-identity")
+	}
+	if p.gcpKeyFile != "" && p.azureIdentity {
+		return errors.New("--gcp-service-account-file and --azure-managed-identity cannot be used together")
+	}
+	if (p.gcpKeyFile != "" || p.azureIdentity) && p.awsAccessKeyID != "" {
+		return errors.New("--gcp-service-account-file and --azure-managed-identity cannot be used with AWS Signature V4 signing")
 	}
 
-	if err != nil {
+	if p.tokenCommand != "" && (p.gcpKeyFile != "" || p.azureIdentity || p.awsAccessKeyID != "") {
+		return errors.New("--token-command cannot be used with --gcp-service-account-file, --azure-managed-identity or AWS Signature V4 signing")
+	}
+	if p.googleIAPAudience != "" && (p.gcpKeyFile != "" || p.azureIdentity || p.tokenCommand != "" || p.awsAccessKeyID != "") {
+		return errors.New("--google-iap-audience cannot be used with --gcp-service-account-file, --azure-managed-identity, --token-command or AWS Signature V4 signing")
+	}
+
+	if err := p.validateOAuthFlags(); err != nil {
 		return err
 	}
 
-	if p.dependencyUpdate {
-		name := filepath.FromSlash(p.chartName)
-		fi, err := os.Stat(name)
+	var cacheFile string
+	if !p.noTokenCache && (p.gcpKeyFile != "" || p.azureIdentity || p.googleIAPAudience != "") {
+		var err error
+		if cacheFile, err = tokenCachePath(); err != nil {
+			return err
+		}
+	}
+	switch {
+	case p.gcpKeyFile != "":
+		tokens, err := auth.NewGCPTokenSource(p.ctx, p.gcpKeyFile, cacheFile)
 		if err != nil {
 			return err
 		}
-		if fi.IsDir() {
-			if validChart, err := chartutil.IsChartDir(name); !validChart {
-				return err
-			}
-			chartPath, err := filepath.Abs(p.chartName)
-			if err != nil {
-				return err
-			}
-			if helm.HelmMajorVersionCurrent() == helm.HelmMajorVersion2 {
-				v2downloadManager := &v2downloader.Manager{
-					Out:       p.out,
-					ChartPath: chartPath,
-					HelmHome:  v2settings.Home,
-					Keyring:   p.keyring,
-					Getters:   v2getter.All(v2settings),
-					Debug:     v2settings.Debug,
-				}
-				if err := v2downloadManager.Update(); err != nil {
-					return err
-				}
-			} else {
-				downloadManager := &downloader.Manager{
-					Out:       p.out,
-					ChartPath: chartPath,
-					Keyring:   p.keyring,
-					Getters:   getter.All(settings),
-					Debug:     v2settings.Debug,
-				}
-				if err := downloadManager.Update(); err != nil {
-					return err
-				}
-			}
+		p.tokens = tokens
+	case p.googleIAPAudience != "":
+		tokens, err := auth.NewGoogleIDTokenSource(p.ctx, p.googleIAPAudience, cacheFile)
+		if err != nil {
+			return fmt.Errorf("--google-iap-audience: %w", err)
+		}
+		p.tokens = tokens
+	case p.azureIdentity:
+		if p.azureAudience == "" {
+			return errors.New("--azure-managed-identity needs --azure-audience, the application ID URI ChartMuseum's tokens are issued for")
+		}
+		p.tokens = auth.NewAzureTokenSource(p.ctx, p.azureAudience, p.azureClientID, cacheFile)
+	case p.tokenCommand != "":
+		p.tokens = auth.NewCommandTokenSource(p.ctx, p.tokenCommand)
+	case p.oauthTokenURL != "":
+		p.tokens = auth.NewClientCredentialsTokenSource(p.ctx, p.oauthTokenURL, p.oauthClientID, p.oauthClientSecret, p.oauthScopes)
+	}
+	return nil
+}
+
+// validateOAuthFlags checks the --oauth-* flags of the client credentials
+// grant, reading the client secret from --oauth-client-secret-file
+func (p *pushCmd) validateOAuthFlags() error {
+	if p.oauthTokenURL == "" {
+		if p.oauthClientID != "" || p.oauthClientSecret != "" || p.oauthSecretFile != "" || len(p.oauthScopes) > 0 {
+			return errors.New("--oauth-client-id, --oauth-client-secret and --oauth-scopes only apply to --oauth-token-url")
+		}
+		return nil
+	}
+	if p.gcpKeyFile != "" || p.azureIdentity || p.googleIAPAudience != "" || p.tokenCommand != "" || p.awsAccessKeyID != "" {
+		return errors.New("--oauth-token-url cannot be used with --gcp-service-account-file, --azure-managed-identity, --google-iap-audience, --token-command or AWS Signature V4 signing")
+	}
+	if p.oauthSecretFile != "" {
+		if p.oauthClientSecret != "" {
+			return errors.New("--oauth-client-secret and --oauth-client-secret-file cannot be used together")
+		}
+		b, err := os.ReadFile(p.oauthSecretFile)
+		if err != nil {
+			return fmt.Errorf("failed to read --oauth-client-secret-file: %s", err)
 		}
+		p.oauthClientSecret = strings.TrimSpace(string(b))
 	}
+	if p.oauthClientID == "" || p.oauthClientSecret == "" {
+		return errors.New("--oauth-token-url needs --oauth-client-id and --oauth-client-secret (or --oauth-client-secret-file or $HELM_REPO_OAUTH_CLIENT_SECRET)")
+	}
+	return nil
+}
 
-	chart, err := helm.GetChartByName(p.chartName)
+// tokenCachePath returns the location of the cache of OAuth2 tokens:
+// $HELM_PUSH_TOKEN_CACHE if set, otherwise
+// ~/.config/helm-push/tokens.json
+func tokenCachePath() (string, error) {
+	if v, ok := os.LookupEnv("HELM_PUSH_TOKEN_CACHE"); ok && v != "" {
+		return v, nil
+	}
+	home, err := os.UserHomeDir()
 	if err != nil {
-		return err
+		return "", err
 	}
+	return filepath.Join(home, ".config", "helm-push", "tokens.json"), nil
+}
 
-	// version override
-	if p.chartVersion != "" {
-		chart.SetVersion(p.chartVersion)
+// awsSigner returns the AWS Signature V4 signer for requests, or nil
+// without AWS credentials
+func (p *pushCmd) awsSigner() *auth.AWSV4Signer {
+	if p.awsAccessKeyID == "" {
+		return nil
+	}
+	return &auth.AWSV4Signer{
+		AccessKeyID:     p.awsAccessKeyID,
+		SecretAccessKey: p.awsSecretAccessKey,
+		SessionToken:    p.awsSessionToken,
+		Region:          p.awsRegion,
+		Service:         p.awsService,
+	}
+}
+
+// getRepo returns the repo to push to, either by name
+// from the local repository list or built from a URL
+func (p *pushCmd) getRepo() (*helm.Repo, error) {
+	// If the argument looks like a URL, just create a temp repo object
+	// instead of looking for the entry in the local repository list
+	if regexp.MustCompile(`^https?://`).MatchString(p.repoName) {
+		repo, err := helm.TempRepoFromURL(p.repoName)
+		if err != nil {
+			return nil, err
+		}
+		p.repoName = repo.Config.URL
+		return repo, nil
+	}
+	return helm.GetRepoByName(p.repoName)
+}
+
+// getRepoURL returns the URL of the repo to push to, either by
+// name from the local repository list or the URL given as the repo
+func (p *pushCmd) getRepoURL() (string, error) {
+	if regexp.MustCompile(`^https?://`).MatchString(p.repoName) {
+		return p.repoName, nil
+	}
+	return helm.GetRepoURL(p.repoName)
+}
+
+// newClientFromRepo creates a ChartMuseum client for the given repo,
+// applying any credential and TLS overrides. If repo is nil, only the
+// URL of the command's repo is looked up, and credentials must come
+// from flags, the environment or the config file
+func (p *pushCmd) newClientFromRepo(repo *helm.Repo) (*cm.Client, error) {
+	var repoURL, username, password string
+	if repo != nil {
+		repoURL = repo.Config.URL
+		username = repo.Config.Username
+		password = repo.Config.Password
+		p.addSecrets(password)
+	} else {
+		var err error
+		if repoURL, err = p.getRepoURL(); err != nil {
+			return nil, err
+		}
 	}
 
 	// username/password override(s)
-	username := repo.Config.Username
-	password := repo.Config.Password
 	if p.username != "" {
 		username = p.username
 	}
 	if p.password != "" {
 		password = p.password
 	}
+	username, password = p.withDefaultCredentials(username, password)
+
+	// an API key replaces the repo credentials
+	if p.apiKey != "" && username != "" && password != "" {
+		p.log.warn("", p.repoName, "both an API key and a username and password are set for %s, only the API key is sent", p.repoName)
+		username, password = "", ""
+	}
 
 	// unset accessToken if repo credentials are provided
 	if username != "" && password != "" {
@@ -280,9 +1973,9 @@ func (p *pushCmd) push() error {
 	// in case the repo is stored with cm:// protocol, remove it
 	var url string
 	if p.useHTTP {
-		url = strings.Replace(repo.Config.URL, "cm://", "http://", 1)
+		url = strings.Replace(repoURL, "cm://", "http://", 1)
 	} else {
-		url = strings.Replace(repo.Config.URL, "cm://", "https://", 1)
+		url = strings.Replace(repoURL, "cm://", "https://", 1)
 	}
 
 	client, err := cm.NewClient(
@@ -290,45 +1983,41 @@ func (p *pushCmd) push() error {
 		cm.Username(username),
 		cm.Password(password),
 		cm.AccessToken(p.accessToken),
+		cm.APIKey(p.apiKey),
 		cm.AuthHeader(p.authHeader),
 		cm.ContextPath(p.contextPath),
+		cm.Timeout(p.timeout),
+		cm.Retries(p.retries),
+		cm.RetryMaxWait(p.retryMaxWait),
+		cm.OnRetry(p.logRetry),
 		cm.CAFile(p.caFile),
 		cm.CertFile(p.certFile),
 		cm.KeyFile(p.keyFile),
 		cm.InsecureSkipVerify(p.insecureSkipVerify),
+		cm.Proxy(p.proxy),
+		cm.NoProxy(p.noProxy),
+		cm.MaxIdleConns(p.maxIdleConns),
+		cm.IdleConnTimeout(p.idleConnTimeout),
+		cm.Debug(debugOutput()),
+		cm.Progress(p.progressOutput()),
+		cm.AWSV4(p.awsSigner()),
+		cm.TokenSource(p.tokens),
 	)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// update context path if not overrided
-	if p.contextPath == "" {
-		index, err := helm.GetIndexByRepo(repo, getIndexDownloader(client))
+	if p.contextPath == "" && repo != nil {
+		index, err := helm.GetIndexByRepo(repo, getIndexDownloader(p.ctx, client))
 		if err != nil {
-			return err
+			return nil, err
 		}
 		client.Option(cm.ContextPath(index.ServerInfo.ContextPath))
 	}
 
-	tmp, err := ioutil.TempDir("", "helm-push-")
-	if err != nil {
-		return err
-	}
-	defer os.RemoveAll(tmp)
-
-	chartPackagePath, err := helm.CreateChartPackage(chart, tmp)
-	if err != nil {
-		return err
-	}
-
-	fmt.Printf("Pushing %s to %s...\n", filepath.Base(chartPackagePath), p.repoName)
-	resp, err := client.UploadChartPackage(chartPackagePath, p.forceUpload)
-	if err != nil {
-		return err
-	}
-
-	return handlePushResponse(resp)
+	return client, nil
 }
 
 func (p *pushCmd) download(fileURL string) error {
@@ -336,6 +2025,9 @@ func (p *pushCmd) download(fileURL string) error {
 	if err != nil {
 		return err
 	}
+	if err := p.checkOutputFile(); err != nil {
+		return err
+	}
 
 	parts := strings.Split(parsedURL.Path, "/")
 	numParts := len(parts)
@@ -359,54 +2051,123 @@ func (p *pushCmd) download(fileURL string) error {
 		parsedURL.Scheme = "https"
 	}
 
+	username, password := p.withDefaultCredentials(p.username, p.password)
+	if p.apiKey != "" && username != "" && password != "" {
+		p.log.warn("", "", "both an API key and a username and password are set, only the API key is sent")
+		username, password = "", ""
+	}
+
 	client, err := cm.NewClient(
 		cm.URL(parsedURL.String()),
-		cm.Username(p.username),
-		cm.Password(p.password),
+		cm.Username(username),
+		cm.Password(password),
 		cm.AccessToken(p.accessToken),
+		cm.APIKey(p.apiKey),
 		cm.AuthHeader(p.authHeader),
 		cm.ContextPath(p.contextPath),
+		cm.Timeout(p.timeout),
+		cm.Retries(p.retries),
+		cm.RetryMaxWait(p.retryMaxWait),
+		cm.OnRetry(p.logRetry),
 		cm.CAFile(p.caFile),
 		cm.CertFile(p.certFile),
 		cm.KeyFile(p.keyFile),
 		cm.InsecureSkipVerify(p.insecureSkipVerify),
+		cm.Proxy(p.proxy),
+		cm.NoProxy(p.noProxy),
+		cm.MaxIdleConns(p.maxIdleConns),
+		cm.IdleConnTimeout(p.idleConnTimeout),
+		cm.Debug(debugOutput()),
+		cm.AWSV4(p.awsSigner()),
+		cm.TokenSource(p.tokens),
 	)
 
 	if err != nil {
 		return err
 	}
 
-	resp, err := client.DownloadFile(filePath)
+	resp, err := client.DownloadFileWithContext(p.ctx, filePath)
+	if err != nil {
+		return err
+	}
+
+	b, err := handleDownloadResponse(resp)
+	if err != nil {
+		return err
+	}
+	if p.outputFile == "" {
+		fmt.Print(string(b))
+		return nil
+	}
+	return p.writeOutputFile(b)
+}
+
+// checkOutputFile fails if the --output-file of a download exists,
+// unless --force is set, before anything is downloaded
+func (p *pushCmd) checkOutputFile() error {
+	if p.outputFile == "" || p.forceUpload {
+		return nil
+	}
+	if _, err := os.Stat(p.outputFile); err == nil {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", p.outputFile)
+	}
+	return nil
+}
+
+// writeOutputFile writes a download to --output-file, only replacing an
+// existing file with --force
+func (p *pushCmd) writeOutputFile(b []byte) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if p.forceUpload {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(p.outputFile, flags, 0644)
+	if os.IsExist(err) {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", p.outputFile)
+	}
 	if err != nil {
 		return err
 	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
 
-	return handleDownloadResponse(resp)
+// handlePushResponse checks the response to pushing a chart version to
+// the repo at repoURL, which ChartMuseum answers with 201 Created
+func handlePushResponse(resp *http.Response, chartName string, chartVersion string, repoURL string) error {
+	if resp.StatusCode != 201 {
+		return fmt.Errorf("failed to push %s-%s to %s: %w", chartName, chartVersion, cm.RedactURL(repoURL), cm.NewResponseError(resp))
+	}
+	return nil
 }
 
-func handlePushResponse(resp *http.Response) error {
+// handleFileUploadResponse checks the response to uploading a
+// provenance file or signature, which ChartMuseum answers with 201 Created
+func handleFileUploadResponse(resp *http.Response, kind string) error {
+	defer resp.Body.Close()
 	if resp.StatusCode != 201 {
-		b, err := ioutil.ReadAll(resp.Body)
+		b, err := io.ReadAll(resp.Body)
 		if err != nil {
 			return err
 		}
-		return getChartmuseumError(b, resp.StatusCode)
+		return fmt.Errorf("failed to upload %s: %s", kind, getChartmuseumError(b, resp.StatusCode))
 	}
-	fmt.Println("Done.")
 	return nil
 }
 
-func handleDownloadResponse(resp *http.Response) error {
-	b, err := ioutil.ReadAll(resp.Body)
+func handleDownloadResponse(resp *http.Response) ([]byte, error) {
+	b, err := io.ReadAll(resp.Body)
 	defer resp.Body.Close()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if resp.StatusCode != 200 {
-		return getChartmuseumError(b, resp.StatusCode)
+		return nil, getChartmuseumError(b, resp.StatusCode)
 	}
-	fmt.Print(string(b))
-	return nil
+	return b, nil
 }
 
 func getChartmuseumError(b []byte, code int) error {
@@ -420,14 +2181,16 @@ func getChartmuseumError(b []byte, code int) error {
 	return fmt.Errorf("%d: %s", code, er.Error)
 }
 
-func getIndexDownloader(client *cm.Client) helm.IndexDownloader {
+// getIndexDownloader returns a function downloading the repo's index.yaml,
+// which stops when ctx is cancelled
+func getIndexDownloader(ctx context.Context, client *cm.Client) helm.IndexDownloader {
 	return func() ([]byte, error) {
-		resp, err := client.DownloadFile("index.yaml")
+		resp, err := client.DownloadFileWithContext(ctx, "index.yaml")
 		if err != nil {
 			return nil, err
 		}
 		defer resp.Body.Close()
-		b, err := ioutil.ReadAll(resp.Body)
+		b, err := io.ReadAll(resp.Body)
 		if err != nil {
 			return nil, err
 		}
@@ -440,11 +2203,64 @@ func getIndexDownloader(client *cm.Client) helm.IndexDownloader {
 
 func main() {
 	cmd := newPushCmd(os.Args[1:])
-	if err := cmd.Execute(); err != nil {
+	if err := cmd.ExecuteContext(interruptContext()); err != nil {
 		os.Exit(1)
 	}
 }
 
+// reportCancelled wraps a command's RunE to print a short note instead
+// of the error and usage when it fails because its context was cancelled,
+// e.g. on Ctrl-C
+func reportCancelled(runE func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		err := runE(cmd, args)
+		if err != nil && cmd.Context() != nil && cmd.Context().Err() != nil {
+			cmd.SilenceErrors = true
+			cmd.SilenceUsage = true
+			fmt.Fprintln(cmd.ErrOrStderr(), "\nPush cancelled.")
+		}
+		return err
+	}
+}
+
+// interruptContext returns a context which is cancelled on SIGINT or
+// SIGTERM, so that pushes and downloads in flight are aborted and their
+// temp files removed. A second signal kills the process as usual
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-c
+		signal.Stop(c)
+		cancel()
+	}()
+	return ctx
+}
+
+// logRetry logs the wait before a request to the repo is retried
+func (p *pushCmd) logRetry(wait time.Duration, reason string) {
+	p.log.warn("", p.repoName, "%s, retrying in %s", reason, wait.Round(time.Millisecond))
+}
+
+// debugOutput returns the writer for debug output ($HELM_DEBUG),
+// or nil if debugging is disabled
+func debugOutput() io.Writer {
+	if v2settings.Debug {
+		return os.Stderr
+	}
+	return nil
+}
+
+// progressOutput returns the writer for upload progress, or nil if
+// progress is disabled or stdout is not a terminal
+func (p *pushCmd) progressOutput() io.Writer {
+	if p.progress && isTerminal(os.Stdout) {
+		return os.Stderr
+	}
+	return nil
+}
+
 // defaultKeyring returns the expanded path to the default keyring.
 func defaultKeyring() string {
 	return os.ExpandEnv("$HOME/.gnupg/pubring.gpg")
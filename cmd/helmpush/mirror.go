@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+	"github.com/spf13/cobra"
+)
+
+type (
+	mirrorCmd struct {
+		*pushCmd
+		charts []string
+		since  string
+	}
+)
+
+func newMirrorCmd(p *pushCmd) *cobra.Command {
+	m := &mirrorCmd{pushCmd: p}
+	cmd := &cobra.Command{
+		Use:   "mirror SOURCE_REPO DEST_REPO",
+		Short: "Copy the chart versions missing from a ChartMuseum repository from another one",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return errors.New("This command needs 2 arguments: name of source chart repository, name of destination chart repository (or repo URLs)")
+			}
+			return nil
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeRepoNames(len(args))(cmd, args, toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			since, err := parseSince(m.since)
+			if err != nil {
+				return err
+			}
+
+			// Each repo is set up with its own settings
+			targets, err := m.setupTargets(cmd, args)
+			if err != nil {
+				return err
+			}
+			return m.mirror(targets[0], targets[1], since)
+		},
+	}
+	f := cmd.Flags()
+	f.StringSliceVar(&m.charts, "charts", nil, "Only mirror these charts (comma-separated)")
+	f.StringVar(&m.since, "since", "", "Only mirror versions created after this time (RFC 3339, e.g. 2020-01-02T15:04:05Z, or a date, e.g. 2020-01-02)")
+	return cmd
+}
+
+// parseSince parses the --since time, given as an RFC 3339 time or a
+// date in UTC. An empty time is the zero time, so every version is newer
+func parseSince(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: must be an RFC 3339 time (e.g. 2020-01-02T15:04:05Z) or a date (e.g. 2020-01-02)", s)
+}
+
+// mirror copies every chart version in the source repo which the
+// destination repo doesn't have, streaming each one as copy does. Chart
+// versions already in the destination are never overwritten
+func (m *mirrorCmd) mirror(source *pushCmd, dest *pushCmd, since time.Time) error {
+	sourceClient, err := source.connect()
+	if err != nil {
+		return err
+	}
+	destClient, err := dest.connect()
+	if err != nil {
+		return err
+	}
+
+	charts, err := sourceClient.ListChartsWithContext(m.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list the charts in %s: %w", source.repoName, err)
+	}
+
+	names := m.charts
+	if len(names) == 0 {
+		for name := range charts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	c := &copyCmd{pushCmd: m.pushCmd}
+	var mirrored, total int
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		versions, ok := charts[name]
+		if !ok {
+			m.log.warn(name, source.repoName, "No chart named %q in %s", name, source.repoName)
+			continue
+		}
+		cm.SortVersions(versions)
+		for _, cv := range versions {
+			if !cv.Created.After(since) {
+				continue
+			}
+			total++
+			_, err := destClient.GetChartInfoWithContext(m.ctx, cv.Name, cv.Version)
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, cm.ErrNotFound) {
+				return fmt.Errorf("failed to check %s for %s-%s: %w", dest.repoName, cv.Name, cv.Version, err)
+			}
+			if err := c.copyVersion(sourceClient, destClient, source.repoName, dest.repoName, cv.Name, cv.Version); err != nil {
+				return err
+			}
+			mirrored++
+		}
+	}
+	m.log.info("", dest.repoName, "Mirrored %d of %d chart versions from %s to %s.", mirrored, total, source.repoName, dest.repoName)
+	return nil
+}
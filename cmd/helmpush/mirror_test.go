@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMirrorCmd(t *testing.T) {
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/x/y/z/api/charts":
+			w.Write([]byte(`{
+  "mychart": [
+    {"name": "mychart", "version": "0.1.0", "created": "2020-01-01T00:00:00Z"},
+    {"name": "mychart", "version": "0.2.0", "created": "2020-01-03T00:00:00Z"}
+  ],
+  "other": [
+    {"name": "other", "version": "1.0.0", "created": "2020-01-02T06:00:00Z"}
+  ]
+}`))
+		case strings.HasPrefix(r.URL.Path, "/x/y/z/charts/") && !strings.HasSuffix(r.URL.Path, ".prov"):
+			w.Write([]byte(strings.TrimPrefix(r.URL.Path, "/x/y/z/charts/")))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer source.Close()
+
+	var existing, uploaded []string
+	status := 201
+	dest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/x/y/z/api/charts" && r.Method == "POST":
+			w.WriteHeader(status)
+			f, h, _ := r.FormFile("chart")
+			if f != nil {
+				uploaded = append(uploaded, h.Filename)
+			}
+			w.Write([]byte(`{"error": "storage full"}`))
+		case strings.HasPrefix(r.URL.Path, "/x/y/z/api/charts/"):
+			nv := strings.Split(strings.TrimPrefix(r.URL.Path, "/x/y/z/api/charts/"), "/")
+			for _, e := range existing {
+				if e == nv[0]+"-"+nv[1] {
+					w.Write([]byte(`{}`))
+					return
+				}
+			}
+			w.WriteHeader(404)
+			w.Write([]byte(`{"error": "improper constraint"}`))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer dest.Close()
+
+	cleanup := setupTestRepo(t, source.URL)
+	defer cleanup()
+
+	mirror := func(args ...string) (string, error) {
+		uploaded = nil
+		args = append([]string{"mirror", "helm-push-test", dest.URL}, args...)
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+		err := cmd.Execute()
+		sort.Strings(uploaded)
+		return out.String(), err
+	}
+
+	existing = []string{"mychart-0.1.0"}
+	out, err := mirror()
+	if err != nil {
+		t.Fatal("unexpected error mirroring", err)
+	}
+	if got := strings.Join(uploaded, ","); got != "mychart-0.2.0.tgz,other-1.0.0.tgz" {
+		t.Errorf("expected the missing versions to be mirrored, instead got %s", got)
+	}
+	if !strings.Contains(out, "Mirrored 2 of 3 chart versions from helm-push-test to "+dest.URL) {
+		t.Errorf("expected a summary, instead got:\n%s", out)
+	}
+
+	existing = nil
+	if _, err := mirror("--charts", "mychart,missing"); err != nil || strings.Join(uploaded, ",") != "mychart-0.1.0.tgz,mychart-0.2.0.tgz" {
+		t.Errorf("expected only mychart to be mirrored, instead got %s (%v)", uploaded, err)
+	}
+	if _, err := mirror("--since", "2020-01-02"); err != nil || strings.Join(uploaded, ",") != "mychart-0.2.0.tgz,other-1.0.0.tgz" {
+		t.Errorf("expected only versions since 2020-01-02 to be mirrored, instead got %s (%v)", uploaded, err)
+	}
+	if _, err := mirror("--since", "2020-01-02T12:00:00Z"); err != nil || strings.Join(uploaded, ",") != "mychart-0.2.0.tgz" {
+		t.Errorf("expected only versions since 2020-01-02T12:00:00Z to be mirrored, instead got %s (%v)", uploaded, err)
+	}
+
+	if _, err := mirror("--since", "yesterday"); err == nil || !strings.HasPrefix(err.Error(), `invalid --since "yesterday"`) {
+		t.Errorf("expected invalid --since error, instead got %v", err)
+	}
+
+	status = 507
+	if _, err := mirror(); err == nil || !strings.Contains(err.Error(), "storage full") || len(uploaded) != 1 {
+		t.Errorf("expected mirroring to stop at the first failed upload, instead got %v after %d uploads", err, len(uploaded))
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	for s, want := range map[string]time.Time{
+		"":                          {},
+		"2020-01-02":                time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		"2020-01-02T15:04:05+01:00": time.Date(2020, 1, 2, 14, 4, 5, 0, time.UTC),
+	} {
+		got, err := parseSince(s)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("%q: expected %s, instead got %s (%v)", s, want, got, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chartmuseum/helm-push/pkg/helm"
+)
+
+// orderByDependencies returns the charts at paths ordered so each one
+// comes after the other charts it depends on, so umbrella charts are
+// pushed after their freshly pushed subcharts. Dependencies are matched
+// to the other charts by name, whatever their repository (file://,
+// @alias or URL), and moved just before the first chart depending on
+// them. Other charts keep their order. Charts which can't be loaded
+// have no dependencies, their push reports the error
+func orderByDependencies(paths []string) ([]string, error) {
+	charts := make([]*helm.Chart, len(paths))
+	names := make([]string, len(paths))
+	byName := map[string][]int{}
+	for i, path := range paths {
+		if c, err := helm.GetChartByName(path); err == nil {
+			charts[i] = c
+			names[i] = c.Name()
+			byName[c.Name()] = append(byName[c.Name()], i)
+		}
+	}
+
+	deps := make([][]int, len(paths))
+	for i, c := range charts {
+		if c == nil {
+			continue
+		}
+		for _, name := range c.DependencyNames() {
+			for _, j := range byName[name] {
+				if j != i {
+					deps[i] = append(deps[i], j)
+				}
+			}
+		}
+	}
+
+	ordered := make([]string, 0, len(paths))
+	done := make([]bool, len(paths))
+	var stack []int
+	var visit func(i int) error
+	visit = func(i int) error {
+		if done[i] {
+			return nil
+		}
+		for k, j := range stack {
+			if j == i {
+				var cycle []string
+				for _, j := range append(stack[k:], i) {
+					cycle = append(cycle, names[j])
+				}
+				return fmt.Errorf("dependency cycle between charts: %s", strings.Join(cycle, " -> "))
+			}
+		}
+		stack = append(stack, i)
+		for _, j := range deps[i] {
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		done[i] = true
+		ordered = append(ordered, paths[i])
+		return nil
+	}
+	for i := range paths {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOrderByDependencies(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	writeChart := func(name string, deps ...string) string {
+		dir := filepath.Join(tmp, name)
+		os.MkdirAll(dir, 0755)
+		chartYaml := "apiVersion: v2\nname: " + name + "\nversion: 0.1.0\n"
+		if len(deps) > 0 {
+			chartYaml += "dependencies:\n"
+			for _, dep := range deps {
+				chartYaml += "- name: " + dep + "\n  version: 0.1.0\n  repository: \"@team\"\n"
+			}
+		}
+		os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte(chartYaml), 0644)
+		return dir
+	}
+	writeRequirementsChart := func(name string, dep string) string {
+		dir := filepath.Join(tmp, name)
+		os.MkdirAll(dir, 0755)
+		os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte("apiVersion: v1\nname: "+name+"\nversion: 0.1.0\n"), 0644)
+		os.WriteFile(filepath.Join(dir, "requirements.yaml"), []byte("dependencies:\n- name: "+dep+"\n  version: 0.1.0\n  repository: alias:team\n"), 0644)
+		return dir
+	}
+
+	umbrella := writeChart("umbrella", "backend", "frontend", "redis")
+	backend := writeChart("backend", "common")
+	common := writeChart("common")
+	frontend := writeRequirementsChart("frontend", "common")
+	other := writeChart("other")
+	broken := filepath.Join(tmp, "broken")
+	os.MkdirAll(broken, 0755)
+
+	ordered, err := orderByDependencies([]string{umbrella, other, frontend, broken, backend, common})
+	if err != nil {
+		t.Fatal("unexpected error ordering charts", err)
+	}
+	var names []string
+	for _, path := range ordered {
+		names = append(names, filepath.Base(path))
+	}
+	if strings.Join(names, ",") != "common,backend,frontend,umbrella,other,broken" {
+		t.Errorf("unexpected order %v", names)
+	}
+
+	// no dependencies between the charts keeps their order
+	ordered, err = orderByDependencies([]string{other, common, broken})
+	if err != nil || strings.Join(ordered, ",") != strings.Join([]string{other, common, broken}, ",") {
+		t.Errorf("expected charts to keep their order, instead got %v (%v)", ordered, err)
+	}
+
+	writeChart("common", "umbrella")
+	if _, err := orderByDependencies([]string{umbrella, other, backend, common}); err == nil || err.Error() != "dependency cycle between charts: umbrella -> backend -> common -> umbrella" {
+		t.Errorf("expected dependency cycle error, instead got %v", err)
+	}
+}
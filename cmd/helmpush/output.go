@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	outputText  = "text"
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+type (
+	// pushResult describes a successful push, for -o json
+	pushResult struct {
+		Chart       string            `json:"chart"`
+		Version     string            `json:"version"`
+		AppVersion  string            `json:"appVersion,omitempty"`
+		Type        string            `json:"type"`
+		Annotations map[string]string `json:"annotations,omitempty"`
+		Repo        string            `json:"repo"`
+		Package     string            `json:"package"`
+		Provenance  string            `json:"provenance,omitempty"`
+		Signature   string            `json:"signature,omitempty"`
+		URL         string            `json:"url,omitempty"`
+		Digest      string            `json:"digest,omitempty"`
+		Created     string            `json:"created,omitempty"`
+		SHA256      string            `json:"sha256,omitempty"`
+		InstallHint string            `json:"installHint,omitempty"`
+		Skipped     bool              `json:"skipped,omitempty"`
+		Error       string            `json:"error,omitempty"`
+		// IdempotencyKey is sent with the upload and its retries
+		IdempotencyKey string `json:"idempotencyKey,omitempty"`
+		// SignatureError is set if the package was pushed but its
+		// signature wasn't
+		SignatureError string `json:"signatureError,omitempty"`
+	}
+)
+
+// validateOutputFormat checks that the requested output format is one of allowed
+func validateOutputFormat(format string, allowed ...string) error {
+	for _, a := range allowed {
+		if format == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid output format %q (must be one of: %s)", format, strings.Join(allowed, ", "))
+}
+
+// printJSON writes v to out as indented JSON
+func printJSON(out io.Writer, v interface{}) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
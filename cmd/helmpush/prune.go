@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+	"github.com/spf13/cobra"
+)
+
+type (
+	pruneCmd struct {
+		*pushCmd
+		keep            int
+		keepPrereleases bool
+		dryRun          bool
+		yes             bool
+		in              io.Reader
+	}
+)
+
+func newPruneCmd(p *pushCmd) *cobra.Command {
+	r := &pruneCmd{pushCmd: p}
+	cmd := &cobra.Command{
+		Use:   "prune CHART REPO --keep N",
+		Short: "Delete all but the newest versions of a chart from ChartMuseum",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return errors.New("This command needs 2 arguments: name of chart, name of chart repository (or repo URL)")
+			}
+			return nil
+		},
+		ValidArgsFunction: completeRepoNames(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if r.keep < 1 {
+				return errors.New("--keep must be at least 1")
+			}
+			r.in = cmd.InOrStdin()
+			r.chartName = args[0]
+			r.repoName = args[1]
+			if err := r.setup(cmd); err != nil {
+				return err
+			}
+			return r.prune()
+		},
+	}
+	f := cmd.Flags()
+	f.IntVar(&r.keep, "keep", 0, "Number of versions to keep")
+	f.BoolVar(&r.keepPrereleases, "keep-prereleases", true, "Count pre-release versions towards --keep (if false, all pre-releases are deleted)")
+	f.BoolVar(&r.dryRun, "dry-run", false, "Print the versions which would be deleted, without deleting them")
+	f.BoolVarP(&r.yes, "yes", "y", false, "Do not prompt for confirmation")
+	cmd.MarkFlagRequired("keep")
+	return cmd
+}
+
+func (r *pruneCmd) prune() error {
+	repo, err := r.getRepo()
+	if err != nil {
+		return err
+	}
+
+	client, err := r.newClientFromRepo(repo)
+	if err != nil {
+		return err
+	}
+
+	versions, found, err := fetchChartVersions(r.ctx, client, r.chartName)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("chart not found: no chart named %q in %s", r.chartName, r.repoName)
+	}
+
+	prune := r.versionsToPrune(versions)
+	if len(prune) == 0 {
+		r.log.info(r.chartName, r.repoName, "Nothing to prune, %s has %d versions in %s.", r.chartName, len(versions), r.repoName)
+		return nil
+	}
+
+	if r.dryRun {
+		for _, cv := range prune {
+			r.log.info(r.chartName, r.repoName, "Would delete %s-%s", cv.Name, cv.Version)
+		}
+		return nil
+	}
+
+	if !r.yes {
+		if !isTerminal(r.in) {
+			return errors.New("refusing to prune without confirmation (use --yes to prune non-interactively)")
+		}
+		prompt := fmt.Sprintf("Delete %d of %d versions of %s from %s?", len(prune), len(versions), r.chartName, r.repoName)
+		ok, err := confirm(r.in, r.out, prompt)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("aborted")
+		}
+	}
+
+	for _, cv := range prune {
+		// A version which is already gone is fine
+		err := client.DeleteChartWithContext(r.ctx, cv.Name, cv.Version)
+		if err != nil && !errors.Is(err, cm.ErrNotFound) {
+			return fmt.Errorf("failed to delete %s-%s: %s", cv.Name, cv.Version, err)
+		}
+		r.log.info(r.chartName, r.repoName, "Deleted %s-%s", cv.Name, cv.Version)
+	}
+	r.log.info(r.chartName, r.repoName, "Pruned %d versions of %s from %s.", len(prune), r.chartName, r.repoName)
+	return nil
+}
+
+// versionsToPrune returns the versions beyond the newest --keep, sorted
+// newest first. Unless --keep-prereleases is set, pre-releases do not
+// count towards --keep and are always pruned
+func (r *pruneCmd) versionsToPrune(versions []*cm.ChartVersion) []*cm.ChartVersion {
+	cm.SortVersions(versions)
+	var prune []*cm.ChartVersion
+	kept := 0
+	for _, cv := range versions {
+		if !r.keepPrereleases && isPrerelease(cv.Version) {
+			prune = append(prune, cv)
+			continue
+		}
+		if kept < r.keep {
+			kept++
+			continue
+		}
+		prune = append(prune, cv)
+	}
+	return prune
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestPruneCmd(t *testing.T) {
+	var deleted []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/x/y/z/api/charts/mychart":
+			w.WriteHeader(200)
+			w.Write([]byte(`[
+				{"name": "mychart", "version": "0.2.0"},
+				{"name": "mychart", "version": "0.10.0"},
+				{"name": "mychart", "version": "0.3.0-rc.1"},
+				{"name": "mychart", "version": "0.1.0"},
+				{"name": "mychart", "version": "0.3.0"}
+			]`))
+		case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/x/y/z/api/charts/mychart/"):
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/x/y/z/api/charts/mychart/"))
+			w.WriteHeader(200)
+			w.Write([]byte("{\"deleted\": true}"))
+		default:
+			w.WriteHeader(404)
+			w.Write([]byte("{\"error\": \"not found\"}"))
+		}
+	}))
+	defer ts.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+
+	prune := func(args ...string) (string, error) {
+		out := &bytes.Buffer{}
+		args = append([]string{"prune", "mychart", "helm-push-test"}, args...)
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		cmd.SetOut(out)
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetIn(&bytes.Buffer{})
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	// --keep is required
+	if _, err := prune(); err == nil {
+		t.Error("expected error without --keep, instead got nil")
+	}
+
+	// Refuses to run without --yes when stdin is not a terminal
+	if _, err := prune("--keep", "2"); err == nil || len(deleted) != 0 {
+		t.Errorf("expected error without confirmation, instead got %v", err)
+	}
+
+	// Dry run
+	out, err := prune("--keep", "2", "--dry-run")
+	if err != nil {
+		t.Fatal("unexpected error with --dry-run", err)
+	}
+	if len(deleted) != 0 {
+		t.Errorf("expected nothing to be deleted with --dry-run, instead deleted %v", deleted)
+	}
+	for _, version := range []string{"0.3.0-rc.1", "0.2.0", "0.1.0"} {
+		if !strings.Contains(out, "Would delete mychart-"+version) {
+			t.Errorf("expected dry run to include %s, instead got %s", version, out)
+		}
+	}
+
+	// Keeps the newest 2 by semver
+	if _, err := prune("--keep", "2", "--yes"); err != nil {
+		t.Fatal("unexpected error pruning", err)
+	}
+	sort.Strings(deleted)
+	if strings.Join(deleted, ",") != "0.1.0,0.2.0,0.3.0-rc.1" {
+		t.Errorf("unexpected versions deleted: %v", deleted)
+	}
+
+	// Pre-releases don't count towards --keep
+	deleted = nil
+	if _, err := prune("--keep", "3", "--keep-prereleases=false", "--yes"); err != nil {
+		t.Fatal("unexpected error pruning", err)
+	}
+	sort.Strings(deleted)
+	if strings.Join(deleted, ",") != "0.1.0,0.3.0-rc.1" {
+		t.Errorf("unexpected versions deleted with --keep-prereleases=false: %v", deleted)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+	"github.com/chartmuseum/helm-push/pkg/helm"
+)
+
+// describePushed records in result where the pushed chart can be
+// downloaded from and, if the server has the chart API, its digest and
+// creation time from the index entry, and prints them
+func (p *pushCmd) describePushed(client *cm.Client, chart *helm.Chart, result *pushResult) {
+	result.URL = chartURL(client.URL(), fmt.Sprintf("charts/%s-%s.tgz", chart.Name(), chart.Version()))
+	if info, err := client.GetChartInfoWithContext(p.ctx, chart.Name(), chart.Version()); err == nil {
+		if len(info.URLs) > 0 {
+			result.URL = chartURL(client.URL(), info.URLs[0])
+		}
+		result.Digest = info.Digest
+		if !info.Created.IsZero() {
+			result.Created = info.Created.UTC().Format(time.RFC3339)
+		}
+	}
+
+	p.log.info(chart.Name(), p.repoName, "URL:     %s", result.URL)
+	p.log.info(chart.Name(), p.repoName, "Version: %s", chart.Version())
+	if result.Digest != "" {
+		p.log.info(chart.Name(), p.repoName, "Digest:  %s", result.Digest)
+	}
+	if result.Created != "" {
+		p.log.info(chart.Name(), p.repoName, "Created: %s", result.Created)
+	}
+	if result.SHA256 != "" {
+		p.log.info(chart.Name(), p.repoName, "SHA256:  %s", result.SHA256)
+	}
+	p.printInstallHint(chart, result)
+}
+
+// printInstallHint prints the helm commands to install the pushed chart
+// with --print-install-hint, and records them in result
+func (p *pushCmd) printInstallHint(chart *helm.Chart, result *pushResult) {
+	if !p.installHint {
+		return
+	}
+	switch {
+	case p.oci:
+		ref := strings.TrimSuffix(result.URL, ":"+strings.Replace(chart.Version(), "+", "_", -1))
+		result.InstallHint = fmt.Sprintf("helm install %s %s --version %s", chart.Name(), ref, chart.Version())
+	case !repoEnvNameRegexp.MatchString(p.repoName):
+		// repos given by URL are not in the local repository list
+		result.InstallHint = fmt.Sprintf("helm install %s %s", chart.Name(), result.URL)
+	default:
+		result.InstallHint = fmt.Sprintf("helm repo update && helm install %s %s/%s --version %s", chart.Name(), p.repoName, chart.Name(), chart.Version())
+	}
+	p.log.info(chart.Name(), p.repoName, "Install with: %s", result.InstallHint)
+}
+
+// chartURL resolves the URL of a chart package in the index, usually
+// relative to the repo, without any credentials in the repo URL
+func chartURL(repoURL string, packageURL string) string {
+	base, err := url.Parse(strings.TrimSuffix(repoURL, "/") + "/")
+	if err != nil {
+		return packageURL
+	}
+	ref, err := url.Parse(packageURL)
+	if err != nil {
+		return packageURL
+	}
+	u := base.ResolveReference(ref)
+	u.User = nil
+	return u.String()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"sync"
+
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+	"github.com/spf13/cobra"
+)
+
+type (
+	// secretSet collects the credentials of a run, from flags, the
+	// environment, config files and helm repo configs, to be redacted
+	// from its output and errors. It is shared by the commands of all
+	// the repos of the run
+	secretSet struct {
+		mu     sync.Mutex
+		values []string
+	}
+
+	// redactedError is an error with its credentials redacted, which
+	// still unwraps to the original error
+	redactedError struct {
+		msg string
+		err error
+	}
+)
+
+// add records secret values, ignoring empty and duplicate ones
+func (s *secretSet) add(values ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, v := range values {
+		if v == "" || s.has(v) {
+			continue
+		}
+		s.values = append(s.values, v)
+	}
+}
+
+func (s *secretSet) has(v string) bool {
+	for _, value := range s.values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// list returns the recorded secret values
+func (s *secretSet) list() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.values...)
+}
+
+// addSecrets records the credentials set on the command, along with any
+// extra ones, such as the password of a helm repo
+func (p *pushCmd) addSecrets(extra ...string) {
+	if p.secrets == nil {
+		p.secrets = &secretSet{}
+	}
+	for _, s := range connectionSettings {
+		if s.secret {
+			p.secrets.add(s.value(p))
+		}
+	}
+	p.secrets.add(p.awsSecretAccessKey, p.awsSessionToken, p.vaultToken)
+	p.secrets.add(extra...)
+}
+
+// redact replaces the credentials in msg with ***: the secrets of the
+// run, credential headers, and the userinfo and token query parameters
+// of URLs
+func (p *pushCmd) redact(msg string) string {
+	p.addSecrets()
+	return cm.Redact(msg, p.secrets.list()...)
+}
+
+// redactErrors wraps a command's RunE so that the error it returns, which
+// cobra prints, has its credentials redacted
+func (p *pushCmd) redactErrors(runE func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		err := runE(cmd, args)
+		if err == nil {
+			return nil
+		}
+		if msg := p.redact(err.Error()); msg != err.Error() {
+			return &redactedError{msg: msg, err: err}
+		}
+		return err
+	}
+}
+
+// redactCommandErrors redacts the errors of cmd and all its subcommands
+func (p *pushCmd) redactCommandErrors(cmd *cobra.Command) {
+	if cmd.RunE != nil {
+		cmd.RunE = p.redactErrors(cmd.RunE)
+	}
+	for _, sub := range cmd.Commands() {
+		p.redactCommandErrors(sub)
+	}
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+	"github.com/spf13/cobra"
+)
+
+type (
+	reindexCmd struct {
+		*pushCmd
+		path string
+	}
+)
+
+func newReindexCmd(p *pushCmd) *cobra.Command {
+	r := &reindexCmd{pushCmd: p}
+	cmd := &cobra.Command{
+		Use:   "reindex REPO",
+		Short: "Regenerate the index of a ChartMuseum repository",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("This command needs 1 argument: name of chart repository (or repo URL)")
+			}
+			return nil
+		},
+		ValidArgsFunction: completeRepoNames(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r.repoName = args[0]
+			if err := r.setup(cmd); err != nil {
+				return err
+			}
+			return r.reindex()
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&r.path, "path", "", "Path of the reindex endpoint relative to the context path, if not /api/charts/regenerate-index")
+	return cmd
+}
+
+func (r *reindexCmd) reindex() error {
+	repo, err := r.getRepo()
+	if err != nil {
+		return err
+	}
+
+	client, err := r.newClientFromRepo(repo)
+	if err != nil {
+		return err
+	}
+	client.Option(cm.ReindexPath(r.path))
+
+	if err := client.ReindexRepoWithContext(r.ctx); err != nil {
+		return fmt.Errorf("failed to regenerate index of %s: %s", r.repoName, err)
+	}
+	r.log.info("", r.repoName, "Regenerated index of %s.", r.repoName)
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReindexCmd(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" && r.URL.Path == "/x/y/z/api/charts/regenerate-index" {
+			w.WriteHeader(200)
+			w.Write([]byte("{\"ok\": true}"))
+		} else {
+			w.WriteHeader(404)
+			w.Write([]byte("{\"error\": \"not found\"}"))
+		}
+	}))
+	defer ts.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+
+	// Happy path
+	out := &bytes.Buffer{}
+	args := []string{"reindex", "helm-push-test"}
+	cmd := newPushCmd(args)
+	cmd.SetArgs(args)
+	cmd.SetOut(out)
+	if err := cmd.Execute(); err != nil {
+		t.Fatal("unexpected error reindexing repo", err)
+	}
+	if !strings.Contains(out.String(), "Regenerated index of helm-push-test") {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+
+	// Endpoint not available
+	args = []string{"reindex", "--path", "/api/reindex", "helm-push-test"}
+	cmd = newPushCmd(args)
+	cmd.SetArgs(args)
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "failed to regenerate index of helm-push-test: not found") {
+		t.Errorf("expecting reindex error, instead got %v", err)
+	}
+}
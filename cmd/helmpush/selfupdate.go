@@ -0,0 +1,336 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/chartmuseum/helm-push/internal/version"
+	"github.com/spf13/cobra"
+)
+
+// githubReleasesURL is the GitHub API endpoint of the plugin's releases,
+// $HELM_PUSH_RELEASES_URL overrides it, e.g. for a GitHub Enterprise mirror
+const githubReleasesURL = "https://api.github.com/repos/chartmuseum/helm-push/releases"
+
+type (
+	selfUpdateCmd struct {
+		version string
+		check   bool
+		out     io.Writer
+		ctx     context.Context
+	}
+
+	// githubRelease is the part of a GitHub release used by self-update
+	githubRelease struct {
+		TagName string        `json:"tag_name"`
+		Assets  []githubAsset `json:"assets"`
+	}
+
+	githubAsset struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	}
+)
+
+func newSelfUpdateCmd() *cobra.Command {
+	u := &selfUpdateCmd{}
+	cmd := &cobra.Command{
+		Use:   "self-update",
+		Short: "Update the plugin to the latest (or a given) release",
+		Long: `Update the plugin to the latest (or a given) release
+
+The release for this platform is downloaded from the plugin's GitHub
+releases, verified against the checksums file published with it, and
+replaces the plugin in $HELM_PLUGIN_DIR, without needing git.
+
+With --check, only reports whether a newer release exists, and exits
+non-zero if it does:
+
+  $ helm push self-update --check || helm push self-update
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			u.out = cmd.OutOrStdout()
+			u.ctx = cmd.Context()
+			if u.ctx == nil {
+				u.ctx = context.Background()
+			}
+			if u.check {
+				err := u.runCheck()
+				if errors.Is(err, errNewerRelease) {
+					cmd.SilenceUsage = true
+				}
+				return err
+			}
+			return u.update()
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&u.version, "version", "", "Release to update to (e.g. v0.10.0), instead of the latest")
+	f.BoolVar(&u.check, "check", false, "Only check whether a newer release exists, exiting non-zero if it does")
+	return cmd
+}
+
+// errNewerRelease is returned by --check when a newer release exists
+var errNewerRelease = errors.New("a newer release is available")
+
+// runCheck reports whether the latest release is newer than the running
+// plugin
+func (u *selfUpdateCmd) runCheck() error {
+	release, err := u.release()
+	if err != nil {
+		return err
+	}
+	latest := strings.TrimPrefix(release.TagName, "v")
+	if !isNewerRelease(latest, version.Version) {
+		fmt.Fprintf(u.out, "helm-push %s is up to date.\n", version.Version)
+		return nil
+	}
+	fmt.Fprintf(u.out, "helm-push %s is available (running %s), update with: helm push self-update\n", latest, version.Version)
+	return errNewerRelease
+}
+
+// update replaces the plugin with the release for this platform
+func (u *selfUpdateCmd) update() error {
+	pluginDir := os.Getenv("HELM_PLUGIN_DIR")
+	if pluginDir == "" {
+		return errors.New("$HELM_PLUGIN_DIR is not set, run self-update as a plugin command: helm push self-update")
+	}
+	release, err := u.release()
+	if err != nil {
+		return err
+	}
+	target := strings.TrimPrefix(release.TagName, "v")
+	if u.version == "" && !isNewerRelease(target, version.Version) {
+		fmt.Fprintf(u.out, "helm-push %s is up to date.\n", version.Version)
+		return nil
+	}
+
+	assetName := fmt.Sprintf("helm-push_%s_%s_%s.tar.gz", target, runtime.GOOS, runtime.GOARCH)
+	checksumsName := fmt.Sprintf("helm-push_%s_checksums.txt", target)
+	asset, checksums := findAsset(release, assetName), findAsset(release, checksumsName)
+	if asset == nil {
+		return fmt.Errorf("release %s has no package for %s/%s (%s)", release.TagName, runtime.GOOS, runtime.GOARCH, assetName)
+	}
+	if checksums == nil {
+		return fmt.Errorf("release %s has no checksums file (%s), refusing to install an unverified package", release.TagName, checksumsName)
+	}
+
+	fmt.Fprintf(u.out, "Updating helm-push from %s to %s...\n", version.Version, target)
+	sums, err := u.download(checksums.URL)
+	if err != nil {
+		return err
+	}
+	expected, err := findChecksum(sums, assetName)
+	if err != nil {
+		return err
+	}
+	archive, err := u.download(asset.URL)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(archive)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", assetName, expected, actual)
+	}
+
+	files, err := extractPlugin(archive)
+	if err != nil {
+		return fmt.Errorf("invalid package %s: %s", assetName, err)
+	}
+	// the binary goes last: a plugin.yaml which failed to update only
+	// shows the old version, while a new binary is the actual update
+	if err := replaceFile(filepath.Join(pluginDir, "plugin.yaml"), files["plugin.yaml"], 0644); err != nil {
+		return err
+	}
+	if err := replaceFile(filepath.Join(pluginDir, "bin", "helmpush"), files["bin/helmpush"], 0755); err != nil {
+		return err
+	}
+	fmt.Fprintf(u.out, "Updated helm-push to %s.\n", target)
+	return nil
+}
+
+// release returns the release given with --version, or the latest one
+func (u *selfUpdateCmd) release() (*githubRelease, error) {
+	base := githubReleasesURL
+	if v := os.Getenv("HELM_PUSH_RELEASES_URL"); v != "" {
+		base = strings.TrimSuffix(v, "/")
+	}
+	url := base + "/latest"
+	if u.version != "" {
+		if _, err := semver.StrictNewVersion(strings.TrimPrefix(u.version, "v")); err != nil {
+			return nil, fmt.Errorf("invalid --version %q: %s", u.version, err)
+		}
+		url = base + "/tags/v" + strings.TrimPrefix(u.version, "v")
+	}
+	resp, err := u.get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && u.version != "" {
+		return nil, fmt.Errorf("no release v%s found", strings.TrimPrefix(u.version, "v"))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get release from %s: %s", url, resp.Status)
+	}
+	var release githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil || release.TagName == "" {
+		return nil, fmt.Errorf("invalid release from %s", url)
+	}
+	return &release, nil
+}
+
+// get sends a GET request to url. $GITHUB_TOKEN is sent if set, to
+// avoid the GitHub API rate limits of anonymous requests
+func (u *selfUpdateCmd) get(url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(u.ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", version.UserAgent())
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+	return http.DefaultClient.Do(req)
+}
+
+// download returns the body of url
+func (u *selfUpdateCmd) download(url string) ([]byte, error) {
+	resp, err := u.get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// isNewerRelease reports whether release is newer than current. Builds
+// which aren't a release version, like "dev", are older than any release
+func isNewerRelease(release string, current string) bool {
+	r, err := semver.NewVersion(release)
+	if err != nil {
+		return false
+	}
+	c, err := semver.NewVersion(current)
+	if err != nil {
+		return true
+	}
+	return r.GreaterThan(c)
+}
+
+func findAsset(release *githubRelease, name string) *githubAsset {
+	for i, a := range release.Assets {
+		if a.Name == name {
+			return &release.Assets[i]
+		}
+	}
+	return nil
+}
+
+// findChecksum returns the SHA-256 of name in a checksums file, as
+// written by sha256sum
+func findChecksum(sums []byte, name string) (string, error) {
+	s := bufio.NewScanner(bytes.NewReader(sums))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s in the checksums file", name)
+}
+
+// extractPlugin returns the plugin.yaml and the binary (as bin/helmpush,
+// whatever its name on this platform) of a release package
+func extractPlugin(archive []byte) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var name string
+		switch path.Clean(strings.TrimPrefix(h.Name, "./")) {
+		case "plugin.yaml":
+			name = "plugin.yaml"
+		case "bin/helmpush", "bin/helmpush.exe":
+			name = "bin/helmpush"
+		default:
+			continue
+		}
+		if files[name], err = io.ReadAll(tr); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range []string{"plugin.yaml", "bin/helmpush"} {
+		if len(files[name]) == 0 {
+			return nil, fmt.Errorf("no %s in the package", name)
+		}
+	}
+	return files, nil
+}
+
+// replaceFile atomically replaces path with data: it is written to a
+// temporary file in the same directory which is then renamed over path.
+// On Windows, where a running executable can't be replaced, the old
+// file is moved aside first
+func replaceFile(path string, data []byte, mode os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), mode)
+	}
+	if err == nil && runtime.GOOS == "windows" {
+		old := path + ".old"
+		os.Remove(old)
+		if err = os.Rename(path, old); os.IsNotExist(err) {
+			err = nil
+		}
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to replace %s: %s", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/chartmuseum/helm-push/internal/version"
+)
+
+func testReleasePackage(t *testing.T, v string) []byte {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	for name, data := range map[string]string{
+		"LICENSE":        "license",
+		"plugin.yaml":    "name: push\nversion: \"" + v + "\"\n",
+		"./bin/helmpush": "helmpush " + v,
+	} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(data))})
+		tw.Write([]byte(data))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal("unexpected error writing test package", err)
+	}
+	gz.Close()
+	return b.Bytes()
+}
+
+func TestSelfUpdateCmd(t *testing.T) {
+	packages := map[string][]byte{
+		"0.10.0": testReleasePackage(t, "0.10.0"),
+		"0.8.0":  testReleasePackage(t, "0.8.0"),
+	}
+	badChecksum := false
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		release := func(v string) {
+			fmt.Fprintf(w, `{"tag_name":"v%s","assets":[{"name":"helm-push_%s_%s_%s.tar.gz","browser_download_url":"%s/download/%s.tar.gz"},{"name":"helm-push_%s_checksums.txt","browser_download_url":"%s/download/%s.txt"}]}`,
+				v, v, runtime.GOOS, runtime.GOARCH, ts.URL, v, v, ts.URL, v)
+		}
+		switch r.URL.Path {
+		case "/releases/latest":
+			release("0.10.0")
+		case "/releases/tags/v0.8.0":
+			release("0.8.0")
+		case "/download/0.10.0.tar.gz", "/download/0.8.0.tar.gz":
+			w.Write(packages[strings.TrimSuffix(filepath.Base(r.URL.Path), ".tar.gz")])
+		case "/download/0.10.0.txt", "/download/0.8.0.txt":
+			v := strings.TrimSuffix(filepath.Base(r.URL.Path), ".txt")
+			sum := sha256.Sum256(packages[v])
+			if badChecksum {
+				sum = sha256.Sum256([]byte("tampered"))
+			}
+			fmt.Fprintf(w, "0000  helm-push_%s_other_arch.tar.gz\n%s  helm-push_%s_%s_%s.tar.gz\n", v, hex.EncodeToString(sum[:]), v, runtime.GOOS, runtime.GOARCH)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+	os.MkdirAll(filepath.Join(tmp, "bin"), 0755)
+	os.WriteFile(filepath.Join(tmp, "bin", "helmpush"), []byte("helmpush 0.9.0"), 0755)
+	os.WriteFile(filepath.Join(tmp, "plugin.yaml"), []byte("name: push\nversion: \"0.9.0\"\n"), 0644)
+
+	os.Setenv("HELM_PUSH_RELEASES_URL", ts.URL+"/releases")
+	defer os.Unsetenv("HELM_PUSH_RELEASES_URL")
+	os.Setenv("HELM_PLUGIN_DIR", tmp)
+	defer os.Unsetenv("HELM_PLUGIN_DIR")
+	defer func(v string) { version.Version = v }(version.Version)
+	version.Version = "0.9.0"
+
+	selfUpdate := func(args ...string) (string, error) {
+		args = append([]string{"self-update"}, args...)
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&bytes.Buffer{})
+		err := cmd.Execute()
+		return out.String(), err
+	}
+	installed := func() string {
+		b, _ := os.ReadFile(filepath.Join(tmp, "bin", "helmpush"))
+		return string(b)
+	}
+
+	out, err := selfUpdate("--check")
+	if err != errNewerRelease || out != "helm-push 0.10.0 is available (running 0.9.0), update with: helm push self-update\n" {
+		t.Errorf("expected newer release with --check, instead got %v:\n%s", err, out)
+	}
+
+	// a package which doesn't match its checksum isn't installed
+	badChecksum = true
+	if _, err := selfUpdate(); err == nil || !strings.Contains(err.Error(), "checksum mismatch for helm-push_0.10.0_") || installed() != "helmpush 0.9.0" {
+		t.Errorf("expected checksum error and the plugin left as is, instead got %v (%s)", err, installed())
+	}
+	badChecksum = false
+
+	if out, err := selfUpdate(); err != nil || !strings.Contains(out, "Updated helm-push to 0.10.0.") {
+		t.Fatalf("unexpected error updating: %v\n%s", err, out)
+	}
+	b, _ := os.ReadFile(filepath.Join(tmp, "plugin.yaml"))
+	fi, _ := os.Stat(filepath.Join(tmp, "bin", "helmpush"))
+	if installed() != "helmpush 0.10.0" || !strings.Contains(string(b), `version: "0.10.0"`) || fi.Mode().Perm() != 0755 {
+		t.Errorf("expected plugin 0.10.0 to be installed, instead got %s %s %v", installed(), b, fi.Mode())
+	}
+	entries, _ := os.ReadDir(filepath.Join(tmp, "bin"))
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files left, instead got %v", entries)
+	}
+
+	version.Version = "0.10.0"
+	if out, err := selfUpdate("--check"); err != nil || out != "helm-push 0.10.0 is up to date.\n" {
+		t.Errorf("expected up to date with --check, instead got %v:\n%s", err, out)
+	}
+	if out, err := selfUpdate(); err != nil || out != "helm-push 0.10.0 is up to date.\n" || installed() != "helmpush 0.10.0" {
+		t.Errorf("expected no update when up to date, instead got %v:\n%s", err, out)
+	}
+
+	// --version pins a release, even an older one
+	if _, err := selfUpdate("--version", "v0.8.0"); err != nil || installed() != "helmpush 0.8.0" {
+		t.Errorf("expected plugin 0.8.0 to be installed with --version, instead got %v (%s)", err, installed())
+	}
+	if _, err := selfUpdate("--version", "0.7.0"); err == nil || err.Error() != "no release v0.7.0 found" {
+		t.Errorf("expected error for a missing release, instead got %v", err)
+	}
+	if _, err := selfUpdate("--version", "latest"); err == nil || !strings.Contains(err.Error(), `invalid --version "latest"`) {
+		t.Errorf("expected error for an invalid version, instead got %v", err)
+	}
+
+	os.Unsetenv("HELM_PLUGIN_DIR")
+	if _, err := selfUpdate(); err == nil || !strings.Contains(err.Error(), "$HELM_PLUGIN_DIR is not set") {
+		t.Errorf("expected error without $HELM_PLUGIN_DIR, instead got %v", err)
+	}
+}
+
+func TestIsNewerRelease(t *testing.T) {
+	for _, tc := range []struct {
+		release, current string
+		expected         bool
+	}{
+		{"0.10.0", "0.9.0", true},
+		{"0.9.0", "0.9.0", false},
+		{"0.9.0", "0.10.0", false},
+		{"0.9.0", "dev", true},
+		{"not-a-version", "0.9.0", false},
+	} {
+		if actual := isNewerRelease(tc.release, tc.current); actual != tc.expected {
+			t.Errorf("%s over %s: expected %v, instead got %v", tc.release, tc.current, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+	"github.com/chartmuseum/helm-push/pkg/helm"
+)
+
+// validateSignatureFlags checks the combination of the --signature,
+// --sign-cmd, --signature-path and --signature-only flags
+func (p *pushCmd) validateSignatureFlags() error {
+	switch {
+	case p.signature != "" && p.signCmd != "":
+		return errors.New("--signature and --sign-cmd cannot be used together")
+	case p.signature == "" && p.signCmd == "":
+		if p.signaturePath != "" {
+			return errors.New("--signature-path only applies to --signature and --sign-cmd")
+		}
+		if p.signatureOnly {
+			return errors.New("--signature-only needs --signature")
+		}
+		return nil
+	case p.signatureOnly && p.signature == "":
+		return errors.New("--signature-only needs --signature, a signature made with --sign-cmd is only valid for the package it signed")
+	case p.signature != "" && (p.recursive || p.changedSince != ""):
+		return errors.New("--signature cannot be used with --recursive or --changed-since, it signs a single chart package")
+	case p.signature != "" && p.withSubcharts:
+		return errors.New("--signature cannot be used with --with-subcharts, it signs a single chart package")
+	}
+	return nil
+}
+
+// checkSignature checks that the chart is a chart package with
+// --signature: the signature is only valid for the package as it is, so
+// it is pushed unchanged
+func (p *pushCmd) checkSignature() error {
+	if p.signature == "" {
+		return nil
+	}
+	if flag := p.chartOverrideFlag(); flag != "" {
+		return fmt.Errorf("--%s can't be used with --signature, signed chart packages are pushed unchanged", flag)
+	}
+	if fi, err := os.Stat(p.chartName); err != nil || fi.IsDir() || helm.IsChartURL(p.chartName) {
+		return fmt.Errorf("--signature needs a chart package (.tgz), %s is not one", p.chartName)
+	}
+	if _, err := os.Stat(p.signature); err != nil {
+		return fmt.Errorf("could not read signature file: %s", err)
+	}
+	return nil
+}
+
+// signPackage runs --sign-cmd with the path of the chart package, and
+// writes the signature it prints to a .sig file in dir
+func (p *pushCmd) signPackage(chartPackagePath string, dir string) (string, error) {
+	args := append(strings.Fields(p.signCmd), chartPackagePath)
+	cmd := exec.CommandContext(p.ctx, args[0], args[1:]...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("--sign-cmd failed to sign %s: %s: %s", filepath.Base(chartPackagePath), err, strings.TrimSpace(stderr.String()))
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return "", fmt.Errorf("--sign-cmd printed no signature for %s", filepath.Base(chartPackagePath))
+	}
+	sigPath := filepath.Join(dir, filepath.Base(chartPackagePath)+".sig")
+	if err := os.WriteFile(sigPath, out, 0644); err != nil {
+		return "", err
+	}
+	return sigPath, nil
+}
+
+// signatureName is the name a chart signature is served back under
+func signatureName(chart *helm.Chart) string {
+	return fmt.Sprintf("%s-%s.tgz.sig", chart.Name(), chart.Version())
+}
+
+// pushSignature uploads the detached signature of the chart package to
+// the command's repo, connecting to it first if client is nil
+func (p *pushCmd) pushSignature(client *cm.Client, chart *helm.Chart, sigPath string) error {
+	if client == nil {
+		var err error
+		if client, err = p.connect(); err != nil {
+			return err
+		}
+	}
+	if p.signaturePath != "" {
+		client.Option(cm.SignaturePath(p.signaturePath))
+	}
+
+	name := signatureName(chart)
+	p.log.info(chart.Name(), p.repoName, "Pushing %s to %s...", name, p.repoName)
+	resp, err := client.UploadSignatureFileWithContext(p.ctx, sigPath, name, p.forceUpload)
+	if err != nil {
+		return err
+	}
+	return handleFileUploadResponse(resp, "signature")
+}
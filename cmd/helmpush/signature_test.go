@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPushCmdSignature(t *testing.T) {
+	var uploads []string
+	signatures := map[string]string{}
+	failSignatures := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimPrefix(r.URL.Path, "/x/y/z") {
+		case "/api/charts":
+			uploads = append(uploads, "chart")
+			w.WriteHeader(201)
+			w.Write([]byte("{}"))
+		case "/api/prov", "/api/signatures":
+			if failSignatures {
+				w.WriteHeader(500)
+				w.Write([]byte(`{"error":"storage unavailable"}`))
+				return
+			}
+			f, fh, err := r.FormFile("prov")
+			if err != nil {
+				w.WriteHeader(400)
+				return
+			}
+			b, _ := io.ReadAll(f)
+			uploads = append(uploads, strings.TrimPrefix(r.URL.Path, "/x/y/z"))
+			signatures[fh.Filename] = string(b)
+			w.WriteHeader(201)
+			w.Write([]byte("{}"))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+
+	cleanupRepo := setupTestRepo(t, ts.URL)
+	defer cleanupRepo()
+
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+	sigPath := filepath.Join(tmp, "mychart.sig")
+	os.WriteFile(sigPath, []byte("MEUCIQ=="), 0644)
+	signScript := filepath.Join(tmp, "sign.sh")
+	os.WriteFile(signScript, []byte("#!/bin/sh\necho \"$(basename \"$2\") signed with $1\"\n"), 0755)
+
+	push := func(args ...string) (pushResult, error) {
+		uploads = nil
+		args = append([]string{testTarballPath, "helm-push-test", "-o", "json"}, args...)
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&bytes.Buffer{})
+		err := cmd.Execute()
+		var result pushResult
+		if bytes.HasPrefix(out.Bytes(), []byte("{")) {
+			// usage follows the JSON output on errors
+			if err := json.NewDecoder(&out).Decode(&result); err != nil {
+				t.Fatalf("unexpected error parsing JSON output %q: %s", out.String(), err)
+			}
+		}
+		return result, err
+	}
+
+	result, err := push("--signature", sigPath)
+	if err != nil {
+		t.Fatal("unexpected error pushing with --signature", err)
+	}
+	if strings.Join(uploads, ",") != "chart,/api/prov" || signatures["mychart-0.1.0.tgz.sig"] != "MEUCIQ==" {
+		t.Errorf("expected package then signature upload, instead got %v %v", uploads, signatures)
+	}
+	if result.Signature != "mychart-0.1.0.tgz.sig" || result.SignatureError != "" {
+		t.Errorf("expected signature in result, instead got %+v", result)
+	}
+
+	if _, err := push("--signature", sigPath, "--version", "0.2.0"); err == nil || !strings.Contains(err.Error(), "--version can't be used with --signature") {
+		t.Errorf("expected error changing a signed package, instead got %v", err)
+	}
+
+	// the signature is made for the final package
+	if _, err := push("--sign-cmd", signScript+" key", "--version", "0.2.0", "--signature-path", "/api/signatures"); err != nil {
+		t.Fatal("unexpected error pushing with --sign-cmd", err)
+	}
+	if strings.Join(uploads, ",") != "chart,/api/signatures" || signatures["mychart-0.2.0.tgz.sig"] != "mychart-0.2.0.tgz signed with key\n" {
+		t.Errorf("expected package then --sign-cmd signature upload, instead got %v %v", uploads, signatures)
+	}
+	if _, err := push("--sign-cmd", "false"); err == nil || !strings.Contains(err.Error(), "--sign-cmd failed to sign mychart-0.1.0.tgz") || len(uploads) != 0 {
+		t.Errorf("expected --sign-cmd error before pushing, instead got %v (uploads %v)", err, uploads)
+	}
+
+	// a failed signature upload is reported apart from the package
+	failSignatures = true
+	result, err = push("--signature", sigPath)
+	if err == nil || !strings.Contains(err.Error(), "pushed mychart-0.1.0 but failed to push its signature to 1 of 1 repositories: helm-push-test (retry with --signature-only)") {
+		t.Errorf("expected signature error, instead got %v", err)
+	}
+	if len(uploads) != 1 || result.Error != "" || !strings.Contains(result.SignatureError, "storage unavailable") {
+		t.Errorf("expected package pushed and signature error in result, instead got %v %+v", uploads, result)
+	}
+
+	failSignatures = false
+	if _, err := push("--signature", sigPath, "--signature-only"); err != nil {
+		t.Fatal("unexpected error pushing with --signature-only", err)
+	}
+	if strings.Join(uploads, ",") != "/api/prov" {
+		t.Errorf("expected only the signature to be pushed, instead got %v", uploads)
+	}
+}
+
+func TestValidateSignatureFlags(t *testing.T) {
+	for _, tc := range []struct {
+		p        pushCmd
+		expected string
+	}{
+		{pushCmd{}, ""},
+		{pushCmd{signature: "a.sig", recursive: false}, ""},
+		{pushCmd{signCmd: "cosign sign-blob", recursive: true}, ""},
+		{pushCmd{signature: "a.sig", signCmd: "cosign sign-blob"}, "--signature and --sign-cmd cannot be used together"},
+		{pushCmd{signaturePath: "/api/signatures"}, "--signature-path only applies to --signature and --sign-cmd"},
+		{pushCmd{signatureOnly: true}, "--signature-only needs --signature"},
+		{pushCmd{signCmd: "cosign sign-blob", signatureOnly: true}, "--signature-only needs --signature, a signature made with --sign-cmd is only valid for the package it signed"},
+		{pushCmd{signature: "a.sig", changedSince: "origin/main"}, "--signature cannot be used with --recursive or --changed-since, it signs a single chart package"},
+		{pushCmd{signature: "a.sig", withSubcharts: true}, "--signature cannot be used with --with-subcharts, it signs a single chart package"},
+	} {
+		err := tc.p.validateSignatureFlags()
+		if (err == nil && tc.expected != "") || (err != nil && err.Error() != tc.expected) {
+			t.Errorf("expected error %q, instead got %v", tc.expected, err)
+		}
+	}
+}
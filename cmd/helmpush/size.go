@@ -0,0 +1,104 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// packageSizeTopFiles is how many of the largest files the package size
+// report lists
+const packageSizeTopFiles = 10
+
+type (
+	// packageFile is a file in a chart package, with its uncompressed size
+	packageFile struct {
+		name string
+		size int64
+	}
+)
+
+// formatSize formats a number of bytes with a decimal unit, e.g. 1.5 MB
+func formatSize(n int64) string {
+	switch {
+	case n >= 1000*1000*1000:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1000*1000*1000))
+	case n >= 1000*1000:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1000*1000))
+	case n >= 1000:
+		return fmt.Sprintf("%.1f kB", float64(n)/1000)
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// largestPackageFiles returns the files of the .tgz package at path,
+// largest first, at most n of them
+func largestPackageFiles(path string, n int) ([]packageFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	var files []packageFile
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			files = append(files, packageFile{name: hdr.Name, size: hdr.Size})
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].size > files[j].size })
+	if len(files) > n {
+		files = files[:n]
+	}
+	return files, nil
+}
+
+// checkPackageSize fails if the chart package is larger than --max-size,
+// reporting its size and largest files to help find what bloats it. The
+// report is also printed with --show-size
+func (p *pushCmd) checkPackageSize(chartName string, chartPackagePath string) error {
+	if p.maxSizeBytes == 0 && !p.showSize {
+		return nil
+	}
+	fi, err := os.Stat(chartPackagePath)
+	if err != nil {
+		return err
+	}
+	tooLarge := p.maxSizeBytes > 0 && fi.Size() > p.maxSizeBytes
+	if !tooLarge && !p.showSize {
+		return nil
+	}
+
+	files, err := largestPackageFiles(chartPackagePath, packageSizeTopFiles)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %s", chartPackagePath, err)
+	}
+	log := p.log.info
+	if tooLarge {
+		log = p.log.error
+	}
+	packageName := filepath.Base(chartPackagePath)
+	log(chartName, p.repoName, "%s is %s, largest files (uncompressed):", packageName, formatSize(fi.Size()))
+	for _, f := range files {
+		log(chartName, p.repoName, "  %10s  %s", formatSize(f.size), f.name)
+	}
+	if tooLarge {
+		return fmt.Errorf("%s is %s, over the maximum size of %s", packageName, formatSize(fi.Size()), p.maxSize)
+	}
+	return nil
+}
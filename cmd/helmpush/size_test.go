@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatSize(t *testing.T) {
+	for n, expected := range map[int64]string{
+		512:        "512 B",
+		1500:       "1.5 kB",
+		20000000:   "20.0 MB",
+		1234567890: "1.2 GB",
+	} {
+		if actual := formatSize(n); actual != expected {
+			t.Errorf("%d: expected %s, instead got %s", n, expected, actual)
+		}
+	}
+}
+
+func TestPushCmdMaxSize(t *testing.T) {
+	var pushed int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			pushed++
+		}
+		w.WriteHeader(201)
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+	chartDir := filepath.Join(tmp, "mychart")
+	os.MkdirAll(filepath.Join(chartDir, "vendor"), 0755)
+	os.MkdirAll(filepath.Join(chartDir, "templates"), 0755)
+	os.WriteFile(filepath.Join(chartDir, "templates", "cm.yaml"), []byte("kind: ConfigMap\n"), 0644)
+	os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("apiVersion: v2\nname: mychart\nversion: 0.1.0\n"), 0644)
+	// random data doesn't compress, so the package is about as large
+	random := make([]byte, 300*1000)
+	rand.Read(random)
+	os.WriteFile(filepath.Join(chartDir, "vendor", "big.bin"), random, 0644)
+	os.WriteFile(filepath.Join(chartDir, "values.yaml"), []byte("x: 1\n"), 0644)
+
+	push := func(args ...string) (string, error) {
+		pushed = 0
+		args = append([]string{chartDir, "helm-push-test"}, args...)
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := push("--max-size", "1KB")
+	if err == nil || !strings.Contains(err.Error(), "mychart-0.1.0.tgz is ") || !strings.Contains(err.Error(), "over the maximum size of 1KB") || pushed != 0 {
+		t.Errorf("expected error for a package over --max-size and nothing pushed, instead got %v (%d pushes)", err, pushed)
+	}
+	if !strings.Contains(out, "largest files") || !strings.Contains(out, "mychart/vendor/big.bin") || strings.Index(out, "big.bin") > strings.Index(out, "values.yaml") {
+		t.Errorf("expected the largest files, largest first, instead got:\n%s", out)
+	}
+
+	if out, err := push("--max-size", "1MB"); err != nil || pushed != 1 || strings.Contains(out, "largest files") {
+		t.Errorf("expected package under --max-size to be pushed without report, instead got %v (%d pushes):\n%s", err, pushed, out)
+	}
+	if out, err := push("--show-size"); err != nil || pushed != 1 || !strings.Contains(out, "300.0 kB  mychart/vendor/big.bin") {
+		t.Errorf("expected size report with --show-size, instead got %v (%d pushes):\n%s", err, pushed, out)
+	}
+
+	if _, err := push("--max-chart-size", "1KB"); err == nil || !strings.Contains(err.Error(), "over the maximum size of 1KB") || pushed != 0 {
+		t.Errorf("expected --max-chart-size to be the same as --max-size, instead got %v (%d pushes)", err, pushed)
+	}
+
+	os.Setenv("HELM_PUSH_MAX_SIZE", "1KB")
+	defer os.Unsetenv("HELM_PUSH_MAX_SIZE")
+	if _, err := push(); err == nil || !strings.Contains(err.Error(), "over the maximum size of 1KB") {
+		t.Errorf("expected $HELM_PUSH_MAX_SIZE to be used, instead got %v", err)
+	}
+	if _, err := push("--max-size", "lots"); err == nil || !strings.Contains(err.Error(), `invalid maximum chart size: invalid size "lots"`) {
+		t.Errorf("expected error for an invalid --max-size, instead got %v", err)
+	}
+}
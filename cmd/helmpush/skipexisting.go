@@ -0,0 +1,51 @@
+package main
+
+import (
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+	"github.com/chartmuseum/helm-push/pkg/helm"
+)
+
+// findExisting reports, for each target, whether its repo already has
+// the chart version, for --skip-existing. It is checked before
+// packaging, so a chart already in every repo isn't even packaged. The
+// clients it connects are kept in clients for the push. A repo which
+// can't be checked, e.g. because reading the chart API isn't allowed, is
+// reported as not having it: its 409 Conflict is then handled on upload
+func (p *pushCmd) findExisting(targets []*pushCmd, clients []*cm.Client, chart *helm.Chart) []bool {
+	existing := make([]bool, len(targets))
+	if !p.skipExisting || p.signatureOnly {
+		return existing
+	}
+	for i, t := range targets {
+		if t.oci {
+			continue
+		}
+		if clients[i] == nil {
+			client, err := t.connect()
+			if err != nil {
+				continue
+			}
+			clients[i] = client
+		}
+		_, err := clients[i].GetChartInfoWithContext(p.ctx, chart.Name(), chart.Version())
+		existing[i] = err == nil
+	}
+	return existing
+}
+
+// allExisting reports whether every repo already has the chart version
+func allExisting(existing []bool) bool {
+	for _, e := range existing {
+		if !e {
+			return false
+		}
+	}
+	return len(existing) > 0
+}
+
+// skipExistingVersion reports the chart version as already in the
+// repo, for --skip-existing
+func (p *pushCmd) skipExistingVersion(chart *helm.Chart, result *pushResult) {
+	p.log.info(chart.Name(), p.repoName, "%s-%s skipped: version already present in %s", chart.Name(), chart.Version(), p.repoName)
+	result.Skipped = true
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPushCmdSkipExisting(t *testing.T) {
+	var requests []string
+	getStatus, postStatus := 200, 409
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/x/y/z/api/charts/mychart/0.1.0":
+			w.WriteHeader(getStatus)
+			w.Write([]byte(`{"name":"mychart","version":"0.1.0"}`))
+		case r.Method == "POST":
+			w.WriteHeader(postStatus)
+			if postStatus == 409 {
+				w.Write([]byte(`{"error":"mychart-0.1.0.tgz already exists"}`))
+			} else {
+				w.Write([]byte(`{"error":"storage unavailable"}`))
+			}
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+
+	push := func(args ...string) (string, error) {
+		requests = nil
+		args = append([]string{testTarballPath, "helm-push-test"}, args...)
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	// the version found before packaging isn't uploaded
+	out, err := push("--skip-existing")
+	if err != nil || !strings.Contains(out, "mychart-0.1.0 skipped: version already present in helm-push-test") {
+		t.Errorf("expected version to be skipped, instead got %v:\n%s", err, out)
+	}
+	if strings.Join(requests, ",") != "GET /x/y/z/api/charts/mychart/0.1.0" {
+		t.Errorf("expected no upload, instead got %v", requests)
+	}
+
+	// without the check, the conflict on upload is a success
+	getStatus = 403
+	out, err = push("--skip-existing")
+	if err != nil || !strings.Contains(out, "skipped: version already present") {
+		t.Errorf("expected conflict to be skipped, instead got %v:\n%s", err, out)
+	}
+	if len(requests) != 2 || requests[1] != "POST /x/y/z/api/charts" {
+		t.Errorf("expected an upload after the failed check, instead got %v", requests)
+	}
+
+	// other errors still fail
+	postStatus = 500
+	if _, err := push("--skip-existing"); err == nil || !strings.Contains(err.Error(), "storage unavailable") {
+		t.Errorf("expected server error with --skip-existing, instead got %v", err)
+	}
+	postStatus = 409
+	if _, err := push(); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected conflict without --skip-existing, instead got %v", err)
+	}
+
+	if _, err := push("--skip-existing", "--force"); err == nil || err.Error() != "--skip-existing and --force cannot be used together" {
+		t.Errorf("expected error with --force, instead got %v", err)
+	}
+	if _, err := push("--skip-existing", "--oci"); err == nil || err.Error() != "--skip-existing cannot be used with --oci or oci:// repos" {
+		t.Errorf("expected error with --oci, instead got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chartmuseum/helm-push/pkg/helm"
+)
+
+const (
+	// own subcharts filters, for --own-subcharts-only
+	ownMaintainerFilter = "maintainer="
+	ownPrefixFilter     = "prefix="
+)
+
+// validateOwnSubchartsFilters checks that each --own-subcharts-only
+// filter is maintainer=<name or email> or prefix=<chart name prefix>
+func validateOwnSubchartsFilters(filters []string) error {
+	for _, f := range filters {
+		if (!strings.HasPrefix(f, ownMaintainerFilter) || f == ownMaintainerFilter) && (!strings.HasPrefix(f, ownPrefixFilter) || f == ownPrefixFilter) {
+			return fmt.Errorf("invalid --own-subcharts-only %q (must be maintainer=<name or email> or prefix=<chart name prefix>)", f)
+		}
+	}
+	return nil
+}
+
+// findSubcharts returns the subcharts of the chart directory at path to
+// push with --with-subcharts, both directories and .tgz packages in its
+// charts/ directory. With --own-subcharts-only, only those matching one
+// of the filters are returned, the others are left to their own
+// maintainers
+func (p *pushCmd) findSubcharts(path string) ([]string, error) {
+	if !isChartDir(path) {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(filepath.Join(path, "charts"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var subcharts []string
+	for _, e := range entries {
+		subchart := filepath.Join(path, "charts", e.Name())
+		if e.IsDir() && !isChartDir(subchart) || !e.IsDir() && !strings.HasSuffix(e.Name(), ".tgz") {
+			continue
+		}
+		if len(p.ownSubcharts) > 0 {
+			c, err := helm.GetChartByName(subchart)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load subchart %s: %s", subchart, err)
+			}
+			if !p.isOwnSubchart(c) {
+				p.log.info(c.Name(), p.repoName, "Skipping subchart %s, not matched by --own-subcharts-only", subchart)
+				continue
+			}
+		}
+		subcharts = append(subcharts, subchart)
+	}
+	return subcharts, nil
+}
+
+// isOwnSubchart reports whether a subchart matches one of the
+// --own-subcharts-only filters
+func (p *pushCmd) isOwnSubchart(c *helm.Chart) bool {
+	for _, f := range p.ownSubcharts {
+		if strings.HasPrefix(f, ownMaintainerFilter) && c.IsMaintainedBy(strings.TrimPrefix(f, ownMaintainerFilter)) {
+			return true
+		}
+		if strings.HasPrefix(f, ownPrefixFilter) && strings.HasPrefix(c.Name(), strings.TrimPrefix(f, ownPrefixFilter)) {
+			return true
+		}
+	}
+	return false
+}
+
+// subchartCmd returns a copy of the command to push a subchart with,
+// without the flags which change the parent chart, so that its name,
+// version and so on don't leak to its subcharts
+func (p *pushCmd) subchartCmd() *pushCmd {
+	c := *p
+	c.nameOverride = ""
+	c.chartVersion = ""
+	c.appVersion = ""
+	c.appVersionFrom = ""
+	c.bump = ""
+	c.annotationArgs = nil
+	c.annotations = nil
+	c.includes = nil
+	c.dependencyUpdate = false
+	return &c
+}
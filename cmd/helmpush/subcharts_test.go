@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPushCmdWithSubcharts(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	umbrella := filepath.Join(tmp, "umbrella")
+	writeChart := func(dir string, chartYaml string) {
+		os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+		os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte(chartYaml), 0644)
+		os.WriteFile(filepath.Join(dir, "templates", "cm.yaml"), []byte("kind: ConfigMap\n"), 0644)
+	}
+	writeChart(umbrella, "apiVersion: v2\nname: umbrella\nversion: 1.0.0\n")
+	writeChart(filepath.Join(umbrella, "charts", "backend"), "apiVersion: v2\nname: backend\nversion: 0.3.0\nmaintainers:\n- name: Platform Team\n")
+	writeChart(filepath.Join(umbrella, "charts", "acme-frontend"), "apiVersion: v2\nname: acme-frontend\nversion: 0.2.0\n")
+	b, _ := os.ReadFile(testTarballPath)
+	os.WriteFile(filepath.Join(umbrella, "charts", "mychart-0.1.0.tgz"), b, 0644)
+
+	var pushed []string
+	ts := httptest.NewServer(uploadsOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, header, err := r.FormFile("chart"); err == nil {
+			pushed = append(pushed, filepath.Base(header.Filename))
+		}
+		w.WriteHeader(201)
+		w.Write([]byte("{}"))
+	})))
+	defer ts.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+
+	push := func(args ...string) (string, error) {
+		pushed = nil
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&bytes.Buffer{})
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	// the version override only applies to the parent
+	out, err := push(umbrella, "helm-push-test", "--with-subcharts", "--version", "2.0.0")
+	if err != nil {
+		t.Fatal("unexpected error pushing with --with-subcharts", err)
+	}
+	if strings.Join(pushed, ",") != "umbrella-2.0.0.tgz,acme-frontend-0.2.0.tgz,backend-0.3.0.tgz,mychart-0.1.0.tgz" {
+		t.Errorf("expected parent then each subchart with its own version, instead got %v", pushed)
+	}
+	for _, expected := range []string{"umbrella", "backend (subchart of umbrella)", "mychart (subchart of umbrella)"} {
+		if !strings.Contains(out, "\n"+expected+" ") {
+			t.Errorf("expected %s in summary, instead got:\n%s", expected, out)
+		}
+	}
+
+	// third-party subcharts are left out
+	out, err = push(umbrella, "helm-push-test", "--with-subcharts", "--own-subcharts-only", "maintainer=platform team", "--own-subcharts-only", "prefix=acme-")
+	if err != nil {
+		t.Fatal("unexpected error pushing with --own-subcharts-only", err)
+	}
+	if strings.Join(pushed, ",") != "umbrella-1.0.0.tgz,acme-frontend-0.2.0.tgz,backend-0.3.0.tgz" {
+		t.Errorf("expected only own subcharts to be pushed, instead got %v", pushed)
+	}
+	if !strings.Contains(out, "Skipping subchart "+filepath.Join(umbrella, "charts", "mychart-0.1.0.tgz")) {
+		t.Errorf("expected skipped subchart to be logged, instead got:\n%s", out)
+	}
+
+	for _, tc := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{umbrella, "helm-push-test", "--own-subcharts-only", "prefix=acme-"}, "--own-subcharts-only only applies to --with-subcharts"},
+		{[]string{umbrella, "helm-push-test", "--with-subcharts", "--own-subcharts-only", "team=platform"}, `invalid --own-subcharts-only "team=platform"`},
+		{[]string{umbrella, "helm-push-test", "--with-subcharts", "--own-subcharts-only", "prefix="}, `invalid --own-subcharts-only "prefix="`},
+		{[]string{testTarballPath, "helm-push-test", "--with-subcharts"}, "--with-subcharts needs a chart directory"},
+	} {
+		if _, err := push(tc.args...); err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%v: expected error %q, instead got %v", tc.args, tc.expected, err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	batchPushed  = "pushed"
+	batchSkipped = "skipped"
+	batchFailed  = "failed"
+	// batchAborted charts weren't pushed as an earlier one failed
+	// with --fail-fast
+	batchAborted = "aborted"
+)
+
+type (
+	// batchJob is a chart to push in a --recursive or --with-subcharts
+	// push
+	batchJob struct {
+		path string
+		// parent is the path of the chart a subchart belongs to
+		parent string
+	}
+
+	// batchResult is the outcome of pushing one of the charts of a
+	// --recursive push, for the summary printed at the end
+	batchResult struct {
+		Chart string
+		// Parent is the chart a subchart pushed with --with-subcharts
+		// belongs to
+		Parent   string
+		Version  string
+		Status   string
+		Duration time.Duration
+		Error    string
+	}
+)
+
+// batchChartName returns the path of a chart relative to the directory
+// pushed with --recursive, or its base name if it is that directory, to
+// name it before it is loaded
+func batchChartName(dir string, path string) string {
+	if rel, err := filepath.Rel(dir, path); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return filepath.Base(abs)
+	}
+	return path
+}
+
+// newBatchResult returns the outcome of pushing the chart at path with
+// the command pushed, which is nil if the push failed before it started
+func newBatchResult(path string, pushed *pushCmd, err error, d time.Duration) batchResult {
+	r := batchResult{Chart: path, Status: batchPushed, Duration: d}
+	if pushed != nil && pushed.pushed != nil {
+		r.Chart = pushed.pushed.Name()
+		r.Version = pushed.pushed.Version()
+	}
+	if err != nil {
+		r.Status = batchFailed
+		r.Error = strings.Join(strings.Fields(err.Error()), " ")
+		return r
+	}
+	if pushed != nil && len(pushed.results) > 0 {
+		r.Status = batchSkipped
+		for _, result := range pushed.results {
+			if !result.Skipped {
+				r.Status = batchPushed
+			}
+		}
+	}
+	return r
+}
+
+// printBatchSummary prints a table of the charts pushed by a --recursive
+// or --with-subcharts push, subcharts apart from their parent. With --quiet it is only printed, on errOut, if a chart failed
+func (p *pushCmd) printBatchSummary(errOut io.Writer, batch []batchResult, failed bool) error {
+	out := p.log.out
+	if p.quiet {
+		if !failed {
+			return nil
+		}
+		out = errOut
+	}
+
+	fmt.Fprintln(out)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHART\tVERSION\tSTATUS\tDURATION\tERROR")
+	for _, r := range batch {
+		duration := ""
+		if r.Status != batchAborted {
+			duration = r.Duration.Round(time.Millisecond).String()
+		}
+		chart := r.Chart
+		if r.Parent != "" {
+			chart = fmt.Sprintf("%s (subchart of %s)", r.Chart, r.Parent)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", chart, r.Version, r.Status, duration, r.Error)
+	}
+	return w.Flush()
+}
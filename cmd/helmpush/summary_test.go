@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushCmdBatchSummary(t *testing.T) {
+	chartsDir, cleanup := setupTestGitCharts(t)
+	defer cleanup()
+
+	var pushed []string
+	failB := true
+	ts := httptest.NewServer(uploadsOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, header, err := r.FormFile("chart")
+		if err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		pushed = append(pushed, filepath.Base(header.Filename))
+		if failB && filepath.Base(header.Filename) == "b-0.1.0.tgz" {
+			w.WriteHeader(500)
+			w.Write([]byte(`{"error":"storage unavailable"}`))
+			return
+		}
+		w.WriteHeader(201)
+		w.Write([]byte("{}"))
+	})))
+	defer ts.Close()
+
+	repoCleanup := setupTestRepo(t, ts.URL)
+	defer repoCleanup()
+
+	push := func(args ...string) (string, string, error) {
+		pushed = nil
+		args = append([]string{"-r", chartsDir, "helm-push-test"}, args...)
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		var out, errOut bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&errOut)
+		err := cmd.Execute()
+		return out.String(), errOut.String(), err
+	}
+	row := func(chart, version, status string) *regexp.Regexp {
+		if version != "" {
+			chart += ` +` + version
+		}
+		return regexp.MustCompile(`(?m)^` + chart + ` +` + status + `\b`)
+	}
+
+	// by default every chart is pushed and the failures reported at the end
+	out, _, err := push()
+	if err == nil || !strings.Contains(err.Error(), "failed to push 1 of 4 charts") {
+		t.Errorf("expected error for one chart, instead got %v", err)
+	}
+	if len(pushed) != 4 {
+		t.Errorf("expected every chart to be pushed, instead got %v", pushed)
+	}
+	if !row("CHART", "VERSION", "STATUS").MatchString(out) || !row("a", "0.1.0", "pushed").MatchString(out) || !row("b", "0.1.0", "failed").MatchString(out) || !strings.Contains(out, "storage unavailable") {
+		t.Errorf("expected summary table, instead got:\n%s", out)
+	}
+
+	// --fail-fast stops at the first failure
+	out, _, err = push("--fail-fast")
+	if err == nil || !strings.Contains(err.Error(), "failed to push 1 of 4 charts") {
+		t.Errorf("expected error with --fail-fast, instead got %v", err)
+	}
+	if strings.Join(pushed, ",") != "a-0.1.0.tgz,common-0.1.0.tgz,b-0.1.0.tgz" {
+		t.Errorf("expected push to stop after b, instead got %v", pushed)
+	}
+	if !row("c", "", "aborted").MatchString(out) {
+		t.Errorf("expected remaining charts to be aborted, instead got:\n%s", out)
+	}
+
+	// --quiet only prints the summary, on stderr, if a chart failed
+	out, errOut, err := push("--quiet")
+	out = strings.SplitN(out, "Usage:", 2)[0]
+	if err == nil || strings.Contains(out, "Pushing") || strings.Contains(out, "CHART") {
+		t.Errorf("expected error and no output but usage with --quiet, instead got %v:\n%s", err, out)
+	}
+	if !row("b", "0.1.0", "failed").MatchString(errOut) || strings.Contains(errOut, "Pushing") {
+		t.Errorf("expected only errors and summary on stderr, instead got:\n%s", errOut)
+	}
+	failB = false
+	out, errOut, err = push("-q")
+	if err != nil || out != "" || strings.Contains(errOut, "CHART") {
+		t.Errorf("expected no output with --quiet, instead got %v:\n%s%s", err, out, errOut)
+	}
+
+	if _, _, err := push("--fail-fast", "--continue-on-error"); err == nil || !strings.Contains(err.Error(), "--fail-fast and --continue-on-error cannot be used together") {
+		t.Errorf("expected error with --fail-fast and --continue-on-error, instead got %v", err)
+	}
+}
+
+func TestNewBatchResult(t *testing.T) {
+	p := &pushCmd{results: []pushResult{{Skipped: true}, {Skipped: true}}}
+	if r := newBatchResult("charts/a", p, nil, time.Second); r.Chart != "charts/a" || r.Status != batchSkipped {
+		t.Errorf("expected chart skipped in every repo to be skipped, instead got %+v", r)
+	}
+	p.results[1].Skipped = false
+	if r := newBatchResult("charts/a", p, nil, time.Second); r.Status != batchPushed {
+		t.Errorf("expected chart pushed to a repo to be pushed, instead got %+v", r)
+	}
+	if r := newBatchResult("charts/a", nil, errors.New("no repo\ngiven"), time.Second); r.Status != batchFailed || r.Error != "no repo given" {
+		t.Errorf("expected failed chart with its error on one line, instead got %+v", r)
+	}
+}
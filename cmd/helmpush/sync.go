@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+	"github.com/chartmuseum/helm-push/pkg/helm"
+	"github.com/spf13/cobra"
+)
+
+type (
+	syncCmd struct {
+		*pushCmd
+		dir      string
+		parallel int
+	}
+
+	// syncChart is a local chart to sync to the repo
+	syncChart struct {
+		name    string
+		version string
+		path    string
+		chart   *helm.Chart
+		err     error
+	}
+)
+
+func newSyncCmd(p *pushCmd) *cobra.Command {
+	s := &syncCmd{pushCmd: p}
+	cmd := &cobra.Command{
+		Use:   "sync DIR REPO",
+		Short: "Push the charts in a local directory which are missing from a ChartMuseum repository",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return errors.New("This command needs 2 arguments: path to charts directory, name of chart repository (or repo URL)")
+			}
+			return nil
+		},
+		ValidArgsFunction: completePushArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if s.parallel < 1 {
+				return errors.New("--parallel must be at least 1")
+			}
+			s.dir = args[0]
+			s.repoName = args[1]
+			if err := s.setup(cmd); err != nil {
+				return err
+			}
+			return s.sync()
+		},
+	}
+	f := cmd.Flags()
+	f.IntVar(&s.parallel, "parallel", 1, "Number of charts to push at the same time")
+	return cmd
+}
+
+func (s *syncCmd) sync() error {
+	paths, err := findLocalCharts(s.dir)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no charts found in %s", s.dir)
+	}
+
+	client, err := s.connect()
+	if err != nil {
+		return err
+	}
+
+	// Always fetch a fresh index, so a sync can be safely run again
+	index, err := helm.GetIndexByDownloader(getIndexDownloader(s.ctx, client))
+	if err != nil {
+		return err
+	}
+
+	var missing []*syncChart
+	skipped, failed := 0, 0
+	seen := map[string]bool{}
+	for _, path := range paths {
+		chart, err := helm.GetChartByName(path)
+		if err != nil {
+			s.log.error("", s.repoName, "Failed to load %s: %s", path, err)
+			failed++
+			continue
+		}
+		key := chart.Name() + "-" + chart.Version()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if _, err := index.Get(chart.Name(), chart.Version()); err == nil {
+			skipped++
+			continue
+		}
+		missing = append(missing, &syncChart{name: chart.Name(), version: chart.Version(), path: path, chart: chart})
+	}
+
+	tmp, err := s.mkdirTemp()
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	// Push the missing charts with --parallel workers
+	queue := make(chan *syncChart)
+	var wg sync.WaitGroup
+	for i := 0; i < s.parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for sc := range queue {
+				sc.err = s.pushChart(client, sc, tmp)
+			}
+		}()
+	}
+	for _, sc := range missing {
+		queue <- sc
+	}
+	close(queue)
+	wg.Wait()
+
+	pushed := 0
+	for _, sc := range missing {
+		if sc.err != nil {
+			s.log.error(sc.name, s.repoName, "Failed to push %s-%s: %s", sc.name, sc.version, sc.err)
+			failed++
+		} else {
+			pushed++
+		}
+	}
+
+	s.log.info("", s.repoName, "Synced %s to %s: %d pushed, %d skipped, %d failed.", s.dir, s.repoName, pushed, skipped, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d charts failed to sync to %s (run sync again to retry)", failed, s.repoName)
+	}
+	return nil
+}
+
+// pushChart uploads a .tgz package unchanged, or packages a chart
+// directory in its own directory within tmp first
+func (s *syncCmd) pushChart(client *cm.Client, sc *syncChart, tmp string) error {
+	chartPackagePath := sc.path
+	if !strings.HasSuffix(sc.path, ".tgz") {
+		outDir := filepath.Join(tmp, sc.name+"-"+sc.version)
+		if err := os.MkdirAll(outDir, 0755); err != nil {
+			return err
+		}
+		var err error
+		if chartPackagePath, err = helm.CreateChartPackage(sc.chart, outDir); err != nil {
+			return err
+		}
+	}
+
+	// only missing charts are pushed, so there is nothing to force
+	resp, err := client.UploadChartPackageWithContext(s.ctx, chartPackagePath, false)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := handlePushResponse(resp, sc.name, sc.version, client.URL()); err != nil {
+		return err
+	}
+	s.log.info(sc.name, s.repoName, "Pushed %s-%s", sc.name, sc.version)
+	return nil
+}
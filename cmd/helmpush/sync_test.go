@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSyncCmd(t *testing.T) {
+	var mu sync.Mutex
+	var index string
+	var pushed []string
+	statusCode := 201
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/x/y/z/index.yaml":
+			w.Write([]byte(index))
+		case r.Method == "POST" && r.URL.Path == "/x/y/z/api/charts":
+			_, header, err := r.FormFile("chart")
+			if err == nil && statusCode == 201 {
+				pushed = append(pushed, header.Filename)
+			}
+			w.WriteHeader(statusCode)
+			w.Write([]byte("{}"))
+		default:
+			w.WriteHeader(404)
+			w.Write([]byte("{\"error\": \"not found\"}"))
+		}
+	}))
+	defer ts.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+
+	syncCharts := func(args ...string) (string, error) {
+		out := &bytes.Buffer{}
+		args = append([]string{"sync"}, args...)
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		cmd.SetOut(out)
+		cmd.SetErr(&bytes.Buffer{})
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	// Only my-v3-chart is missing
+	index = `{"apiVersion": "v1", "entries": {"mychart": [{"version": "0.1.0"}]}}`
+	out, err := syncCharts("--parallel", "2", "../../testdata/charts", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error syncing charts", err)
+	}
+	if len(pushed) != 1 || !strings.HasSuffix(pushed[0], "my-v3-chart-0.1.0.tgz") {
+		t.Errorf("expected only my-v3-chart to be pushed, instead got %v", pushed)
+	}
+	if !strings.Contains(out, "1 pushed, 1 skipped, 0 failed") {
+		t.Errorf("unexpected sync summary: %s", out)
+	}
+
+	// Nothing left to push when run again
+	pushed = nil
+	index = `{"apiVersion": "v1", "entries": {"mychart": [{"version": "0.1.0"}], "my-v3-chart": [{"version": "0.1.0"}]}}`
+	if _, err := syncCharts("../../testdata/charts", "helm-push-test"); err != nil {
+		t.Fatal("unexpected error syncing charts again", err)
+	}
+	if len(pushed) != 0 {
+		t.Errorf("expected nothing to be pushed, instead got %v", pushed)
+	}
+
+	// Failures
+	index = `{"apiVersion": "v1", "entries": {}}`
+	statusCode = 500
+	out, err = syncCharts("../../testdata/charts", "helm-push-test")
+	if err == nil {
+		t.Error("expected error when pushes fail, instead got nil")
+	}
+	if !strings.Contains(out, "0 pushed, 0 skipped, 2 failed") {
+		t.Errorf("unexpected sync summary: %s", out)
+	}
+}
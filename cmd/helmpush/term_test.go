@@ -0,0 +1,19 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestConfirm(t *testing.T) {
+	for answer, expected := range map[string]bool{"y\n": true, "YES\n": true, "n\n": false, "\n": false, "": false} {
+		ok, err := confirm(strings.NewReader(answer), &bytes.Buffer{}, "Continue?")
+		if err != nil {
+			t.Error("unexpected error confirming", err)
+		}
+		if ok != expected {
+			t.Errorf("expected %v for answer %q, instead got %v", expected, answer, ok)
+		}
+	}
+}
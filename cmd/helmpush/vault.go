@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+type (
+	// vaultSecret is the response to reading a secret from Vault
+	vaultSecret struct {
+		Data   map[string]interface{} `json:"data"`
+		Errors []string               `json:"errors"`
+	}
+
+	// vaultMount is the response to looking up the mount of a path
+	vaultMount struct {
+		Data struct {
+			Path    string            `json:"path"`
+			Type    string            `json:"type"`
+			Options map[string]string `json:"options"`
+		} `json:"data"`
+	}
+)
+
+// setVaultCredentials replaces the username and password with the keys
+// of the --vault-path secret, so they don't need to be given in the
+// environment
+func (p *pushCmd) setVaultCredentials(f *pflag.FlagSet) error {
+	if p.vaultPath == "" {
+		if f.Changed("vault-addr") || f.Changed("vault-token") || f.Changed("vault-keys") {
+			return errors.New("--vault-addr, --vault-token and --vault-keys only apply to --vault-path")
+		}
+		return nil
+	}
+	for _, name := range []string{"username", "password", "repo-username", "repo-password"} {
+		if f.Changed(name) {
+			return fmt.Errorf("--%s cannot be used with --vault-path", name)
+		}
+	}
+	keys := strings.Split(p.vaultKeys, ",")
+	if len(keys) != 2 || strings.TrimSpace(keys[0]) == "" || strings.TrimSpace(keys[1]) == "" {
+		return fmt.Errorf("invalid --vault-keys %q (must be the username and password keys, e.g. username,password)", p.vaultKeys)
+	}
+	if p.vaultAddr == "" {
+		return errors.New("--vault-path needs the Vault address (--vault-addr or $VAULT_ADDR)")
+	}
+	if p.vaultToken == "" {
+		p.vaultToken = vaultTokenFile()
+	}
+	if p.vaultToken == "" {
+		return errors.New("--vault-path needs a Vault token (--vault-token, $VAULT_TOKEN or ~/.vault-token from vault login)")
+	}
+
+	data, err := p.readVaultSecret()
+	if err != nil {
+		return err
+	}
+	var creds [2]string
+	for i, key := range keys {
+		key = strings.TrimSpace(key)
+		v, ok := data[key].(string)
+		if !ok || v == "" {
+			return fmt.Errorf("secret %s in Vault has no %q key", p.vaultPath, key)
+		}
+		creds[i] = v
+	}
+	p.username, p.password = creds[0], creds[1]
+	return nil
+}
+
+// readVaultSecret returns the data of the --vault-path secret, reading
+// it from the data/ path of KV version 2 engines
+func (p *pushCmd) readVaultSecret() (map[string]interface{}, error) {
+	client := &http.Client{Timeout: time.Duration(p.timeout) * time.Second}
+	secretPath := strings.Trim(p.vaultPath, "/")
+
+	// as the vault CLI does, the mount tells the KV version. Tokens
+	// which can't look it up are tried as is
+	kv2 := false
+	var mount vaultMount
+	if err := p.vaultRequest(client, "sys/internal/ui/mounts/"+secretPath, &mount); err == nil && mount.Data.Options["version"] == "2" {
+		kv2 = true
+		prefix := strings.Trim(mount.Data.Path, "/")
+		if rest := strings.TrimPrefix(secretPath, prefix+"/"); !strings.HasPrefix(rest, "data/") {
+			secretPath = prefix + "/data/" + rest
+		}
+	}
+
+	var secret vaultSecret
+	if err := p.vaultRequest(client, secretPath, &secret); err != nil {
+		return nil, fmt.Errorf("failed to read secret %s from Vault: %s", p.vaultPath, err)
+	}
+	data := secret.Data
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, hasMetadata := data["metadata"]; kv2 || hasMetadata {
+			data = inner
+		}
+	}
+	return data, nil
+}
+
+// vaultRequest reads the Vault API path into v
+func (p *pushCmd) vaultRequest(client *http.Client, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(p.ctx, "GET", strings.TrimSuffix(p.vaultAddr, "/")+"/v1/"+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", p.vaultToken)
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errors.New("not found")
+	}
+	if resp.StatusCode != http.StatusOK {
+		var secret vaultSecret
+		json.NewDecoder(resp.Body).Decode(&secret)
+		return fmt.Errorf("%d: %s", resp.StatusCode, strings.Join(secret.Errors, ", "))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// vaultTokenFile returns the token saved by vault login, if any
+func vaultTokenFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	b, err := os.ReadFile(filepath.Join(home, ".vault-token"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPushCmdVault(t *testing.T) {
+	var vaultToken string
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vaultToken = r.Header.Get("X-Vault-Token")
+		switch r.URL.Path {
+		case "/v1/sys/internal/ui/mounts/secret/chartmuseum":
+			w.Write([]byte(`{"data":{"path":"secret/","type":"kv","options":{"version":"2"}}}`))
+		case "/v1/secret/data/chartmuseum":
+			w.Write([]byte(`{"data":{"data":{"user":"ci-v2","pass":"s3cr3t-v2"},"metadata":{"version":3}}}`))
+		case "/v1/sys/internal/ui/mounts/kv/chartmuseum":
+			w.Write([]byte(`{"data":{"path":"kv/","type":"kv","options":null}}`))
+		case "/v1/kv/chartmuseum":
+			w.Write([]byte(`{"data":{"username":"ci-v1","password":"s3cr3t-v1"}}`))
+		case "/v1/locked/data/chartmuseum":
+			// the mount can't be looked up, but the response tells KV v2
+			w.Write([]byte(`{"data":{"data":{"username":"ci-locked","password":"s3cr3t"},"metadata":{"version":1}}}`))
+		case "/v1/denied/chartmuseum":
+			w.WriteHeader(403)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+		default:
+			w.WriteHeader(404)
+			w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	defer vault.Close()
+
+	var username, password string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ = r.BasicAuth()
+		w.WriteHeader(201)
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+	os.Setenv("VAULT_ADDR", vault.URL)
+	defer os.Unsetenv("VAULT_ADDR")
+	os.Setenv("HELM_REPO_USERNAME", "env-user")
+	defer os.Unsetenv("HELM_REPO_USERNAME")
+
+	push := func(args ...string) error {
+		username, password = "", ""
+		args = append([]string{testTarballPath, "helm-push-test"}, args...)
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		return cmd.Execute()
+	}
+
+	for _, tc := range []struct {
+		args               []string
+		username, password string
+	}{
+		{[]string{"--vault-path", "secret/chartmuseum", "--vault-keys", "user,pass"}, "ci-v2", "s3cr3t-v2"},
+		{[]string{"--vault-path", "/secret/data/chartmuseum/", "--vault-keys", "user,pass"}, "ci-v2", "s3cr3t-v2"},
+		{[]string{"--vault-path", "kv/chartmuseum"}, "ci-v1", "s3cr3t-v1"},
+		{[]string{"--vault-path", "locked/data/chartmuseum"}, "ci-locked", "s3cr3t"},
+	} {
+		if err := push(append(tc.args, "--vault-token", "s.token")...); err != nil {
+			t.Fatalf("%v: unexpected error pushing with Vault credentials: %s", tc.args, err)
+		}
+		if username != tc.username || password != tc.password || vaultToken != "s.token" {
+			t.Errorf("%v: expected %s:%s, instead got %s:%s (Vault token %q)", tc.args, tc.username, tc.password, username, password, vaultToken)
+		}
+	}
+
+	for _, tc := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--vault-path", "secret/chartmuseum"}, `secret secret/chartmuseum in Vault has no "username" key`},
+		{[]string{"--vault-path", "secret/missing"}, "failed to read secret secret/missing from Vault: not found"},
+		{[]string{"--vault-path", "denied/chartmuseum"}, "failed to read secret denied/chartmuseum from Vault: 403: permission denied"},
+		{[]string{"--vault-path", "kv/chartmuseum", "--vault-keys", "username"}, `invalid --vault-keys "username"`},
+		{[]string{"--vault-path", "kv/chartmuseum", "-u", "admin"}, "--username cannot be used with --vault-path"},
+		{[]string{"--vault-addr", vault.URL}, "--vault-addr, --vault-token and --vault-keys only apply to --vault-path"},
+	} {
+		if err := push(append(tc.args, "--vault-token", "s.token")...); err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%v: expected error %q, instead got %v", tc.args, tc.expected, err)
+		}
+	}
+}
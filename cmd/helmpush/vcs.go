@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+	"github.com/chartmuseum/helm-push/pkg/helm"
+)
+
+const (
+	// annotations set with --annotate-vcs
+	vcsRevisionAnnotation = "org.opencontainers.image.revision"
+	vcsSourceAnnotation   = "org.opencontainers.image.source"
+	// there is no OCI annotation for the branch
+	vcsBranchAnnotation = "helm-push/branch"
+)
+
+// vcsAnnotations returns the annotations recording the git commit the
+// chart was pushed from: the revision, with a -dirty suffix if the work
+// tree has uncommitted changes, the URL of the origin remote and the
+// branch. Remotes and branches are left out if there are none
+func vcsAnnotations(chartName string) (map[string]string, error) {
+	dir, err := localChartDir(chartName)
+	if err != nil {
+		return nil, fmt.Errorf("can't annotate with VCS metadata: %s", err)
+	}
+	revision, err := gitOutput(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("can't annotate with VCS metadata: %s is not in a git work tree with commits: %s", chartName, err)
+	}
+	status, err := gitOutput(dir, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return nil, fmt.Errorf("can't annotate with VCS metadata: %s", err)
+	}
+	if status != "" {
+		revision += "-dirty"
+	}
+
+	annotations := map[string]string{vcsRevisionAnnotation: revision}
+	if remote, err := gitOutput(dir, "remote", "get-url", "origin"); err == nil && remote != "" {
+		annotations[vcsSourceAnnotation] = cm.RedactURL(remote)
+	}
+	// a detached HEAD, as in most CI checkouts, has no branch
+	if branch, err := gitOutput(dir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil && branch != "HEAD" {
+		annotations[vcsBranchAnnotation] = branch
+	}
+	return annotations, nil
+}
+
+// localChartDir returns the directory of a local chart, or the directory
+// containing a chart package
+func localChartDir(chartName string) (string, error) {
+	if helm.IsChartURL(chartName) {
+		return "", fmt.Errorf("%s is not in a git work tree", chartName)
+	}
+	if fi, err := os.Stat(chartName); err == nil && !fi.IsDir() {
+		return filepath.Dir(chartName), nil
+	}
+	return chartName, nil
+}
+
+// gitOutput runs a git command in dir and returns its trimmed output
+func gitOutput(dir string, args ...string) (string, error) {
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		if e, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git %s failed: %s", args[0], strings.TrimSpace(string(e.Stderr)))
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
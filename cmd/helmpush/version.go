@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/chartmuseum/helm-push/internal/version"
+	"github.com/chartmuseum/helm-push/pkg/helm"
+	"github.com/spf13/cobra"
+)
+
+type (
+	versionCmd struct {
+		output string
+	}
+
+	// chartVersionResult is the version of a local chart, for -o json
+	chartVersionResult struct {
+		Version string `json:"version"`
+	}
+)
+
+func newVersionCmd() *cobra.Command {
+	v := &versionCmd{}
+	cmd := &cobra.Command{
+		Use:   "version [PATH]",
+		Short: "Print the plugin version, or the version of the chart at PATH",
+		Long: `Print the plugin version, or the version of the chart at PATH
+
+Given a chart directory or .tgz package, only the chart version is printed,
+for use in scripts:
+
+  $ VERSION=$(helm push version mychart/)
+`,
+		Args: cobra.MaximumNArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				return completeChartPaths(toComplete)
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutputFormat(v.output, outputTable, outputJSON); err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if len(args) == 1 {
+				chartVersion, err := helm.GetChartVersion(args[0])
+				if err != nil {
+					return err
+				}
+				if v.output == outputJSON {
+					return printJSON(out, chartVersionResult{Version: chartVersion})
+				}
+				fmt.Fprintln(out, chartVersion)
+				return nil
+			}
+			info := version.Get()
+			if v.output == outputJSON {
+				return printJSON(out, info)
+			}
+			fmt.Fprintf(out, "Version:    %s\n", info.Version)
+			fmt.Fprintf(out, "Git Commit: %s\n", info.GitCommit)
+			fmt.Fprintf(out, "Go Version: %s\n", info.GoVersion)
+			return nil
+		},
+	}
+	f := cmd.Flags()
+	f.StringVarP(&v.output, "output", "o", outputTable, "Output format (table, json)")
+	return cmd
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/chartmuseum/helm-push/internal/version"
+)
+
+func TestVersionCmd(t *testing.T) {
+	out := &bytes.Buffer{}
+	args := []string{"version"}
+	cmd := newPushCmd(args)
+	cmd.SetArgs(args)
+	cmd.SetOut(out)
+	if err := cmd.Execute(); err != nil {
+		t.Fatal("unexpected error printing version", err)
+	}
+	if !strings.Contains(out.String(), "Version:    "+version.Version) {
+		t.Errorf("unexpected version output: %s", out.String())
+	}
+
+	out.Reset()
+	args = []string{"version", "-o", "json"}
+	cmd = newPushCmd(args)
+	cmd.SetArgs(args)
+	cmd.SetOut(out)
+	if err := cmd.Execute(); err != nil {
+		t.Fatal("unexpected error printing version", err)
+	}
+	var info version.BuildInfo
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+		t.Fatal("unexpected error parsing json output", err)
+	}
+	if info != version.Get() {
+		t.Errorf("unexpected json version output: %+v", info)
+	}
+}
+
+func TestVersionCmdChartPath(t *testing.T) {
+	out := &bytes.Buffer{}
+	args := []string{"version", testTarballPath}
+	cmd := newPushCmd(args)
+	cmd.SetArgs(args)
+	cmd.SetOut(out)
+	if err := cmd.Execute(); err != nil {
+		t.Fatal("unexpected error printing chart version", err)
+	}
+	if out.String() != "0.1.0\n" {
+		t.Errorf("unexpected chart version output: %q", out.String())
+	}
+
+	args = []string{"version", "/this/is/not/a/chart"}
+	cmd = newPushCmd(args)
+	cmd.SetArgs(args)
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	if err := cmd.Execute(); err == nil {
+		t.Error("expected error with bad chart path, instead got nil")
+	}
+}
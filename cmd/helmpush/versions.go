@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+	"github.com/spf13/cobra"
+)
+
+type (
+	versionsCmd struct {
+		*pushCmd
+		output  string
+		devel   bool
+		pattern string
+	}
+)
+
+func newVersionsCmd(p *pushCmd) *cobra.Command {
+	v := &versionsCmd{pushCmd: p}
+	cmd := &cobra.Command{
+		Use:   "versions CHART REPO",
+		Short: "List the versions of a chart in a ChartMuseum repository",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return errors.New("This command needs 2 arguments: name of chart, name of chart repository (or repo URL)")
+			}
+			return nil
+		},
+		ValidArgsFunction: completeRepoNames(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutputFormat(v.output, outputTable, outputJSON); err != nil {
+				return err
+			}
+			if _, err := path.Match(v.pattern, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %s", v.pattern, err)
+			}
+			v.chartName = args[0]
+			v.repoName = args[1]
+			if err := v.setup(cmd); err != nil {
+				return err
+			}
+			return v.versions()
+		},
+	}
+	f := cmd.Flags()
+	f.StringVarP(&v.output, "output", "o", outputTable, "Output format (table, json)")
+	f.BoolVar(&v.devel, "devel", false, "Include pre-release versions")
+	f.StringVar(&v.pattern, "pattern", "", `Only include versions matching this glob pattern (e.g. "1.2.*")`)
+	return cmd
+}
+
+func (v *versionsCmd) versions() error {
+	repo, err := v.getRepo()
+	if err != nil {
+		return err
+	}
+
+	client, err := v.newClientFromRepo(repo)
+	if err != nil {
+		return err
+	}
+
+	versions, found, err := fetchChartVersions(v.ctx, client, v.chartName)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("chart not found: no chart named %q in %s", v.chartName, v.repoName)
+	}
+
+	versions = v.filter(versions)
+	cm.SortVersions(versions)
+
+	if v.output == outputJSON {
+		return printJSON(v.out, versions)
+	}
+
+	w := tabwriter.NewWriter(v.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tCREATED\tDIGEST")
+	for _, cv := range versions {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", cv.Version, cv.Created.Format(time.RFC3339), cv.Digest)
+	}
+	return w.Flush()
+}
+
+// fetchChartVersions returns all versions of the named chart from the
+// chart API, newest first, and whether the chart was found at all
+func fetchChartVersions(ctx context.Context, client *cm.Client, name string) ([]*cm.ChartVersion, bool, error) {
+	versions, err := client.ListChartVersionsWithContext(ctx, name)
+	if errors.Is(err, cm.ErrNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return versions, true, nil
+}
+
+// filter removes pre-release versions (unless --devel is set)
+// and versions not matching --pattern
+func (v *versionsCmd) filter(versions []*cm.ChartVersion) []*cm.ChartVersion {
+	filtered := []*cm.ChartVersion{}
+	for _, cv := range versions {
+		if !v.devel && isPrerelease(cv.Version) {
+			continue
+		}
+		if v.pattern != "" {
+			if ok, _ := path.Match(v.pattern, cv.Version); !ok {
+				continue
+			}
+		}
+		filtered = append(filtered, cv)
+	}
+	return filtered
+}
+
+// isPrerelease reports whether version is a semver pre-release
+func isPrerelease(version string) bool {
+	sv, err := semver.NewVersion(version)
+	return err == nil && sv.Prerelease() != ""
+}
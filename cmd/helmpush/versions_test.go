@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+)
+
+var testChartVersionsResponse = `[
+  {"name": "mychart", "version": "0.2.0", "created": "2020-01-02T00:00:00Z", "digest": "bbb"},
+  {"name": "mychart", "version": "0.10.0-rc.1", "created": "2020-01-04T00:00:00Z", "digest": "ddd"},
+  {"name": "mychart", "version": "0.10.0", "created": "2020-01-03T00:00:00Z", "digest": "ccc"},
+  {"name": "mychart", "version": "0.1.0", "created": "2020-01-01T00:00:00Z", "digest": "aaa"}
+]`
+
+func runVersionsCmd(t *testing.T, args ...string) ([]*cm.ChartVersion, error) {
+	out := &bytes.Buffer{}
+	args = append([]string{"versions", "-o", "json"}, args...)
+	cmd := newPushCmd(args)
+	cmd.SetArgs(args)
+	cmd.SetOut(out)
+	cmd.SetErr(&bytes.Buffer{})
+	if err := cmd.Execute(); err != nil {
+		return nil, err
+	}
+	var versions []*cm.ChartVersion
+	if err := json.Unmarshal(out.Bytes(), &versions); err != nil {
+		t.Fatal("unexpected error parsing json output", err)
+	}
+	return versions, nil
+}
+
+func TestVersionsCmd(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/x/y/z/api/charts/mychart" {
+			w.WriteHeader(404)
+			w.Write([]byte("{\"error\": \"chart not found\"}"))
+			return
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(testChartVersionsResponse))
+	}))
+	defer ts.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+
+	// Sorted by semver, pre-releases excluded
+	versions, err := runVersionsCmd(t, "mychart", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error listing versions", err)
+	}
+	var got []string
+	for _, v := range versions {
+		got = append(got, v.Version)
+	}
+	if strings.Join(got, ",") != "0.10.0,0.2.0,0.1.0" {
+		t.Errorf("unexpected versions: %v", got)
+	}
+
+	// Pre-releases included with --devel
+	versions, err = runVersionsCmd(t, "--devel", "mychart", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error listing versions", err)
+	}
+	if len(versions) != 4 || versions[0].Version != "0.10.0" || versions[1].Version != "0.10.0-rc.1" {
+		t.Errorf("unexpected versions with --devel: %+v", versions)
+	}
+
+	// Filter by pattern
+	versions, err = runVersionsCmd(t, "--pattern", "0.1.*", "mychart", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error listing versions", err)
+	}
+	if len(versions) != 1 || versions[0].Digest != "aaa" {
+		t.Errorf("unexpected versions with --pattern: %+v", versions)
+	}
+
+	// Missing chart
+	_, err = runVersionsCmd(t, "otherchart", "helm-push-test")
+	if err == nil || !strings.Contains(err.Error(), "chart not found") {
+		t.Errorf("expected chart not found error, instead got %v", err)
+	}
+
+	// Table output
+	out := &bytes.Buffer{}
+	args := []string{"versions", "mychart", "helm-push-test"}
+	cmd := newPushCmd(args)
+	cmd.SetArgs(args)
+	cmd.SetOut(out)
+	if err := cmd.Execute(); err != nil {
+		t.Fatal("unexpected error listing versions", err)
+	}
+	if !strings.Contains(out.String(), "0.10.0   2020-01-03T00:00:00Z  ccc") {
+		t.Errorf("unexpected table output:\n%s", out.String())
+	}
+}
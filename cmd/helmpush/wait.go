@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+	"github.com/chartmuseum/helm-push/pkg/helm"
+)
+
+// waitInterval is how often --wait checks whether the pushed chart is in
+// the repo index
+var waitInterval = 5 * time.Second
+
+// defaultWait is how long --wait without a value waits for
+const defaultWait = "1m"
+
+// waitForIndex waits, with --wait, until the pushed chart version is in
+// the repo index, since ChartMuseum may regenerate it after the upload
+// is done. It fails if the chart isn't there before the --wait timeout,
+// or if the chart API can't be read
+func (p *pushCmd) waitForIndex(client *cm.Client, chart *helm.Chart) error {
+	if p.wait <= 0 {
+		return nil
+	}
+	p.log.info(chart.Name(), p.repoName, "Waiting for %s-%s to appear in the index of %s...", chart.Name(), chart.Version(), p.repoName)
+	deadline := time.Now().Add(p.wait)
+	for {
+		_, err := client.GetChartInfoWithContext(p.ctx, chart.Name(), chart.Version())
+		if err == nil {
+			p.log.info(chart.Name(), p.repoName, "%s-%s is in the index of %s", chart.Name(), chart.Version(), p.repoName)
+			return nil
+		}
+		if !errors.Is(err, cm.ErrNotFound) {
+			return fmt.Errorf("failed to check the index of %s for %s-%s: %w", p.repoName, chart.Name(), chart.Version(), err)
+		}
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return fmt.Errorf("pushed %s-%s but it did not appear in the index of %s within %s", chart.Name(), chart.Version(), p.repoName, p.wait)
+		}
+		if wait > waitInterval {
+			wait = waitInterval
+		}
+		select {
+		case <-time.After(wait):
+		case <-p.ctx.Done():
+			return p.ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushCmdWait(t *testing.T) {
+	defer func(i time.Duration) { waitInterval = i }(waitInterval)
+	waitInterval = time.Millisecond
+
+	var checks, indexedAfter, status int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST":
+			w.WriteHeader(201)
+			w.Write([]byte("{}"))
+		case r.URL.Path == "/x/y/z/api/charts/mychart/0.1.0":
+			checks++
+			if status != 0 {
+				w.WriteHeader(status)
+				return
+			}
+			if indexedAfter < 0 || checks <= indexedAfter {
+				w.WriteHeader(404)
+				w.Write([]byte(`{"error":"improper constraint: 0.1.0"}`))
+				return
+			}
+			w.Write([]byte(`{"name":"mychart","version":"0.1.0"}`))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+
+	push := func(args ...string) (string, error) {
+		checks = 0
+		args = append([]string{testTarballPath, "helm-push-test"}, args...)
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	indexedAfter = 2
+	out, err := push("--wait")
+	if err != nil || !strings.Contains(out, "mychart-0.1.0 is in the index of helm-push-test") {
+		t.Errorf("expected push to wait for the index, instead got %v:\n%s", err, out)
+	}
+	// the last check is by describePushed
+	if checks != 4 {
+		t.Errorf("expected 3 checks of the index, instead got %d", checks-1)
+	}
+
+	indexedAfter = -1
+	if _, err := push("--wait=20ms"); err == nil || err.Error() != "pushed mychart-0.1.0 but it did not appear in the index of helm-push-test within 20ms" {
+		t.Errorf("expected timeout waiting for the index, instead got %v", err)
+	}
+	if checks < 2 {
+		t.Errorf("expected the index to be checked until the timeout, instead got %d checks", checks)
+	}
+
+	status = 401
+	if _, err := push("--wait"); err == nil || !strings.Contains(err.Error(), "failed to check the index of helm-push-test for mychart-0.1.0") || checks != 1 {
+		t.Errorf("expected error checking the index, instead got %v after %d checks", err, checks)
+	}
+	status = 0
+
+	// the duration must follow an "=", as the value is optional
+	if _, err := push("--wait", "5m"); err == nil || err.Error() != `the value of --wait must be given after "=", e.g. --wait=5m ("5m" was taken as an argument)` {
+		t.Errorf("expected error for a duration after a space, instead got %v", err)
+	}
+
+	if _, err := push("--wait", "--oci"); err == nil || err.Error() != "--wait cannot be used with --oci or oci:// repos" {
+		t.Errorf("expected error with --oci, instead got %v", err)
+	}
+	cmd := newPushCmd(nil)
+	cmd.ParseFlags([]string{"--wait"})
+	if v := cmd.Flags().Lookup("wait").Value.String(); v != "1m0s" {
+		t.Errorf("expected --wait to default to 1m, instead got %s", v)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chartmuseum/helm-push/pkg/helm"
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	watchVersionDev       = "dev"
+	watchVersionTimestamp = "timestamp"
+
+	watchTimestampFormat = "20060102150405"
+)
+
+var (
+	// watchDebounce is how long --watch waits for changes to settle
+	// before pushing, so saving several files pushes once
+	watchDebounce = 500 * time.Millisecond
+)
+
+// validateWatchFlags checks the flags given with --watch, which re-pushes
+// a single chart directory
+func (p *pushCmd) validateWatchFlags(chartName string) error {
+	switch p.watchVersion {
+	case watchVersionDev, watchVersionTimestamp:
+	default:
+		return fmt.Errorf("invalid --watch-version value %q (must be one of: %s, %s)", p.watchVersion, watchVersionDev, watchVersionTimestamp)
+	}
+	if !p.watch {
+		if p.watchVersion != watchVersionDev {
+			return errors.New("--watch-version only applies to --watch")
+		}
+		return nil
+	}
+	if p.recursive || p.changedSince != "" || p.withSubcharts {
+		return errors.New("--watch cannot be used with --recursive, --changed-since or --with-subcharts, it watches a single chart")
+	}
+	if p.fromFile != "" {
+		return errors.New("--watch cannot be used with --from-file, it watches a single chart")
+	}
+	if p.bump != "" || p.signature != "" {
+		return errors.New("--watch cannot be used with --bump or --signature, each push gets its own development version")
+	}
+	if !isChartDir(chartName) {
+		return errors.New("--watch needs a chart directory")
+	}
+	return nil
+}
+
+// watchVersion returns the version of the nth push of --watch: a
+// -dev.N or -dev.<timestamp> pre-release of version, so that every push
+// gets a new version instead of a conflict. Build metadata is kept
+func watchVersion(version string, style string, n int, now time.Time) string {
+	id := strconv.Itoa(n)
+	if style == watchVersionTimestamp {
+		id = now.UTC().Format(watchTimestampFormat)
+	}
+	build := ""
+	if i := strings.Index(version, "+"); i >= 0 {
+		version, build = version[:i], version[i:]
+	}
+	sep := "-"
+	if strings.Contains(version, "-") {
+		// already a pre-release, e.g. 1.0.0-rc.1.dev.2
+		sep = "."
+	}
+	return version + sep + "dev." + id + build
+}
+
+// watchIgnored reports whether a change to path should not trigger a
+// push: editor swap and backup files, and with --dependency-update the
+// dependencies it rewrites itself
+func (p *pushCmd) watchIgnored(path string) bool {
+	name := filepath.Base(path)
+	if strings.HasSuffix(name, "~") || strings.HasPrefix(name, ".#") || name == "4913" ||
+		strings.HasSuffix(name, ".swp") || strings.HasSuffix(name, ".swx") || strings.HasSuffix(name, ".tmp") {
+		return true
+	}
+	if !p.dependencyUpdate {
+		return false
+	}
+	rel, err := filepath.Rel(p.chartName, path)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	return rel == "charts" || strings.HasPrefix(rel, "charts/") || rel == "Chart.lock" || rel == "requirements.lock"
+}
+
+// addWatches watches dir and its subdirectories, as fsnotify only
+// reports changes to the files directly in a watched directory
+func (p *pushCmd) addWatches(w *fsnotify.Watcher, dir string) error {
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			return nil
+		}
+		if path != p.chartName && p.watchIgnored(path) {
+			return filepath.SkipDir
+		}
+		return w.Add(path)
+	})
+}
+
+// watchAndPush pushes the chart directory to targets, then again after
+// every change to it until the command's context is cancelled, e.g. by
+// Ctrl-C. Each push gets a new development version. A failed push is
+// reported and the watch goes on. Only repos marked as development repos,
+// with --dev-repo or dev: true in the config file, can be pushed to
+func (p *pushCmd) watchAndPush(targets []*pushCmd) error {
+	for _, t := range targets {
+		if !t.devRepo {
+			return fmt.Errorf("--watch only pushes to development repos, mark %s as one with --dev-repo or dev: true in the config file", t.repoName)
+		}
+		// the development versions are pre-releases
+		t.allowPrerelease = true
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if err := p.addWatches(w, p.chartName); err != nil {
+		return err
+	}
+
+	baseVersion := p.chartVersion
+	n := 0
+	pushOnce := func() {
+		n++
+		version := baseVersion
+		if version == "" {
+			c, err := helm.GetChartByName(p.chartName)
+			if err != nil {
+				p.log.error("", p.repoName, "Failed to push %s: %s", p.chartName, err)
+				return
+			}
+			version = c.Version()
+		}
+		p.chartVersion = watchVersion(version, p.watchVersion, n, time.Now())
+		if err := p.push(targets); err != nil && p.ctx.Err() == nil {
+			p.log.error("", p.repoName, "Failed to push %s: %s", p.chartName, err)
+		}
+		if p.ctx.Err() == nil {
+			p.log.info("", p.repoName, "Watching %s for changes, press Ctrl-C to stop...", p.chartName)
+		}
+	}
+
+	pushOnce()
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-p.ctx.Done():
+			p.log.info("", p.repoName, "Stopped watching %s.", p.chartName)
+			return nil
+		case event, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if p.watchIgnored(event.Name) {
+				continue
+			}
+			if event.Op&fsnotify.Create != 0 {
+				if fi, err := os.Stat(event.Name); err == nil && fi.IsDir() {
+					if err := p.addWatches(w, event.Name); err != nil {
+						p.log.warn("", p.repoName, "failed to watch %s: %s", event.Name, err)
+					}
+				}
+			}
+			settled = time.After(watchDebounce)
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			p.log.warn("", p.repoName, "watch error: %s", err)
+		case <-settled:
+			settled = nil
+			pushOnce()
+		}
+	}
+}
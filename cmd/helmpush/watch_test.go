@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchVersion(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+	for _, tc := range []struct {
+		version, style string
+		expected       string
+	}{
+		{"0.1.0", watchVersionDev, "0.1.0-dev.3"},
+		{"1.0.0-rc.1", watchVersionDev, "1.0.0-rc.1.dev.3"},
+		{"1.0.0+build.7", watchVersionDev, "1.0.0-dev.3+build.7"},
+		{"0.1.0", watchVersionTimestamp, "0.1.0-dev.20260102020405"},
+	} {
+		if actual := watchVersion(tc.version, tc.style, 3, now); actual != tc.expected {
+			t.Errorf("%s %s: expected %s, instead got %s", tc.version, tc.style, tc.expected, actual)
+		}
+	}
+}
+
+func TestValidateWatchFlags(t *testing.T) {
+	for _, tc := range []struct {
+		p         pushCmd
+		chartName string
+		expected  string
+	}{
+		{pushCmd{watchVersion: watchVersionDev}, testTarballPath, ""},
+		{pushCmd{watch: true, watchVersion: watchVersionTimestamp}, "../../testdata/charts/helm3/my-v3-chart", ""},
+		{pushCmd{watch: true, watchVersion: "semver"}, "../../testdata/charts/helm3/my-v3-chart", `invalid --watch-version value "semver" (must be one of: dev, timestamp)`},
+		{pushCmd{watchVersion: watchVersionTimestamp}, testTarballPath, "--watch-version only applies to --watch"},
+		{pushCmd{watch: true, watchVersion: watchVersionDev}, testTarballPath, "--watch needs a chart directory"},
+		{pushCmd{watch: true, watchVersion: watchVersionDev, recursive: true}, "../../testdata/charts", "--watch cannot be used with --recursive, --changed-since or --with-subcharts, it watches a single chart"},
+		{pushCmd{watch: true, watchVersion: watchVersionDev, bump: bumpPatch}, "../../testdata/charts/helm3/my-v3-chart", "--watch cannot be used with --bump or --signature, each push gets its own development version"},
+	} {
+		err := tc.p.validateWatchFlags(tc.chartName)
+		if (err == nil && tc.expected != "") || (err != nil && err.Error() != tc.expected) {
+			t.Errorf("expected error %q, instead got %v", tc.expected, err)
+		}
+	}
+}
+
+func TestPushCmdWatch(t *testing.T) {
+	pushes := make(chan string, 10)
+	failing := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.WriteHeader(404)
+			return
+		}
+		_, fh, err := r.FormFile("chart")
+		if err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		if failing {
+			w.WriteHeader(500)
+			w.Write([]byte(`{"error":"storage unavailable"}`))
+		} else {
+			w.WriteHeader(201)
+			w.Write([]byte("{}"))
+		}
+		pushes <- fh.Filename
+	}))
+	defer ts.Close()
+
+	cleanup := setupTestRepo(t, ts.URL)
+	defer cleanup()
+
+	defer func(d time.Duration) { watchDebounce = d }(watchDebounce)
+	watchDebounce = 50 * time.Millisecond
+
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+	chartDir := filepath.Join(tmp, "mychart")
+	os.MkdirAll(filepath.Join(chartDir, "templates"), 0755)
+	os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("apiVersion: v2\nname: mychart\nversion: 0.1.0\n"), 0644)
+	os.WriteFile(filepath.Join(chartDir, "templates", "cm.yaml"), []byte("kind: ConfigMap\n"), 0644)
+
+	run := func(ctx context.Context, args ...string) (string, error) {
+		args = append([]string{chartDir, "helm-push-test", "--watch"}, args...)
+		cmd := newPushCmd(args)
+		cmd.SetArgs(args)
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+		err := cmd.ExecuteContext(ctx)
+		return out.String(), err
+	}
+	expectPush := func(expected string) {
+		select {
+		case name := <-pushes:
+			if name != expected {
+				t.Errorf("expected push of %s, instead got %s", expected, name)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for push of %s", expected)
+		}
+	}
+
+	if _, err := run(context.Background()); err == nil || !strings.Contains(err.Error(), "--watch only pushes to development repos, mark helm-push-test as one with --dev-repo") {
+		t.Errorf("expected error for a repo not marked as a development repo, instead got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	type result struct {
+		out string
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		out, err := run(ctx, "--dev-repo")
+		done <- result{out, err}
+	}()
+
+	expectPush("mychart-0.1.0-dev.1.tgz")
+	os.WriteFile(filepath.Join(chartDir, "templates", ".cm.yaml.swp"), []byte("swap"), 0644)
+	os.WriteFile(filepath.Join(chartDir, "templates", "cm.yaml"), []byte("kind: ConfigMap\ndata: {}\n"), 0644)
+	os.WriteFile(filepath.Join(chartDir, "values.yaml"), []byte("x: 1\n"), 0644)
+	expectPush("mychart-0.1.0-dev.2.tgz")
+
+	// a failed push doesn't stop the watch
+	failing = true
+	os.WriteFile(filepath.Join(chartDir, "values.yaml"), []byte("x: 2\n"), 0644)
+	expectPush("mychart-0.1.0-dev.3.tgz")
+	time.Sleep(100 * time.Millisecond)
+	failing = false
+	os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("apiVersion: v2\nname: mychart\nversion: 0.2.0\n"), 0644)
+	expectPush("mychart-0.2.0-dev.4.tgz")
+
+	cancel()
+	var r result
+	select {
+	case r = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for --watch to stop")
+	}
+	if r.err != nil {
+		t.Errorf("expected --watch to stop cleanly, instead got %v", r.err)
+	}
+	for _, expected := range []string{"Watching " + chartDir + " for changes", "storage unavailable", "Stopped watching " + chartDir} {
+		if !strings.Contains(r.out, expected) {
+			t.Errorf("expected output to contain %q, instead got:\n%s", expected, r.out)
+		}
+	}
+	select {
+	case name := <-pushes:
+		t.Errorf("unexpected push of %s", name)
+	default:
+	}
+}
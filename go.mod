@@ -1,11 +1,20 @@
 module github.com/chartmuseum/helm-push
 
-go 1.15
+go 1.16
 
 require (
+	cloud.google.com/go v0.46.3
 	github.com/Masterminds/semver v1.5.0 // indirect
+	github.com/Masterminds/semver/v3 v3.1.0
+	github.com/Masterminds/sprig v2.22.0+incompatible // indirect
+	github.com/fsnotify/fsnotify v1.4.7
 	github.com/ghodss/yaml v1.0.0
+	github.com/golang/protobuf v1.3.2
+	github.com/mattn/go-isatty v0.0.4
 	github.com/spf13/cobra v1.1.0
+	github.com/spf13/pflag v1.0.5
+	github.com/xeipuuv/gojsonschema v1.2.0
+	golang.org/x/oauth2 v0.0.0-20190604053449-0f29369cfe45
 	helm.sh/helm/v3 v3.3.4
 	k8s.io/helm v2.16.12+incompatible
 )
@@ -0,0 +1,38 @@
+// Package version holds build information, injected at build time with
+// -ldflags "-X github.com/chartmuseum/helm-push/internal/version.Version=..."
+package version
+
+import (
+	"runtime"
+)
+
+var (
+	// Version is the plugin version
+	Version = "dev"
+
+	// GitCommit is the git commit the plugin was built from
+	GitCommit = "unknown"
+)
+
+type (
+	// BuildInfo describes the running plugin build
+	BuildInfo struct {
+		Version   string `json:"version"`
+		GitCommit string `json:"gitCommit"`
+		GoVersion string `json:"goVersion"`
+	}
+)
+
+// Get returns the build information
+func Get() BuildInfo {
+	return BuildInfo{
+		Version:   Version,
+		GitCommit: GitCommit,
+		GoVersion: runtime.Version(),
+	}
+}
+
+// UserAgent returns the User-Agent sent with HTTP requests
+func UserAgent() string {
+	return "helm-push/" + Version
+}
@@ -0,0 +1,23 @@
+package version
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	Version = "v1.2.3"
+	GitCommit = "abc123"
+	defer func() {
+		Version = "dev"
+		GitCommit = "unknown"
+	}()
+
+	info := Get()
+	if info.Version != "v1.2.3" || info.GitCommit != "abc123" || info.GoVersion != runtime.Version() {
+		t.Errorf("unexpected build info: %+v", info)
+	}
+	if ua := UserAgent(); ua != "helm-push/v1.2.3" {
+		t.Errorf("expected user agent to be helm-push/v1.2.3, instead got %s", ua)
+	}
+}
@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	awsV4Algorithm  = "AWS4-HMAC-SHA256"
+	awsV4TimeFormat = "20060102T150405Z"
+	awsV4DateFormat = "20060102"
+
+	// AWSServiceS3 is the service name of S3, whose requests are signed
+	// slightly differently
+	AWSServiceS3 = "s3"
+)
+
+type (
+	// AWSV4Signer is an http.RoundTripper which signs requests with AWS
+	// Signature Version 4 before passing them on to Transport, for
+	// ChartMuseum servers behind IAM-authenticated endpoints
+	AWSV4Signer struct {
+		AccessKeyID     string
+		SecretAccessKey string
+		// SessionToken is sent as X-Amz-Security-Token for temporary
+		// credentials
+		SessionToken string
+		Region       string
+		// Service is the signing name of the AWS service in front of
+		// ChartMuseum, e.g. execute-api for API Gateway
+		Service string
+		// Transport sends the signed requests, http.DefaultTransport
+		// if nil
+		Transport http.RoundTripper
+
+		// now is the signing time, time.Now if nil
+		now func() time.Time
+	}
+)
+
+// RoundTrip signs a copy of the request and sends it
+func (s *AWSV4Signer) RoundTrip(req *http.Request) (*http.Response, error) {
+	signed, err := s.sign(req)
+	if err != nil {
+		return nil, err
+	}
+	transport := s.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return transport.RoundTrip(signed)
+}
+
+// sign returns a copy of the request with the X-Amz-* headers and the
+// Authorization header set
+func (s *AWSV4Signer) sign(req *http.Request) (*http.Request, error) {
+	payloadHash, body, err := hashBody(req)
+	if err != nil {
+		return nil, err
+	}
+	signed := req.Clone(req.Context())
+	if body != nil {
+		signed.Body = body
+	}
+
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	t := now().UTC()
+	signed.Header.Set("X-Amz-Date", t.Format(awsV4TimeFormat))
+	if s.SessionToken != "" {
+		signed.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+	if s.Service == AWSServiceS3 {
+		signed.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+	signed.Header.Del("Authorization")
+
+	headers, signedHeaders := canonicalHeaders(signed)
+	canonicalRequest := strings.Join([]string{
+		signed.Method,
+		canonicalURI(signed, s.Service != AWSServiceS3),
+		canonicalQuery(signed),
+		headers,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{t.Format(awsV4DateFormat), s.Region, s.Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{awsV4Algorithm, t.Format(awsV4TimeFormat), scope, hexSHA256([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), t.Format(awsV4DateFormat))
+	for _, part := range []string{s.Region, s.Service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	signed.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsV4Algorithm, s.AccessKeyID, scope, signedHeaders, signature))
+	return signed, nil
+}
+
+// hashBody returns the hex SHA-256 of the request body. If the body
+// can't be read again with GetBody, it is read into memory and a new
+// body is returned to send instead
+func hashBody(req *http.Request) (string, io.ReadCloser, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return hexSHA256(nil), nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return "", nil, err
+		}
+		defer body.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, body); err != nil {
+			return "", nil, err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil, nil
+	}
+	b, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", nil, err
+	}
+	return hexSHA256(b), io.NopCloser(bytes.NewReader(b)), nil
+}
+
+// canonicalHeaders returns the canonical headers of the request and the
+// list of signed headers. Only the host and X-Amz-* headers are signed,
+// as proxies may change the others
+func canonicalHeaders(req *http.Request) (string, string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	values := map[string]string{"host": host}
+	for name, v := range req.Header {
+		if name := strings.ToLower(name); strings.HasPrefix(name, "x-amz-") {
+			values[name] = strings.Join(v, ",")
+		}
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + strings.Join(strings.Fields(values[name]), " ") + "\n")
+	}
+	return b.String(), strings.Join(names, ";")
+}
+
+// canonicalURI returns the URI-encoded path of the request. Except for
+// S3, each path segment is encoded twice
+func canonicalURI(req *http.Request, encodeTwice bool) string {
+	p := req.URL.EscapedPath()
+	if p == "" {
+		return "/"
+	}
+	if !encodeTwice {
+		return p
+	}
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery returns the query parameters of the request, encoded and
+// sorted by name and value
+func canonicalQuery(req *http.Request) string {
+	var params []string
+	for name, values := range req.URL.Query() {
+		for _, v := range values {
+			params = append(params, uriEncode(name)+"="+uriEncode(v))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// uriEncode percent-encodes every byte of s except the unreserved
+// characters, as Signature Version 4 requires
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSHA256(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// requests from the AWS Signature Version 4 test suite
+func TestAWSV4SignerTestSuite(t *testing.T) {
+	signer := &AWSV4Signer{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:          "us-east-1",
+		Service:         "service",
+		now: func() time.Time {
+			return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+		},
+	}
+	for _, tc := range []struct {
+		name      string
+		method    string
+		url       string
+		signature string
+	}{
+		{"get-vanilla", "GET", "https://example.amazonaws.com/", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-vanilla-query-order-key-case", "GET", "https://example.amazonaws.com/?Param2=value2&Param1=value1", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+		{"post-vanilla", "POST", "https://example.amazonaws.com/", "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b"},
+	} {
+		req, _ := http.NewRequest(tc.method, tc.url, nil)
+		signed, err := signer.sign(req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error signing request: %s", tc.name, err)
+		}
+		expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=" + tc.signature
+		if auth := signed.Header.Get("Authorization"); auth != expected {
+			t.Errorf("%s: expected %s, instead got %s", tc.name, expected, auth)
+		}
+		if signed.Header.Get("X-Amz-Date") != "20150830T123600Z" {
+			t.Errorf("%s: unexpected X-Amz-Date %s", tc.name, signed.Header.Get("X-Amz-Date"))
+		}
+		if req.Header.Get("Authorization") != "" {
+			t.Errorf("%s: expected the original request to be left unsigned", tc.name)
+		}
+	}
+}
+
+func TestAWSV4SignerRoundTrip(t *testing.T) {
+	var auth, token, contentHash, body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		token = r.Header.Get("X-Amz-Security-Token")
+		contentHash = r.Header.Get("X-Amz-Content-Sha256")
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(201)
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: &AWSV4Signer{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+		SessionToken:    "session",
+		Region:          "eu-west-1",
+		Service:         AWSServiceS3,
+	}}
+
+	// a body which can't be read again is buffered to be hashed
+	req, _ := http.NewRequest("POST", ts.URL+"/api/charts?force", io.NopCloser(strings.NewReader("chart")))
+	req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal("unexpected error sending signed request", err)
+	}
+	resp.Body.Close()
+
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token, Signature=") {
+		t.Errorf("unexpected Authorization header %s", auth)
+	}
+	if token != "session" || body != "chart" {
+		t.Errorf("unexpected security token %q or body %q", token, body)
+	}
+	if contentHash != hexSHA256([]byte("chart")) {
+		t.Errorf("expected S3 request to have its payload hash, instead got %s", contentHash)
+	}
+}
+
+func TestURIEncode(t *testing.T) {
+	for s, expected := range map[string]string{
+		"mychart-0.1.0.tgz": "mychart-0.1.0.tgz",
+		"a b/c":             "a%20b%2Fc",
+		"%2F":               "%252F",
+		"~_.":               "~_.",
+	} {
+		if actual := uriEncode(s); actual != expected {
+			t.Errorf("%s: expected %s, instead got %s", s, expected, actual)
+		}
+	}
+}
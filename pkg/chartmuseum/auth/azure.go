@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// AzureIMDSEndpoint is the token endpoint of the Azure Instance Metadata
+// Service, reached from VMs and AKS pods
+const AzureIMDSEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+type (
+	// AzureManagedIdentity is an oauth2.TokenSource of access tokens for
+	// an Azure managed identity, fetched from the Instance Metadata
+	// Service without any secret
+	AzureManagedIdentity struct {
+		// Resource is the audience of the tokens, e.g. the application
+		// ID URI of ChartMuseum
+		Resource string
+		// ClientID selects a user-assigned identity, the system-assigned
+		// identity is used if empty
+		ClientID string
+		// Endpoint is the token endpoint, AzureIMDSEndpoint if empty
+		Endpoint string
+		// Client sends the token requests if set. By default they
+		// bypass any proxy, as the metadata service is only reachable
+		// directly
+		Client *http.Client
+
+		ctx context.Context
+	}
+
+	azureTokenResponse struct {
+		AccessToken      string      `json:"access_token"`
+		TokenType        string      `json:"token_type"`
+		ExpiresOn        json.Number `json:"expires_on"`
+		Error            string      `json:"error"`
+		ErrorDescription string      `json:"error_description"`
+	}
+)
+
+// NewAzureTokenSource returns a source of access tokens for resource from
+// the managed identity of the VM or pod, the user-assigned one with
+// clientID if given. $AZURE_POD_IDENTITY_AUTHORITY_HOST overrides the
+// metadata service host, as in the Azure SDKs. Tokens are cached, in the
+// cacheFile if given, and refreshed before they expire
+func NewAzureTokenSource(ctx context.Context, resource string, clientID string, cacheFile string) oauth2.TokenSource {
+	identity := &AzureManagedIdentity{Resource: resource, ClientID: clientID, ctx: ctx}
+	if host := os.Getenv("AZURE_POD_IDENTITY_AUTHORITY_HOST"); host != "" {
+		identity.Endpoint = strings.TrimSuffix(host, "/") + "/metadata/identity/oauth2/token"
+	}
+	endpoint := identity.Endpoint
+	if endpoint == "" {
+		endpoint = AzureIMDSEndpoint
+	}
+	return NewCachedTokenSource(cacheFile, TokenCacheKey(endpoint, clientID, resource), identity)
+}
+
+// Token fetches a new access token from the metadata service
+func (a *AzureManagedIdentity) Token() (*oauth2.Token, error) {
+	endpoint := a.Endpoint
+	if endpoint == "" {
+		endpoint = AzureIMDSEndpoint
+	}
+	q := url.Values{"api-version": {"2018-02-01"}, "resource": {a.Resource}}
+	if a.ClientID != "" {
+		q.Set("client_id", a.ClientID)
+	}
+	ctx := a.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata", "true")
+
+	client := a.Client
+	if client == nil {
+		client = &http.Client{Transport: &http.Transport{}, Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Azure managed identity token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var r azureTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("failed to get Azure managed identity token: %d: invalid response: %s", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || r.AccessToken == "" {
+		return nil, fmt.Errorf("failed to get Azure managed identity token: %d: %s %s", resp.StatusCode, r.Error, r.ErrorDescription)
+	}
+
+	token := &oauth2.Token{AccessToken: r.AccessToken, TokenType: r.TokenType}
+	if expiresOn, err := strconv.ParseInt(r.ExpiresOn.String(), 10, 64); err == nil {
+		token.Expiry = time.Unix(expiresOn, 0)
+	}
+	return token, nil
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAzureManagedIdentity(t *testing.T) {
+	var query, metadata string
+	expiresOn := time.Now().Add(time.Hour).Unix()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		metadata = r.Header.Get("Metadata")
+		if r.URL.Query().Get("client_id") == "unknown" {
+			w.WriteHeader(400)
+			w.Write([]byte(`{"error":"invalid_request","error_description":"Identity not found"}`))
+			return
+		}
+		w.Write([]byte(`{"access_token":"eyJ0eXAi","token_type":"Bearer","expires_on":"` + strconv.FormatInt(expiresOn, 10) + `","resource":"api://chartmuseum"}`))
+	}))
+	defer ts.Close()
+
+	identity := &AzureManagedIdentity{Resource: "api://chartmuseum", Endpoint: ts.URL}
+	token, err := identity.Token()
+	if err != nil {
+		t.Fatal("unexpected error getting token", err)
+	}
+	if token.AccessToken != "eyJ0eXAi" || token.Expiry.Unix() != expiresOn {
+		t.Errorf("unexpected token %+v", token)
+	}
+	if query != "api-version=2018-02-01&resource=api%3A%2F%2Fchartmuseum" || metadata != "true" {
+		t.Errorf("unexpected token request %s (Metadata: %q)", query, metadata)
+	}
+
+	identity.ClientID = "unknown"
+	if _, err := identity.Token(); err == nil || !strings.Contains(err.Error(), "400: invalid_request Identity not found") {
+		t.Errorf("expected error for unknown identity, instead got %v", err)
+	}
+	if !strings.Contains(query, "client_id=unknown") {
+		t.Errorf("expected user-assigned identity client ID in request, instead got %s", query)
+	}
+}
+
+func TestNewAzureTokenSource(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/metadata/identity/oauth2/token" {
+			w.WriteHeader(404)
+			return
+		}
+		w.Write([]byte(`{"access_token":"eyJ0eXAi","token_type":"Bearer","expires_on":"` + strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10) + `"}`))
+	}))
+	defer ts.Close()
+
+	os.Setenv("AZURE_POD_IDENTITY_AUTHORITY_HOST", ts.URL+"/")
+	defer os.Unsetenv("AZURE_POD_IDENTITY_AUTHORITY_HOST")
+
+	tokens := NewAzureTokenSource(context.Background(), "api://chartmuseum", "", "")
+	for i := 0; i < 2; i++ {
+		if _, err := tokens.Token(); err != nil {
+			t.Fatal("unexpected error getting token", err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected the token to be reused, instead got %d requests", requests)
+	}
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// TokenCacheRefresh is how long before they expire cached tokens are
+// refreshed, so a push doesn't start with a token about to expire
+const TokenCacheRefresh = 60 * time.Second
+
+type (
+	// CachedTokenSource is an oauth2.TokenSource which keeps the tokens
+	// of Source in a file, so that they are reused by the next helm push
+	// runs until they are about to expire instead of requested again
+	CachedTokenSource struct {
+		// Path is the cache file, e.g. ~/.config/helm-push/tokens.json
+		Path string
+		// Key identifies the tokens of Source in the cache, see
+		// TokenCacheKey
+		Key    string
+		Source oauth2.TokenSource
+
+		// now is the current time, time.Now if nil
+		now func() time.Time
+	}
+
+	cachedToken struct {
+		AccessToken string    `json:"access_token"`
+		TokenType   string    `json:"token_type,omitempty"`
+		Expiry      time.Time `json:"expiry"`
+	}
+)
+
+// the cache file is shared by all the token sources of the process
+var tokenCacheMu sync.Mutex
+
+// TokenCacheKey returns the cache key of the tokens issued by issuer to
+// clientID for audience: a hash, so the file doesn't list who
+// authenticates where
+func TokenCacheKey(issuer string, clientID string, audience string) string {
+	sum := sha256.Sum256([]byte(issuer + "\n" + clientID + "\n" + audience))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewCachedTokenSource returns a source of the tokens of src, cached in
+// the file at path, or only in memory if path is empty
+func NewCachedTokenSource(path string, key string, src oauth2.TokenSource) oauth2.TokenSource {
+	if path == "" {
+		return oauth2.ReuseTokenSource(nil, src)
+	}
+	return oauth2.ReuseTokenSource(nil, &CachedTokenSource{Path: path, Key: key, Source: src})
+}
+
+// Token returns the cached token if it doesn't expire in the next
+// TokenCacheRefresh, otherwise gets a new one from Source and caches it.
+// Tokens without expiry are never cached. A cache file which can't be
+// read or written is ignored, as it only saves token requests
+func (c *CachedTokenSource) Token() (*oauth2.Token, error) {
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+	tokenCacheMu.Lock()
+	defer tokenCacheMu.Unlock()
+
+	tokens := readTokenCache(c.Path)
+	if t, ok := tokens[c.Key]; ok && t.AccessToken != "" && t.Expiry.After(now().Add(TokenCacheRefresh)) {
+		return &oauth2.Token{AccessToken: t.AccessToken, TokenType: t.TokenType, Expiry: t.Expiry}, nil
+	}
+
+	token, err := c.Source.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token.Expiry.IsZero() {
+		return token, nil
+	}
+	for key, t := range tokens {
+		if !t.Expiry.After(now()) {
+			delete(tokens, key)
+		}
+	}
+	tokens[c.Key] = cachedToken{AccessToken: token.AccessToken, TokenType: token.TokenType, Expiry: token.Expiry}
+	writeTokenCache(c.Path, tokens)
+	return token, nil
+}
+
+func readTokenCache(path string) map[string]cachedToken {
+	tokens := map[string]cachedToken{}
+	if b, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(b, &tokens); err != nil {
+			return map[string]cachedToken{}
+		}
+	}
+	return tokens
+}
+
+// writeTokenCache replaces the cache file, readable only by the user
+// (as made by os.CreateTemp). The tokens are written to a temporary file
+// first so that concurrent runs never read half a file
+func writeTokenCache(path string, tokens map[string]cachedToken) {
+	b, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".tokens-")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
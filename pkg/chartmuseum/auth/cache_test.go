@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+type countingTokenSource struct {
+	tokens []*oauth2.Token
+	err    error
+	calls  int
+}
+
+func (s *countingTokenSource) Token() (*oauth2.Token, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.tokens[s.calls-1], nil
+}
+
+func TestCachedTokenSource(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+	path := filepath.Join(tmp, "helm-push", "tokens.json")
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	src := &countingTokenSource{tokens: []*oauth2.Token{
+		{AccessToken: "first", TokenType: "Bearer", Expiry: now.Add(time.Hour)},
+		{AccessToken: "second", TokenType: "Bearer", Expiry: now.Add(2 * time.Hour)},
+		{AccessToken: "no-expiry"},
+	}}
+	key := TokenCacheKey("https://oauth2.example.com/token", "helm-push", "api://chartmuseum")
+	cached := func() *CachedTokenSource {
+		return &CachedTokenSource{Path: path, Key: key, Source: src, now: func() time.Time { return now }}
+	}
+
+	token, err := cached().Token()
+	if err != nil || token.AccessToken != "first" {
+		t.Fatalf("expected a new token, instead got %v %v", token, err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("expected cache file readable only by the user, instead got %v %v", fi, err)
+	}
+
+	// a later run reuses the token until it is about to expire
+	now = now.Add(58 * time.Minute)
+	if token, err := cached().Token(); err != nil || token.AccessToken != "first" || src.calls != 1 {
+		t.Errorf("expected the cached token, instead got %v %v after %d token requests", token, err, src.calls)
+	}
+	now = now.Add(time.Minute + time.Second)
+	if token, err := cached().Token(); err != nil || token.AccessToken != "second" || src.calls != 2 {
+		t.Errorf("expected a refreshed token, instead got %v %v after %d token requests", token, err, src.calls)
+	}
+
+	// other clients don't share the token, and tokens without expiry
+	// aren't cached
+	other := cached()
+	other.Key = TokenCacheKey("https://oauth2.example.com/token", "other", "api://chartmuseum")
+	if token, err := other.Token(); err != nil || token.AccessToken != "no-expiry" || src.calls != 3 {
+		t.Errorf("expected a new token for another client, instead got %v %v after %d token requests", token, err, src.calls)
+	}
+	b, _ := os.ReadFile(path)
+	var tokens map[string]cachedToken
+	if err := json.Unmarshal(b, &tokens); err != nil || len(tokens) != 1 || tokens[key].AccessToken != "second" {
+		t.Errorf("unexpected cache file %s (%v)", b, err)
+	}
+
+	// a corrupt cache file is replaced
+	os.WriteFile(path, []byte("{"), 0600)
+	src.calls = 1
+	if token, err := cached().Token(); err != nil || token.AccessToken != "second" {
+		t.Errorf("expected a new token with a corrupt cache, instead got %v %v", token, err)
+	}
+
+	src.err = errors.New("token endpoint unavailable")
+	src.calls = 0
+	os.Remove(path)
+	if _, err := cached().Token(); err != src.err {
+		t.Errorf("expected token source error, instead got %v", err)
+	}
+}
+
+func TestTokenCacheKey(t *testing.T) {
+	key := TokenCacheKey("https://oauth2.example.com/token", "helm-push", "api://chartmuseum")
+	if len(key) != 64 || key == TokenCacheKey("https://oauth2.example.com/token", "helm-push", "api://other") {
+		t.Errorf("unexpected cache key %s", key)
+	}
+}
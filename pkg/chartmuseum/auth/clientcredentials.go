@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+type (
+	// clientCredentialsTokens is an oauth2.TokenSource of access tokens
+	// from the OAuth2 client credentials grant, with the errors of the
+	// token endpoint turned into readable ones
+	clientCredentialsTokens struct {
+		tokenURL string
+		src      oauth2.TokenSource
+	}
+
+	// OAuthError is returned when the token endpoint refuses to issue a
+	// token, with the error and description of its response (RFC 6749,
+	// section 5.2), or its status and body if it isn't an OAuth error
+	OAuthError struct {
+		TokenURL    string
+		Code        string
+		Description string
+	}
+
+	oauthErrorResponse struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+)
+
+// NewClientCredentialsTokenSource returns a source of access tokens for
+// the client clientID from the token endpoint at tokenURL, e.g. of a
+// Keycloak realm, with the client credentials grant. Tokens are only
+// cached in memory, for the run, and requested again when they expire
+func NewClientCredentialsTokenSource(ctx context.Context, tokenURL string, clientID string, clientSecret string, scopes []string) oauth2.TokenSource {
+	conf := &clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenURL:     tokenURL,
+		Scopes:       scopes,
+	}
+	return oauth2.ReuseTokenSource(nil, &clientCredentialsTokens{tokenURL: tokenURL, src: conf.TokenSource(ctx)})
+}
+
+// Token requests a new access token
+func (c *clientCredentialsTokens) Token() (*oauth2.Token, error) {
+	token, err := c.src.Token()
+	if err == nil {
+		return token, nil
+	}
+	var rErr *oauth2.RetrieveError
+	if !errors.As(err, &rErr) {
+		return nil, fmt.Errorf("OAuth token request to %s failed: %w", c.tokenURL, err)
+	}
+	var r oauthErrorResponse
+	if json.Unmarshal(rErr.Body, &r) == nil && r.Error != "" {
+		return nil, &OAuthError{TokenURL: c.tokenURL, Code: r.Error, Description: r.ErrorDescription}
+	}
+	return nil, &OAuthError{TokenURL: c.tokenURL, Code: rErr.Response.Status, Description: strings.TrimSpace(string(rErr.Body))}
+}
+
+func (e *OAuthError) Error() string {
+	if e.Description == "" {
+		return fmt.Sprintf("OAuth token request to %s failed: %s", e.TokenURL, e.Code)
+	}
+	return fmt.Sprintf("OAuth token request to %s failed: %s: %s", e.TokenURL, e.Code, e.Description)
+}
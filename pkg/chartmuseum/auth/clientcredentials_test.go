@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientCredentialsTokenSource(t *testing.T) {
+	var requests int
+	var scope, clientID string
+	var fail bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		r.ParseForm()
+		scope = r.PostForm.Get("scope")
+		clientID, _, _ = r.BasicAuth()
+		w.Header().Set("Content-Type", "application/json")
+		if fail {
+			w.WriteHeader(401)
+			w.Write([]byte(`{"error":"unauthorized_client","error_description":"Invalid client secret"}`))
+			return
+		}
+		if r.PostForm.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(400)
+			return
+		}
+		w.Write([]byte(`{"access_token":"eyJ.cc","token_type":"Bearer","expires_in":300}`))
+	}))
+	defer ts.Close()
+
+	tokens := NewClientCredentialsTokenSource(context.Background(), ts.URL, "helm-push", "s3cr3t", []string{"charts:write", "charts:read"})
+	for i := 0; i < 2; i++ {
+		token, err := tokens.Token()
+		if err != nil {
+			t.Fatal("unexpected error getting token", err)
+		}
+		if token.AccessToken != "eyJ.cc" {
+			t.Errorf("unexpected access token %s", token.AccessToken)
+		}
+	}
+	// the token is reused until it expires
+	if requests != 1 || scope != "charts:write charts:read" || clientID != "helm-push" {
+		t.Errorf("expected one token request for helm-push with the scopes, instead got %d requests for %q with %q", requests, clientID, scope)
+	}
+
+	fail = true
+	_, err := NewClientCredentialsTokenSource(context.Background(), ts.URL, "helm-push", "wrong", nil).Token()
+	var oauthErr *OAuthError
+	if !errors.As(err, &oauthErr) || err.Error() != "OAuth token request to "+ts.URL+" failed: unauthorized_client: Invalid client secret" {
+		t.Errorf("expected the OAuth error description, instead got %v", err)
+	}
+}
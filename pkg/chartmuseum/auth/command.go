@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+type (
+	// TokenCommand is an oauth2.TokenSource of access tokens printed by
+	// a shell command, e.g. vault kv get or gcloud auth
+	// print-identity-token. The command is run for every token, so each
+	// request gets a fresh one
+	TokenCommand struct {
+		// Command is run with sh -c (cmd /C on Windows)
+		Command string
+
+		ctx context.Context
+	}
+
+	// TokenCommandError is returned when the token command fails. It
+	// has the command's stderr, but never the command itself or its
+	// output, which may hold credentials
+	TokenCommandError struct {
+		Err    error
+		Stderr string
+	}
+)
+
+// NewCommandTokenSource returns a source of access tokens read from the
+// output of command, run each time a token is needed. Tokens are not
+// cached, since they can't be told apart from each other
+func NewCommandTokenSource(ctx context.Context, command string) oauth2.TokenSource {
+	return &TokenCommand{Command: command, ctx: ctx}
+}
+
+// Token runs the command, returning its trimmed stdout as the token
+func (c *TokenCommand) Token() (*oauth2.Token, error) {
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", c.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", c.Command)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, &TokenCommandError{Err: err, Stderr: strings.TrimSpace(stderr.String())}
+	}
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return nil, &TokenCommandError{Err: errors.New("no token printed"), Stderr: strings.TrimSpace(stderr.String())}
+	}
+	return &oauth2.Token{AccessToken: token, TokenType: "Bearer"}, nil
+}
+
+func (e *TokenCommandError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("token command failed: %s", e.Err)
+	}
+	return fmt.Sprintf("token command failed: %s: %s", e.Err, e.Stderr)
+}
+
+func (e *TokenCommandError) Unwrap() error {
+	return e.Err
+}
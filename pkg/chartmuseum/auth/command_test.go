@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTokenCommand(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+	counter := filepath.Join(tmp, "counter")
+
+	// each token is new, as the command runs every time
+	ts := NewCommandTokenSource(context.Background(), "echo x >> "+counter+"; echo \"  token-$(wc -l < "+counter+" | tr -d ' ')\"")
+	for _, expected := range []string{"token-1", "token-2"} {
+		token, err := ts.Token()
+		if err != nil {
+			t.Fatal("unexpected error getting token", err)
+		}
+		if token.AccessToken != expected || token.TokenType != "Bearer" {
+			t.Errorf("expected token %s, instead got %+v", expected, token)
+		}
+	}
+
+	_, err = NewCommandTokenSource(context.Background(), "echo s3cr3t; echo 'permission denied' >&2; exit 3").Token()
+	var cmdErr *TokenCommandError
+	if !errors.As(err, &cmdErr) || err.Error() != "token command failed: exit status 3: permission denied" {
+		t.Errorf("expected error with the command's stderr, instead got %v", err)
+	}
+	if strings.Contains(err.Error(), "s3cr3t") || strings.Contains(err.Error(), "echo") {
+		t.Errorf("expected neither the command nor its output in the error, instead got %v", err)
+	}
+
+	if _, err := NewCommandTokenSource(context.Background(), "true").Token(); err == nil || err.Error() != "token command failed: no token printed" {
+		t.Errorf("expected error for no token, instead got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewCommandTokenSource(ctx, "echo token").Token(); err == nil {
+		t.Error("expected error with a cancelled context")
+	}
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// GCPScope is the OAuth2 scope of the access tokens requested for a GCP
+// service account
+const GCPScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// NewGCPTokenSource returns a source of OAuth2 access tokens for the
+// service account in the given JSON key file, for ChartMuseum servers
+// behind IAP or Cloud Endpoints. Tokens are cached, in the cacheFile
+// if given, and refreshed before they expire
+func NewGCPTokenSource(ctx context.Context, serviceAccountFile string, cacheFile string) (oauth2.TokenSource, error) {
+	b, err := os.ReadFile(serviceAccountFile)
+	if err != nil {
+		return nil, err
+	}
+	conf, err := google.JWTConfigFromJSON(b, GCPScope)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GCP service account file %s: %s", serviceAccountFile, err)
+	}
+	key := TokenCacheKey(conf.TokenURL, conf.Email, GCPScope)
+	return NewCachedTokenSource(cacheFile, key, conf.TokenSource(ctx)), nil
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewGCPTokenSource(t *testing.T) {
+	var requests int
+	var assertion string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		r.ParseForm()
+		assertion = r.PostForm.Get("assertion")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"ya29.token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer ts.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("unexpected error generating key", err)
+	}
+	b, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "helm-push@project.iam.gserviceaccount.com",
+		"private_key_id": "1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		"token_uri":      ts.URL,
+	})
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+	keyFile := filepath.Join(tmp, "sa.json")
+	os.WriteFile(keyFile, b, 0600)
+
+	tokens, err := NewGCPTokenSource(context.Background(), keyFile, "")
+	if err != nil {
+		t.Fatal("unexpected error creating token source", err)
+	}
+	for i := 0; i < 2; i++ {
+		token, err := tokens.Token()
+		if err != nil {
+			t.Fatal("unexpected error getting token", err)
+		}
+		if token.AccessToken != "ya29.token" {
+			t.Errorf("unexpected access token %s", token.AccessToken)
+		}
+	}
+	if requests != 1 || strings.Count(assertion, ".") != 2 {
+		t.Errorf("expected one token request with a JWT assertion, instead got %d requests with %q", requests, assertion)
+	}
+
+	os.WriteFile(keyFile, []byte(`{"type":"authorized_user"}`), 0600)
+	if _, err := NewGCPTokenSource(context.Background(), keyFile, ""); err == nil || !strings.Contains(err.Error(), "invalid GCP service account file") {
+		t.Errorf("expected invalid service account error, instead got %v", err)
+	}
+	if _, err := NewGCPTokenSource(context.Background(), filepath.Join(tmp, "missing.json"), ""); err == nil {
+		t.Error("expected error for missing file, instead got nil")
+	}
+}
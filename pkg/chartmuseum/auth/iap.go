@@ -0,0 +1,277 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"golang.org/x/oauth2/jws"
+)
+
+// googleJWTBearerGrant is the grant type of a self-signed service account
+// assertion exchanged for a Google-signed token
+const googleJWTBearerGrant = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+// errNoGoogleCredentials is returned without Application Default
+// Credentials
+var errNoGoogleCredentials = errors.New("no Google Application Default Credentials found, run `gcloud auth application-default login --impersonate-service-account SERVICE_ACCOUNT` or set GOOGLE_APPLICATION_CREDENTIALS to a service account key file")
+
+type (
+	// googleServiceAccountIDTokens mints ID tokens with the key of a
+	// service account, exchanging a self-signed assertion with a
+	// target_audience for a Google-signed ID token
+	googleServiceAccountIDTokens struct {
+		audience string
+		email    string
+		keyID    string
+		key      *rsa.PrivateKey
+		tokenURL string
+		ctx      context.Context
+	}
+
+	// googleImpersonatedIDTokens mints ID tokens for an impersonated
+	// service account with the IAM Credentials API, authenticated with
+	// the access tokens of the source credentials
+	googleImpersonatedIDTokens struct {
+		audience string
+		url      string
+		source   oauth2.TokenSource
+		ctx      context.Context
+	}
+
+	// googleMetadataIDTokens gets ID tokens for the service account of the
+	// GCE VM, GKE pod or Cloud Run service from the metadata server
+	googleMetadataIDTokens struct {
+		audience string
+	}
+
+	googleCredentialsFile struct {
+		Type                           string          `json:"type"`
+		ServiceAccountImpersonationURL string          `json:"service_account_impersonation_url"`
+		SourceCredentials              json.RawMessage `json:"source_credentials"`
+	}
+)
+
+// NewGoogleIDTokenSource returns a source of OIDC ID tokens for audience,
+// e.g. the OAuth client ID of an Identity-Aware Proxy, minted with the
+// Application Default Credentials: a service account key file, an
+// impersonated service account or, on Google Cloud, the metadata server.
+// The ID tokens are returned as access tokens, to be sent as bearer
+// tokens. Tokens are cached, in the cacheFile if given, and refreshed
+// before they expire
+func NewGoogleIDTokenSource(ctx context.Context, audience string, cacheFile string) (oauth2.TokenSource, error) {
+	b, err := readGoogleCredentialsFile()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", errNoGoogleCredentials, err)
+	}
+	if b == nil {
+		if !metadata.OnGCE() {
+			return nil, errNoGoogleCredentials
+		}
+		email, _ := metadata.Email("default")
+		src := &googleMetadataIDTokens{audience: audience}
+		return NewCachedTokenSource(cacheFile, TokenCacheKey("metadata", email, audience), src), nil
+	}
+
+	var f googleCredentialsFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("invalid Google Application Default Credentials: %s", err)
+	}
+	switch f.Type {
+	case "service_account":
+		conf, err := google.JWTConfigFromJSON(b)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Google Application Default Credentials: %s", err)
+		}
+		key, err := parseRSAKey(conf.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Google Application Default Credentials: %s", err)
+		}
+		src := &googleServiceAccountIDTokens{
+			audience: audience,
+			email:    conf.Email,
+			keyID:    conf.PrivateKeyID,
+			key:      key,
+			tokenURL: conf.TokenURL,
+			ctx:      ctx,
+		}
+		return NewCachedTokenSource(cacheFile, TokenCacheKey(conf.TokenURL, conf.Email, audience), src), nil
+	case "impersonated_service_account":
+		source, err := google.CredentialsFromJSON(ctx, f.SourceCredentials, GCPScope)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source credentials of the impersonated service account: %s", err)
+		}
+		u := strings.Replace(f.ServiceAccountImpersonationURL, ":generateAccessToken", ":generateIdToken", 1)
+		if !strings.HasSuffix(u, ":generateIdToken") {
+			return nil, fmt.Errorf("invalid service account impersonation URL %q", f.ServiceAccountImpersonationURL)
+		}
+		src := &googleImpersonatedIDTokens{audience: audience, url: u, source: source.TokenSource, ctx: ctx}
+		return NewCachedTokenSource(cacheFile, TokenCacheKey(u, "", audience), src), nil
+	case "authorized_user":
+		return nil, errors.New("Google Application Default Credentials of a user can't get ID tokens for IAP, run `gcloud auth application-default login --impersonate-service-account SERVICE_ACCOUNT` or set GOOGLE_APPLICATION_CREDENTIALS to a service account key file")
+	default:
+		return nil, fmt.Errorf("unsupported Google Application Default Credentials type %q", f.Type)
+	}
+}
+
+// readGoogleCredentialsFile reads the Application Default Credentials
+// file: $GOOGLE_APPLICATION_CREDENTIALS if set, otherwise the one written
+// by gcloud auth application-default login, if any. The file is read
+// here rather than with google.FindDefaultCredentials, which doesn't know
+// impersonated service accounts
+func readGoogleCredentialsFile() ([]byte, error) {
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		return os.ReadFile(path)
+	}
+	var dir string
+	if runtime.GOOS == "windows" {
+		dir = filepath.Join(os.Getenv("APPDATA"), "gcloud")
+	} else if home, err := os.UserHomeDir(); err == nil {
+		dir = filepath.Join(home, ".config", "gcloud")
+	}
+	if v := os.Getenv("CLOUDSDK_CONFIG"); v != "" {
+		dir = v
+	}
+	if dir == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "application_default_credentials.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return b, err
+}
+
+// Token exchanges a self-signed assertion for an ID token
+func (s *googleServiceAccountIDTokens) Token() (*oauth2.Token, error) {
+	now := time.Now()
+	claims := &jws.ClaimSet{
+		Iss:           s.email,
+		Aud:           s.tokenURL,
+		Iat:           now.Unix(),
+		Exp:           now.Add(time.Hour).Unix(),
+		PrivateClaims: map[string]interface{}{"target_audience": s.audience},
+	}
+	assertion, err := jws.Encode(&jws.Header{Algorithm: "RS256", Typ: "JWT", KeyID: s.keyID}, claims, s.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Google ID token: %s", err)
+	}
+	form := url.Values{"grant_type": {googleJWTBearerGrant}, "assertion": {assertion}}
+	req, err := http.NewRequestWithContext(contextOrBackground(s.ctx), "POST", s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var r struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := doGoogleTokenRequest(req, &r); err != nil {
+		return nil, err
+	}
+	return idToken(r.IDToken)
+}
+
+// Token gets an ID token for the impersonated service account
+func (s *googleImpersonatedIDTokens) Token() (*oauth2.Token, error) {
+	source, err := s.source.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Google ID token: %w", err)
+	}
+	b, _ := json.Marshal(map[string]interface{}{"audience": s.audience, "includeEmail": true})
+	req, err := http.NewRequestWithContext(contextOrBackground(s.ctx), "POST", s.url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	source.SetAuthHeader(req)
+	var r struct {
+		Token string `json:"token"`
+	}
+	if err := doGoogleTokenRequest(req, &r); err != nil {
+		return nil, err
+	}
+	return idToken(r.Token)
+}
+
+// Token gets an ID token from the metadata server
+func (s *googleMetadataIDTokens) Token() (*oauth2.Token, error) {
+	raw, err := metadata.Get("instance/service-accounts/default/identity?audience=" + url.QueryEscape(s.audience) + "&format=full")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Google ID token from the metadata server: %w", err)
+	}
+	return idToken(strings.TrimSpace(raw))
+}
+
+// doGoogleTokenRequest sends a token request, decoding the JSON response
+// into v
+func doGoogleTokenRequest(req *http.Request, v interface{}) error {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to get Google ID token: %w", err)
+	}
+	defer resp.Body.Close()
+	var b bytes.Buffer
+	b.ReadFrom(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get Google ID token: %d: %s", resp.StatusCode, strings.TrimSpace(b.String()))
+	}
+	if err := json.Unmarshal(b.Bytes(), v); err != nil {
+		return fmt.Errorf("failed to get Google ID token: %d: invalid response: %s", resp.StatusCode, err)
+	}
+	return nil
+}
+
+// idToken returns an ID token as a bearer token, expiring when the ID
+// token does
+func idToken(raw string) (*oauth2.Token, error) {
+	if raw == "" {
+		return nil, errors.New("failed to get Google ID token: no token in response")
+	}
+	claims, err := jws.Decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Google ID token: invalid token: %s", err)
+	}
+	return &oauth2.Token{AccessToken: raw, TokenType: "Bearer", Expiry: time.Unix(claims.Exp, 0)}, nil
+}
+
+// parseRSAKey parses the PEM-encoded PKCS #8 or PKCS #1 private key of a
+// service account
+func parseRSAKey(b []byte) (*rsa.PrivateKey, error) {
+	if block, _ := pem.Decode(b); block != nil {
+		b = block.Bytes
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(b); err == nil {
+		if rsaKey, ok := key.(*rsa.PrivateKey); ok {
+			return rsaKey, nil
+		}
+		return nil, errors.New("private key is not an RSA key")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %s", err)
+	}
+	return key, nil
+}
+
+func contextOrBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testIDToken returns an unsigned JWT for audience, expiring in an hour
+func testIDToken(audience string) string {
+	enc := base64.RawURLEncoding.EncodeToString
+	claims := fmt.Sprintf(`{"aud":%q,"exp":%d}`, audience, time.Now().Add(time.Hour).Unix())
+	return enc([]byte(`{"alg":"RS256"}`)) + "." + enc([]byte(claims)) + ".sig"
+}
+
+func TestNewGoogleIDTokenSource(t *testing.T) {
+	const audience = "123-abc.apps.googleusercontent.com"
+	var requests int
+	var targetAudience, impersonationAuth string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/token":
+			r.ParseForm()
+			parts := strings.Split(r.PostForm.Get("assertion"), ".")
+			var claims map[string]interface{}
+			if len(parts) == 3 {
+				b, _ := base64.RawURLEncoding.DecodeString(parts[1])
+				json.Unmarshal(b, &claims)
+			}
+			if claims["scope"] != nil {
+				// an access token for the impersonation source
+				w.Write([]byte(`{"access_token":"ya29.source","token_type":"Bearer","expires_in":3600}`))
+				return
+			}
+			targetAudience, _ = claims["target_audience"].(string)
+			w.Write([]byte(`{"id_token":"` + testIDToken(targetAudience) + `"}`))
+		case "/v1/projects/-/serviceAccounts/chartmuseum@project.iam.gserviceaccount.com:generateIdToken":
+			impersonationAuth = r.Header.Get("Authorization")
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			w.Write([]byte(`{"token":"` + testIDToken(body["audience"].(string)) + `"}`))
+		case "/computeMetadata/v1/instance/service-accounts/default/email":
+			w.Write([]byte("vm@project.iam.gserviceaccount.com"))
+		case "/computeMetadata/v1/instance/service-accounts/default/identity":
+			if r.Header.Get("Metadata-Flavor") != "Google" || r.URL.Query().Get("format") != "full" {
+				w.WriteHeader(400)
+				return
+			}
+			w.Write([]byte(testIDToken(r.URL.Query().Get("audience"))))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("unexpected error generating key", err)
+	}
+	serviceAccount := map[string]string{
+		"type":           "service_account",
+		"client_email":   "helm-push@project.iam.gserviceaccount.com",
+		"private_key_id": "1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		"token_uri":      ts.URL + "/token",
+	}
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+	credsFile := filepath.Join(tmp, "adc.json")
+	writeCreds := func(v interface{}) {
+		b, _ := json.Marshal(v)
+		os.WriteFile(credsFile, b, 0600)
+	}
+	for _, env := range []string{"GOOGLE_APPLICATION_CREDENTIALS", "GCE_METADATA_HOST", "CLOUDSDK_CONFIG"} {
+		v, ok := os.LookupEnv(env)
+		defer func(env string) {
+			if ok {
+				os.Setenv(env, v)
+			} else {
+				os.Unsetenv(env)
+			}
+		}(env)
+	}
+	os.Setenv("CLOUDSDK_CONFIG", tmp)
+	os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credsFile)
+
+	checkTokens := func(name string, wantRequests int) {
+		t.Helper()
+		tokens, err := NewGoogleIDTokenSource(context.Background(), audience, "")
+		if err != nil {
+			t.Fatalf("%s: unexpected error creating token source: %s", name, err)
+		}
+		requests = 0
+		for i := 0; i < 2; i++ {
+			token, err := tokens.Token()
+			if err != nil {
+				t.Fatalf("%s: unexpected error getting token: %s", name, err)
+			}
+			if token.AccessToken != testIDToken(audience) || time.Until(token.Expiry) < 59*time.Minute {
+				t.Errorf("%s: expected an ID token for %s expiring in an hour, instead got %s expiring at %s", name, audience, token.AccessToken, token.Expiry)
+			}
+		}
+		// the second token is cached
+		if requests != wantRequests {
+			t.Errorf("%s: expected %d token requests, instead got %d", name, wantRequests, requests)
+		}
+	}
+
+	writeCreds(serviceAccount)
+	checkTokens("service account", 1)
+	if targetAudience != audience {
+		t.Errorf("expected an assertion for %s, instead got %q", audience, targetAudience)
+	}
+
+	writeCreds(map[string]interface{}{
+		"type":                              "impersonated_service_account",
+		"service_account_impersonation_url": ts.URL + "/v1/projects/-/serviceAccounts/chartmuseum@project.iam.gserviceaccount.com:generateAccessToken",
+		"source_credentials":                serviceAccount,
+	})
+	checkTokens("impersonated service account", 2)
+	if impersonationAuth != "Bearer ya29.source" {
+		t.Errorf("expected the ID token to be requested with the source access token, instead got %q", impersonationAuth)
+	}
+
+	writeCreds(map[string]string{"type": "authorized_user", "client_id": "id", "client_secret": "secret", "refresh_token": "token"})
+	if _, err := NewGoogleIDTokenSource(context.Background(), audience, ""); err == nil || !strings.Contains(err.Error(), "gcloud auth application-default login --impersonate-service-account") {
+		t.Errorf("expected user credentials error, instead got %v", err)
+	}
+
+	os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", filepath.Join(tmp, "missing.json"))
+	if _, err := NewGoogleIDTokenSource(context.Background(), audience, ""); err == nil || !strings.HasPrefix(err.Error(), "no Google Application Default Credentials found, run `gcloud auth application-default login") {
+		t.Errorf("expected missing credentials error, instead got %v", err)
+	}
+
+	// the file written by gcloud is used without the variable
+	os.Unsetenv("GOOGLE_APPLICATION_CREDENTIALS")
+	writeCreds(serviceAccount)
+	os.Rename(credsFile, filepath.Join(tmp, "application_default_credentials.json"))
+	checkTokens("gcloud credentials", 1)
+
+	os.Remove(filepath.Join(tmp, "application_default_credentials.json"))
+	os.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(ts.URL, "http://"))
+	checkTokens("metadata server", 1)
+}
@@ -0,0 +1,184 @@
+package chartmuseum
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+type (
+	// ChartVersion describes a chart version as returned by the ChartMuseum chart API
+	ChartVersion struct {
+		Name        string            `json:"name"`
+		Version     string            `json:"version"`
+		Description string            `json:"description,omitempty"`
+		APIVersion  string            `json:"apiVersion,omitempty"`
+		AppVersion  string            `json:"appVersion,omitempty"`
+		Type        string            `json:"type,omitempty"`
+		Home        string            `json:"home,omitempty"`
+		Sources     []string          `json:"sources,omitempty"`
+		Keywords    []string          `json:"keywords,omitempty"`
+		Maintainers []*Maintainer     `json:"maintainers,omitempty"`
+		Icon        string            `json:"icon,omitempty"`
+		Deprecated  bool              `json:"deprecated,omitempty"`
+		Annotations map[string]string `json:"annotations,omitempty"`
+		Created     time.Time         `json:"created"`
+		Digest      string            `json:"digest,omitempty"`
+		URLs        []string          `json:"urls,omitempty"`
+	}
+
+	// Maintainer describes a chart maintainer
+	Maintainer struct {
+		Name  string `json:"name,omitempty"`
+		Email string `json:"email,omitempty"`
+		URL   string `json:"url,omitempty"`
+	}
+)
+
+// ListCharts returns all charts in ChartMuseum (GET /api/charts), keyed
+// by chart name. Versions are in the order returned by the server
+func (client *Client) ListCharts() (map[string][]*ChartVersion, error) {
+	return client.ListChartsWithContext(context.Background())
+}
+
+// ListChartsWithContext is ListCharts with a context to cancel the request
+func (client *Client) ListChartsWithContext(ctx context.Context) (map[string][]*ChartVersion, error) {
+	var charts map[string][]*ChartVersion
+	if err := client.getChartAPI(ctx, &charts); err != nil {
+		return nil, err
+	}
+	return charts, nil
+}
+
+// GetChart describes all versions of a chart in ChartMuseum (GET /api/charts/<name>)
+func (client *Client) GetChart(name string) (*http.Response, error) {
+	return client.GetChartWithContext(context.Background(), name)
+}
+
+// GetChartWithContext is GetChart with a context to cancel the request
+func (client *Client) GetChartWithContext(ctx context.Context, name string) (*http.Response, error) {
+	u, err := client.chartAPIURL(name)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client.setHeaders(req)
+
+	return client.Do(req)
+}
+
+// ListChartVersions returns all versions of a chart in ChartMuseum, sorted
+// newest first. Returns an error wrapping ErrNotFound if there is no such chart
+func (client *Client) ListChartVersions(name string) ([]*ChartVersion, error) {
+	return client.ListChartVersionsWithContext(context.Background(), name)
+}
+
+// ListChartVersionsWithContext is ListChartVersions with a context to
+// cancel the request
+func (client *Client) ListChartVersionsWithContext(ctx context.Context, name string) ([]*ChartVersion, error) {
+	var versions []*ChartVersion
+	if err := client.getChartAPI(ctx, &versions, name); err != nil {
+		return nil, err
+	}
+	SortVersions(versions)
+	return versions, nil
+}
+
+// GetChartInfo describes a chart version in ChartMuseum (GET /api/charts/<name>/<version>).
+// Returns an error wrapping ErrNotFound if there is no such version, or
+// ErrServer if the server failed
+func (client *Client) GetChartInfo(name string, version string) (*ChartVersion, error) {
+	return client.GetChartInfoWithContext(context.Background(), name, version)
+}
+
+// GetChartInfoWithContext is GetChartInfo with a context to cancel the request
+func (client *Client) GetChartInfoWithContext(ctx context.Context, name string, version string) (*ChartVersion, error) {
+	var cv ChartVersion
+	if err := client.getChartAPI(ctx, &cv, name, version); err != nil {
+		return nil, err
+	}
+	return &cv, nil
+}
+
+// GetChartVersions returns the version strings of a chart in ChartMuseum,
+// sorted newest first. Returns an error wrapping ErrNotFound if there is
+// no such chart
+func (client *Client) GetChartVersions(name string) ([]string, error) {
+	return client.GetChartVersionsWithContext(context.Background(), name)
+}
+
+// GetChartVersionsWithContext is GetChartVersions with a context to
+// cancel the request
+func (client *Client) GetChartVersionsWithContext(ctx context.Context, name string) ([]string, error) {
+	chartVersions, err := client.ListChartVersionsWithContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]string, len(chartVersions))
+	for i, cv := range chartVersions {
+		versions[i] = cv.Version
+	}
+	return versions, nil
+}
+
+// getChartAPI gets a chart API resource, decoding the JSON response into v
+func (client *Client) getChartAPI(ctx context.Context, v interface{}, elem ...string) error {
+	u, err := client.chartAPIURL(elem...)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return err
+	}
+
+	client.setHeaders(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return NewResponseError(resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidResponse, err)
+	}
+	return nil
+}
+
+// SortVersions sorts chart versions from newest to oldest by semver.
+// Versions which are not valid semver come after all the others, from
+// newest to oldest by created time
+func SortVersions(versions []*ChartVersion) {
+	parsed := make(map[*ChartVersion]*semver.Version, len(versions))
+	for _, cv := range versions {
+		if v, err := semver.NewVersion(cv.Version); err == nil {
+			parsed[cv] = v
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		vi, vj := parsed[versions[i]], parsed[versions[j]]
+		switch {
+		case vi != nil && vj != nil:
+			return vi.GreaterThan(vj)
+		case vi != nil || vj != nil:
+			return vi != nil
+		default:
+			return versions[i].Created.After(versions[j].Created)
+		}
+	})
+}
@@ -0,0 +1,194 @@
+package chartmuseum
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListCharts(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && r.URL.Path == "/my/context/path/api/charts" {
+			w.WriteHeader(200)
+			w.Write([]byte(body))
+		} else {
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+
+	cmClient, err := NewClient(
+		URL(ts.URL),
+		ContextPath("/my/context/path"),
+	)
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+
+	body = `{"mychart":[{"name":"mychart","version":"0.2.0","description":"A chart","created":"2020-01-02T00:00:00Z"},{"name":"mychart","version":"0.1.0","created":"2020-01-01T00:00:00Z"}],"other":[]}`
+	charts, err := cmClient.ListCharts()
+	if err != nil {
+		t.Fatal("error listing charts", err)
+	}
+	if len(charts) != 2 || len(charts["mychart"]) != 2 || len(charts["other"]) != 0 {
+		t.Fatalf("unexpected charts: %v", charts)
+	}
+	cv := charts["mychart"][0]
+	if cv.Name != "mychart" || cv.Version != "0.2.0" || cv.Description != "A chart" || cv.Created.Day() != 2 {
+		t.Errorf("unexpected chart version: %+v", cv)
+	}
+
+	body = "<html>not chartmuseum</html>"
+	_, err = cmClient.ListCharts()
+	if !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("expecting ErrInvalidResponse instead got %v", err)
+	}
+
+	cmClient, _ = NewClient(URL(ts.URL))
+	_, err = cmClient.ListCharts()
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expecting ErrNotFound instead got %v", err)
+	}
+}
+
+func TestGetChart(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && r.URL.Path == "/api/charts/mychart" {
+			w.WriteHeader(200)
+			w.Write([]byte("[]"))
+		} else {
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+
+	cmClient, err := NewClient(URL(ts.URL))
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+
+	resp, err := cmClient.GetChart("mychart")
+	if err != nil {
+		t.Fatal("error getting chart", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Errorf("expecting 200 instead got %d", resp.StatusCode)
+	}
+
+	resp, err = cmClient.GetChart("otherchart")
+	if err != nil {
+		t.Fatal("error getting chart", err)
+	}
+	if resp.StatusCode != 404 {
+		t.Errorf("expecting 404 instead got %d", resp.StatusCode)
+	}
+}
+
+func TestGetChartVersions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/charts/mychart":
+			w.WriteHeader(200)
+			w.Write([]byte(`[{"name": "mychart", "version": "0.2.0"}, {"name": "mychart", "version": "0.10.0"}, {"name": "mychart", "version": "0.9.0"}]`))
+		case "/api/charts/badchart":
+			w.WriteHeader(500)
+			w.Write([]byte(`{"error": "storage unavailable"}`))
+		default:
+			w.WriteHeader(404)
+			w.Write([]byte(`{"error": "chart not found"}`))
+		}
+	}))
+	defer ts.Close()
+
+	cmClient, err := NewClient(URL(ts.URL))
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+
+	versions, err := cmClient.GetChartVersions("mychart")
+	if err != nil {
+		t.Fatal("error getting chart versions", err)
+	}
+	if strings.Join(versions, ",") != "0.10.0,0.9.0,0.2.0" {
+		t.Errorf("unexpected chart versions: %v", versions)
+	}
+
+	_, err = cmClient.GetChartVersions("otherchart")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, instead got %v", err)
+	}
+
+	_, err = cmClient.GetChartVersions("badchart")
+	if !errors.Is(err, ErrServer) || !strings.HasSuffix(err.Error(), "500: storage unavailable") {
+		t.Errorf("unexpected error with server error: %v", err)
+	}
+}
+
+func TestSortVersions(t *testing.T) {
+	now := time.Now()
+	versions := []*ChartVersion{
+		{Version: "1.0.0"},
+		{Version: "1.10.0"},
+		{Version: "1.2.0-rc.1"},
+		{Version: "1.2.0"},
+		{Version: "nightly", Created: now.Add(-time.Hour)},
+		{Version: "latest", Created: now},
+	}
+	SortVersions(versions)
+
+	expected := []string{"1.10.0", "1.2.0", "1.2.0-rc.1", "1.0.0", "latest", "nightly"}
+	for i, v := range versions {
+		if v.Version != expected[i] {
+			t.Errorf("expected version %d to be %s, instead got %s", i, expected[i], v.Version)
+		}
+	}
+}
+
+func TestGetChartInfo(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/charts/mychart/0.1.0":
+			w.WriteHeader(200)
+			w.Write([]byte(`{"name":"mychart","version":"0.1.0","description":"A chart","apiVersion":"v2","appVersion":"1.0","home":"https://example.com",` +
+				`"maintainers":[{"name":"me","email":"me@example.com"}],"annotations":{"team":"a"},` +
+				`"urls":["charts/mychart-0.1.0.tgz"],"created":"2020-01-01T00:00:00Z","digest":"abc"}`))
+		case "/api/charts/broken/0.1.0":
+			w.WriteHeader(500)
+			w.Write([]byte(`{"error":"storage unavailable"}`))
+		default:
+			w.WriteHeader(404)
+			w.Write([]byte(`{"error":"improper constraint: 0.2.0"}`))
+		}
+	}))
+	defer ts.Close()
+
+	cmClient, err := NewClient(URL(ts.URL))
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+
+	cv, err := cmClient.GetChartInfo("mychart", "0.1.0")
+	if err != nil {
+		t.Fatal("error getting chart info", err)
+	}
+	if cv.Name != "mychart" || cv.Version != "0.1.0" || cv.APIVersion != "v2" || cv.AppVersion != "1.0" || cv.Digest != "abc" {
+		t.Errorf("unexpected chart version: %+v", cv)
+	}
+	if len(cv.Maintainers) != 1 || cv.Maintainers[0].Email != "me@example.com" || cv.Annotations["team"] != "a" {
+		t.Errorf("unexpected chart metadata: %+v", cv)
+	}
+
+	_, err = cmClient.GetChartInfo("mychart", "0.2.0")
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrServer) {
+		t.Errorf("expecting ErrNotFound instead got %v", err)
+	}
+
+	_, err = cmClient.GetChartInfo("broken", "0.1.0")
+	if !errors.Is(err, ErrServer) || !strings.Contains(err.Error(), "storage unavailable") {
+		t.Errorf("expecting ErrServer instead got %v", err)
+	}
+}
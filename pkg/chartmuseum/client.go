@@ -3,7 +3,12 @@ package chartmuseum
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"path"
+	"strings"
 
+	"github.com/chartmuseum/helm-push/internal/version"
+	"golang.org/x/oauth2"
 	v2tlsutil "k8s.io/helm/pkg/tlsutil"
 )
 
@@ -12,9 +17,27 @@ type (
 	Client struct {
 		*http.Client
 		opts options
+		// base is the transport without the token and AWS signing
+		// transports, for requests to other hosts than the repo's
+		base http.RoundTripper
+	}
+
+	// ClientInterface is the part of Client used to manage charts, so
+	// it can be substituted with a mock in tests
+	ClientInterface interface {
+		UploadChartPackage(chartPackagePath string, force bool) (*http.Response, error)
+		DownloadFile(filePath string) (*http.Response, error)
+		DeleteChart(name string, version string) error
+		ListCharts() (map[string][]*ChartVersion, error)
+		GetChartInfo(name string, version string) (*ChartVersion, error)
 	}
 )
 
+var _ ClientInterface = (*Client)(nil)
+
+// ArtifactoryAPIKeyHeader is the header Artifactory API keys are sent in
+const ArtifactoryAPIKeyHeader = "X-JFrog-Art-Api"
+
 // Option configures the client with the provided options.
 func (client *Client) Option(opts ...Option) *Client {
 	for _, opt := range opts {
@@ -27,8 +50,11 @@ func (client *Client) Option(opts ...Option) *Client {
 func NewClient(opts ...Option) (*Client, error) {
 	var client Client
 	client.Client = &http.Client{}
-	client.Option(Timeout(30))
+	client.Option(Timeout(30), MaxIdleConns(10), IdleConnTimeout(90))
 	client.Option(opts...)
+	if client.opts.urlErr != nil {
+		return nil, client.opts.urlErr
+	}
 	client.Timeout = client.opts.timeout
 
 	//Enable tls config if configured
@@ -42,11 +68,106 @@ func NewClient(opts ...Option) (*Client, error) {
 		return nil, err
 	}
 
+	// Requests all go to the same repo, so allow it every idle connection
+	tr.MaxIdleConns = client.opts.maxIdleConns
+	tr.MaxIdleConnsPerHost = client.opts.maxIdleConns
+	tr.IdleConnTimeout = client.opts.idleConnTimeout
+
+	tr.Proxy, err = newProxyFunc(client.opts.proxy, client.opts.noProxy)
+	if err != nil {
+		return nil, err
+	}
+
 	client.Transport = tr
+	if client.opts.debug != nil {
+		client.Transport = &debugTransport{
+			next:       tr,
+			out:        client.opts.debug,
+			authHeader: client.opts.authHeader,
+		}
+	}
+	client.base = client.Transport
+	if client.opts.tokenSource != nil && client.opts.authHeader != "" {
+		client.Transport = &tokenHeaderTransport{
+			source: client.opts.tokenSource,
+			header: client.opts.authHeader,
+			next:   client.Transport,
+		}
+	} else if client.opts.tokenSource != nil {
+		client.Transport = &oauth2.Transport{
+			Source: client.opts.tokenSource,
+			Base:   client.Transport,
+		}
+	}
+	if client.opts.awsSigner != nil {
+		// outermost, so --debug shows the signed headers
+		signer := *client.opts.awsSigner
+		signer.Transport = client.Transport
+		client.Transport = &signer
+	}
 
 	return &client, nil
 }
 
+// URL returns the chart repo URL the client connects to
+func (client *Client) URL() string {
+	return client.opts.url
+}
+
+// validateURL checks that the chart repo URL is an absolute http(s) URL,
+// so a bad URL fails when creating the client instead of on the first request
+func validateURL(repoURL string) error {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return fmt.Errorf("%w %q: %s", ErrInvalidURL, repoURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w %q: scheme must be http or https", ErrInvalidURL, repoURL)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%w %q: no host", ErrInvalidURL, repoURL)
+	}
+	return nil
+}
+
+// chartAPIURL returns the URL of the chart API (<context path>/api/charts)
+// followed by the given path elements
+func (client *Client) chartAPIURL(elem ...string) (string, error) {
+	return client.apiURL("charts", elem...)
+}
+
+// apiURL returns the URL of an API resource (<context path>/api/<resource>)
+// followed by the given path elements
+func (client *Client) apiURL(resource string, elem ...string) (string, error) {
+	u, err := url.Parse(client.opts.url)
+	if err != nil {
+		return "", err
+	}
+
+	elem = append([]string{client.opts.contextPath, "api", strings.TrimPrefix(u.Path, client.opts.contextPath), resource}, elem...)
+	u.Path = path.Join(elem...)
+	return u.String(), nil
+}
+
+// setHeaders adds the User-Agent and the configured API key, token
+// or basic auth credentials to a request. An API key replaces basic auth
+func (client *Client) setHeaders(req *http.Request) {
+	req.Header.Set("User-Agent", version.UserAgent())
+
+	if client.opts.apiKey != "" {
+		req.Header.Set(ArtifactoryAPIKeyHeader, client.opts.apiKey)
+	}
+	if client.opts.accessToken != "" {
+		if client.opts.authHeader != "" {
+			req.Header.Set(client.opts.authHeader, client.opts.accessToken)
+		} else {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", client.opts.accessToken))
+		}
+	} else if client.opts.username != "" && client.opts.password != "" && client.opts.apiKey == "" {
+		req.SetBasicAuth(client.opts.username, client.opts.password)
+	}
+}
+
 //Create transport with TLS config
 func newTransport(certFile, keyFile, caFile string, insecureSkipVerify bool) (*http.Transport, error) {
 	transport := &http.Transport{}
@@ -58,7 +179,52 @@ func newTransport(certFile, keyFile, caFile string, insecureSkipVerify bool) (*h
 	tlsConf.InsecureSkipVerify = insecureSkipVerify
 
 	transport.TLSClientConfig = tlsConf
-	transport.Proxy = http.ProxyFromEnvironment
 
 	return transport, nil
 }
+
+// newProxyFunc returns the proxy function for the transport, using the
+// given proxy URL (or the one from the environment), except for requests
+// to the hosts listed in noProxy
+func newProxyFunc(proxy string, noProxy string) (func(*http.Request) (*url.URL, error), error) {
+	proxyFunc := http.ProxyFromEnvironment
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL: %s", proxy)
+		}
+		proxyFunc = http.ProxyURL(proxyURL)
+	}
+	if noProxy == "" {
+		return proxyFunc, nil
+	}
+
+	var hosts []string
+	for _, host := range strings.Split(noProxy, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(strings.ToLower(req.URL.Hostname()), hosts) {
+			return nil, nil
+		}
+		return proxyFunc(req)
+	}, nil
+}
+
+// bypassProxy reports whether host matches one of the no proxy hosts,
+// either exactly or as a subdomain ("example.com" and ".example.com"
+// both match "charts.example.com")
+func bypassProxy(host string, noProxyHosts []string) bool {
+	for _, h := range noProxyHosts {
+		if h == "*" {
+			return true
+		}
+		h = strings.TrimPrefix(h, ".")
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,8 +1,16 @@
 package chartmuseum
 
 import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/chartmuseum/helm-push/internal/version"
+	"github.com/chartmuseum/helm-push/pkg/chartmuseum/auth"
+	"golang.org/x/oauth2"
 )
 
 func TestNewClient(t *testing.T) {
@@ -22,8 +30,8 @@ func TestNewClient(t *testing.T) {
 		t.Fatalf("expect creating a client instance but met error: %s", err)
 	}
 
-	if cmClient.opts.url != "http://localhost:8080" {
-		t.Errorf("expected url to be http://localhost:8080, got %v", cmClient.opts.url)
+	if cmClient.URL() != "http://localhost:8080" {
+		t.Errorf("expected url to be http://localhost:8080, got %v", cmClient.URL())
 	}
 
 	if cmClient.opts.username != "user" {
@@ -58,3 +66,196 @@ func TestNewClient(t *testing.T) {
 		t.Errorf("expected insecure flag to be 'true' but got %v", cmClient.opts.insecureSkipVerify)
 	}
 }
+
+func TestUserAgent(t *testing.T) {
+	var userAgent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		w.WriteHeader(200)
+	}))
+	defer ts.Close()
+
+	cmClient, err := NewClient(URL(ts.URL))
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+	if _, err = cmClient.DownloadFile("index.yaml"); err != nil {
+		t.Fatal("error downloading index.yaml", err)
+	}
+	if userAgent != version.UserAgent() {
+		t.Errorf("expected user agent to be %s, got %s", version.UserAgent(), userAgent)
+	}
+}
+
+func TestAWSV4(t *testing.T) {
+	var authorization string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.WriteHeader(200)
+	}))
+	defer ts.Close()
+
+	signer := &auth.AWSV4Signer{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret", Region: "us-east-1", Service: "execute-api"}
+	cmClient, err := NewClient(URL(ts.URL), Username("user"), Password("pass"), AWSV4(signer))
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+	if _, err = cmClient.DownloadFile("index.yaml"); err != nil {
+		t.Fatal("error downloading index.yaml", err)
+	}
+	if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") || !strings.Contains(authorization, "/us-east-1/execute-api/aws4_request") {
+		t.Errorf("expected request signed instead of basic auth, got %s", authorization)
+	}
+	if signer.Transport != nil {
+		t.Error("expected the signer passed in to be left unchanged")
+	}
+}
+
+func TestTokenSource(t *testing.T) {
+	var authorization string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.WriteHeader(200)
+	}))
+	defer ts.Close()
+
+	tokens := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "ya29.token"})
+	cmClient, err := NewClient(URL(ts.URL), Username("user"), Password("pass"), TokenSource(tokens))
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+	if _, err = cmClient.DownloadFile("index.yaml"); err != nil {
+		t.Fatal("error downloading index.yaml", err)
+	}
+	if authorization != "Bearer ya29.token" {
+		t.Errorf("expected bearer token instead of basic auth, got %s", authorization)
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	var authorization, apiKey string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		apiKey = r.Header.Get("X-JFrog-Art-Api")
+		w.WriteHeader(200)
+	}))
+	defer ts.Close()
+
+	cmClient, err := NewClient(URL(ts.URL), Username("user"), Password("pass"), APIKey("AKCp8key"))
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+	if _, err = cmClient.DownloadFile("index.yaml"); err != nil {
+		t.Fatal("error downloading index.yaml", err)
+	}
+	if apiKey != "AKCp8key" || authorization != "" {
+		t.Errorf("expected the API key instead of basic auth, got %q and %q", apiKey, authorization)
+	}
+
+	cmClient, err = NewClient(URL(ts.URL), AccessToken("reftoken"), APIKey("AKCp8key"))
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+	if _, err = cmClient.DownloadFile("index.yaml"); err != nil {
+		t.Fatal("error downloading index.yaml", err)
+	}
+	if apiKey != "AKCp8key" || authorization != "Bearer reftoken" {
+		t.Errorf("expected the API key with the access token, got %q and %q", apiKey, authorization)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	var proxiedHost string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedHost = r.URL.Host
+		w.WriteHeader(200)
+	}))
+	defer proxy.Close()
+
+	cmClient, err := NewClient(
+		URL("http://charts.example.com"),
+		Proxy(proxy.URL),
+	)
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+	if _, err := cmClient.DownloadFile("index.yaml"); err != nil {
+		t.Fatal("unexpected error downloading through proxy", err)
+	}
+	if proxiedHost != "charts.example.com" {
+		t.Errorf("expected request to charts.example.com to go through proxy, instead got %q", proxiedHost)
+	}
+
+	if _, err := NewClient(Proxy("not a url")); err == nil {
+		t.Error("expected error with invalid proxy URL, instead got nil")
+	}
+}
+
+func TestNewClientInvalidURL(t *testing.T) {
+	for _, u := range []string{
+		"",
+		"kjebnrkvjbnerv",
+		"localhost:8080",
+		"cm://localhost:8080",
+		"ftp://localhost/charts",
+		"http://",
+		"http://local host",
+	} {
+		if _, err := NewClient(URL(u)); !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("expected invalid URL error for %q, instead got %v", u, err)
+		}
+	}
+
+	for _, u := range []string{"http://localhost:8080", "https://charts.example.com/my/repo"} {
+		if _, err := NewClient(WithBaseURL(u)); err != nil {
+			t.Errorf("unexpected error creating client for %q: %s", u, err)
+		}
+	}
+}
+
+func TestNoProxy(t *testing.T) {
+	proxyFunc, err := newProxyFunc("http://proxy.example.com:3128", "localhost, .internal.example.com")
+	if err != nil {
+		t.Fatal("unexpected error creating proxy func", err)
+	}
+
+	for host, proxied := range map[string]bool{
+		"localhost":                   false,
+		"charts.internal.example.com": false,
+		"internal.example.com":        false,
+		"charts.example.com":          true,
+	} {
+		req, _ := http.NewRequest("GET", "https://"+host+"/index.yaml", nil)
+		u, err := proxyFunc(req)
+		if err != nil {
+			t.Fatal("unexpected error getting proxy", err)
+		}
+		if proxied != (u != nil) {
+			t.Errorf("expected proxy to be used for %s: %v", host, proxied)
+		}
+	}
+}
+
+func TestConnectionPooling(t *testing.T) {
+	cmClient, err := NewClient(URL("http://localhost:8080"))
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+	tr := cmClient.Transport.(*http.Transport)
+	if tr.MaxIdleConns != 10 || tr.MaxIdleConnsPerHost != 10 || tr.IdleConnTimeout != 90*time.Second {
+		t.Errorf("unexpected default connection pooling: %d, %d, %s", tr.MaxIdleConns, tr.MaxIdleConnsPerHost, tr.IdleConnTimeout)
+	}
+
+	cmClient, err = NewClient(
+		URL("http://localhost:8080"),
+		MaxIdleConns(50),
+		IdleConnTimeout(5),
+	)
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+	tr = cmClient.Transport.(*http.Transport)
+	if tr.MaxIdleConns != 50 || tr.MaxIdleConnsPerHost != 50 || tr.IdleConnTimeout != 5*time.Second {
+		t.Errorf("unexpected connection pooling: %d, %d, %s", tr.MaxIdleConns, tr.MaxIdleConnsPerHost, tr.IdleConnTimeout)
+	}
+}
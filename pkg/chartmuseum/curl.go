@@ -0,0 +1,126 @@
+package chartmuseum
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/chartmuseum/helm-push/internal/version"
+)
+
+// UploadCurlCommand returns a curl command sending the same request as
+// UploadChartPackage, to reproduce a push outside of the plugin: the same
+// method, URL, headers, TLS and proxy settings, and the package as the
+// "chart" part of a multipart form. Credentials are never included, they
+// are replaced with references to the environment variables they can be
+// given in (e.g. $HELM_REPO_PASSWORD)
+func (client *Client) UploadCurlCommand(chartPackagePath string, force bool) (string, error) {
+	u, err := client.chartAPIURL()
+	if err != nil {
+		return "", err
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", err
+	}
+	parsed.User = nil
+	if force {
+		parsed.RawQuery = "force"
+	}
+
+	args := []string{"curl", "-X", "POST"}
+	args = append(args, "-H", shellQuote("User-Agent: "+version.UserAgent()))
+	args = append(args, client.curlAuthArgs()...)
+	if client.opts.caFile != "" {
+		args = append(args, "--cacert", shellQuote(client.opts.caFile))
+	}
+	if client.opts.certFile != "" {
+		args = append(args, "--cert", shellQuote(client.opts.certFile))
+	}
+	if client.opts.keyFile != "" {
+		args = append(args, "--key", shellQuote(client.opts.keyFile))
+	}
+	if client.opts.insecureSkipVerify {
+		args = append(args, "--insecure")
+	}
+	if client.opts.proxy != "" {
+		args = append(args, curlProxyArgs(client.opts.proxy)...)
+	}
+	if client.opts.noProxy != "" {
+		args = append(args, "--noproxy", shellQuote(client.opts.noProxy))
+	}
+	if client.opts.timeout > 0 {
+		args = append(args, "--max-time", fmt.Sprint(int64(client.opts.timeout.Seconds())))
+	}
+	args = append(args, "-F", shellQuote("chart=@"+chartPackagePath))
+	args = append(args, shellQuote(parsed.String()))
+	return strings.Join(args, " "), nil
+}
+
+// curlAuthArgs returns the curl arguments sending the client's
+// credentials, taking precedence as in the requests the client sends:
+// AWS Signature V4, then OAuth2 tokens, access token and basic auth. An
+// Artifactory API key is sent with any of them but basic auth
+func (client *Client) curlAuthArgs() []string {
+	var args []string
+	if client.opts.apiKey != "" {
+		args = []string{"-H", `"` + ArtifactoryAPIKeyHeader + `: $HELM_REPO_API_KEY"`}
+	}
+	return append(args, client.curlTokenArgs()...)
+}
+
+// curlTokenArgs returns the curl arguments sending the client's
+// credentials other than an API key
+func (client *Client) curlTokenArgs() []string {
+	switch {
+	case client.opts.awsSigner != nil:
+		s := client.opts.awsSigner
+		args := []string{
+			"--aws-sigv4", shellQuote("aws:amz:" + s.Region + ":" + s.Service),
+			"-u", `"$AWS_ACCESS_KEY_ID:$AWS_SECRET_ACCESS_KEY"`,
+		}
+		if s.SessionToken != "" {
+			args = append(args, "-H", `"X-Amz-Security-Token: $AWS_SESSION_TOKEN"`)
+		}
+		return args
+	case client.opts.tokenSource != nil, client.opts.accessToken != "":
+		header := "Authorization"
+		value := "Bearer $HELM_REPO_ACCESS_TOKEN"
+		if client.opts.authHeader != "" {
+			header, value = client.opts.authHeader, "$HELM_REPO_ACCESS_TOKEN"
+		}
+		return []string{"-H", `"` + shellEscapeDouble(http.CanonicalHeaderKey(header)+": ") + value + `"`}
+	case client.opts.username != "" && client.opts.password != "" && client.opts.apiKey == "":
+		return []string{"-u", `"` + shellEscapeDouble(client.opts.username) + `:$HELM_REPO_PASSWORD"`}
+	}
+	return nil
+}
+
+// curlProxyArgs returns the curl arguments connecting through proxy,
+// with the password of a proxy URL replaced by $PROXY_PASSWORD
+func curlProxyArgs(proxy string) []string {
+	u, err := url.Parse(proxy)
+	if err != nil || u.User == nil {
+		return []string{"--proxy", shellQuote(proxy)}
+	}
+	user := u.User.Username()
+	_, hasPassword := u.User.Password()
+	u.User = nil
+	args := []string{"--proxy", shellQuote(u.String())}
+	if hasPassword {
+		return append(args, "--proxy-user", `"`+shellEscapeDouble(user)+`:$PROXY_PASSWORD"`)
+	}
+	return append(args, "--proxy-user", shellQuote(user))
+}
+
+// shellQuote quotes s as a single word for POSIX shells
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellEscapeDouble escapes s to be used literally inside double quotes
+func shellEscapeDouble(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+	return r.Replace(s)
+}
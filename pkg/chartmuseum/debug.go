@@ -0,0 +1,124 @@
+package chartmuseum
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// debugBodyLimit is the most of a response body which is printed
+const debugBodyLimit = 4 << 10
+
+type (
+	// debugTransport logs requests and responses before handing them back
+	debugTransport struct {
+		next       http.RoundTripper
+		out        io.Writer
+		authHeader string
+	}
+
+	// debugBody is a response body with its printed start read back first
+	debugBody struct {
+		io.Reader
+		io.Closer
+	}
+)
+
+// RoundTrip implements http.RoundTripper
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fmt.Fprintf(t.out, "> %s %s\n", req.Method, RedactURL(req.URL.String()))
+	t.writeHeaders(">", req.Header)
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		fmt.Fprintf(t.out, "! %s\n", Redact(err.Error()))
+		return resp, err
+	}
+
+	fmt.Fprintf(t.out, "< %s\n", resp.Status)
+	t.writeHeaders("<", resp.Header)
+
+	if resp.ContentLength == 0 {
+		return resp, nil
+	}
+	if !isTextContent(resp.Header.Get("Content-Type")) {
+		// e.g. a chart package, which is streamed without being buffered
+		if resp.ContentLength > 0 {
+			fmt.Fprintf(t.out, "<%d bytes>\n", resp.ContentLength)
+		} else {
+			fmt.Fprintln(t.out, "<unknown number of bytes>")
+		}
+		return resp, nil
+	}
+
+	// only the start of the body is buffered, and put back in front of the
+	// rest of it
+	head := make([]byte, debugBodyLimit+1)
+	n, err := io.ReadFull(resp.Body, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		resp.Body.Close()
+		return nil, err
+	}
+	head = head[:n]
+	resp.Body = debugBody{Reader: io.MultiReader(bytes.NewReader(head), resp.Body), Closer: resp.Body}
+	if n > debugBodyLimit {
+		// error bodies may echo the request URL and its credentials
+		fmt.Fprintf(t.out, "%s\n<truncated after %d bytes>\n", Redact(string(head[:debugBodyLimit])), debugBodyLimit)
+	} else if n > 0 {
+		fmt.Fprintf(t.out, "%s\n", Redact(string(head)))
+	}
+
+	return resp, nil
+}
+
+func (t *debugTransport) writeHeaders(prefix string, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			if t.isSensitive(name) {
+				value = redactHeaderValue(value)
+			}
+			fmt.Fprintf(t.out, "%s %s: %s\n", prefix, name, value)
+		}
+	}
+}
+
+func (t *debugTransport) isSensitive(name string) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "Authorization", "Proxy-Authorization", "X-Amz-Security-Token", http.CanonicalHeaderKey(ArtifactoryAPIKeyHeader):
+		return true
+	}
+	return t.authHeader != "" && http.CanonicalHeaderKey(name) == http.CanonicalHeaderKey(t.authHeader)
+}
+
+// redactHeaderValue hides credentials, keeping the auth scheme (e.g. "Basic ***")
+func redactHeaderValue(value string) string {
+	if i := strings.Index(value, " "); i > 0 {
+		return value[:i] + " ***"
+	}
+	return "***"
+}
+
+// isTextContent reports whether a response body of the content type is
+// readable text (JSON, YAML or text), worth printing
+func isTextContent(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/json", strings.HasSuffix(mediaType, "+json"),
+		mediaType == "application/yaml", mediaType == "application/x-yaml", strings.HasSuffix(mediaType, "+yaml"):
+		return true
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package chartmuseum
+
+import (
+	"context"
+	"net/http"
+)
+
+// DeleteChart deletes a chart version from ChartMuseum (DELETE /api/charts/<name>/<version>).
+// The returned error wraps ErrNotFound if the version does not exist
+func (client *Client) DeleteChart(name string, version string) error {
+	return client.DeleteChartWithContext(context.Background(), name, version)
+}
+
+// DeleteChartWithContext is DeleteChart with a context to cancel the request
+func (client *Client) DeleteChartWithContext(ctx context.Context, name string, version string) error {
+	u, err := client.chartAPIURL(name, version)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", u, nil)
+	if err != nil {
+		return err
+	}
+
+	client.setHeaders(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return NewResponseError(resp)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package chartmuseum
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDeleteChart(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" {
+			w.WriteHeader(405)
+		} else if r.URL.Path == "/my/context/path/api/charts/mychart/0.1.0" {
+			w.WriteHeader(200)
+		} else {
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+
+	cmClient, err := NewClient(
+		URL(ts.URL),
+		ContextPath("/my/context/path"),
+	)
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+
+	err = cmClient.DeleteChart("mychart", "0.1.0")
+	if err != nil {
+		t.Fatal("error deleting chart", err)
+	}
+
+	err = cmClient.DeleteChart("mychart", "0.2.0")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expecting ErrNotFound instead got %v", err)
+	}
+}
+
+func TestDeleteChartServerError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+		w.Write([]byte(`{"error":"storage unavailable"}`))
+	}))
+	defer ts.Close()
+
+	cmClient, err := NewClient(URL(ts.URL))
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+
+	err = cmClient.DeleteChart("mychart", "0.1.0")
+	if err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("expecting server error instead got %v", err)
+	}
+	if !strings.Contains(err.Error(), "storage unavailable") {
+		t.Errorf("expecting error to contain server message, instead got %s", err)
+	}
+}
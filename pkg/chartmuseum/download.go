@@ -1,7 +1,7 @@
 package chartmuseum
 
 import (
-	"fmt"
+	"context"
 	"net/http"
 	"net/url"
 	"path"
@@ -10,26 +10,23 @@ import (
 
 // DownloadFile downloads a file from ChartMuseum
 func (client *Client) DownloadFile(filePath string) (*http.Response, error) {
+	return client.DownloadFileWithContext(context.Background(), filePath)
+}
+
+// DownloadFileWithContext is DownloadFile with a context to cancel the download
+func (client *Client) DownloadFileWithContext(ctx context.Context, filePath string) (*http.Response, error) {
 	u, err := url.Parse(client.opts.url)
 	if err != nil {
 		return nil, err
 	}
 
 	u.Path = path.Join(client.opts.contextPath, strings.TrimPrefix(u.Path, client.opts.contextPath), filePath)
-	req, err := http.NewRequest("GET", u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
 	if err != nil {
 		return nil, err
 	}
 
-	if client.opts.accessToken != "" {
-		if client.opts.authHeader != "" {
-			req.Header.Set(client.opts.authHeader, client.opts.accessToken)
-		} else {
-			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", client.opts.accessToken))
-		}
-	} else if client.opts.username != "" && client.opts.password != "" {
-		req.SetBasicAuth(client.opts.username, client.opts.password)
-	}
+	client.setHeaders(req)
 
 	return client.Do(req)
 }
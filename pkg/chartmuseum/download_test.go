@@ -3,7 +3,7 @@ package chartmuseum
 import (
 	"crypto/tls"
 	"encoding/base64"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -34,7 +34,7 @@ func TestDownloadFile(t *testing.T) {
 	if err != nil {
 		t.Fatal("error downloading testfile", err)
 	}
-	b, err := ioutil.ReadAll(resp.Body)
+	b, err := io.ReadAll(resp.Body)
 	if err != nil {
 		t.Fatal("error reading response body", err)
 	}
@@ -43,13 +43,13 @@ func TestDownloadFile(t *testing.T) {
 	}
 
 	// trigger request failure
-	cmClient, err = NewClient(URL("kjebnrkvjbnerv"))
+	cmClient, err = NewClient(URL("http://127.0.0.1:0"))
 	if err != nil {
 		t.Fatalf("expect creating a client instance but met error: %s", err)
 	}
 	_, err = cmClient.DownloadFile("testfile")
 	if err == nil {
-		t.Fatal("expecting error with bad auth instead got nil")
+		t.Fatal("expecting error with bad URL instead got nil")
 	}
 }
 
@@ -103,7 +103,7 @@ func TestDownloadFileFromTlsServer(t *testing.T) {
 	if err != nil {
 		t.Fatalf("[with ca file] error downloading testfile: %s", err)
 	}
-	b, err := ioutil.ReadAll(resp.Body)
+	b, err := io.ReadAll(resp.Body)
 	if err != nil {
 		t.Fatalf("[with ca file] error reading response body: %s", err)
 	}
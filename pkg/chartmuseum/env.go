@@ -0,0 +1,26 @@
+package chartmuseum
+
+import (
+	"errors"
+	"os"
+)
+
+// NewClientFromEnv creates a new client for the repo URL in $HELM_REPO_URL,
+// using the credentials and context path in $HELM_REPO_USERNAME,
+// $HELM_REPO_PASSWORD, $HELM_REPO_ACCESS_TOKEN, $HELM_REPO_AUTH_HEADER and
+// $HELM_REPO_CONTEXT_PATH. Any given options are applied after these
+func NewClientFromEnv(opts ...Option) (*Client, error) {
+	url := os.Getenv("HELM_REPO_URL")
+	if url == "" {
+		return nil, errors.New("HELM_REPO_URL is not set")
+	}
+	envOpts := []Option{
+		URL(url),
+		Username(os.Getenv("HELM_REPO_USERNAME")),
+		Password(os.Getenv("HELM_REPO_PASSWORD")),
+		AccessToken(os.Getenv("HELM_REPO_ACCESS_TOKEN")),
+		AuthHeader(os.Getenv("HELM_REPO_AUTH_HEADER")),
+		ContextPath(os.Getenv("HELM_REPO_CONTEXT_PATH")),
+	}
+	return NewClient(append(envOpts, opts...)...)
+}
@@ -0,0 +1,46 @@
+package chartmuseum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestNewClientFromEnv(t *testing.T) {
+	var username, password, path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ = r.BasicAuth()
+		path = r.URL.Path
+		w.WriteHeader(200)
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+
+	for _, name := range []string{"HELM_REPO_URL", "HELM_REPO_USERNAME", "HELM_REPO_PASSWORD", "HELM_REPO_ACCESS_TOKEN", "HELM_REPO_AUTH_HEADER", "HELM_REPO_CONTEXT_PATH"} {
+		defer os.Setenv(name, os.Getenv(name))
+		os.Unsetenv(name)
+	}
+
+	if _, err := NewClientFromEnv(); err == nil {
+		t.Error("expected error without HELM_REPO_URL, instead got nil")
+	}
+
+	os.Setenv("HELM_REPO_URL", ts.URL)
+	os.Setenv("HELM_REPO_USERNAME", "myuser")
+	os.Setenv("HELM_REPO_PASSWORD", "mypass")
+	os.Setenv("HELM_REPO_CONTEXT_PATH", "/my/context/path")
+	cmClient, err := NewClientFromEnv(Timeout(5))
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+	if cmClient.Timeout.Seconds() != 5 {
+		t.Errorf("expected options to be applied, instead got timeout %s", cmClient.Timeout)
+	}
+	if _, err := cmClient.ListCharts(); err != nil {
+		t.Fatal("error listing charts", err)
+	}
+	if username != "myuser" || password != "mypass" || path != "/my/context/path/api/charts" {
+		t.Errorf("unexpected request from env client: %s:%s %s", username, password, path)
+	}
+}
@@ -0,0 +1,95 @@
+package chartmuseum
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+var (
+	// ErrNotFound is returned when ChartMuseum responds 404 Not Found
+	ErrNotFound = errors.New("not found")
+
+	// ErrServer is returned when ChartMuseum responds with a 5xx server error
+	ErrServer = errors.New("server error")
+
+	// ErrServerError is the same as ErrServer
+	ErrServerError = ErrServer
+
+	// ErrUnauthorized is returned when ChartMuseum responds 401 Unauthorized,
+	// usually because of missing or wrong credentials
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrForbidden is returned when ChartMuseum responds 403 Forbidden
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrChartAlreadyExists is returned when ChartMuseum responds 409
+	// Conflict to pushing a chart version it already has
+	ErrChartAlreadyExists = errors.New("chart already exists")
+
+	// ErrInvalidResponse is returned when a response from the chart API
+	// can't be parsed, usually because the server is not ChartMuseum
+	ErrInvalidResponse = errors.New("could not parse chart API response")
+
+	// ErrInvalidURL is returned by NewClient when the chart repo URL
+	// is not an absolute http(s) URL
+	ErrInvalidURL = errors.New("invalid chart repo URL")
+)
+
+// ResponseError is an unexpected response from the ChartMuseum API. It
+// unwraps to the sentinel error for its status code, if there is one, so
+// it can be checked with errors.Is (e.g. errors.Is(err, ErrUnauthorized))
+type ResponseError struct {
+	StatusCode int
+	// Message is the "error" field of the JSON response body, or the
+	// whole body if it has none
+	Message string
+}
+
+// Error returns the status code and message, prefixed with "not found"
+// or "server error" for 404 and 5xx responses
+func (e *ResponseError) Error() string {
+	switch {
+	case e.StatusCode == 404:
+		return fmt.Sprintf("%s: %s", ErrNotFound, e.Message)
+	case e.StatusCode >= 500:
+		return fmt.Sprintf("%s: %d: %s", ErrServer, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%d: %s", e.StatusCode, e.Message)
+}
+
+// Unwrap returns the sentinel error for the status code, or nil
+func (e *ResponseError) Unwrap() error {
+	switch {
+	case e.StatusCode == 401:
+		return ErrUnauthorized
+	case e.StatusCode == 403:
+		return ErrForbidden
+	case e.StatusCode == 404:
+		return ErrNotFound
+	case e.StatusCode == 409:
+		return ErrChartAlreadyExists
+	case e.StatusCode >= 500:
+		return ErrServer
+	}
+	return nil
+}
+
+// NewResponseError reads the body of an unexpected API response,
+// returning a *ResponseError describing it
+func NewResponseError(resp *http.Response) error {
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var er struct {
+		Error string `json:"error"`
+	}
+	msg := string(b)
+	if err := json.Unmarshal(b, &er); err == nil && er.Error != "" {
+		msg = er.Error
+	}
+	return &ResponseError{StatusCode: resp.StatusCode, Message: msg}
+}
@@ -0,0 +1,45 @@
+package chartmuseum
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNewResponseError(t *testing.T) {
+	for _, tc := range []struct {
+		statusCode int
+		body       string
+		sentinel   error
+		message    string
+	}{
+		{401, `{"error":"unauthorized"}`, ErrUnauthorized, "401: unauthorized"},
+		{403, `{"error":"forbidden"}`, ErrForbidden, "403: forbidden"},
+		{404, `{"error":"chart not found"}`, ErrNotFound, "not found: chart not found"},
+		{409, `{"error":"file already exists"}`, ErrChartAlreadyExists, "409: file already exists"},
+		{500, "storage unavailable", ErrServerError, "server error: 500: storage unavailable"},
+		{503, `{"error":"index is locked"}`, ErrServer, "server error: 503: index is locked"},
+		{400, `{"error":"bad request"}`, nil, "400: bad request"},
+	} {
+		resp := &http.Response{StatusCode: tc.statusCode, Body: io.NopCloser(strings.NewReader(tc.body))}
+		err := NewResponseError(resp)
+		if err.Error() != tc.message {
+			t.Errorf("%d: expected error %q, instead got %q", tc.statusCode, tc.message, err)
+		}
+		if tc.sentinel != nil && !errors.Is(err, tc.sentinel) {
+			t.Errorf("%d: expected error to be %v", tc.statusCode, tc.sentinel)
+		}
+		var respErr *ResponseError
+		if !errors.As(err, &respErr) || respErr.StatusCode != tc.statusCode {
+			t.Errorf("%d: expected a *ResponseError, instead got %#v", tc.statusCode, err)
+		}
+	}
+
+	// other sentinels don't match
+	resp := &http.Response{StatusCode: 401, Body: io.NopCloser(strings.NewReader(""))}
+	if err := NewResponseError(resp); errors.Is(err, ErrForbidden) || errors.Is(err, ErrServer) {
+		t.Errorf("expected 401 to only be ErrUnauthorized, instead got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package chartmuseum
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/chartmuseum/helm-push/internal/version"
+)
+
+type (
+	// repoHostTransport adds the client's credentials to requests to
+	// the chart repo's host, and only the User-Agent to any other
+	repoHostTransport struct {
+		client *Client
+	}
+)
+
+// HTTPClient returns an http.Client with the client's TLS, proxy and
+// timeout settings, to fetch files from outside of the chart repo's API,
+// such as a chart package given by URL. The client's credentials are only
+// sent to the chart repo's host
+func (client *Client) HTTPClient() *http.Client {
+	return &http.Client{
+		Timeout:   client.Timeout,
+		Transport: &repoHostTransport{client: client},
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *repoHostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if !t.client.isRepoHost(req.URL) {
+		req.Header.Set("User-Agent", version.UserAgent())
+		return t.client.base.RoundTrip(req)
+	}
+	t.client.setHeaders(req)
+	return t.client.Transport.RoundTrip(req)
+}
+
+// isRepoHost reports whether u has the same scheme and host as the
+// chart repo
+func (client *Client) isRepoHost(u *url.URL) bool {
+	repoURL, err := url.Parse(client.opts.url)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == repoURL.Scheme && strings.EqualFold(u.Host, repoURL.Host)
+}
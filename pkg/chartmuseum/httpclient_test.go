@@ -0,0 +1,34 @@
+package chartmuseum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPClient(t *testing.T) {
+	var auth []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+	})
+	repo := httptest.NewServer(handler)
+	defer repo.Close()
+	other := httptest.NewServer(handler)
+	defer other.Close()
+
+	client, err := NewClient(URL(repo.URL), Username("user"), Password("pass"))
+	if err != nil {
+		t.Fatal("unexpected error creating client", err)
+	}
+	httpClient := client.HTTPClient()
+	for _, u := range []string{repo.URL + "/charts/mychart-0.1.0.tgz", other.URL + "/mychart-0.1.0.tgz"} {
+		resp, err := httpClient.Get(u)
+		if err != nil {
+			t.Fatal("unexpected error getting", u, err)
+		}
+		resp.Body.Close()
+	}
+	if len(auth) != 2 || auth[0] != "Basic dXNlcjpwYXNz" || auth[1] != "" {
+		t.Errorf("expected credentials to only be sent to the repo host, instead got %q", auth)
+	}
+}
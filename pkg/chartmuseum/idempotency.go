@@ -0,0 +1,29 @@
+package chartmuseum
+
+import (
+	"context"
+	"net/http"
+)
+
+// IdempotencyKeyHeader is the header chart package uploads are sent
+// with their idempotency key in
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+type idempotencyKeyContextKey struct{}
+
+// WithIdempotencyKey returns a context for uploading a chart package
+// with key in the Idempotency-Key header, on the upload and every retry
+// of it, so that gateways and servers which support it can tell a retry
+// from a new push. Only chart package uploads are sent with it: the
+// provenance file of the same push is a different request
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey{}, key)
+}
+
+// setIdempotencyKey sets the Idempotency-Key header of req from its
+// context, if it has a key
+func setIdempotencyKey(req *http.Request) {
+	if key, ok := req.Context().Value(idempotencyKeyContextKey{}).(string); ok && key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+}
@@ -0,0 +1,57 @@
+package chartmuseum
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestUploadIdempotencyKey(t *testing.T) {
+	defer func(b time.Duration) { retryBackoff = b }(retryBackoff)
+	retryBackoff = time.Millisecond
+
+	var keys []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		if len(keys) == 1 {
+			w.WriteHeader(503)
+			return
+		}
+		w.WriteHeader(201)
+	}))
+	defer ts.Close()
+
+	cmClient, err := NewClient(URL(ts.URL), Retries(1))
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+	ctx := WithIdempotencyKey(context.Background(), "4a7c1c3e-0d5b-4d3c-9d0e-3f1c2b6a7e11")
+	resp, err := cmClient.UploadChartPackageWithContext(ctx, testTarballPath, false)
+	if err != nil {
+		t.Fatal("unexpected error uploading chart package", err)
+	}
+	resp.Body.Close()
+	if len(keys) != 2 || keys[0] != "4a7c1c3e-0d5b-4d3c-9d0e-3f1c2b6a7e11" || keys[1] != keys[0] {
+		t.Errorf("expected the same key on the upload and its retry, instead got %q", keys)
+	}
+
+	keys = []string{"retried"}
+	if resp, err = cmClient.UploadProvenanceFileWithContext(ctx, testTarballPath, false); err != nil {
+		t.Fatal("unexpected error uploading provenance file", err)
+	}
+	resp.Body.Close()
+	if keys[1] != "" {
+		t.Errorf("expected no key on the provenance file upload, instead got %q", keys[1])
+	}
+
+	keys = []string{"retried"}
+	if resp, err = cmClient.UploadChartPackage(testTarballPath, false); err != nil {
+		t.Fatal("unexpected error uploading chart package", err)
+	}
+	resp.Body.Close()
+	if keys[1] != "" {
+		t.Errorf("expected no key without one in the context, instead got %q", keys[1])
+	}
+}
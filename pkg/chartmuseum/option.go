@@ -1,7 +1,11 @@
 package chartmuseum
 
 import (
+	"io"
 	"time"
+
+	"github.com/chartmuseum/helm-push/pkg/chartmuseum/auth"
+	"golang.org/x/oauth2"
 )
 
 type (
@@ -11,26 +15,48 @@ type (
 	// options specify optional settings
 	options struct {
 		url                string
+		urlErr             error
 		username           string
 		password           string
 		accessToken        string
 		authHeader         string
+		apiKey             string
 		contextPath        string
 		timeout            time.Duration
+		retries            int
+		retryMaxWait       time.Duration
+		onRetry            func(wait time.Duration, reason string)
+		reindexPath        string
+		signaturePath      string
+		awsSigner          *auth.AWSV4Signer
+		tokenSource        oauth2.TokenSource
 		caFile             string
 		certFile           string
 		keyFile            string
 		insecureSkipVerify bool
+		maxIdleConns       int
+		idleConnTimeout    time.Duration
+		proxy              string
+		noProxy            string
+		debug              io.Writer
+		progress           io.Writer
 	}
 )
 
-// URL specifies the chart repo URL
+// URL specifies the chart repo URL, which must be an absolute http(s)
+// URL. NewClient fails if it isn't
 func URL(url string) Option {
 	return func(opts *options) {
 		opts.url = url
+		opts.urlErr = validateURL(url)
 	}
 }
 
+// WithBaseURL specifies the chart repo URL, see URL
+func WithBaseURL(url string) Option {
+	return URL(url)
+}
+
 // Username is HTTP basic auth username
 func Username(username string) Option {
 	return func(opts *options) {
@@ -59,6 +85,14 @@ func AuthHeader(authHeader string) Option {
 	}
 }
 
+// APIKey is an Artifactory API key, sent in the X-JFrog-Art-Api header
+// instead of basic auth
+func APIKey(apiKey string) Option {
+	return func(opts *options) {
+		opts.apiKey = apiKey
+	}
+}
+
 // ContextPath is the URL prefix for ChartMuseum installation
 func ContextPath(contextPath string) Option {
 	return func(opts *options) {
@@ -73,6 +107,66 @@ func Timeout(timeout int64) Option {
 	}
 }
 
+// Retries is the number of times a request is retried when the
+// connection fails or the server is temporarily unavailable
+func Retries(retries int) Option {
+	return func(opts *options) {
+		opts.retries = retries
+	}
+}
+
+// RetryMaxWait specifies the longest duration (in seconds) to wait
+// before a retry, even if the server's Retry-After asks for longer
+func RetryMaxWait(retryMaxWait int64) Option {
+	return func(opts *options) {
+		opts.retryMaxWait = time.Duration(retryMaxWait) * time.Second
+	}
+}
+
+// OnRetry is called with how long the client waits before retrying a
+// request, and why, e.g. to log it
+func OnRetry(onRetry func(wait time.Duration, reason string)) Option {
+	return func(opts *options) {
+		opts.onRetry = onRetry
+	}
+}
+
+// ReindexPath is the path of the index regeneration endpoint, relative
+// to the context path, for ChartMuseum versions which don't use the
+// default /api/charts/regenerate-index
+func ReindexPath(reindexPath string) Option {
+	return func(opts *options) {
+		opts.reindexPath = reindexPath
+	}
+}
+
+// SignaturePath is the path of the endpoint detached chart signatures
+// are uploaded to, relative to the context path, for servers which don't
+// take them on the default /api/prov
+func SignaturePath(signaturePath string) Option {
+	return func(opts *options) {
+		opts.signaturePath = signaturePath
+	}
+}
+
+// AWSV4 signs every request with AWS Signature Version 4, replacing
+// basic auth and token headers, for ChartMuseum behind IAM auth
+func AWSV4(signer *auth.AWSV4Signer) Option {
+	return func(opts *options) {
+		opts.awsSigner = signer
+	}
+}
+
+// TokenSource sends a bearer token from ts with every request, replacing
+// basic auth and token headers, e.g. for GCP service accounts whose
+// tokens expire while pushing. With AuthHeader, the token is sent in that
+// header instead, as the access token is
+func TokenSource(ts oauth2.TokenSource) Option {
+	return func(opts *options) {
+		opts.tokenSource = ts
+	}
+}
+
 //CAFile specifies the path of CA bundle
 func CAFile(caFile string) Option {
 	return func(opts *options) {
@@ -100,3 +194,49 @@ func InsecureSkipVerify(insecureSkipVerify bool) Option {
 		opts.insecureSkipVerify = insecureSkipVerify
 	}
 }
+
+// MaxIdleConns is the maximum number of idle (keep-alive) connections
+// kept open to reuse for later requests
+func MaxIdleConns(maxIdleConns int) Option {
+	return func(opts *options) {
+		opts.maxIdleConns = maxIdleConns
+	}
+}
+
+// IdleConnTimeout specifies the duration (in seconds) an idle
+// connection is kept open before closing it
+func IdleConnTimeout(idleConnTimeout int64) Option {
+	return func(opts *options) {
+		opts.idleConnTimeout = time.Duration(idleConnTimeout) * time.Second
+	}
+}
+
+// Proxy specifies the URL of an HTTP proxy to connect through,
+// instead of the one from $HTTPS_PROXY/$HTTP_PROXY
+func Proxy(proxy string) Option {
+	return func(opts *options) {
+		opts.proxy = proxy
+	}
+}
+
+// NoProxy is a comma-separated list of hosts to connect to directly,
+// bypassing the proxy
+func NoProxy(noProxy string) Option {
+	return func(opts *options) {
+		opts.noProxy = noProxy
+	}
+}
+
+// Debug specifies a writer to log HTTP requests and responses to
+func Debug(debug io.Writer) Option {
+	return func(opts *options) {
+		opts.debug = debug
+	}
+}
+
+// Progress specifies a writer to report chart upload progress to
+func Progress(progress io.Writer) Option {
+	return func(opts *options) {
+		opts.progress = progress
+	}
+}
@@ -0,0 +1,151 @@
+package chartmuseum
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// progressBarWidth is the number of characters inside the progress bar
+	progressBarWidth = 20
+
+	// progressSamples is the size of the ring buffer used to average the
+	// upload speed, one sample per second over the last 5 seconds
+	progressSamples = 6
+)
+
+type (
+	// progressReader reports how much of an upload has been read,
+	// along with the upload speed and estimated time remaining
+	progressReader struct {
+		r       io.Reader
+		out     io.Writer
+		name    string
+		total   int64
+		read    int64
+		percent int64
+		lineLen int
+		now     func() time.Time
+		samples [progressSamples]progressSample
+		next    int
+		count   int
+	}
+
+	// progressBody is an upload request body reporting its progress as
+	// it is sent. The bodies of the request's GetBody aren't wrapped, as
+	// they may be read for other reasons, e.g. to sign the request
+	progressBody struct {
+		*progressReader
+		io.Closer
+	}
+
+	progressSample struct {
+		at   time.Time
+		read int64
+	}
+)
+
+func newProgressReader(r io.Reader, total int64, name string, out io.Writer) *progressReader {
+	pr := &progressReader{r: r, out: out, name: name, total: total, percent: -1, now: time.Now}
+	pr.sample(pr.now())
+	return pr
+}
+
+func newProgressBody(body io.ReadCloser, total int64, name string, out io.Writer) progressBody {
+	return progressBody{progressReader: newProgressReader(body, total, name, out), Closer: body}
+}
+
+// rewind returns a progress body for body, a new copy of the same upload,
+// e.g. when the request is retried
+func (b progressBody) rewind(body io.ReadCloser) progressBody {
+	return newProgressBody(body, b.total, b.name, b.out)
+}
+
+// Read implements io.Reader
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	pr.read += int64(n)
+
+	now := pr.now()
+	if now.Sub(pr.latest().at) >= time.Second {
+		pr.sample(now)
+	}
+
+	if pr.total > 0 {
+		percent := pr.read * 100 / pr.total
+		if percent != pr.percent {
+			pr.percent = percent
+			pr.print(now)
+		}
+	}
+
+	return n, err
+}
+
+// sample records the bytes read so far, overwriting the oldest sample
+// once the ring buffer is full
+func (pr *progressReader) sample(at time.Time) {
+	pr.samples[pr.next] = progressSample{at: at, read: pr.read}
+	pr.next = (pr.next + 1) % progressSamples
+	if pr.count < progressSamples {
+		pr.count++
+	}
+}
+
+func (pr *progressReader) latest() progressSample {
+	return pr.samples[(pr.next+progressSamples-1)%progressSamples]
+}
+
+func (pr *progressReader) oldest() progressSample {
+	return pr.samples[(pr.next+progressSamples-pr.count)%progressSamples]
+}
+
+// rate returns the average upload speed in bytes per second
+func (pr *progressReader) rate(now time.Time) float64 {
+	oldest := pr.oldest()
+	elapsed := now.Sub(oldest.at).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(pr.read-oldest.read) / elapsed
+}
+
+func (pr *progressReader) print(now time.Time) {
+	line := fmt.Sprintf("Pushing %s %s %3d%%", pr.name, progressBar(pr.percent), pr.percent)
+	if rate := pr.rate(now); rate > 0 {
+		line += " " + formatRate(rate)
+		if pr.read < pr.total {
+			eta := time.Duration(float64(pr.total-pr.read)/rate+0.5) * time.Second
+			line += " ETA " + eta.String()
+		}
+	}
+
+	// Pad to clear what is left of a longer previous line
+	padding := ""
+	if len(line) < pr.lineLen {
+		padding = strings.Repeat(" ", pr.lineLen-len(line))
+	}
+	pr.lineLen = len(line)
+	fmt.Fprintf(pr.out, "\r%s%s", line, padding)
+
+	if pr.read >= pr.total {
+		fmt.Fprintln(pr.out)
+	}
+}
+
+func progressBar(percent int64) string {
+	filled := int(percent) * progressBarWidth / 100
+	if filled >= progressBarWidth {
+		return "[" + strings.Repeat("=", progressBarWidth) + "]"
+	}
+	return "[" + strings.Repeat("=", filled) + ">" + strings.Repeat(" ", progressBarWidth-filled-1) + "]"
+}
+
+func formatRate(rate float64) string {
+	if rate >= 1024*1024 {
+		return fmt.Sprintf("%.1f MB/s", rate/(1024*1024))
+	}
+	return fmt.Sprintf("%d KB/s", int64(rate/1024))
+}
@@ -0,0 +1,138 @@
+package chartmuseum
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chartmuseum/helm-push/pkg/chartmuseum/auth"
+)
+
+// fakeClock advances by one second every time it is read
+func fakeClock() func() time.Time {
+	now := time.Unix(0, 0)
+	first := true
+	return func() time.Time {
+		if !first {
+			now = now.Add(time.Second)
+		}
+		first = false
+		return now
+	}
+}
+
+func newFakeClockProgressReader(r io.Reader, total int64, out io.Writer) *progressReader {
+	pr := &progressReader{r: r, out: out, name: "mychart-0.1.0.tgz", total: total, percent: -1, now: fakeClock()}
+	pr.sample(pr.now())
+	return pr
+}
+
+func TestProgressReader(t *testing.T) {
+	var out bytes.Buffer
+	data := strings.Repeat("x", 40*1024)
+	pr := newFakeClockProgressReader(strings.NewReader(data), int64(len(data)), &out)
+
+	buf := make([]byte, 10*1024)
+	for {
+		if _, err := pr.Read(buf); err != nil {
+			break
+		}
+	}
+
+	expected := "\rPushing mychart-0.1.0.tgz [=====>              ]  25% 10 KB/s ETA 3s" +
+		"\rPushing mychart-0.1.0.tgz [==========>         ]  50% 10 KB/s ETA 2s" +
+		"\rPushing mychart-0.1.0.tgz [===============>    ]  75% 10 KB/s ETA 1s" +
+		"\rPushing mychart-0.1.0.tgz [====================] 100% 10 KB/s       \n"
+	if out.String() != expected {
+		t.Errorf("unexpected progress output: %q", out.String())
+	}
+}
+
+func TestProgressReaderRate(t *testing.T) {
+	pr := newFakeClockProgressReader(strings.NewReader(strings.Repeat("x", 20*1024)), 0, io.Discard)
+
+	// 1 KB/s for 5 seconds, then 3 KB/s for 5 seconds
+	for _, size := range []int{1, 1, 1, 1, 1, 3, 3, 3, 3, 3} {
+		pr.Read(make([]byte, size*1024))
+	}
+
+	// Only the last 5 seconds count towards the average
+	if rate := pr.rate(pr.latest().at); rate != 3*1024 {
+		t.Errorf("expected rate to be 3072 bytes/s, instead got %f", rate)
+	}
+}
+
+func TestFormatRate(t *testing.T) {
+	if s := formatRate(512 * 1024); s != "512 KB/s" {
+		t.Errorf("unexpected rate format: %s", s)
+	}
+	if s := formatRate(1.5 * 1024 * 1024); s != "1.5 MB/s" {
+		t.Errorf("unexpected rate format: %s", s)
+	}
+}
+
+func TestUploadChartPackageWithProgress(t *testing.T) {
+	var contentLength int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		contentLength = int64(len(b))
+		w.WriteHeader(201)
+	}))
+	defer ts.Close()
+
+	var out bytes.Buffer
+	cmClient, err := NewClient(
+		URL(ts.URL),
+		Progress(&out),
+	)
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+	resp, err := cmClient.UploadChartPackage(testTarballPath, false)
+	if err != nil {
+		t.Fatal("error uploading chart package", err)
+	}
+	if resp.StatusCode != 201 {
+		t.Errorf("expecting 201 instead got %d", resp.StatusCode)
+	}
+	if contentLength == 0 {
+		t.Error("expected chart package to be uploaded")
+	}
+	if !strings.Contains(out.String(), "\rPushing mychart-0.1.0.tgz [====================] 100%") || !strings.HasSuffix(out.String(), "\n") {
+		t.Errorf("unexpected progress output: %q", out.String())
+	}
+}
+
+func TestUploadChartPackageWithProgressAndAWSV4(t *testing.T) {
+	var authorization string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		io.ReadAll(r.Body)
+		w.WriteHeader(201)
+	}))
+	defer ts.Close()
+
+	var out bytes.Buffer
+	cmClient, err := NewClient(
+		URL(ts.URL),
+		Progress(&out),
+		AWSV4(&auth.AWSV4Signer{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret", Region: "us-east-1", Service: "execute-api"}),
+	)
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+	if _, err := cmClient.UploadChartPackage(testTarballPath, false); err != nil {
+		t.Fatal("error uploading chart package", err)
+	}
+	if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 ") {
+		t.Errorf("expected a signed upload, instead got %q", authorization)
+	}
+	// hashing the body to sign it doesn't show as a first upload
+	if n := strings.Count(out.String(), "] 100%"); n != 1 {
+		t.Errorf("expected progress to reach 100%% once, instead got %d times: %q", n, out.String())
+	}
+}
@@ -0,0 +1,141 @@
+package chartmuseum
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Redacted replaces credentials in output and errors
+const Redacted = "***"
+
+var (
+	// urlRegexp matches the URLs in a message
+	urlRegexp = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]+`)
+
+	// authHeaderRegexp matches credential headers in a message, keeping
+	// the auth scheme of Authorization headers. References to environment
+	// variables, as in the curl commands of --dry-run, are left alone
+	authHeaderRegexp = regexp.MustCompile(`(?i)((?:proxy-)?authorization:\s*(?:(?:basic|bearer|token)\s+)?|x-jfrog-art-api:\s*)[^\s"',$][^\s"',]*`)
+
+	// sensitiveQueryParams are the query parameters whose values are
+	// credentials, e.g. tokens of presigned URLs
+	sensitiveQueryParams = map[string]bool{
+		"access_token":         true,
+		"api_key":              true,
+		"apikey":               true,
+		"auth":                 true,
+		"client_secret":        true,
+		"key":                  true,
+		"password":             true,
+		"secret":               true,
+		"sig":                  true,
+		"signature":            true,
+		"token":                true,
+		"x-amz-credential":     true,
+		"x-amz-security-token": true,
+		"x-amz-signature":      true,
+		"x-goog-signature":     true,
+	}
+)
+
+// RedactURL replaces the userinfo of a URL, and the values of query
+// parameters holding credentials such as token or X-Amz-Signature, with
+// ***. Strings which aren't URLs are returned unchanged
+func RedactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" {
+		return s
+	}
+	if u.User == nil && u.RawQuery == "" {
+		return s
+	}
+	var b strings.Builder
+	b.WriteString(u.Scheme + "://")
+	if u.User != nil {
+		b.WriteString(Redacted + "@")
+	}
+	b.WriteString(u.Host + u.EscapedPath())
+	if u.RawQuery != "" || u.ForceQuery {
+		b.WriteString("?" + redactQuery(u.RawQuery))
+	}
+	if u.Fragment != "" {
+		b.WriteString("#" + u.EscapedFragment())
+	}
+	return b.String()
+}
+
+// redactQuery redacts the credentials in a raw query, leaving the rest
+// of it as it was
+func redactQuery(rawQuery string) string {
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		name := param
+		if j := strings.Index(param, "="); j >= 0 {
+			name = param[:j]
+		}
+		if n, err := url.QueryUnescape(name); err == nil && sensitiveQueryParams[strings.ToLower(n)] && name != param {
+			params[i] = name + "=" + Redacted
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// Redact replaces the credentials in a message with ***: the given
+// secrets, e.g. the password and tokens from flags and the environment,
+// credential headers, and the userinfo and credential query parameters
+// of URLs. Secrets are only replaced as whole tokens, so that a short
+// password such as "pass" doesn't mangle words like "password"
+func Redact(msg string, secrets ...string) string {
+	// longest first, so that a secret containing another is replaced whole
+	sorted := append([]string(nil), secrets...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	for _, secret := range sorted {
+		if secret != "" {
+			msg = replaceToken(msg, secret)
+		}
+	}
+	msg = urlRegexp.ReplaceAllStringFunc(msg, RedactURL)
+	return authHeaderRegexp.ReplaceAllString(msg, "${1}"+Redacted)
+}
+
+// replaceToken replaces the occurrences of secret in msg which aren't
+// part of a longer word
+func replaceToken(msg string, secret string) string {
+	var b strings.Builder
+	start := 0
+	for {
+		i := strings.Index(msg[start:], secret)
+		if i < 0 {
+			break
+		}
+		i += start
+		end := i + len(secret)
+		b.WriteString(msg[start:i])
+		if (i == 0 || !isWordByte(msg[i-1]) || !isWordByte(secret[0])) &&
+			(end == len(msg) || !isWordByte(msg[end]) || !isWordByte(secret[len(secret)-1])) {
+			b.WriteString(Redacted)
+		} else {
+			b.WriteString(secret)
+		}
+		start = end
+	}
+	b.WriteString(msg[start:])
+	return b.String()
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// redactError redacts the URL of the *url.Error returned by http.Client,
+// whose message includes it
+func redactError(err error) error {
+	var uErr *url.Error
+	if errors.As(err, &uErr) {
+		uErr.URL = RedactURL(uErr.URL)
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+package chartmuseum
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+// ReindexRepo asks ChartMuseum to regenerate its index, e.g. after charts
+// were imported directly into its storage (POST /api/charts/regenerate-index,
+// or the path set with ReindexPath). Returns the server's error message
+// for non-2xx responses
+func (client *Client) ReindexRepo() error {
+	return client.ReindexRepoWithContext(context.Background())
+}
+
+// ReindexRepoWithContext is ReindexRepo with a context to cancel the request
+func (client *Client) ReindexRepoWithContext(ctx context.Context) error {
+	u, err := client.reindexURL()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", u, nil)
+	if err != nil {
+		return err
+	}
+
+	client.setHeaders(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return NewResponseError(resp)
+	}
+	return nil
+}
+
+// reindexURL returns the URL of the index regeneration endpoint
+func (client *Client) reindexURL() (string, error) {
+	if client.opts.reindexPath == "" {
+		return client.chartAPIURL("regenerate-index")
+	}
+	u, err := url.Parse(client.opts.url)
+	if err != nil {
+		return "", err
+	}
+	u.Path = path.Join("/", client.opts.contextPath, client.opts.reindexPath)
+	return u.String(), nil
+}
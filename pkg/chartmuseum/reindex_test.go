@@ -0,0 +1,63 @@
+package chartmuseum
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReindexRepo(t *testing.T) {
+	var reindexed string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method != "POST":
+			w.WriteHeader(405)
+		case r.URL.Path == "/my/context/path/api/charts/regenerate-index", r.URL.Path == "/my/context/path/admin/reindex":
+			reindexed = r.URL.Path
+			w.WriteHeader(202)
+		case r.URL.Path == "/locked/api/charts/regenerate-index":
+			w.WriteHeader(500)
+			w.Write([]byte(`{"error":"index is locked"}`))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+
+	cmClient, err := NewClient(
+		URL(ts.URL),
+		ContextPath("/my/context/path"),
+	)
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+	if err := cmClient.ReindexRepo(); err != nil {
+		t.Fatal("error reindexing repo", err)
+	}
+	if reindexed != "/my/context/path/api/charts/regenerate-index" {
+		t.Errorf("expecting default reindex path instead got %s", reindexed)
+	}
+
+	// Custom endpoint path
+	cmClient.Option(ReindexPath("/admin/reindex"))
+	if err := cmClient.ReindexRepo(); err != nil {
+		t.Fatal("error reindexing repo with custom path", err)
+	}
+	if reindexed != "/my/context/path/admin/reindex" {
+		t.Errorf("expecting custom reindex path instead got %s", reindexed)
+	}
+
+	// Error message from the response body
+	cmClient, _ = NewClient(URL(ts.URL), ContextPath("/locked"))
+	err = cmClient.ReindexRepo()
+	if !errors.Is(err, ErrServer) || !strings.Contains(err.Error(), "index is locked") {
+		t.Errorf("expecting server error instead got %v", err)
+	}
+
+	cmClient, _ = NewClient(URL(ts.URL))
+	if err := cmClient.ReindexRepo(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expecting ErrNotFound instead got %v", err)
+	}
+}
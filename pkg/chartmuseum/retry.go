@@ -0,0 +1,121 @@
+package chartmuseum
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chartmuseum/helm-push/pkg/chartmuseum/auth"
+)
+
+// retryBackoff is the delay before the first retry, doubled for each
+// retry after that
+var retryBackoff = time.Second
+
+// Do sends a request, retrying it up to the configured number of times
+// when the connection fails, the server is temporarily unavailable (502,
+// 503 or 504) or it is throttling requests (429). A 429 or 503 response's
+// Retry-After is waited for instead of the backoff, up to the configured
+// maximum wait. The timeout applies to each attempt. Retries stop when
+// the request's context is done
+func (client *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := client.Client.Do(req)
+		err = redactError(err)
+		if attempt >= client.opts.retries || !shouldRetry(resp, err) {
+			return resp, err
+		}
+		if req.Body != nil {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, gerr := req.GetBody()
+			if gerr != nil {
+				return resp, err
+			}
+			if pb, ok := req.Body.(progressBody); ok {
+				body = pb.rewind(body)
+			}
+			req.Body = body
+		}
+		wait := backoff
+		if after, ok := retryAfter(resp, time.Now()); ok {
+			wait = after
+		}
+		if client.opts.retryMaxWait > 0 && wait > client.opts.retryMaxWait {
+			wait = client.opts.retryMaxWait
+		}
+		if client.opts.onRetry != nil {
+			client.opts.onRetry(wait, retryReason(resp, err))
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// retryAfter returns how long a 429 or 503 response asks to wait before
+// retrying, from its Retry-After header in seconds or as an HTTP date
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return 0, false
+	}
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	if wait := t.Sub(now); wait > 0 {
+		return wait, true
+	}
+	return 0, true
+}
+
+// retryReason describes why a request is retried, for logging
+func retryReason(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("server returned %s", resp.Status)
+}
+
+// shouldRetry reports whether a failed request may succeed if sent again
+func shouldRetry(resp *http.Response, err error) bool {
+	var tokenErr *auth.TokenCommandError
+	if errors.As(err, &tokenErr) {
+		// the command failing again wouldn't help
+		return false
+	}
+	var oauthErr *auth.OAuthError
+	if errors.As(err, &oauthErr) {
+		// neither would asking for a token which was refused
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
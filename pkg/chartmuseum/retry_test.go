@@ -0,0 +1,223 @@
+package chartmuseum
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetries(t *testing.T) {
+	defer func(b time.Duration) { retryBackoff = b }(retryBackoff)
+	retryBackoff = time.Millisecond
+
+	var attempts int
+	var failures int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts <= failures {
+			w.WriteHeader(503)
+			return
+		}
+		// The upload must arrive complete on every attempt
+		if _, _, err := r.FormFile("chart"); err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		w.WriteHeader(201)
+	}))
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		retries  int
+		failures int
+		status   int
+		attempts int
+	}{
+		{retries: 0, failures: 1, status: 503, attempts: 1},
+		{retries: 2, failures: 2, status: 201, attempts: 3},
+		{retries: 2, failures: 3, status: 503, attempts: 3},
+	} {
+		attempts, failures = 0, tc.failures
+		cmClient, err := NewClient(URL(ts.URL), Retries(tc.retries))
+		if err != nil {
+			t.Fatalf("expect creating a client instance but met error: %s", err)
+		}
+		resp, err := cmClient.UploadChartPackage(testTarballPath, false)
+		if err != nil {
+			t.Fatal("unexpected error uploading chart package", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Errorf("retries %d: expected status %d, instead got %d", tc.retries, tc.status, resp.StatusCode)
+		}
+		if attempts != tc.attempts {
+			t.Errorf("retries %d: expected %d attempts, instead got %d", tc.retries, tc.attempts, attempts)
+		}
+	}
+}
+
+func TestNoRetryOnServerError(t *testing.T) {
+	var attempts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(500)
+	}))
+	defer ts.Close()
+
+	cmClient, err := NewClient(URL(ts.URL), Retries(3))
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+	resp, err := cmClient.DownloadFile("index.yaml")
+	if err != nil {
+		t.Fatal("unexpected error downloading file", err)
+	}
+	resp.Body.Close()
+	if attempts != 1 {
+		t.Errorf("expected 1 attempt, instead got %d", attempts)
+	}
+}
+
+func TestRetriesStopWhenCancelled(t *testing.T) {
+	defer func(b time.Duration) { retryBackoff = b }(retryBackoff)
+	retryBackoff = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var attempts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		cancel()
+		w.WriteHeader(503)
+	}))
+	defer ts.Close()
+
+	cmClient, err := NewClient(URL(ts.URL), Retries(3))
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+	_, err = cmClient.UploadChartPackageWithContext(ctx, testTarballPath, false)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected upload to be cancelled, instead got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected 1 attempt, instead got %d", attempts)
+	}
+
+	if _, err := cmClient.DownloadFileWithContext(ctx, "index.yaml"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected download with cancelled context to fail, instead got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected no request with cancelled context, instead got %d attempts", attempts)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		status     int
+		retryAfter string
+		wait       time.Duration
+		ok         bool
+	}{
+		{status: 429, retryAfter: "30", wait: 30 * time.Second, ok: true},
+		{status: 503, retryAfter: "0", wait: 0, ok: true},
+		{status: 503, retryAfter: "Thu, 01 Oct 2020 12:01:30 GMT", wait: 90 * time.Second, ok: true},
+		{status: 429, retryAfter: "Thu, 01 Oct 2020 11:00:00 GMT", wait: 0, ok: true},
+		{status: 429, retryAfter: "", ok: false},
+		{status: 429, retryAfter: "soon", ok: false},
+		{status: 429, retryAfter: "-1", ok: false},
+		{status: 502, retryAfter: "30", ok: false},
+	} {
+		resp := &http.Response{StatusCode: tc.status, Header: http.Header{}}
+		if tc.retryAfter != "" {
+			resp.Header.Set("Retry-After", tc.retryAfter)
+		}
+		wait, ok := retryAfter(resp, now)
+		if wait != tc.wait || ok != tc.ok {
+			t.Errorf("%d %q: expected %s (%t), instead got %s (%t)", tc.status, tc.retryAfter, tc.wait, tc.ok, wait, ok)
+		}
+	}
+}
+
+func TestRetriesHonorRetryAfter(t *testing.T) {
+	defer func(b time.Duration) { retryBackoff = b }(retryBackoff)
+	retryBackoff = time.Hour
+
+	var attempts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(429)
+			return
+		}
+		w.WriteHeader(201)
+	}))
+	defer ts.Close()
+
+	var waits []time.Duration
+	var reasons []string
+	cmClient, err := NewClient(URL(ts.URL), Retries(1), OnRetry(func(wait time.Duration, reason string) {
+		waits = append(waits, wait)
+		reasons = append(reasons, reason)
+	}))
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+	resp, err := cmClient.UploadChartPackage(testTarballPath, false)
+	if err != nil {
+		t.Fatal("unexpected error uploading chart package", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 201 || attempts != 2 {
+		t.Errorf("expected upload to succeed on the second attempt, instead got %d after %d attempts", resp.StatusCode, attempts)
+	}
+	if len(waits) != 1 || waits[0] != 0 || reasons[0] != "server returned 429 Too Many Requests" {
+		t.Errorf("expected the Retry-After wait to be reported instead of the backoff, instead got %v %v", waits, reasons)
+	}
+}
+
+func TestRetryMaxWait(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var attempts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(503)
+	}))
+	defer ts.Close()
+
+	var waits []time.Duration
+	cmClient, err := NewClient(URL(ts.URL), Retries(2), RetryMaxWait(0), OnRetry(func(wait time.Duration, reason string) {
+		waits = append(waits, wait)
+	}))
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+
+	// without a maximum, the whole Retry-After is waited for, until
+	// the wait is interrupted
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := cmClient.UploadChartPackageWithContext(ctx, testTarballPath, false); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected upload to be cancelled, instead got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected cancelling to stop the wait promptly, instead took %s", elapsed)
+	}
+	if len(waits) != 1 || waits[0] != time.Hour {
+		t.Errorf("expected a wait of 1h, instead got %v", waits)
+	}
+
+	attempts, waits = 0, nil
+	cmClient.Option(RetryMaxWait(1))
+	if _, err := cmClient.DownloadFile("index.yaml"); err != nil {
+		t.Fatal("unexpected error downloading file", err)
+	}
+	if attempts != 3 || len(waits) != 2 || waits[0] != time.Second || waits[1] != time.Second {
+		t.Errorf("expected 3 attempts with waits capped at 1s, instead got %d attempts and %v", attempts, waits)
+	}
+}
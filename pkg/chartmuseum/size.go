@@ -0,0 +1,41 @@
+package chartmuseum
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// sizeUnits are the units accepted by ParseSize, decimal for KB, MB and
+// GB as in most tools, binary for KiB, MiB and GiB
+var sizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"k":   1000,
+	"kb":  1000,
+	"kib": 1 << 10,
+	"m":   1000 * 1000,
+	"mb":  1000 * 1000,
+	"mib": 1 << 20,
+	"g":   1000 * 1000 * 1000,
+	"gb":  1000 * 1000 * 1000,
+	"gib": 1 << 30,
+}
+
+// ParseSize parses a human-readable size like "50MB", "512KiB" or
+// "1048576" (bytes) into a number of bytes, e.g. for a limit on the size
+// of chart packages
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if !ok || err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q (e.g. 20MB, 512KiB or a number of bytes)", s)
+	}
+	return int64(n * float64(unit)), nil
+}
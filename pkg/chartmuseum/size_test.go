@@ -0,0 +1,29 @@
+package chartmuseum
+
+import (
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	for s, expected := range map[string]int64{
+		"1048576": 1048576,
+		"20MB":    20000000,
+		"20 mb":   20000000,
+		"1.5M":    1500000,
+		"512KiB":  512 * 1024,
+		"1GiB":    1 << 30,
+		"0":       -1,
+		"20XB":    -1,
+		"MB":      -1,
+		"":        -1,
+	} {
+		actual, err := ParseSize(s)
+		if expected == -1 {
+			if err == nil {
+				t.Errorf("%q: expected error, instead got %d", s, actual)
+			}
+		} else if err != nil || actual != expected {
+			t.Errorf("%q: expected %d, instead got %d (%v)", s, expected, actual, err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package chartmuseum
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+)
+
+// UploadChartPackageStream uploads a chart package named fileName to
+// ChartMuseum (POST /api/charts), sending it as it is read from r, e.g.
+// while it is downloaded from another repo. Since r can't be read again,
+// the upload is not retried
+func (client *Client) UploadChartPackageStream(fileName string, r io.Reader, force bool) (*http.Response, error) {
+	return client.UploadChartPackageStreamWithContext(context.Background(), fileName, r, force)
+}
+
+// UploadChartPackageStreamWithContext is UploadChartPackageStream with a
+// context to cancel the upload
+func (client *Client) UploadChartPackageStreamWithContext(ctx context.Context, fileName string, r io.Reader, force bool) (*http.Response, error) {
+	u, err := client.chartAPIURL()
+	if err != nil {
+		return nil, err
+	}
+	return client.uploadStream(ctx, u, "chart", fileName, r, force)
+}
+
+// UploadProvenanceFileStream uploads a provenance file named fileName to
+// ChartMuseum (POST /api/prov), sending it as it is read from r, as
+// UploadChartPackageStream does
+func (client *Client) UploadProvenanceFileStream(fileName string, r io.Reader, force bool) (*http.Response, error) {
+	return client.UploadProvenanceFileStreamWithContext(context.Background(), fileName, r, force)
+}
+
+// UploadProvenanceFileStreamWithContext is UploadProvenanceFileStream
+// with a context to cancel the upload
+func (client *Client) UploadProvenanceFileStreamWithContext(ctx context.Context, fileName string, r io.Reader, force bool) (*http.Response, error) {
+	u, err := client.apiURL("prov")
+	if err != nil {
+		return nil, err
+	}
+	return client.uploadStream(ctx, u, "prov", fileName, r, force)
+}
+
+// uploadStream posts the contents of r to u as the multipart part field,
+// named fileName, encoding it through a pipe as it is sent
+func (client *Client) uploadStream(ctx context.Context, u string, field string, fileName string, r io.Reader, force bool) (*http.Response, error) {
+	pr, pw := io.Pipe()
+	req, err := http.NewRequestWithContext(ctx, "POST", u, pr)
+	if err != nil {
+		return nil, err
+	}
+
+	if force {
+		req.URL.RawQuery = "force"
+	}
+
+	w := multipart.NewWriter(pw)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	go func() {
+		fw, err := w.CreateFormFile(field, fileName)
+		if err == nil {
+			_, err = io.Copy(fw, r)
+		}
+		if err == nil {
+			err = w.Close()
+		}
+		// the request fails with err if r can't be read, and the copy
+		// stops if the request fails first and closes the pipe
+		pw.CloseWithError(err)
+	}()
+
+	client.setHeaders(req)
+
+	return client.Do(req)
+}
@@ -0,0 +1,82 @@
+package chartmuseum
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestUploadChartPackageStream(t *testing.T) {
+	defer func(b time.Duration) { retryBackoff = b }(retryBackoff)
+	retryBackoff = time.Millisecond
+
+	tarball, err := os.ReadFile(testTarballPath)
+	if err != nil {
+		t.Fatal("unexpected error reading test tarball", err)
+	}
+
+	var attempts int
+	var status int
+	var uploaded []byte
+	var name string
+	var force bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		_, force = r.URL.Query()["force"]
+		f, h, err := r.FormFile("chart")
+		if err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		name = h.Filename
+		uploaded, _ = io.ReadAll(f)
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	cmClient, err := NewClient(URL(ts.URL), Retries(2))
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+
+	status = 201
+	resp, err := cmClient.UploadChartPackageStream("mychart-0.1.0.tgz", bytes.NewReader(tarball), true)
+	if err != nil {
+		t.Fatal("unexpected error uploading chart package", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 201 || !bytes.Equal(uploaded, tarball) || name != "mychart-0.1.0.tgz" || !force {
+		t.Errorf("expected the package to be uploaded complete with force, instead got %d, %q, %d bytes", resp.StatusCode, name, len(uploaded))
+	}
+
+	// The stream can't be read again, so the upload is not retried
+	attempts, status = 0, 503
+	resp, err = cmClient.UploadChartPackageStream("mychart-0.1.0.tgz", bytes.NewReader(tarball), false)
+	if err != nil {
+		t.Fatal("unexpected error uploading chart package", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 503 || attempts != 1 {
+		t.Errorf("expected a single attempt with status 503, instead got %d attempts with status %d", attempts, resp.StatusCode)
+	}
+
+	// A failed read fails the upload
+	readErr := errors.New("connection reset")
+	_, err = cmClient.UploadChartPackageStream("mychart-0.1.0.tgz", io.MultiReader(bytes.NewReader(tarball[:10]), &errReader{readErr}), false)
+	if !errors.Is(err, readErr) {
+		t.Errorf("expected the read error, instead got %v", err)
+	}
+}
+
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,178 @@
+// Package testing provides a mock ChartMuseum client for unit tests of
+// code using the chartmuseum package
+package testing
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+)
+
+type (
+	// Call is a call made to a MockClient method, with its arguments
+	Call struct {
+		Method string
+		Args   []interface{}
+	}
+
+	// MockClient implements cm.ClientInterface without a server, recording
+	// the calls made to it and returning the configured responses
+	MockClient struct {
+		// StatusCode and Body are the response to UploadChartPackage and
+		// DownloadFile. Error status codes also make DeleteChart, ListCharts
+		// and GetChartInfo fail with a *cm.ResponseError, like the real client
+		StatusCode int
+		Body       string
+
+		// Files are served by DownloadFile instead of Body if not nil,
+		// keyed by path. Other paths are not found
+		Files map[string]string
+
+		// Charts are returned by ListCharts, looked up by GetChartInfo
+		// and removed from by DeleteChart
+		Charts map[string][]*cm.ChartVersion
+
+		// Err is returned by every method if not nil
+		Err error
+
+		mu    sync.Mutex
+		calls []Call
+	}
+)
+
+var _ cm.ClientInterface = (*MockClient)(nil)
+
+// NewMockClient returns a mock client with no charts which responds 200
+func NewMockClient() *MockClient {
+	return NewMockClientThatReturns(200)
+}
+
+// NewMockClientThatReturns returns a mock client with no charts which
+// responds with statusCode
+func NewMockClientThatReturns(statusCode int) *MockClient {
+	return &MockClient{
+		StatusCode: statusCode,
+		Body:       "{}",
+		Charts:     map[string][]*cm.ChartVersion{},
+	}
+}
+
+// Calls returns the calls made to the client so far, in order
+func (c *MockClient) Calls() []Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Call(nil), c.calls...)
+}
+
+// CallsTo returns the calls made to the named method so far, in order
+func (c *MockClient) CallsTo(method string) []Call {
+	var calls []Call
+	for _, call := range c.Calls() {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// UploadChartPackage records the upload and returns the configured response
+func (c *MockClient) UploadChartPackage(chartPackagePath string, force bool) (*http.Response, error) {
+	c.record("UploadChartPackage", chartPackagePath, force)
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	return c.response(c.StatusCode, c.Body), nil
+}
+
+// DownloadFile returns the file from Files, or the configured response
+func (c *MockClient) DownloadFile(filePath string) (*http.Response, error) {
+	c.record("DownloadFile", filePath)
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	if c.Files != nil {
+		content, ok := c.Files[filePath]
+		if !ok {
+			return c.response(404, `{"error":"not found"}`), nil
+		}
+		return c.response(200, content), nil
+	}
+	return c.response(c.StatusCode, c.Body), nil
+}
+
+// DeleteChart removes the chart version from Charts, failing with
+// cm.ErrNotFound if it isn't there
+func (c *MockClient) DeleteChart(name string, version string) error {
+	c.record("DeleteChart", name, version)
+	if err := c.err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, v := range c.Charts[name] {
+		if v.Version == version {
+			c.Charts[name] = append(c.Charts[name][:i], c.Charts[name][i+1:]...)
+			if len(c.Charts[name]) == 0 {
+				delete(c.Charts, name)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s-%s", cm.ErrNotFound, name, version)
+}
+
+// ListCharts returns Charts
+func (c *MockClient) ListCharts() (map[string][]*cm.ChartVersion, error) {
+	c.record("ListCharts")
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return c.Charts, nil
+}
+
+// GetChartInfo returns the chart version from Charts, failing with
+// cm.ErrNotFound if it isn't there
+func (c *MockClient) GetChartInfo(name string, version string) (*cm.ChartVersion, error) {
+	c.record("GetChartInfo", name, version)
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	for _, v := range c.Charts[name] {
+		if v.Version == version {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s-%s", cm.ErrNotFound, name, version)
+}
+
+func (c *MockClient) record(method string, args ...interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, Call{Method: method, Args: args})
+}
+
+// err returns Err, or the error the real client would return for an
+// error StatusCode
+func (c *MockClient) err() error {
+	if c.Err != nil {
+		return c.Err
+	}
+	if c.StatusCode >= 400 {
+		return &cm.ResponseError{StatusCode: c.StatusCode, Message: c.Body}
+	}
+	return nil
+}
+
+func (c *MockClient) response(statusCode int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: statusCode,
+		Status:     fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
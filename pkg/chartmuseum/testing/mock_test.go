@@ -0,0 +1,73 @@
+package testing
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	cm "github.com/chartmuseum/helm-push/pkg/chartmuseum"
+)
+
+func TestMockClient(t *testing.T) {
+	client := NewMockClient()
+	client.Charts["mychart"] = []*cm.ChartVersion{{Name: "mychart", Version: "0.2.0"}, {Name: "mychart", Version: "0.1.0"}}
+
+	resp, err := client.UploadChartPackage("mychart-0.3.0.tgz", true)
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("expected upload to succeed, instead got %v", err)
+	}
+	if b, _ := io.ReadAll(resp.Body); string(b) != "{}" {
+		t.Errorf("expected response body {}, instead got %s", b)
+	}
+
+	if v, err := client.GetChartInfo("mychart", "0.1.0"); err != nil || v.Version != "0.1.0" {
+		t.Errorf("expected chart info for mychart-0.1.0, instead got %v, %v", v, err)
+	}
+	if err := client.DeleteChart("mychart", "0.1.0"); err != nil {
+		t.Error("unexpected error deleting chart", err)
+	}
+	if err := client.DeleteChart("mychart", "0.1.0"); !errors.Is(err, cm.ErrNotFound) {
+		t.Errorf("expected deleted chart to not be found, instead got %v", err)
+	}
+	if _, err := client.GetChartInfo("mychart", "0.1.0"); !errors.Is(err, cm.ErrNotFound) {
+		t.Errorf("expected deleted chart info to not be found, instead got %v", err)
+	}
+	if charts, err := client.ListCharts(); err != nil || len(charts["mychart"]) != 1 {
+		t.Errorf("expected one chart version to be left, instead got %v, %v", charts, err)
+	}
+
+	calls := client.Calls()
+	if len(calls) != 6 || calls[0].Method != "UploadChartPackage" || calls[0].Args[0] != "mychart-0.3.0.tgz" || calls[0].Args[1] != true {
+		t.Errorf("unexpected calls recorded: %+v", calls)
+	}
+	if deletes := client.CallsTo("DeleteChart"); len(deletes) != 2 || deletes[0].Args[1] != "0.1.0" {
+		t.Errorf("unexpected DeleteChart calls recorded: %+v", deletes)
+	}
+}
+
+func TestMockClientThatReturns(t *testing.T) {
+	client := NewMockClientThatReturns(503)
+	if resp, err := client.UploadChartPackage("mychart-0.1.0.tgz", false); err != nil || resp.StatusCode != 503 {
+		t.Errorf("expected upload to respond 503, instead got %v", err)
+	}
+	if _, err := client.ListCharts(); !errors.Is(err, cm.ErrServer) {
+		t.Errorf("expected server error listing charts, instead got %v", err)
+	}
+	if err := NewMockClientThatReturns(404).DeleteChart("mychart", "0.1.0"); !errors.Is(err, cm.ErrNotFound) {
+		t.Errorf("expected not found error deleting chart, instead got %v", err)
+	}
+
+	client = NewMockClient()
+	client.Files = map[string]string{"index.yaml": "apiVersion: v1"}
+	if resp, _ := client.DownloadFile("index.yaml"); resp.StatusCode != 200 {
+		t.Errorf("expected index.yaml to be found, instead got %d", resp.StatusCode)
+	}
+	if resp, _ := client.DownloadFile("charts/mychart-0.1.0.tgz"); resp.StatusCode != 404 {
+		t.Errorf("expected missing file to not be found, instead got %d", resp.StatusCode)
+	}
+
+	client.Err = errors.New("connection refused")
+	if _, err := client.DownloadFile("index.yaml"); err != client.Err {
+		t.Errorf("expected configured error, instead got %v", err)
+	}
+}
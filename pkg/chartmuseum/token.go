@@ -0,0 +1,32 @@
+package chartmuseum
+
+import (
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+type (
+	// tokenHeaderTransport sends the tokens of a token source in a custom
+	// header, as the access token is with AuthHeader, instead of as a
+	// bearer token in the Authorization header
+	tokenHeaderTransport struct {
+		source oauth2.TokenSource
+		header string
+		next   http.RoundTripper
+	}
+)
+
+// RoundTrip implements http.RoundTripper
+func (t *tokenHeaderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.source.Token()
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set(t.header, token.AccessToken)
+	return t.next.RoundTrip(req)
+}
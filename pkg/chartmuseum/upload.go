@@ -2,26 +2,31 @@ package chartmuseum
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
-	"strings"
+	"path/filepath"
 )
 
 // UploadChartPackage uploads a chart package to ChartMuseum (POST /api/charts)
 func (client *Client) UploadChartPackage(chartPackagePath string, force bool) (*http.Response, error) {
-	u, err := url.Parse(client.opts.url)
+	return client.UploadChartPackageWithContext(context.Background(), chartPackagePath, force)
+}
+
+// UploadChartPackageWithContext is UploadChartPackage with a context to
+// cancel the upload
+func (client *Client) UploadChartPackageWithContext(ctx context.Context, chartPackagePath string, force bool) (*http.Response, error) {
+	u, err := client.chartAPIURL()
 	if err != nil {
 		return nil, err
 	}
 
-	u.Path = path.Join(client.opts.contextPath, "api", strings.TrimPrefix(u.Path, client.opts.contextPath), "charts")
-	req, err := http.NewRequest("POST", u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "POST", u, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -31,34 +36,134 @@ func (client *Client) UploadChartPackage(chartPackagePath string, force bool) (*
 		req.URL.RawQuery = "force"
 	}
 
-	err = setUploadChartPackageRequestBody(req, chartPackagePath)
+	err = setUploadRequestBody(req, "chart", chartPackagePath)
 	if err != nil {
 		return nil, err
 	}
 
-	if client.opts.accessToken != "" {
-		if client.opts.authHeader != "" {
-			req.Header.Set(client.opts.authHeader, client.opts.accessToken)
-		} else {
-			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", client.opts.accessToken))
-		}
-	} else if client.opts.username != "" && client.opts.password != "" {
-		req.SetBasicAuth(client.opts.username, client.opts.password)
+	if client.opts.progress != nil {
+		req.Body = newProgressBody(req.Body, req.ContentLength, filepath.Base(chartPackagePath), client.opts.progress)
 	}
 
+	client.setHeaders(req)
+	setIdempotencyKey(req)
+
 	return client.Do(req)
 }
 
-func setUploadChartPackageRequestBody(req *http.Request, chartPackagePath string) error {
+// UploadProvenanceFile uploads a chart provenance file to ChartMuseum (POST /api/prov)
+// as an application/octet-stream multipart part named "prov". Any response
+// other than 201 Created is returned as an error wrapping a *ResponseError
+func (client *Client) UploadProvenanceFile(provPath string) (*http.Response, error) {
+	return client.UploadProvenanceFileWithContext(context.Background(), provPath, false)
+}
+
+// UploadProvenanceFileWithContext is UploadProvenanceFile with a context
+// to cancel the upload. force overwrites a provenance file which already
+// exists, as it does for UploadChartPackage, so a chart pushed again with
+// force can have its provenance file pushed again too
+func (client *Client) UploadProvenanceFileWithContext(ctx context.Context, provPath string, force bool) (*http.Response, error) {
+	u, err := client.apiURL("prov")
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if force {
+		req.URL.RawQuery = "force"
+	}
+
+	err = setUploadRequestBody(req, "prov", provPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read provenance file: %s", err)
+	}
+
+	client.setHeaders(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp, handleProvUploadResponse(resp)
+}
+
+// handleProvUploadResponse checks the response to uploading a provenance
+// file, which ChartMuseum answers with 201 Created, and closes its body
+func handleProvUploadResponse(resp *http.Response) error {
+	defer resp.Body.Close()
+	if resp.StatusCode != 201 {
+		return fmt.Errorf("failed to upload provenance file: %w", NewResponseError(resp))
+	}
+	return nil
+}
+
+// UploadSignatureFile uploads a detached chart signature, such as one made
+// with cosign sign-blob, as a multipart part named "prov" with the file
+// name name, e.g. mychart-0.1.0.tgz.sig. It is sent to /api/prov, or the
+// path set with SignaturePath
+func (client *Client) UploadSignatureFile(sigPath string, name string, force bool) (*http.Response, error) {
+	return client.UploadSignatureFileWithContext(context.Background(), sigPath, name, force)
+}
+
+// UploadSignatureFileWithContext is UploadSignatureFile with a context to
+// cancel the upload
+func (client *Client) UploadSignatureFileWithContext(ctx context.Context, sigPath string, name string, force bool) (*http.Response, error) {
+	u, err := client.signatureURL()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if force {
+		req.URL.RawQuery = "force"
+	}
+
+	err = setNamedUploadRequestBody(req, "prov", name, sigPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read signature file: %s", err)
+	}
+
+	client.setHeaders(req)
+
+	return client.Do(req)
+}
+
+// signatureURL returns the URL detached signatures are uploaded to
+func (client *Client) signatureURL() (string, error) {
+	if client.opts.signaturePath == "" {
+		return client.apiURL("prov")
+	}
+	u, err := url.Parse(client.opts.url)
+	if err != nil {
+		return "", err
+	}
+	u.Path = path.Join("/", client.opts.contextPath, client.opts.signaturePath)
+	return u.String(), nil
+}
+
+func setUploadRequestBody(req *http.Request, field string, filePath string) error {
+	return setNamedUploadRequestBody(req, field, filePath, filePath)
+}
+
+// setNamedUploadRequestBody is setUploadRequestBody with the file name
+// sent in the multipart part set to fileName
+func setNamedUploadRequestBody(req *http.Request, field string, fileName string, filePath string) error {
 	var body bytes.Buffer
 	w := multipart.NewWriter(&body)
-	defer w.Close()
-	fw, err := w.CreateFormFile("chart", chartPackagePath)
+	fw, err := w.CreateFormFile(field, fileName)
 	if err != nil {
 		return err
 	}
 	w.FormDataContentType()
-	fd, err := os.Open(chartPackagePath)
+	fd, err := os.Open(filePath)
 	if err != nil {
 		return err
 	}
@@ -67,7 +172,15 @@ func setUploadChartPackageRequestBody(req *http.Request, chartPackagePath string
 	if err != nil {
 		return err
 	}
+	if err = w.Close(); err != nil {
+		return err
+	}
 	req.Header.Set("Content-Type", w.FormDataContentType())
-	req.Body = ioutil.NopCloser(&body)
+	b := body.Bytes()
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+	req.Body, _ = req.GetBody()
+	req.ContentLength = int64(len(b))
 	return nil
 }
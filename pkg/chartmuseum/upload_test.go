@@ -1,11 +1,16 @@
 package chartmuseum
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/tls"
 	"encoding/base64"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -87,10 +92,8 @@ func TestUploadChartPackage(t *testing.T) {
 	}
 
 	// Bad URL
-	cmClient, _ = NewClient(URL("jaswehfgew"))
-	_, err = cmClient.UploadChartPackage(testTarballPath, false)
-	if err == nil {
-		t.Error("[bad URL] expecting error with bad package path, instead got nil")
+	if _, err = NewClient(URL("jaswehfgew")); err == nil {
+		t.Error("[bad URL] expecting error with bad URL, instead got nil")
 	}
 
 	// Bad context path
@@ -148,6 +151,150 @@ func TestUploadChartPackage(t *testing.T) {
 	}
 }
 
+func TestUploadProvenanceFile(t *testing.T) {
+	var provUploaded []byte
+	var contentType string
+	var forced bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/my/context/path/api/prov" {
+			w.WriteHeader(404)
+			return
+		}
+		f, fh, err := r.FormFile("prov")
+		if err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		provUploaded, _ = io.ReadAll(f)
+		contentType = fh.Header.Get("Content-Type")
+		_, forced = r.URL.Query()["force"]
+		w.WriteHeader(201)
+	}))
+	defer ts.Close()
+
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+	provPath := filepath.Join(tmp, "mychart-0.1.0.tgz.prov")
+	os.WriteFile(provPath, []byte("-----BEGIN PGP SIGNED MESSAGE-----"), 0644)
+
+	cmClient, err := NewClient(
+		URL(ts.URL),
+		ContextPath("/my/context/path"),
+	)
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+	resp, err := cmClient.UploadProvenanceFile(provPath)
+	if err != nil {
+		t.Fatal("error uploading provenance file", err)
+	}
+	if resp.StatusCode != 201 {
+		t.Errorf("expecting 201 instead got %d", resp.StatusCode)
+	}
+	if string(provUploaded) != "-----BEGIN PGP SIGNED MESSAGE-----" || forced {
+		t.Errorf("unexpected provenance file upload: %q (force %v)", provUploaded, forced)
+	}
+	if contentType != "application/octet-stream" {
+		t.Errorf("expecting content type application/octet-stream instead got %s", contentType)
+	}
+
+	if _, err = cmClient.UploadProvenanceFileWithContext(context.Background(), provPath, true); err != nil {
+		t.Fatal("error uploading provenance file with force", err)
+	}
+	if !forced {
+		t.Error("expecting ?force on provenance file upload with force")
+	}
+
+	_, err = cmClient.UploadProvenanceFile(filepath.Join(tmp, "missing.tgz.prov"))
+	if err == nil || !strings.Contains(err.Error(), "could not read provenance file") {
+		t.Errorf("expecting error uploading missing provenance file instead got %v", err)
+	}
+}
+
+func TestUploadProvenanceFileError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		w.Write([]byte(`{"error":"invalid provenance file"}`))
+	}))
+	defer ts.Close()
+
+	cmClient, err := NewClient(URL(ts.URL))
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+	resp, err := cmClient.UploadProvenanceFile(testTarballPath)
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != 400 {
+		t.Fatalf("expecting a 400 *ResponseError instead got %v", err)
+	}
+	if err.Error() != "failed to upload provenance file: 400: invalid provenance file" {
+		t.Errorf("unexpected error %q", err)
+	}
+	if resp == nil || resp.StatusCode != 400 {
+		t.Errorf("expecting the 400 response along with the error, instead got %v", resp)
+	}
+}
+
+func TestUploadSignatureFile(t *testing.T) {
+	var uploadedPath, uploadedName string
+	var sigUploaded []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, fh, err := r.FormFile("prov")
+		if err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		uploadedPath = r.URL.Path
+		uploadedName = fh.Filename
+		sigUploaded, _ = io.ReadAll(f)
+		w.WriteHeader(201)
+	}))
+	defer ts.Close()
+
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+	sigPath := filepath.Join(tmp, "cosign.sig")
+	os.WriteFile(sigPath, []byte("MEUCIQ=="), 0644)
+
+	cmClient, err := NewClient(
+		URL(ts.URL),
+		ContextPath("/my/context/path"),
+	)
+	if err != nil {
+		t.Fatalf("expect creating a client instance but met error: %s", err)
+	}
+	resp, err := cmClient.UploadSignatureFile(sigPath, "mychart-0.1.0.tgz.sig", false)
+	if err != nil {
+		t.Fatal("error uploading signature file", err)
+	}
+	if resp.StatusCode != 201 {
+		t.Errorf("expecting 201 instead got %d", resp.StatusCode)
+	}
+	if uploadedPath != "/my/context/path/api/prov" || uploadedName != "mychart-0.1.0.tgz.sig" || string(sigUploaded) != "MEUCIQ==" {
+		t.Errorf("unexpected signature upload: %s %s %q", uploadedPath, uploadedName, sigUploaded)
+	}
+
+	// Custom endpoint path
+	cmClient.Option(SignaturePath("/api/signatures"))
+	if _, err := cmClient.UploadSignatureFile(sigPath, "mychart-0.1.0.tgz.sig", false); err != nil {
+		t.Fatal("error uploading signature file to custom path", err)
+	}
+	if uploadedPath != "/my/context/path/api/signatures" {
+		t.Errorf("expecting custom signature path instead got %s", uploadedPath)
+	}
+
+	_, err = cmClient.UploadSignatureFile(filepath.Join(tmp, "missing.sig"), "mychart-0.1.0.tgz.sig", false)
+	if err == nil || !strings.Contains(err.Error(), "could not read signature file") {
+		t.Errorf("expecting error uploading missing signature file instead got %v", err)
+	}
+}
+
 func TestUploadChartPackageWithTlsServer(t *testing.T) {
 	basicAuthHeader := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:pass"))
 	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
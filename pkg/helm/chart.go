@@ -1,9 +1,20 @@
 package helm
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/any"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
-	"helm.sh/helm/v3/pkg/chartutil"
 	v2chartutil "k8s.io/helm/pkg/chartutil"
 	v2chart "k8s.io/helm/pkg/proto/hapi/chart"
 )
@@ -25,9 +36,54 @@ func (c *Chart) SetVersion(version string) {
 	}
 }
 
-// GetChartByName returns a chart by "name", which can be
-// either a directory or .tgz package
+// SetAppVersion overrides the chart appVersion
+func (c *Chart) SetAppVersion(appVersion string) {
+	if c.V2 != nil {
+		c.V2.Metadata.AppVersion = appVersion
+	} else {
+		c.V3.Metadata.AppVersion = appVersion
+	}
+}
+
+// SetName overrides the chart name
+func (c *Chart) SetName(name string) {
+	if c.V2 != nil {
+		c.V2.Metadata.Name = name
+	} else {
+		c.V3.Metadata.Name = name
+	}
+}
+
+const (
+	chartTypeApplication = "application"
+	chartTypeLibrary     = "library"
+)
+
+var (
+	chartURLRegexp = regexp.MustCompile(`^https?://`)
+
+	// defaultDownloadClient downloads charts given by URL to
+	// GetChartByName, which has no settings of its own
+	defaultDownloadClient = &http.Client{Timeout: 30 * time.Second}
+)
+
+// IsChartURL reports whether a chart name is the URL of a .tgz package
+func IsChartURL(name string) bool {
+	return chartURLRegexp.MatchString(name)
+}
+
+// GetChartByName returns a chart by "name", which can be either
+// a directory, .tgz package or http(s) URL of a .tgz package
 func GetChartByName(name string) (*Chart, error) {
+	if IsChartURL(name) {
+		path, err := DownloadChart(context.Background(), defaultDownloadClient, name)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(path)
+		name = path
+	}
+
 	c := &Chart{}
 	v3c, err := loader.Load(name)
 	if err != nil {
@@ -49,11 +105,291 @@ func GetChartByName(name string) (*Chart, error) {
 	return c, nil
 }
 
-// CreateChartPackage creates a new .tgz package in directory
-func CreateChartPackage(c *Chart, outDir string) (string, error) {
+// GetChartVersion returns the version of the chart at path, which
+// can be either a directory or .tgz package
+func GetChartVersion(path string) (string, error) {
+	c, err := GetChartByName(path)
+	if err != nil {
+		return "", err
+	}
+	return c.Version(), nil
+}
+
+// ValidateChart checks that the chart directory or .tgz package at path
+// is structurally valid, returning an error describing the first problem
+func ValidateChart(path string) error {
+	// Loading also validates the Chart.yaml fields
+	c, err := loader.Load(path)
+	if err != nil {
+		return fmt.Errorf("chart %s is not valid: %s", path, err)
+	}
+
+	// Charts without templates only make sense as umbrella charts, as
+	// library charts, or to install the CRDs in crds/
+	if !isLibraryChart(c) && len(c.Templates) == 0 && len(c.CRDs()) == 0 && len(c.Dependencies()) == 0 && len(c.Metadata.Dependencies) == 0 {
+		return fmt.Errorf("chart %s is not valid: no templates found in templates/", path)
+	}
+	return nil
+}
+
+// DownloadChart downloads the .tgz package at url to a temp file with
+// client, returning its path. The download stops when ctx is cancelled
+func DownloadChart(ctx context.Context, client *http.Client, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("failed to download chart %s: %s", url, resp.Status)
+	}
+
+	f, err := os.CreateTemp("", "helm-push-*.tgz")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// Name returns the chart name
+func (c *Chart) Name() string {
+	if c.V2 != nil {
+		return c.V2.Metadata.Name
+	}
+	return c.V3.Metadata.Name
+}
+
+// Version returns the chart version
+func (c *Chart) Version() string {
+	if c.V2 != nil {
+		return c.V2.Metadata.Version
+	}
+	return c.V3.Metadata.Version
+}
+
+// AppVersion returns the chart appVersion
+func (c *Chart) AppVersion() string {
 	if c.V2 != nil {
-		return v2chartutil.Save(c.V2, outDir)
+		return c.V2.Metadata.AppVersion
+	}
+	return c.V3.Metadata.AppVersion
+}
+
+// Type returns the chart type, application or library. Helm 2 charts
+// are always application charts
+func (c *Chart) Type() string {
+	if c.V2 == nil && isLibraryChart(c.V3) {
+		return chartTypeLibrary
+	}
+	return chartTypeApplication
+}
+
+// IsLibrary reports whether the chart is a Helm 3 library chart, which
+// only provides templates to other charts and can't be installed
+func (c *Chart) IsLibrary() bool {
+	return c.Type() == chartTypeLibrary
+}
+
+func isLibraryChart(c *chart.Chart) bool {
+	return c.Metadata != nil && c.Metadata.Type == chartTypeLibrary
+}
+
+// IsMaintainedBy reports whether one of the chart's maintainers has the
+// given name or email, ignoring case
+func (c *Chart) IsMaintainedBy(nameOrEmail string) bool {
+	var maintainers [][2]string
+	if c.V2 != nil {
+		for _, m := range c.V2.Metadata.Maintainers {
+			maintainers = append(maintainers, [2]string{m.Name, m.Email})
+		}
+	} else {
+		for _, m := range c.V3.Metadata.Maintainers {
+			maintainers = append(maintainers, [2]string{m.Name, m.Email})
+		}
+	}
+	for _, m := range maintainers {
+		if strings.EqualFold(m[0], nameOrEmail) || (m[1] != "" && strings.EqualFold(m[1], nameOrEmail)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Annotation returns the value of the named Chart.yaml annotation,
+// or "" if it is not set
+func (c *Chart) Annotation(name string) string {
+	if c.V2 != nil {
+		return c.V2.Metadata.Annotations[name]
+	}
+	return c.V3.Metadata.Annotations[name]
+}
+
+// Annotations returns the Chart.yaml annotations
+func (c *Chart) Annotations() map[string]string {
+	if c.V2 != nil {
+		return c.V2.Metadata.Annotations
+	}
+	return c.V3.Metadata.Annotations
+}
+
+// SetAnnotations merges annotations into the chart metadata, overwriting
+// existing keys. Keys with an empty value are removed
+func (c *Chart) SetAnnotations(annotations map[string]string) {
+	current := c.Annotations()
+	if current == nil {
+		current = map[string]string{}
+	}
+	for k, v := range annotations {
+		if v == "" {
+			delete(current, k)
+		} else {
+			current[k] = v
+		}
+	}
+	if len(current) == 0 {
+		current = nil
+	}
+	if c.V2 != nil {
+		c.V2.Metadata.Annotations = current
 	} else {
-		return chartutil.Save(c.V3, outDir)
+		c.V3.Metadata.Annotations = current
+	}
+}
+
+// ErrFileExists is returned by AddFile when the chart already has a
+// file with the same name
+var ErrFileExists = errors.New("file already exists in the chart")
+
+// reservedFiles are written from the chart metadata and values, so
+// they can't be added with AddFile
+var reservedFiles = map[string]bool{
+	"Chart.yaml":         true,
+	"Chart.lock":         true,
+	"values.yaml":        true,
+	"values.schema.json": true,
+	"requirements.yaml":  true,
+	"requirements.lock":  true,
+}
+
+// AddFile adds a file to the chart at name, a slash-separated path
+// relative to the chart root. Files under templates/ are added as
+// templates. Unless overwrite is set, adding a file which already
+// exists in the chart fails
+func (c *Chart) AddFile(name string, data []byte, overwrite bool) error {
+	name = path.Clean(name)
+	if path.IsAbs(name) || name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return fmt.Errorf("invalid file name %s, must be relative to the chart root", name)
+	}
+	if reservedFiles[name] || name == "charts" || strings.HasPrefix(name, "charts/") {
+		return fmt.Errorf("can't add %s, it is managed by helm", name)
+	}
+	isTemplate := strings.HasPrefix(name, "templates/")
+
+	if c.V2 != nil {
+		if isTemplate {
+			for _, t := range c.V2.Templates {
+				if t.Name == name {
+					if !overwrite {
+						return fmt.Errorf("%w: %s", ErrFileExists, name)
+					}
+					t.Data = data
+					return nil
+				}
+			}
+			c.V2.Templates = append(c.V2.Templates, &v2chart.Template{Name: name, Data: data})
+			return nil
+		}
+		for _, f := range c.V2.Files {
+			if f.TypeUrl == name {
+				if !overwrite {
+					return fmt.Errorf("%w: %s", ErrFileExists, name)
+				}
+				f.Value = data
+				return nil
+			}
+		}
+		c.V2.Files = append(c.V2.Files, &any.Any{TypeUrl: name, Value: data})
+		return nil
+	}
+
+	files := &c.V3.Files
+	if isTemplate {
+		files = &c.V3.Templates
+	}
+	for _, f := range *files {
+		if f.Name == name {
+			if !overwrite {
+				return fmt.Errorf("%w: %s", ErrFileExists, name)
+			}
+			f.Data = data
+			return nil
+		}
+	}
+	*files = append(*files, &chart.File{Name: name, Data: data})
+	return nil
+}
+
+// dependency is a dependency of a chart, from Chart.yaml (Helm 3) or
+// requirements.yaml (Helm 2)
+type dependency struct{ name, alias, condition string }
+
+func (c *Chart) dependencies() []dependency {
+	var deps []dependency
+	if c.V2 != nil {
+		reqs, err := v2chartutil.LoadRequirements(c.V2)
+		if err == nil {
+			for _, d := range reqs.Dependencies {
+				deps = append(deps, dependency{d.Name, d.Alias, d.Condition})
+			}
+		}
+	} else {
+		for _, d := range c.V3.Metadata.Dependencies {
+			deps = append(deps, dependency{d.Name, d.Alias, d.Condition})
+		}
+	}
+	return deps
+}
+
+// DependencyNames returns the chart names of the chart's dependencies
+func (c *Chart) DependencyNames() []string {
+	var names []string
+	for _, d := range c.dependencies() {
+		names = append(names, d.name)
+	}
+	return names
+}
+
+// DependenciesReferencing returns the names of the chart's dependencies
+// which refer to name, either as their own name or alias, or in their
+// condition (e.g. "name.enabled")
+func (c *Chart) DependenciesReferencing(name string) []string {
+	var names []string
+	for _, d := range c.dependencies() {
+		if d.name == name || d.alias == name || conditionReferences(d.condition, name) {
+			names = append(names, d.name)
+		}
+	}
+	return names
+}
+
+// conditionReferences reports whether any of the comma-separated value
+// paths in a dependency condition has name as one of its elements
+func conditionReferences(condition string, name string) bool {
+	for _, path := range strings.Split(condition, ",") {
+		for _, elem := range strings.Split(strings.TrimSpace(path), ".") {
+			if elem == name {
+				return true
+			}
+		}
 	}
+	return false
 }
@@ -1,9 +1,13 @@
 package helm
 
 import (
-	"io/ioutil"
+	"archive/tar"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
+	"strings"
 	"testing"
 )
 
@@ -15,11 +19,27 @@ func TestSetVersion(t *testing.T) {
 		t.Error("unexpected error getting test tarball chart", err)
 	}
 	c.SetVersion("latest")
+	if c.Version() != "latest" {
+		t.Errorf("expected chart Version() to be latest, instead got %s", c.Version())
+	}
 	if c.V2.Metadata.Version != "latest" {
 		t.Errorf("expected chart version to be latest, instead got %s", c.V2.Metadata.Version)
 	}
 }
 
+func TestSetAppVersion(t *testing.T) {
+	for _, chartPath := range []string{testTarballPath, "../../testdata/charts/helm3/my-v3-chart"} {
+		c, err := GetChartByName(chartPath)
+		if err != nil {
+			t.Fatal("unexpected error getting chart", err)
+		}
+		c.SetAppVersion("2.0.0")
+		if c.AppVersion() != "2.0.0" {
+			t.Errorf("%s: expected chart AppVersion() to be 2.0.0, instead got %s", chartPath, c.AppVersion())
+		}
+	}
+}
+
 func TestGetChartByName(t *testing.T) {
 	// Bad name
 	_, err := GetChartByName("/non/existant/path/mychart-0.1.0.tgz")
@@ -35,18 +55,115 @@ func TestGetChartByName(t *testing.T) {
 	if c.V2.Metadata.Name != "mychart" {
 		t.Errorf("expexted chart name to be mychart, instead got %s", c.V2.Metadata.Name)
 	}
+	if c.Name() != "mychart" {
+		t.Errorf("expexted chart Name() to be mychart, instead got %s", c.Name())
+	}
 	if c.V2.Metadata.Version != "0.1.0" {
 		t.Errorf("expexted chart version to be 0.1.0, instead got %s", c.V2.Metadata.Version)
 	}
 }
 
+func TestGetChartByNameFromURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/charts/mychart-0.1.0.tgz" {
+			w.WriteHeader(404)
+			return
+		}
+		http.ServeFile(w, r, testTarballPath)
+	}))
+	defer ts.Close()
+
+	c, err := GetChartByName(ts.URL + "/charts/mychart-0.1.0.tgz")
+	if err != nil {
+		t.Fatal("unexpected error getting chart by URL", err)
+	}
+	if c.Name() != "mychart" || c.Version() != "0.1.0" {
+		t.Errorf("expected chart mychart-0.1.0, instead got %s-%s", c.Name(), c.Version())
+	}
+
+	_, err = GetChartByName(ts.URL + "/charts/missing-0.1.0.tgz")
+	if err == nil {
+		t.Error("expected error getting chart with bad URL, instead got nil")
+	}
+}
+
+func TestGetChartVersion(t *testing.T) {
+	version, err := GetChartVersion("../../testdata/charts/helm3/my-v3-chart")
+	if err != nil {
+		t.Error("unexpected error getting chart version", err)
+	}
+	if version != "0.1.0" {
+		t.Errorf("expected chart version to be 0.1.0, instead got %s", version)
+	}
+
+	if _, err := GetChartVersion("/non/existant/path/mychart"); err == nil {
+		t.Error("expected error getting version of chart with bad path, instead got nil")
+	}
+}
+
+func TestValidateChart(t *testing.T) {
+	for _, path := range []string{
+		testTarballPath,
+		"../../testdata/charts/helm2/mychart",
+		"../../testdata/charts/helm3/my-v3-chart",
+	} {
+		if err := ValidateChart(path); err != nil {
+			t.Errorf("unexpected error validating %s: %s", path, err)
+		}
+	}
+
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	// Missing Chart.yaml
+	if err := ValidateChart(tmp); err == nil {
+		t.Error("expected error validating chart without Chart.yaml, instead got nil")
+	}
+
+	// Missing version
+	os.WriteFile(path.Join(tmp, "Chart.yaml"), []byte("apiVersion: v2\nname: mychart\n"), 0644)
+	if err := ValidateChart(tmp); err == nil {
+		t.Error("expected error validating chart without version, instead got nil")
+	}
+
+	// Missing templates
+	os.WriteFile(path.Join(tmp, "Chart.yaml"), []byte("apiVersion: v2\nname: mychart\nversion: 0.1.0\n"), 0644)
+	if err := ValidateChart(tmp); err == nil || !strings.Contains(err.Error(), "no templates") {
+		t.Errorf("expected error validating chart without templates, instead got %v", err)
+	}
+
+	// Library charts may have no templates
+	os.WriteFile(path.Join(tmp, "Chart.yaml"), []byte("apiVersion: v2\nname: mylib\nversion: 0.1.0\ntype: library\n"), 0644)
+	if err := ValidateChart(tmp); err != nil {
+		t.Error("unexpected error validating library chart without templates", err)
+	}
+
+	// CRD-only charts have no templates either
+	os.WriteFile(path.Join(tmp, "Chart.yaml"), []byte("apiVersion: v2\nname: mycrds\nversion: 0.1.0\n"), 0644)
+	os.MkdirAll(path.Join(tmp, "crds"), 0755)
+	os.WriteFile(path.Join(tmp, "crds", "crontab.yaml"), []byte("apiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: crontabs.stable.example.com\n"), 0644)
+	if err := ValidateChart(tmp); err != nil {
+		t.Error("unexpected error validating CRD-only chart", err)
+	}
+	os.RemoveAll(path.Join(tmp, "crds"))
+
+	os.MkdirAll(path.Join(tmp, "templates"), 0755)
+	os.WriteFile(path.Join(tmp, "templates", "cm.yaml"), []byte("kind: ConfigMap\n"), 0644)
+	if err := ValidateChart(tmp); err != nil {
+		t.Error("unexpected error validating chart", err)
+	}
+}
+
 func TestCreateChartPackage(t *testing.T) {
 	c, err := GetChartByName(testTarballPath)
 	if err != nil {
 		t.Error("unexpected error getting test tarball chart", err)
 	}
 
-	tmp, err := ioutil.TempDir("", "helm-push-test")
+	tmp, err := os.MkdirTemp("", "helm-push-test")
 	if err != nil {
 		t.Error("unexpected error creating temp test dir", err)
 	}
@@ -62,3 +179,303 @@ func TestCreateChartPackage(t *testing.T) {
 		t.Errorf("expected chart path to be %s, but was %s", expectedPath, chartPackagePath)
 	}
 }
+
+func TestCreateChartPackageIsReproducible(t *testing.T) {
+	c, err := GetChartByName("../../testdata/charts/helm3/my-v3-chart")
+	if err != nil {
+		t.Fatal("unexpected error getting test chart", err)
+	}
+
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	chartPackagePath, err := CreateChartPackage(c, tmp)
+	if err != nil {
+		t.Fatal("unexpected error creating chart package", err)
+	}
+	f, err := os.Open(chartPackagePath)
+	if err != nil {
+		t.Fatal("unexpected error opening chart package", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal("unexpected error reading chart package", err)
+	}
+	if zr.Header.Comment != "Helm" {
+		t.Errorf("expected gzip header to be kept, instead got comment %q", zr.Header.Comment)
+	}
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		if !hdr.ModTime.IsZero() && hdr.ModTime.Unix() != 0 {
+			t.Errorf("expected no modification time for %s, instead got %s", hdr.Name, hdr.ModTime)
+		}
+	}
+
+	if c, err = GetChartByName(chartPackagePath); err != nil || c.Name() != "my-v3-chart" {
+		t.Errorf("unexpected error loading normalized chart package: %v", err)
+	}
+}
+
+func TestAddFile(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, chartPath := range []string{testTarballPath, "../../testdata/charts/helm3/my-v3-chart"} {
+		c, err := GetChartByName(chartPath)
+		if err != nil {
+			t.Fatal("unexpected error getting chart", err)
+		}
+		if err := c.AddFile("README.md", []byte("# readme"), false); err != nil {
+			t.Fatalf("%s: unexpected error adding file: %s", chartPath, err)
+		}
+		if err := c.AddFile("templates/extra.yaml", []byte("kind: ConfigMap"), false); err != nil {
+			t.Fatalf("%s: unexpected error adding template: %s", chartPath, err)
+		}
+
+		for name, expected := range map[string]string{
+			"README.md":     "file already exists",
+			"Chart.yaml":    "managed by helm",
+			"charts/x.tgz":  "managed by helm",
+			"../LICENSE":    "must be relative",
+			"/etc/passwd":   "must be relative",
+			"docs/../../x":  "must be relative",
+			"templates/../": "must be relative",
+		} {
+			if err := c.AddFile(name, nil, false); err == nil || !strings.Contains(err.Error(), expected) {
+				t.Errorf("%s: expected error adding %s to contain %q, instead got %v", chartPath, name, expected, err)
+			}
+		}
+		if err := c.AddFile("README.md", []byte("# new readme"), true); err != nil {
+			t.Errorf("%s: unexpected error overwriting file: %s", chartPath, err)
+		}
+
+		chartPackagePath, err := CreateChartPackage(c, tmp)
+		if err != nil {
+			t.Fatal("unexpected error creating chart package", err)
+		}
+		c, err = GetChartByName(chartPackagePath)
+		if err != nil {
+			t.Fatal("unexpected error loading chart package", err)
+		}
+		files := map[string]string{}
+		if c.V2 != nil {
+			for _, f := range c.V2.Files {
+				files[f.TypeUrl] = string(f.Value)
+			}
+			for _, f := range c.V2.Templates {
+				files[f.Name] = string(f.Data)
+			}
+		} else {
+			for _, f := range c.V3.Files {
+				files[f.Name] = string(f.Data)
+			}
+			for _, f := range c.V3.Templates {
+				files[f.Name] = string(f.Data)
+			}
+		}
+		if files["README.md"] != "# new readme" || files["templates/extra.yaml"] != "kind: ConfigMap" {
+			t.Errorf("%s: expected added files in chart package, instead got %v", chartPath, files)
+		}
+	}
+}
+
+func TestChartType(t *testing.T) {
+	for path, expected := range map[string]string{
+		testTarballPath: "application",
+		"../../testdata/charts/helm3/my-v3-chart": "application",
+	} {
+		c, err := GetChartByName(path)
+		if err != nil {
+			t.Fatalf("unexpected error loading %s: %s", path, err)
+		}
+		if c.Type() != expected || c.IsLibrary() {
+			t.Errorf("%s: expected %s chart, instead got %s", path, expected, c.Type())
+		}
+	}
+
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+	os.WriteFile(path.Join(tmp, "Chart.yaml"), []byte("apiVersion: v2\nname: mylib\nversion: 0.1.0\ntype: library\n"), 0644)
+	c, err := GetChartByName(tmp)
+	if err != nil {
+		t.Fatal("unexpected error loading library chart", err)
+	}
+	if c.Type() != "library" || !c.IsLibrary() {
+		t.Errorf("expected library chart, instead got %s", c.Type())
+	}
+}
+
+func TestIsMaintainedBy(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, apiVersion := range []string{"v1", "v2"} {
+		os.WriteFile(path.Join(tmp, "Chart.yaml"), []byte("apiVersion: "+apiVersion+"\nname: mychart\nversion: 0.1.0\nmaintainers:\n- name: Platform Team\n  email: platform@example.com\n- name: Jane\n"), 0644)
+		c, err := GetChartByName(tmp)
+		if err != nil {
+			t.Fatal("unexpected error getting chart", err)
+		}
+		for nameOrEmail, expected := range map[string]bool{
+			"platform team":        true,
+			"Platform@example.com": true,
+			"Jane":                 true,
+			"":                     false,
+			"bitnami":              false,
+		} {
+			if c.IsMaintainedBy(nameOrEmail) != expected {
+				t.Errorf("%s: expected IsMaintainedBy(%q) to be %t", apiVersion, nameOrEmail, expected)
+			}
+		}
+	}
+}
+
+func TestAnnotation(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, apiVersion := range []string{"v1", "v2"} {
+		os.WriteFile(path.Join(tmp, "Chart.yaml"), []byte("apiVersion: "+apiVersion+"\nname: mychart\nversion: 0.1.0\nannotations:\n  helm-push/repo: team-a\n"), 0644)
+		c, err := GetChartByName(tmp)
+		if err != nil {
+			t.Fatal("unexpected error getting chart", err)
+		}
+		if repo := c.Annotation("helm-push/repo"); repo != "team-a" {
+			t.Errorf("%s: expected annotation to be team-a, instead got %q", apiVersion, repo)
+		}
+		if v := c.Annotation("missing"); v != "" {
+			t.Errorf("%s: expected missing annotation to be empty, instead got %q", apiVersion, v)
+		}
+	}
+}
+
+func TestSetAnnotations(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, chartPath := range []string{testTarballPath, "../../testdata/charts/helm3/my-v3-chart"} {
+		c, err := GetChartByName(chartPath)
+		if err != nil {
+			t.Fatal("unexpected error getting chart", err)
+		}
+		c.SetAnnotations(map[string]string{"build": "42", "ticket": "ABC-1"})
+		c.SetAnnotations(map[string]string{"build": "43", "ticket": ""})
+		if a := c.Annotations(); len(a) != 1 || a["build"] != "43" {
+			t.Errorf("%s: unexpected annotations %v", chartPath, a)
+		}
+
+		// Annotations are kept when packaging
+		chartPackagePath, err := CreateChartPackage(c, tmp)
+		if err != nil {
+			t.Fatal("unexpected error creating chart package", err)
+		}
+		if c, err = GetChartByName(chartPackagePath); err != nil {
+			t.Fatal("unexpected error loading chart package", err)
+		}
+		if v := c.Annotation("build"); v != "43" {
+			t.Errorf("%s: expected packaged annotation to be 43, instead got %q", chartPath, v)
+		}
+	}
+}
+
+func TestSetName(t *testing.T) {
+	c, err := GetChartByName("../../testdata/charts/helm3/my-v3-chart")
+	if err != nil {
+		t.Fatal("unexpected error getting test chart", err)
+	}
+	c.SetName("acme-chart")
+	if c.Name() != "acme-chart" {
+		t.Errorf("expected chart Name() to be acme-chart, instead got %s", c.Name())
+	}
+
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+	chartPackagePath, err := CreateChartPackage(c, tmp)
+	if err != nil {
+		t.Fatal("unexpected error creating chart package", err)
+	}
+	if expected := path.Join(tmp, "acme-chart-0.1.0.tgz"); chartPackagePath != expected {
+		t.Errorf("expected chart path to be %s, but was %s", expected, chartPackagePath)
+	}
+	if c, err = GetChartByName(chartPackagePath); err != nil || c.Name() != "acme-chart" {
+		t.Errorf("expected renamed chart package, instead got %v", err)
+	}
+}
+
+func TestDependenciesReferencing(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	deps := `
+- name: common
+  version: 1.0.0
+  repository: file://../common
+- name: redis
+  version: 1.0.0
+  repository: https://example.com
+  condition: mychart.redis.enabled
+- name: postgres
+  version: 1.0.0
+  repository: https://example.com
+  condition: postgres.enabled
+`
+	for apiVersion, files := range map[string]map[string]string{
+		"v1": {
+			"Chart.yaml":        "apiVersion: v1\nname: mychart\nversion: 0.1.0\n",
+			"requirements.yaml": "dependencies:" + deps,
+		},
+		"v2": {
+			"Chart.yaml": "apiVersion: v2\nname: mychart\nversion: 0.1.0\ndependencies:" + deps,
+		},
+	} {
+		dir := path.Join(tmp, apiVersion)
+		os.MkdirAll(dir, 0755)
+		for name, content := range files {
+			os.WriteFile(path.Join(dir, name), []byte(content), 0644)
+		}
+		c, err := GetChartByName(dir)
+		if err != nil {
+			t.Fatal("unexpected error getting test chart", err)
+		}
+		if names := c.DependenciesReferencing("mychart"); len(names) != 1 || names[0] != "redis" {
+			t.Errorf("%s: expected redis to reference mychart, instead got %v", apiVersion, names)
+		}
+		if names := c.DependenciesReferencing("common"); len(names) != 1 || names[0] != "common" {
+			t.Errorf("%s: expected common to reference common, instead got %v", apiVersion, names)
+		}
+		if names := c.DependenciesReferencing("other"); len(names) != 0 {
+			t.Errorf("%s: expected no dependencies to reference other, instead got %v", apiVersion, names)
+		}
+		if names := c.DependencyNames(); strings.Join(names, ",") != "common,redis,postgres" {
+			t.Errorf("%s: unexpected dependency names %v", apiVersion, names)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package helm
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	v2chart "k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+type (
+	// ForbiddenFile is a file in a chart which looks like a secret
+	ForbiddenFile struct {
+		// Path is the path of the file in the chart, subchart files
+		// are under charts/<subchart>/
+		Path string
+		// Reason is the pattern the file matched, or "private key"
+		Reason string
+	}
+)
+
+var (
+	// DefaultForbiddenPatterns are the names of files which usually hold
+	// secrets. Files containing a PEM private key are also forbidden by
+	// default
+	DefaultForbiddenPatterns = []string{"*.p12", ".env", "id_rsa"}
+
+	privateKeyRegexp = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----`)
+)
+
+// String returns the path of the file and why it is forbidden
+func (f ForbiddenFile) String() string {
+	return fmt.Sprintf("%s (%s)", f.Path, f.Reason)
+}
+
+// ValidateForbidPattern checks the syntax of a forbidden file pattern
+func ValidateForbidPattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q: %s", pattern, err)
+	}
+	return nil
+}
+
+// ForbiddenFiles returns the files of the chart and its subcharts matching
+// one of the patterns, sorted by path. Patterns without a "/" are matched
+// against file names, others against paths in the chart. With privateKeys,
+// files containing a PEM private key are returned too
+func (c *Chart) ForbiddenFiles(patterns []string, privateKeys bool) ([]ForbiddenFile, error) {
+	for _, pattern := range patterns {
+		if err := ValidateForbidPattern(pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	files := map[string][]byte{}
+	if c.V2 != nil {
+		collectV2Files(c.V2, "", files)
+	} else {
+		collectFiles(c.V3, "", files)
+	}
+
+	var forbidden []ForbiddenFile
+	for name, data := range files {
+		if reason := forbiddenReason(name, data, patterns, privateKeys); reason != "" {
+			forbidden = append(forbidden, ForbiddenFile{Path: name, Reason: reason})
+		}
+	}
+	sort.Slice(forbidden, func(i, j int) bool {
+		return forbidden[i].Path < forbidden[j].Path
+	})
+	return forbidden, nil
+}
+
+func forbiddenReason(name string, data []byte, patterns []string, privateKeys bool) string {
+	for _, pattern := range patterns {
+		subject := name
+		if !strings.Contains(pattern, "/") {
+			subject = path.Base(name)
+		}
+		if ok, _ := path.Match(pattern, subject); ok {
+			return pattern
+		}
+	}
+	if privateKeys && privateKeyRegexp.Match(data) {
+		return "private key"
+	}
+	return ""
+}
+
+// collectFiles adds the files of a chart to files, keyed by their path
+// under prefix. Files added after loading are not in c.Raw
+func collectFiles(c *chart.Chart, prefix string, files map[string][]byte) {
+	for _, f := range c.Raw {
+		if !strings.HasPrefix(f.Name, "charts/") {
+			files[prefix+f.Name] = f.Data
+		}
+	}
+	for _, f := range c.Templates {
+		files[prefix+f.Name] = f.Data
+	}
+	for _, f := range c.Files {
+		files[prefix+f.Name] = f.Data
+	}
+	for _, dep := range c.Dependencies() {
+		collectFiles(dep, prefix+"charts/"+dep.Name()+"/", files)
+	}
+}
+
+// collectV2Files is collectFiles for Helm 2 charts
+func collectV2Files(c *v2chart.Chart, prefix string, files map[string][]byte) {
+	if c.Values != nil {
+		files[prefix+"values.yaml"] = []byte(c.Values.Raw)
+	}
+	for _, t := range c.Templates {
+		files[prefix+t.Name] = t.Data
+	}
+	for _, f := range c.Files {
+		files[prefix+f.TypeUrl] = f.Value
+	}
+	for _, dep := range c.Dependencies {
+		collectV2Files(dep, prefix+"charts/"+dep.Metadata.Name+"/", files)
+	}
+}
@@ -0,0 +1,143 @@
+package helm
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	v2chartutil "k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/ignore"
+	"k8s.io/helm/pkg/sympath"
+)
+
+var utf8bom = []byte{0xEF, 0xBB, 0xBF}
+
+// ReadIgnoreFile returns the patterns in a .helmignore style file
+func ReadIgnoreFile(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read ignore file: %s", err)
+	}
+	return strings.Split(string(b), "\n"), nil
+}
+
+// GetChartByNameIgnoring returns a chart like GetChartByName, but chart
+// directories are loaded as if patterns were appended to their .helmignore.
+// If excluded is not nil, it is called with the path of each file or
+// directory left out because of patterns
+func GetChartByNameIgnoring(name string, patterns []string, excluded func(string)) (*Chart, error) {
+	if len(patterns) == 0 || IsChartURL(name) {
+		return GetChartByName(name)
+	}
+	if fi, err := os.Stat(name); err != nil || !fi.IsDir() {
+		return GetChartByName(name)
+	}
+
+	files, err := loadDirFiles(name, patterns, excluded)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Chart{}
+	v3files := make([]*loader.BufferedFile, len(files))
+	for i, f := range files {
+		v3files[i] = &loader.BufferedFile{Name: f.Name, Data: bytes.TrimPrefix(f.Data, utf8bom)}
+	}
+	v3c, err := loader.LoadFiles(v3files)
+	if err != nil {
+		return nil, err
+	}
+
+	// Same as GetChartByName, Helm 2 charts use the old loader
+	if v3c.Metadata.APIVersion == chart.APIVersionV1 {
+		v2c, err := v2chartutil.LoadFiles(files)
+		if err != nil {
+			return nil, err
+		}
+		c.V2 = v2c
+	} else {
+		c.V3 = v3c
+	}
+	return c, nil
+}
+
+// loadDirFiles reads the files of the chart directory the same way helm
+// does, applying its .helmignore followed by patterns
+func loadDirFiles(dir string, patterns []string, excluded func(string)) ([]*v2chartutil.BufferedFile, error) {
+	topdir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var helmignore []byte
+	ifile := filepath.Join(topdir, ignore.HelmIgnore)
+	if _, err := os.Stat(ifile); err == nil {
+		if helmignore, err = os.ReadFile(ifile); err != nil {
+			return nil, err
+		}
+	}
+	base, err := ignore.Parse(bytes.NewReader(helmignore))
+	if err != nil {
+		return nil, err
+	}
+	base.AddDefaults()
+	rules, err := ignore.Parse(strings.NewReader(string(helmignore) + "\n" + strings.Join(patterns, "\n")))
+	if err != nil {
+		return nil, err
+	}
+	rules.AddDefaults()
+
+	// reports paths only left out because of the extra patterns
+	ignored := func(n string, fi os.FileInfo) bool {
+		if !rules.Ignore(n, fi) {
+			return false
+		}
+		if excluded != nil && !base.Ignore(n, fi) {
+			if fi.IsDir() {
+				n += "/"
+			}
+			excluded(n)
+		}
+		return true
+	}
+
+	var files []*v2chartutil.BufferedFile
+	topdir += string(filepath.Separator)
+	walk := func(name string, fi os.FileInfo, err error) error {
+		n := strings.TrimPrefix(name, topdir)
+		if n == "" {
+			return nil
+		}
+		n = filepath.ToSlash(n)
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if ignored(n, fi) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ignored(n, fi) {
+			return nil
+		}
+		if !fi.Mode().IsRegular() {
+			return fmt.Errorf("cannot load irregular file %s as it has file mode type bits set", name)
+		}
+
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %s", n, err)
+		}
+		files = append(files, &v2chartutil.BufferedFile{Name: n, Data: data})
+		return nil
+	}
+	if err := sympath.Walk(topdir, walk); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
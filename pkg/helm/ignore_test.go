@@ -0,0 +1,105 @@
+package helm
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestGetChartByNameIgnoring(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	for name, content := range map[string]string{
+		"Chart.yaml":            "apiVersion: v2\nname: mychart\nversion: 0.1.0\n",
+		"values.yaml":           "replicas: 1\n",
+		".helmignore":           "*.bak",
+		"templates/cm.yaml":     "kind: ConfigMap\n",
+		"templates/cm.yaml.bak": "kind: ConfigMap\n",
+		"tests/fixtures/a.json": "{}",
+		"golden/cm.golden":      "kind: ConfigMap\n",
+		"golden/README.md":      "golden files",
+	} {
+		path := filepath.Join(tmp, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	patterns := []string{"tests/", "*.golden"}
+	var excluded []string
+	c, err := GetChartByNameIgnoring(tmp, patterns, func(name string) {
+		excluded = append(excluded, name)
+	})
+	if err != nil {
+		t.Fatal("unexpected error loading chart with ignore patterns", err)
+	}
+	if expected := []string{"golden/cm.golden", "tests/"}; !reflect.DeepEqual(excluded, expected) {
+		t.Errorf("expected excluded files %v, instead got %v", expected, excluded)
+	}
+
+	// The same files are loaded as with the patterns in .helmignore
+	os.WriteFile(filepath.Join(tmp, ".helmignore"), []byte("*.bak\ntests/\n*.golden\n"), 0644)
+	expected, err := GetChartByName(tmp)
+	if err != nil {
+		t.Fatal("unexpected error loading chart", err)
+	}
+	if names, expectedNames := rawFileNames(c), rawFileNames(expected); !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("expected chart files %v, instead got %v", expectedNames, names)
+	}
+
+	// Helm 2 charts are still loaded with the old loader
+	c, err = GetChartByNameIgnoring("../../testdata/charts/helm2/mychart", []string{"requirements.lock"}, nil)
+	if err != nil {
+		t.Fatal("unexpected error loading Helm 2 chart with ignore patterns", err)
+	}
+	if c.V2 == nil {
+		t.Fatal("expected Helm 2 chart to be loaded with the Helm 2 loader")
+	}
+	for _, f := range c.V2.Files {
+		if f.TypeUrl == "requirements.lock" {
+			t.Error("expected requirements.lock to be ignored")
+		}
+	}
+}
+
+func TestReadIgnoreFile(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	path := filepath.Join(tmp, "ignore")
+	os.WriteFile(path, []byte("# fixtures\ntests/\n*.golden\n"), 0644)
+	patterns, err := ReadIgnoreFile(path)
+	if err != nil {
+		t.Fatal("unexpected error reading ignore file", err)
+	}
+	if expected := []string{"# fixtures", "tests/", "*.golden", ""}; !reflect.DeepEqual(patterns, expected) {
+		t.Errorf("expected patterns %v, instead got %v", expected, patterns)
+	}
+
+	if _, err := ReadIgnoreFile(filepath.Join(tmp, "nonexistant")); err == nil {
+		t.Error("expected error reading missing ignore file, instead got nil")
+	}
+}
+
+// rawFileNames returns the sorted names of the files a chart was loaded
+// from, except .helmignore
+func rawFileNames(c *Chart) []string {
+	var names []string
+	for _, f := range c.V3.Raw {
+		if f.Name != ".helmignore" {
+			names = append(names, f.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
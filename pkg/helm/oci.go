@@ -0,0 +1,99 @@
+package helm
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ociScheme is the scheme of OCI registry references
+const ociScheme = "oci://"
+
+// IsOCIRef returns whether a repo is an oci:// registry reference
+func IsOCIRef(repo string) bool {
+	return strings.HasPrefix(repo, ociScheme)
+}
+
+// OCIRef returns the oci:// reference for a chart repo URL
+func OCIRef(repoURL string) (string, error) {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid repo URL for OCI: %s", repoURL)
+	}
+	u.Scheme = "oci"
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
+
+// RegistryLogin logs in to the OCI registry of ref with the helm CLI
+func RegistryLogin(ref string, username string, password string, out io.Writer) error {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return err
+	}
+	cmd := helmCommand(out, "registry", "login", u.Host, "--username", username, "--password-stdin")
+	cmd.Stdin = strings.NewReader(password)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("helm registry login %s failed: %s", u.Host, err)
+	}
+	return nil
+}
+
+// PushOCI pushes a chart package to the OCI registry ref with the
+// helm CLI, which needs Helm 3.8 or later. It returns the digest of the
+// pushed chart helm reports, or "" if it reported none
+func PushOCI(chartPackagePath string, ref string, out io.Writer) (string, error) {
+	var output bytes.Buffer
+	cmd := helmCommand(io.MultiWriter(out, &output), "push", chartPackagePath, ref)
+	// depending on its version, helm reports the digest on stdout or stderr
+	cmd.Stderr = io.MultiWriter(os.Stderr, &output)
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("helm push %s failed: %s", ref, err)
+	}
+	return pushedDigest(&output), nil
+}
+
+// pushedDigest returns the digest from the "Digest: sha256:..." line
+// printed by helm push
+func pushedDigest(output io.Reader) string {
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		if digest := strings.TrimPrefix(scanner.Text(), "Digest: "); digest != scanner.Text() {
+			return strings.TrimSpace(digest)
+		}
+	}
+	return ""
+}
+
+// OCIChartRef returns the reference of a chart version pushed to the
+// OCI registry ref, with the digest if it is known. Helm tags charts
+// with their version, with "+" replaced as tags cannot contain it
+func OCIChartRef(ref string, name string, version string, digest string) string {
+	chartRef := fmt.Sprintf("%s/%s:%s", strings.TrimSuffix(ref, "/"), name, strings.Replace(version, "+", "_", -1))
+	if digest != "" {
+		chartRef += "@" + digest
+	}
+	return chartRef
+}
+
+func helmCommand(out io.Writer, args ...string) *exec.Cmd {
+	cmd := exec.Command(helmBin(), args...)
+	cmd.Stdout = out
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// helmBin returns the helm binary, $HELM_BIN when run as a plugin
+func helmBin() string {
+	if helmBin, ok := os.LookupEnv("HELM_BIN"); ok {
+		return helmBin
+	}
+	return "helm"
+}
@@ -0,0 +1,90 @@
+package helm
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestOCIRef(t *testing.T) {
+	for repoURL, expected := range map[string]string{
+		"https://registry.example.com/charts/": "oci://registry.example.com/charts",
+		"cm://registry.example.com":            "oci://registry.example.com",
+	} {
+		ref, err := OCIRef(repoURL)
+		if err != nil {
+			t.Errorf("unexpected error getting OCI ref for %s: %s", repoURL, err)
+		}
+		if ref != expected {
+			t.Errorf("expected OCI ref for %s to be %s, instead got %s", repoURL, expected, ref)
+		}
+	}
+
+	if _, err := OCIRef("not a url"); err == nil {
+		t.Error("expected error getting OCI ref for bad URL, instead got nil")
+	}
+}
+
+func TestPushOCI(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a shell script as helm")
+	}
+
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	// Fake helm which records its args and stdin
+	logPath := filepath.Join(tmp, "helm.log")
+	helmPath := filepath.Join(tmp, "helm")
+	script := "#!/bin/sh\necho \"$@\" >> " + logPath + "\nif [ \"$1\" = registry ]; then cat >> " + logPath + "; echo >> " + logPath + "; fi\n"
+	os.WriteFile(helmPath, []byte(script), 0755)
+	os.Setenv("HELM_BIN", helmPath)
+	defer os.Unsetenv("HELM_BIN")
+
+	var out bytes.Buffer
+	if err := RegistryLogin("oci://registry.example.com/charts", "user", "pass", &out); err != nil {
+		t.Fatal("unexpected error logging in to registry", err)
+	}
+	if digest, err := PushOCI("mychart-0.1.0.tgz", "oci://registry.example.com/charts", &out); err != nil || digest != "" {
+		t.Fatalf("unexpected result pushing to registry: %q, %v", digest, err)
+	}
+
+	b, _ := os.ReadFile(logPath)
+	expected := "registry login registry.example.com --username user --password-stdin\npass\npush mychart-0.1.0.tgz oci://registry.example.com/charts\n"
+	if string(b) != expected {
+		t.Errorf("unexpected helm commands: %q", string(b))
+	}
+
+	// the digest is taken from the output of helm push
+	os.WriteFile(helmPath, []byte("#!/bin/sh\necho Pushed: registry.example.com/charts/mychart:0.1.0 >&2\necho Digest: sha256:0123abcd >&2\n"), 0755)
+	if digest, err := PushOCI("mychart-0.1.0.tgz", "oci://registry.example.com/charts", &out); err != nil || digest != "sha256:0123abcd" {
+		t.Errorf("expected digest from helm push output, instead got %q, %v", digest, err)
+	}
+
+	os.Setenv("HELM_BIN", filepath.Join(tmp, "missing"))
+	if _, err := PushOCI("mychart-0.1.0.tgz", "oci://registry.example.com/charts", &out); err == nil || !strings.Contains(err.Error(), "helm push") {
+		t.Errorf("expected error pushing with missing helm, instead got %v", err)
+	}
+}
+
+func TestOCIChartRef(t *testing.T) {
+	if !IsOCIRef("oci://registry.example.com/charts") || IsOCIRef("https://registry.example.com/charts") {
+		t.Error("expected only oci:// repos to be OCI references")
+	}
+	for _, tc := range []struct {
+		ref, version, digest, expected string
+	}{
+		{"oci://registry.example.com/charts", "0.1.0", "sha256:0123abcd", "oci://registry.example.com/charts/mychart:0.1.0@sha256:0123abcd"},
+		{"oci://registry.example.com/charts/", "0.1.0+build.1", "", "oci://registry.example.com/charts/mychart:0.1.0_build.1"},
+	} {
+		if actual := OCIChartRef(tc.ref, "mychart", tc.version, tc.digest); actual != tc.expected {
+			t.Errorf("expected %s, instead got %s", tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package helm
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/ghodss/yaml"
+	"helm.sh/helm/v3/pkg/chart"
+	v2chart "k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// packageHeaderExtra is the gzip header extra field helm package writes
+var packageHeaderExtra = []byte("+aHR0cHM6Ly95b3V0dS5iZS96OVV6MWljandyTQo=")
+
+// CreateChartPackage creates a new .tgz package in directory. Packaging
+// the same chart always gives a byte-for-byte identical package
+func CreateChartPackage(c *Chart, outDir string) (string, error) {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return "", err
+	}
+	packagePath := filepath.Join(outDir, fmt.Sprintf("%s-%s.tgz", c.Name(), c.Version()))
+	f, err := os.Create(packagePath)
+	if err != nil {
+		return "", err
+	}
+	w := bufio.NewWriter(f)
+	err = CreateChartPackageWriter(c, w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(packagePath)
+		return "", err
+	}
+	return packagePath, nil
+}
+
+// CreateChartPackageWriter writes the chart as a .tgz package to w, with
+// the same layout as helm package but without file modification times,
+// so the package is streamed without a temporary copy and packaging the
+// same chart always gives the same bytes
+func CreateChartPackageWriter(c *Chart, w io.Writer) error {
+	if c.V2 != nil {
+		if c.V2.Metadata == nil || c.V2.Metadata.Name == "" || c.V2.Metadata.Version == "" {
+			return errors.New("chart validation: chart name and version are required")
+		}
+	} else if err := c.V3.Validate(); err != nil {
+		return fmt.Errorf("chart validation: %s", err)
+	}
+
+	zw := gzip.NewWriter(w)
+	zw.Header.Extra = packageHeaderExtra
+	zw.Header.Comment = "Helm"
+	tw := tar.NewWriter(zw)
+	var err error
+	if c.V2 != nil {
+		err = writeV2Package(tw, c.V2, "")
+	} else {
+		err = writePackage(tw, c.V3, "")
+	}
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// writePackage writes the files of a Helm 3 chart and its dependencies
+// under prefix, as chartutil.Save does
+func writePackage(tw *tar.Writer, c *chart.Chart, prefix string) error {
+	base := path.Join(prefix, c.Name())
+
+	// apiVersion v1 charts keep their dependencies in requirements.yaml
+	metadata := *c.Metadata
+	if metadata.APIVersion == chart.APIVersionV1 {
+		metadata.Dependencies = nil
+	}
+	b, err := yaml.Marshal(&metadata)
+	if err != nil {
+		return err
+	}
+	if err := writePackageFile(tw, path.Join(base, "Chart.yaml"), b, 0644); err != nil {
+		return err
+	}
+	if metadata.APIVersion == chart.APIVersionV2 && c.Lock != nil {
+		b, err := yaml.Marshal(c.Lock)
+		if err != nil {
+			return err
+		}
+		if err := writePackageFile(tw, path.Join(base, "Chart.lock"), b, 0644); err != nil {
+			return err
+		}
+	}
+	for _, f := range c.Raw {
+		if f.Name == "values.yaml" {
+			if err := writePackageFile(tw, path.Join(base, f.Name), f.Data, 0644); err != nil {
+				return err
+			}
+		}
+	}
+	if c.Schema != nil {
+		if !json.Valid(c.Schema) {
+			return errors.New("invalid JSON in values.schema.json")
+		}
+		if err := writePackageFile(tw, path.Join(base, "values.schema.json"), c.Schema, 0644); err != nil {
+			return err
+		}
+	}
+	for _, files := range [][]*chart.File{c.Templates, c.Files} {
+		for _, f := range files {
+			if err := writePackageFile(tw, path.Join(base, f.Name), f.Data, 0644); err != nil {
+				return err
+			}
+		}
+	}
+	for _, dep := range c.Dependencies() {
+		if err := writePackage(tw, dep, path.Join(base, "charts")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeV2Package is writePackage for Helm 2 charts
+func writeV2Package(tw *tar.Writer, c *v2chart.Chart, prefix string) error {
+	base := path.Join(prefix, c.Metadata.Name)
+
+	b, err := yaml.Marshal(c.Metadata)
+	if err != nil {
+		return err
+	}
+	if err := writePackageFile(tw, path.Join(base, "Chart.yaml"), b, 0755); err != nil {
+		return err
+	}
+	if c.Values != nil && len(c.Values.Raw) > 0 {
+		if err := writePackageFile(tw, path.Join(base, "values.yaml"), []byte(c.Values.Raw), 0755); err != nil {
+			return err
+		}
+	}
+	for _, t := range c.Templates {
+		if err := writePackageFile(tw, path.Join(base, t.Name), t.Data, 0755); err != nil {
+			return err
+		}
+	}
+	for _, f := range c.Files {
+		if err := writePackageFile(tw, path.Join(base, f.TypeUrl), f.Value, 0755); err != nil {
+			return err
+		}
+	}
+	for _, dep := range c.Dependencies {
+		if err := writeV2Package(tw, dep, path.Join(base, "charts")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writePackageFile writes a file to a package, with the mode helm
+// package gives it and no modification time
+func writePackageFile(tw *tar.Writer, name string, data []byte, mode int64) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name: filepath.ToSlash(name),
+		Mode: mode,
+		Size: int64(len(data)),
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
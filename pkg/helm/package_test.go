@@ -0,0 +1,64 @@
+package helm
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateChartPackageWriter(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	// the package is written straight to w: creating a temp file fails
+	defer os.Setenv("TMPDIR", os.Getenv("TMPDIR"))
+	os.Setenv("TMPDIR", filepath.Join(tmp, "missing"))
+	outDir := filepath.Join(tmp, "out")
+
+	for _, chartPath := range []string{testTarballPath, "../../testdata/charts/helm3/my-v3-chart"} {
+		c, err := GetChartByName(chartPath)
+		if err != nil {
+			t.Fatal("unexpected error getting chart", err)
+		}
+		var b bytes.Buffer
+		if err := CreateChartPackageWriter(c, &b); err != nil {
+			t.Fatalf("%s: unexpected error writing chart package: %s", chartPath, err)
+		}
+		chartPackagePath, err := CreateChartPackage(c, outDir)
+		if err != nil {
+			t.Fatalf("%s: unexpected error creating chart package: %s", chartPath, err)
+		}
+		if files, _ := os.ReadDir(outDir); len(files) != 1 {
+			t.Errorf("%s: expected only the package in the output dir, instead found %d files", chartPath, len(files))
+		}
+		written, _ := os.ReadFile(chartPackagePath)
+		os.Remove(chartPackagePath)
+		if !bytes.Equal(b.Bytes(), written) {
+			t.Errorf("%s: expected the same package written to a file and to a writer", chartPath)
+		}
+
+		streamed := filepath.Join(tmp, "streamed.tgz")
+		os.WriteFile(streamed, b.Bytes(), 0644)
+		loaded, err := GetChartByName(streamed)
+		if err != nil || loaded.Name() != c.Name() || loaded.Version() != c.Version() {
+			t.Errorf("%s: unexpected error loading streamed package: %v", chartPath, err)
+		}
+	}
+
+	c, _ := GetChartByName("../../testdata/charts/helm3/my-v3-chart")
+	c.SetVersion("")
+	if err := CreateChartPackageWriter(c, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "chart validation") {
+		t.Errorf("expected validation error for a chart without version, instead got %v", err)
+	}
+	if _, err := CreateChartPackage(c, tmp); err == nil {
+		t.Error("expected error creating a package for a chart without version")
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "my-v3-chart-.tgz")); !os.IsNotExist(err) {
+		t.Errorf("expected no package left after a failure, instead got %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package helm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+var (
+	// ErrProvenanceSignature is returned when the signature of a
+	// provenance file can't be verified against the keyring
+	ErrProvenanceSignature = errors.New("provenance signature mismatch")
+
+	// ErrProvenanceDigest is returned when the digest in a provenance
+	// file doesn't match the chart package
+	ErrProvenanceDigest = errors.New("provenance digest mismatch")
+)
+
+// ProvenanceFile returns the path of the provenance file next to the
+// .tgz package at path, or "" if there is none
+func ProvenanceFile(path string) string {
+	provPath := path + ".prov"
+	if fi, err := os.Stat(provPath); err != nil || fi.IsDir() {
+		return ""
+	}
+	return provPath
+}
+
+// VerifyChartPackage verifies the provenance file at provPath for the
+// .tgz package at path, checking its signature against the public keys
+// in keyring and then the package digest
+func VerifyChartPackage(path string, provPath string, keyring string) error {
+	sig, err := provenance.NewFromKeyring(keyring, "")
+	if err != nil {
+		return fmt.Errorf("could not load keyring %s: %s", keyring, err)
+	}
+
+	// The signature is checked first, so the signer is only
+	// known if it's the digest that doesn't match
+	ver, err := sig.Verify(path, provPath)
+	if err != nil {
+		if ver == nil || ver.SignedBy == nil {
+			return fmt.Errorf("%w: %s: %s", ErrProvenanceSignature, provPath, err)
+		}
+		return fmt.Errorf("%w: %s: %s", ErrProvenanceDigest, provPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package helm
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+var (
+	testKeyringPath = "../../testdata/pgp/helm-test-key.pub"
+	testSecretPath  = "../../testdata/pgp/helm-test-key.secret"
+)
+
+// signTestPackage copies the test chart package to dir and signs it
+// with the test key, returning the paths of the package and .prov file
+func signTestPackage(t *testing.T, dir string) (string, string) {
+	b, err := os.ReadFile(testTarballPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, filepath.Base(testTarballPath))
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := provenance.NewFromKeyring(testSecretPath, "helm-testing@helm.sh")
+	if err != nil {
+		t.Fatal("unexpected error loading signing key", err)
+	}
+	prov, err := signer.ClearSign(path)
+	if err != nil {
+		t.Fatal("unexpected error signing chart package", err)
+	}
+	if err := os.WriteFile(path+".prov", []byte(prov), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path, path + ".prov"
+}
+
+func TestProvenanceFile(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if provPath := ProvenanceFile(testTarballPath); provPath != "" {
+		t.Errorf("expected no provenance file for unsigned package, instead got %s", provPath)
+	}
+	path, expected := signTestPackage(t, tmp)
+	if provPath := ProvenanceFile(path); provPath != expected {
+		t.Errorf("expected provenance file %s, instead got %s", expected, provPath)
+	}
+}
+
+func TestVerifyChartPackage(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	path, provPath := signTestPackage(t, tmp)
+	if err := VerifyChartPackage(path, provPath, testKeyringPath); err != nil {
+		t.Fatal("unexpected error verifying signed package", err)
+	}
+
+	if err := VerifyChartPackage(path, provPath, filepath.Join(tmp, "nonexistant.gpg")); err == nil || !strings.Contains(err.Error(), "could not load keyring") {
+		t.Errorf("expected keyring error, instead got %v", err)
+	}
+
+	// Changing the package after signing breaks the digest
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("tampered"))
+	f.Close()
+	if err := VerifyChartPackage(path, provPath, testKeyringPath); !errors.Is(err, ErrProvenanceDigest) {
+		t.Errorf("expected digest mismatch, instead got %v", err)
+	}
+
+	// Changing the signed metadata breaks the signature
+	path, provPath = signTestPackage(t, tmp)
+	b, err := os.ReadFile(provPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = []byte(strings.Replace(string(b), "name: mychart", "name: theirchart", 1))
+	if err := os.WriteFile(provPath, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyChartPackage(path, provPath, testKeyringPath); !errors.Is(err, ErrProvenanceSignature) {
+		t.Errorf("expected signature mismatch, instead got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package helm
+
+import (
+	"errors"
+
+	"github.com/ghodss/yaml"
+	"github.com/golang/protobuf/proto"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	v2chartutil "k8s.io/helm/pkg/chartutil"
+	v2chart "k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/renderutil"
+)
+
+var (
+	// ErrLibraryChart is returned by Render for library charts, which
+	// have no templates to render on their own
+	ErrLibraryChart = errors.New("library charts cannot be rendered")
+
+	// renderRelease is the release the templates are rendered for, the
+	// same as helm template's
+	renderRelease = chartutil.ReleaseOptions{
+		Name:      "release-name",
+		Namespace: "default",
+		Revision:  1,
+		IsInstall: true,
+	}
+)
+
+// Render renders the templates of the chart and its subcharts, with vals
+// overriding values.yaml, as helm template would but without contacting
+// a cluster. Only template errors are reported, the manifests are dropped.
+// The chart itself is left unchanged
+func (c *Chart) Render(vals map[string]interface{}) error {
+	if c.V2 != nil {
+		raw, err := yaml.Marshal(vals)
+		if err != nil {
+			return err
+		}
+		if vals == nil {
+			raw = []byte("{}")
+		}
+		ch := proto.Clone(c.V2).(*v2chart.Chart)
+		_, err = renderutil.Render(ch, &v2chart.Config{Raw: string(raw)}, renderutil.Options{
+			ReleaseOptions: v2chartutil.ReleaseOptions{
+				Name:      renderRelease.Name,
+				Namespace: renderRelease.Namespace,
+				Revision:  renderRelease.Revision,
+				IsInstall: renderRelease.IsInstall,
+			},
+		})
+		return err
+	}
+
+	if c.IsLibrary() {
+		return ErrLibraryChart
+	}
+	if vals == nil {
+		vals = map[string]interface{}{}
+	}
+	ch := copyChart(c.V3)
+	if err := chartutil.ProcessDependencies(ch, vals); err != nil {
+		return err
+	}
+	values, err := chartutil.ToRenderValues(ch, vals, renderRelease, nil)
+	if err != nil {
+		return err
+	}
+	_, err = engine.Render(ch, values)
+	return err
+}
+
+// copyChart copies the parts of a chart that rendering changes: the
+// metadata, values and subcharts
+func copyChart(c *chart.Chart) *chart.Chart {
+	cp := *c
+	if c.Metadata != nil {
+		md := *c.Metadata
+		md.Dependencies = make([]*chart.Dependency, len(c.Metadata.Dependencies))
+		for i, d := range c.Metadata.Dependencies {
+			dep := *d
+			md.Dependencies[i] = &dep
+		}
+		cp.Metadata = &md
+	}
+	var deps []*chart.Chart
+	for _, d := range c.Dependencies() {
+		deps = append(deps, copyChart(d))
+	}
+	cp.SetDependencies(deps...)
+	return &cp
+}
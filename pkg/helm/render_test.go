@@ -0,0 +1,84 @@
+package helm
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeChartFiles(t *testing.T, files map[string]string) string {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	for name, content := range files {
+		path := filepath.Join(tmp, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tmp
+}
+
+func TestRender(t *testing.T) {
+	deps := "dependencies:\n- name: sub\n  version: 0.1.0\n  condition: sub.enabled\n"
+	for _, apiVersion := range []string{"v1", "v2"} {
+		files := map[string]string{
+			"Chart.yaml":                   "apiVersion: " + apiVersion + "\nname: mychart\nversion: 0.1.0\n",
+			"values.yaml":                  "sub:\n  enabled: true\n",
+			"templates/cm.yaml":            "kind: ConfigMap\nname: {{ .Release.Name }}\n",
+			"charts/sub/Chart.yaml":        "apiVersion: v1\nname: sub\nversion: 0.1.0\n",
+			"charts/sub/templates/cm.yaml": "kind: ConfigMap\nimage: {{ required \"image is required\" .Values.image }}\n",
+		}
+		if apiVersion == "v1" {
+			files["requirements.yaml"] = deps
+		} else {
+			files["Chart.yaml"] += deps
+		}
+		tmp := writeChartFiles(t, files)
+		defer os.RemoveAll(tmp)
+
+		c, err := GetChartByName(tmp)
+		if err != nil {
+			t.Fatal("unexpected error loading chart", err)
+		}
+
+		err = c.Render(nil)
+		if err == nil || !strings.Contains(err.Error(), "mychart/charts/sub/templates/cm.yaml:2") || !strings.Contains(err.Error(), "image is required") {
+			t.Errorf("%s: expected missing value error with template path and line, instead got %v", apiVersion, err)
+		}
+		if err := c.Render(map[string]interface{}{"sub": map[string]interface{}{"image": "nginx"}}); err != nil {
+			t.Errorf("%s: unexpected error rendering with values set: %s", apiVersion, err)
+		}
+		if err := c.Render(map[string]interface{}{"sub": map[string]interface{}{"enabled": false}}); err != nil {
+			t.Errorf("%s: unexpected error rendering with subchart disabled: %s", apiVersion, err)
+		}
+
+		// disabling the subchart when rendering must not drop it from the chart
+		if c.V3 != nil && (len(c.V3.Dependencies()) != 1 || c.V3.Metadata.Dependencies[0].Enabled) {
+			t.Errorf("%s: expected chart to be unchanged by rendering", apiVersion)
+		}
+		if c.V2 != nil && len(c.V2.Dependencies) != 1 {
+			t.Errorf("%s: expected chart to be unchanged by rendering", apiVersion)
+		}
+	}
+}
+
+func TestRenderLibraryChart(t *testing.T) {
+	tmp := writeChartFiles(t, map[string]string{
+		"Chart.yaml":             "apiVersion: v2\nname: mylib\nversion: 0.1.0\ntype: library\n",
+		"templates/_helpers.tpl": "{{- define \"mylib.name\" -}}{{ .Chart.Name }}{{- end -}}\n",
+	})
+	defer os.RemoveAll(tmp)
+
+	c, err := GetChartByName(tmp)
+	if err != nil {
+		t.Fatal("unexpected error loading chart", err)
+	}
+	if err := c.Render(nil); !errors.Is(err, ErrLibraryChart) {
+		t.Errorf("expected library chart error, instead got %v", err)
+	}
+}
@@ -5,8 +5,10 @@ import (
 	urllib "net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/ghodss/yaml"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/repo"
@@ -19,18 +21,33 @@ type (
 	Repo struct {
 		*repo.ChartRepository
 	}
+
+	repoFileLocation struct {
+		path      string
+		cachePath string
+	}
+
+	loadedRepoFile struct {
+		*repo.File
+		repoFileLocation
+	}
+)
+
+var (
+	// helmHomeOverride is the directory given to SetHelmHome
+	helmHomeOverride string
+
+	// repoConfigOverride is the file given to SetRepoConfig
+	repoConfigOverride string
 )
 
-// GetRepoByName returns repository by name
+// GetRepoByName returns repository by name, from the first repositories
+// file which has it (see RepoFilePaths)
 func GetRepoByName(name string) (*Repo, error) {
-	r, err := repoFile()
+	entry, repoFile, err := findRepo(name)
 	if err != nil {
 		return nil, err
 	}
-	entry, exists := findRepoEntry(name, r)
-	if !exists {
-		return nil, fmt.Errorf("no repo named %q found", name)
-	}
 
 	settings := cli.New()
 	getters := getter.All(settings)
@@ -38,13 +55,68 @@ func GetRepoByName(name string) (*Repo, error) {
 	if err != nil {
 		return nil, err
 	}
+	cr.CachePath = repoFile.cachePath
+	return &Repo{cr}, nil
+}
 
-	if HelmMajorVersionCurrent() == HelmMajorVersion2 {
-		home := v2helmHome()
-		cr.CachePath = filepath.Join(home.Repository(), "cache")
+// GetRepoURL returns the URL of a locally configured repository by name
+func GetRepoURL(name string) (string, error) {
+	entry, _, err := findRepo(name)
+	if err != nil {
+		return "", err
 	}
+	return entry.URL, nil
+}
 
-	return &Repo{cr}, nil
+// GetRepoNames returns the sorted names of all locally configured
+// repositories, in any of the repositories files
+func GetRepoNames() ([]string, error) {
+	files, err := loadRepoFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var names []string
+	for _, f := range files {
+		for _, re := range f.Repositories {
+			if !seen[re.Name] {
+				seen[re.Name] = true
+				names = append(names, re.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// SetHelmHome makes the repositories only be looked up in dir, in the
+// Helm 2 ($HELM_HOME/repository/repositories.yaml) or Helm 3
+// (repositories.yaml) layout, instead of the default locations
+func SetHelmHome(dir string) {
+	helmHomeOverride = dir
+}
+
+// SetRepoConfig makes the repositories only be looked up in path, a
+// repositories file in either format, instead of the default locations
+// or the directory given to SetHelmHome
+func SetRepoConfig(path string) {
+	repoConfigOverride = path
+}
+
+// RepoFilePaths returns the repositories files repositories are looked
+// up in, in order: the one in $HELM_HOME for Helm 2, the Helm 3 one
+// ($HELM_REPOSITORY_CONFIG, or repositories.yaml in $XDG_CONFIG_HOME/helm
+// or the default Helm 3 config directory), then the one in the default
+// Helm 2 home. Only the file given to SetRepoConfig, or else the
+// directory given to SetHelmHome, is used if set
+func RepoFilePaths() []string {
+	files := repoFileLocations()
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.path
+	}
+	return paths
 }
 
 // TempRepoFromURL builds a temporary Repo from a given URL
@@ -70,27 +142,104 @@ func TempRepoFromURL(url string) (*Repo, error) {
 	return &Repo{cr}, nil
 }
 
-func repoFile() (*repo.File, error) {
-	var repoFilePath string
-	if HelmMajorVersionCurrent() == HelmMajorVersion2 {
-		home := v2helmHome()
-		repoFilePath = home.RepositoryFile()
-	} else {
-		settings := cli.New()
-		repoFilePath = settings.RepositoryConfig
+func repoFileLocations() []repoFileLocation {
+	if repoConfigOverride != "" {
+		return []repoFileLocation{{path: repoConfigOverride, cachePath: cli.New().RepositoryCache}}
 	}
-	repoFile, err := repo.LoadFile(repoFilePath)
-	return repoFile, err
+	if helmHomeOverride != "" {
+		home := v2helmpath.Home(helmHomeOverride)
+		return []repoFileLocation{
+			{path: home.RepositoryFile(), cachePath: filepath.Join(home.Repository(), "cache")},
+			{path: filepath.Join(helmHomeOverride, "repositories.yaml"), cachePath: filepath.Join(helmHomeOverride, "cache")},
+		}
+	}
+
+	var files []repoFileLocation
+	if v, ok := os.LookupEnv("HELM_HOME"); ok && v != "" {
+		home := v2helmpath.Home(v)
+		files = append(files, repoFileLocation{path: home.RepositoryFile(), cachePath: filepath.Join(home.Repository(), "cache")})
+	}
+	settings := cli.New()
+	files = append(files, repoFileLocation{path: settings.RepositoryConfig, cachePath: settings.RepositoryCache})
+	home := v2helmpath.Home(v2environment.DefaultHelmHome)
+	files = append(files, repoFileLocation{path: home.RepositoryFile(), cachePath: filepath.Join(home.Repository(), "cache")})
+	return files
 }
 
-func v2helmHome() v2helmpath.Home {
-	var helmHomePath string
-	if v, ok := os.LookupEnv("HELM_HOME"); ok {
-		helmHomePath = v
-	} else {
-		helmHomePath = v2environment.DefaultHelmHome
+// loadRepoFiles loads the repositories files which exist, in the order
+// of RepoFilePaths. It fails if none does
+func loadRepoFiles() ([]*loadedRepoFile, error) {
+	var files []*loadedRepoFile
+	for _, location := range repoFileLocations() {
+		if _, err := os.Stat(location.path); os.IsNotExist(err) {
+			continue
+		}
+		f, err := loadRepoFile(location.path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, &loadedRepoFile{File: f, repoFileLocation: location})
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no repositories file found in %s", strings.Join(RepoFilePaths(), ", "))
+	}
+	return files, nil
+}
+
+// loadRepoFile loads a repositories file, telling its format apart by
+// its apiVersion. Files written by Helm 2 and 3 have an apiVersion (v1,
+// or empty when Helm 3 created the file) and a repositories list, whose
+// Helm 2 only fields (the index cache file) are ignored. Files without
+// apiVersion are from before Helm 2.0.0-alpha.5, which only mapped repo
+// names to URLs
+func loadRepoFile(path string) (*repo.File, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load repositories file (%s): %s", path, err)
+	}
+	var header map[string]interface{}
+	if err := yaml.Unmarshal(b, &header); err != nil {
+		return nil, fmt.Errorf("invalid repositories file %s: %s", path, err)
+	}
+	_, hasAPIVersion := header["apiVersion"]
+	_, hasRepositories := header["repositories"]
+	if hasAPIVersion || hasRepositories || len(header) == 0 {
+		f := &repo.File{}
+		if err := yaml.Unmarshal(b, f); err != nil {
+			return nil, fmt.Errorf("invalid repositories file %s: %s", path, err)
+		}
+		return f, nil
+	}
+
+	urls := map[string]string{}
+	if err := yaml.Unmarshal(b, &urls); err != nil {
+		return nil, fmt.Errorf("invalid repositories file %s: %s", path, err)
+	}
+	names := make([]string, 0, len(urls))
+	for name := range urls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	f := repo.NewFile()
+	for _, name := range names {
+		f.Add(&repo.Entry{Name: name, URL: urls[name]})
+	}
+	return f, nil
+}
+
+// findRepo returns the entry of the named repository from the first
+// repositories file which has it
+func findRepo(name string) (*repo.Entry, *loadedRepoFile, error) {
+	files, err := loadRepoFiles()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, f := range files {
+		if entry, exists := findRepoEntry(name, f.File); exists {
+			return entry, f, nil
+		}
 	}
-	return v2helmpath.Home(helmHomePath)
+	return nil, nil, fmt.Errorf("no repo named %q found in %s", name, strings.Join(RepoFilePaths(), ", "))
 }
 
 func findRepoEntry(name string, r *repo.File) (*repo.Entry, bool) {
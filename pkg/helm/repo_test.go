@@ -1,8 +1,9 @@
 package helm
 
 import (
-	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"k8s.io/helm/pkg/getter"
@@ -23,7 +24,7 @@ func TestGetRepoByName(t *testing.T) {
 	}
 
 	// Create new Helm home w/ test repo
-	tmp, err := ioutil.TempDir("", "helm-push-test")
+	tmp, err := os.MkdirTemp("", "helm-push-test")
 	if err != nil {
 		t.Error("unexpected error creating temp test dir", err)
 	}
@@ -62,6 +63,203 @@ func TestGetRepoByName(t *testing.T) {
 
 }
 
+func TestGetRepoURL(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	home := helmpath.Home(tmp)
+	f := repo.NewRepoFile()
+	f.Update(&repo.Entry{Name: "helm-push-test", URL: "cm://localhost:8080", Username: "myuser"})
+	os.MkdirAll(home.Repository(), 0777)
+	f.WriteFile(home.RepositoryFile(), 0644)
+	os.Setenv("HELM_HOME", home.String())
+
+	url, err := GetRepoURL("helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error getting test repo URL", err)
+	}
+	if url != "cm://localhost:8080" {
+		t.Errorf("expected repo URL to be cm://localhost:8080, instead got %s", url)
+	}
+
+	if _, err := GetRepoURL("nonexistantrepo"); err == nil {
+		t.Error("expecting error with bad repo name, instead got nil")
+	}
+}
+
+func TestGetRepoNames(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Error("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	home := helmpath.Home(tmp)
+	f := repo.NewRepoFile()
+	for _, name := range []string{"zeta", "alpha", "mid"} {
+		f.Update(&repo.Entry{Name: name, URL: "http://localhost:8080/" + name})
+	}
+	os.MkdirAll(home.Repository(), 0777)
+	f.WriteFile(home.RepositoryFile(), 0644)
+	os.Setenv("HELM_HOME", home.String())
+
+	names, err := GetRepoNames()
+	if err != nil {
+		t.Error("unexpected error getting repo names", err)
+	}
+	if strings.Join(names, ",") != "alpha,mid,zeta" {
+		t.Errorf("expected sorted repo names, instead got %v", names)
+	}
+
+	// Err, missing repofile
+	os.RemoveAll(tmp)
+	_, err = GetRepoNames()
+	if err == nil {
+		t.Error("expecting error getting repo names after removed, instead got nil")
+	}
+}
+
+func TestRepoFileLookupOrder(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	writeRepoFile := func(path string, names ...string) {
+		f := repo.NewRepoFile()
+		for _, name := range names {
+			f.Update(&repo.Entry{Name: name, URL: "http://" + filepath.Base(filepath.Dir(path)) + "/" + name})
+		}
+		os.MkdirAll(filepath.Dir(path), 0777)
+		f.WriteFile(path, 0644)
+	}
+	v2home := helmpath.Home(filepath.Join(tmp, "v2"))
+	writeRepoFile(v2home.RepositoryFile(), "shared", "v2only")
+	writeRepoFile(filepath.Join(tmp, "xdg", "helm", "repositories.yaml"), "shared", "v3only")
+	writeRepoFile(filepath.Join(tmp, "override", "repositories.yaml"), "shared")
+
+	os.Setenv("HELM_HOME", v2home.String())
+	os.Setenv("XDG_CONFIG_HOME", filepath.Join(tmp, "xdg"))
+	os.Setenv("XDG_CACHE_HOME", filepath.Join(tmp, "cache"))
+	defer os.Unsetenv("XDG_CONFIG_HOME")
+	defer os.Unsetenv("XDG_CACHE_HOME")
+
+	paths := RepoFilePaths()
+	if len(paths) != 3 || paths[0] != v2home.RepositoryFile() || paths[1] != filepath.Join(tmp, "xdg", "helm", "repositories.yaml") {
+		t.Errorf("expected $HELM_HOME then $XDG_CONFIG_HOME repositories files, instead got %v", paths)
+	}
+	for name, expected := range map[string]string{
+		"shared": "http://repository/shared",
+		"v2only": "http://repository/v2only",
+		"v3only": "http://helm/v3only",
+	} {
+		if url, err := GetRepoURL(name); err != nil || url != expected {
+			t.Errorf("%s: expected %s, instead got %s %v", name, expected, url, err)
+		}
+	}
+	if r, err := GetRepoByName("v3only"); err != nil || r.CachePath != filepath.Join(tmp, "cache", "helm", "repository") {
+		t.Errorf("expected Helm 3 cache path, instead got %v %v", r, err)
+	}
+	if names, err := GetRepoNames(); err != nil || strings.Join(names, ",") != "shared,v2only,v3only" {
+		t.Errorf("expected the repo names of both files, instead got %v %v", names, err)
+	}
+
+	// the Helm home given explicitly replaces the others
+	SetHelmHome(filepath.Join(tmp, "override"))
+	defer SetHelmHome("")
+	if url, err := GetRepoURL("shared"); err != nil || url != "http://override/shared" {
+		t.Errorf("expected repo from the --helm-home directory, instead got %s %v", url, err)
+	}
+	if _, err := GetRepoURL("v3only"); err == nil || !strings.Contains(err.Error(), `no repo named "v3only" found in `+filepath.Join(tmp, "override")) {
+		t.Errorf("expected error for a repo outside of the --helm-home directory, instead got %v", err)
+	}
+
+	// the repositories file given explicitly replaces the Helm home
+	SetRepoConfig(filepath.Join(tmp, "xdg", "helm", "repositories.yaml"))
+	defer SetRepoConfig("")
+	if paths := RepoFilePaths(); len(paths) != 1 || paths[0] != filepath.Join(tmp, "xdg", "helm", "repositories.yaml") {
+		t.Errorf("expected only the --repo-config file, instead got %v", paths)
+	}
+	if names, err := GetRepoNames(); err != nil || strings.Join(names, ",") != "shared,v3only" {
+		t.Errorf("expected the repo names of the --repo-config file, instead got %v %v", names, err)
+	}
+	if r, err := GetRepoByName("shared"); err != nil || r.Config.URL != "http://helm/shared" || r.CachePath != filepath.Join(tmp, "cache", "helm", "repository") {
+		t.Errorf("expected repo from the --repo-config file, instead got %v %v", r, err)
+	}
+	SetRepoConfig(filepath.Join(tmp, "missing.yaml"))
+	if _, err := GetRepoNames(); err == nil || !strings.Contains(err.Error(), "no repositories file found in "+filepath.Join(tmp, "missing.yaml")) {
+		t.Errorf("expected error for a missing --repo-config file, instead got %v", err)
+	}
+}
+
+func TestLoadRepoFile(t *testing.T) {
+	for _, tc := range []struct {
+		file     string
+		expected string
+	}{
+		{"helm2.yaml", "chartmuseum=https://charts.example.com user:pass,stable=https://charts.helm.sh/stable :"},
+		{"helm3.yaml", "chartmuseum=https://charts.example.com user:pass"},
+		{"legacy.yaml", "chartmuseum=https://charts.example.com :,stable=https://charts.helm.sh/stable :"},
+	} {
+		f, err := loadRepoFile(filepath.Join("../../testdata/repositories", tc.file))
+		if err != nil {
+			t.Errorf("%s: unexpected error loading repositories file: %s", tc.file, err)
+			continue
+		}
+		var entries []string
+		for _, e := range f.Repositories {
+			entries = append(entries, e.Name+"="+e.URL+" "+e.Username+":"+e.Password)
+		}
+		if strings.Join(entries, ",") != tc.expected {
+			t.Errorf("%s: expected repositories %s, instead got %s", tc.file, tc.expected, strings.Join(entries, ","))
+		}
+	}
+
+	f, err := loadRepoFile("../../testdata/repositories/helm3.yaml")
+	if err != nil || f.Repositories[0].CAFile != "/etc/ssl/ca.crt" || !f.Repositories[0].InsecureSkipTLSverify {
+		t.Errorf("expected the Helm 3 TLS settings, instead got %+v %v", f, err)
+	}
+	if _, err := loadRepoFile("../../testdata/repositories/missing.yaml"); err == nil {
+		t.Error("expected error loading a missing repositories file, instead got nil")
+	}
+}
+
+func TestGetRepoByNameFileFormats(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+	defer SetHelmHome("")
+
+	// each format, in the Helm 2 and Helm 3 layouts
+	for _, file := range []string{"helm2.yaml", "helm3.yaml", "legacy.yaml"} {
+		b, err := os.ReadFile(filepath.Join("../../testdata/repositories", file))
+		if err != nil {
+			t.Fatal("unexpected error reading test repositories file", err)
+		}
+		for _, layout := range []string{"repository/repositories.yaml", "repositories.yaml"} {
+			os.MkdirAll(filepath.Join(tmp, file, filepath.Dir(layout)), 0755)
+			os.WriteFile(filepath.Join(tmp, file, layout), b, 0644)
+			SetHelmHome(filepath.Join(tmp, file))
+
+			r, err := GetRepoByName("chartmuseum")
+			if err != nil {
+				t.Errorf("%s in %s: unexpected error getting repo: %s", file, layout, err)
+				continue
+			}
+			if r.Config.URL != "https://charts.example.com" {
+				t.Errorf("%s in %s: unexpected repo URL %s", file, layout, r.Config.URL)
+			}
+			os.Remove(filepath.Join(tmp, file, layout))
+		}
+	}
+}
+
 func TestTempRepoFromURL(t *testing.T) {
 	url := "https://my.chart.repo.com"
 	repo, err := TempRepoFromURL(url)
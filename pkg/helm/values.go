@@ -0,0 +1,96 @@
+package helm
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+type (
+	// SchemaViolation is a value in a chart's values.yaml which doesn't
+	// match its values.schema.json
+	SchemaViolation struct {
+		// Chart is the path of the chart, e.g. "mychart/charts/mysubchart"
+		// for a subchart
+		Chart string
+		// Path is the JSON pointer to the value, "" for the root
+		Path    string
+		Message string
+	}
+)
+
+// String returns the chart, JSON pointer and message of the violation
+func (v SchemaViolation) String() string {
+	p := v.Path
+	if p == "" {
+		p = "(root)"
+	}
+	return fmt.Sprintf("%s: %s: %s", v.Chart, p, v.Message)
+}
+
+// ValidateValues validates the values.yaml of the chart, and of each
+// subchart in charts/, against their own values.schema.json. Charts
+// without a schema are not validated, nor are Helm 2 charts
+func (c *Chart) ValidateValues() ([]SchemaViolation, error) {
+	if c.V2 != nil {
+		return nil, nil
+	}
+	violations, err := validateChartValues(c.V3, c.V3.Name())
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Chart < violations[j].Chart
+	})
+	return violations, nil
+}
+
+func validateChartValues(ch *chart.Chart, chartPath string) ([]SchemaViolation, error) {
+	var violations []SchemaViolation
+	if ch.Schema != nil {
+		values, err := json.Marshal(ch.Values)
+		if err != nil {
+			return nil, err
+		}
+		if string(values) == "null" {
+			values = []byte("{}")
+		}
+		result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(ch.Schema), gojsonschema.NewBytesLoader(values))
+		if err != nil {
+			return nil, fmt.Errorf("could not validate values of %s: %s", chartPath, err)
+		}
+		for _, e := range result.Errors() {
+			violations = append(violations, SchemaViolation{
+				Chart:   chartPath,
+				Path:    jsonPointer(e),
+				Message: e.Description(),
+			})
+		}
+	}
+
+	for _, sub := range ch.Dependencies() {
+		subViolations, err := validateChartValues(sub, path.Join(chartPath, "charts", sub.Name()))
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, subViolations...)
+	}
+	return violations, nil
+}
+
+// jsonPointer returns the JSON pointer to the value of a schema error.
+// For missing required properties, it points at the property
+func jsonPointer(e gojsonschema.ResultError) string {
+	p := strings.TrimPrefix(e.Context().String("/"), gojsonschema.STRING_CONTEXT_ROOT)
+	if e.Type() == "required" {
+		if property, ok := e.Details()["property"].(string); ok {
+			p += "/" + property
+		}
+	}
+	return p
+}
@@ -0,0 +1,84 @@
+package helm
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestValidateValues(t *testing.T) {
+	tmp, err := os.MkdirTemp("", "helm-push-test")
+	if err != nil {
+		t.Fatal("unexpected error creating temp test dir", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	schema := `{
+  "type": "object",
+  "required": ["image"],
+  "properties": {
+    "replicas": {"type": "integer"},
+    "image": {"type": "object", "required": ["tag"], "properties": {"tag": {"type": "string"}}}
+  }
+}`
+	for name, content := range map[string]string{
+		"Chart.yaml":                        "apiVersion: v2\nname: mychart\nversion: 0.1.0\n",
+		"values.yaml":                       "replicas: one\nimage:\n  repository: nginx\n",
+		"values.schema.json":                schema,
+		"templates/cm.yaml":                 "kind: ConfigMap\n",
+		"charts/sub/Chart.yaml":             "apiVersion: v2\nname: sub\nversion: 0.1.0\n",
+		"charts/sub/values.yaml":            "replicas: 1\n",
+		"charts/sub/values.schema.json":     schema,
+		"charts/sub/templates/cm.yaml":      "kind: ConfigMap\n",
+		"charts/noschema/Chart.yaml":        "apiVersion: v2\nname: noschema\nversion: 0.1.0\n",
+		"charts/noschema/values.yaml":       "replicas: one\n",
+		"charts/noschema/templates/cm.yaml": "kind: ConfigMap\n",
+	} {
+		path := filepath.Join(tmp, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := GetChartByName(tmp)
+	if err != nil {
+		t.Fatal("unexpected error loading chart", err)
+	}
+	violations, err := c.ValidateValues()
+	if err != nil {
+		t.Fatal("unexpected error validating values", err)
+	}
+	var paths []string
+	for _, v := range violations {
+		paths = append(paths, v.Chart+"#"+v.Path)
+	}
+	sort.Strings(paths)
+	if expected := []string{"mychart#/image/tag", "mychart#/replicas", "mychart/charts/sub#/image"}; !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected violations at %v, instead got %v", expected, violations)
+	}
+
+	// Charts without a schema, and Helm 2 charts, are not validated
+	for _, chartPath := range []string{"../../testdata/charts/helm3/my-v3-chart", testTarballPath} {
+		c, err := GetChartByName(chartPath)
+		if err != nil {
+			t.Fatal("unexpected error loading chart", err)
+		}
+		if violations, err := c.ValidateValues(); err != nil || len(violations) != 0 {
+			t.Errorf("%s: expected no violations, instead got %v, %v", chartPath, violations, err)
+		}
+	}
+}
+
+func TestSchemaViolationString(t *testing.T) {
+	v := SchemaViolation{Chart: "mychart", Path: "/replicas", Message: "Invalid type. Expected: integer, given: string"}
+	if s := v.String(); s != "mychart: /replicas: Invalid type. Expected: integer, given: string" {
+		t.Errorf("unexpected violation string %q", s)
+	}
+	v = SchemaViolation{Chart: "mychart", Message: "Invalid type. Expected: object, given: array"}
+	if s := v.String(); s != "mychart: (root): Invalid type. Expected: object, given: array" {
+		t.Errorf("unexpected violation string %q", s)
+	}
+}
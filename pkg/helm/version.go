@@ -1,7 +1,6 @@
 package helm
 
 import (
-	"os"
 	"os/exec"
 )
 
@@ -22,11 +21,7 @@ func HelmMajorVersionCurrent() HelmMajorVersion {
 	if helmMajorVersionCurrent != 0 {
 		return helmMajorVersionCurrent
 	}
-	helmBin, helmBinVarSet := os.LookupEnv("HELM_BIN")
-	if !helmBinVarSet {
-		helmBin = "helm"
-	}
-	helmVersion2CheckCmd := exec.Command(helmBin, "version", "-c", "--tls")
+	helmVersion2CheckCmd := exec.Command(helmBin(), "version", "-c", "--tls")
 	err := helmVersion2CheckCmd.Run()
 	if e, ok := err.(*exec.ExitError); ok && !e.Success() {
 		helmMajorVersionCurrent = HelmMajorVersion3